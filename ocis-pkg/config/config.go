@@ -38,6 +38,7 @@ import (
 	web "github.com/owncloud/ocis/v2/services/web/pkg/config"
 	webdav "github.com/owncloud/ocis/v2/services/webdav/pkg/config"
 	webfinger "github.com/owncloud/ocis/v2/services/webfinger/pkg/config"
+	webhooks "github.com/owncloud/ocis/v2/services/webhooks/pkg/config"
 )
 
 type Mode int
@@ -110,5 +111,6 @@ type Config struct {
 	Web               *web.Config            `yaml:"web"`
 	WebDAV            *webdav.Config         `yaml:"webdav"`
 	Webfinger         *webfinger.Config      `yaml:"webfinger"`
+	Webhooks          *webhooks.Config       `yaml:"webhooks"`
 	Search            *search.Config         `yaml:"search"`
 }
@@ -37,6 +37,7 @@ import (
 	web "github.com/owncloud/ocis/v2/services/web/pkg/config/defaults"
 	webdav "github.com/owncloud/ocis/v2/services/webdav/pkg/config/defaults"
 	webfinger "github.com/owncloud/ocis/v2/services/webfinger/pkg/config/defaults"
+	webhooks "github.com/owncloud/ocis/v2/services/webhooks/pkg/config/defaults"
 )
 
 func DefaultConfig() *Config {
@@ -83,5 +84,6 @@ func DefaultConfig() *Config {
 		Web:               web.DefaultConfig(),
 		WebDAV:            webdav.DefaultConfig(),
 		Webfinger:         webfinger.DefaultConfig(),
+		Webhooks:          webhooks.DefaultConfig(),
 	}
 }
@@ -0,0 +1,112 @@
+// Package event contains the events the ocis services exchange in addition to the events defined by reva.
+// They live in a shared package so consumers like the audit or userlog service don't depend on the producing service.
+package event
+
+import (
+	"encoding/json"
+	"time"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+)
+
+// StartRescan triggers a rescan of existing files
+type StartRescan struct {
+	ExecutantID *user.UserId
+	// SpaceID restricts the rescan to a single space
+	SpaceID string
+	// Force rescans files even if they were scanned with the current signatures already
+	Force bool
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (StartRescan) Unmarshal(v []byte) (interface{}, error) {
+	e := StartRescan{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
+
+// FileInfected is emitted when a rescan finds a virus in an existing file
+type FileInfected struct {
+	ResourceID  *provider.ResourceId
+	Filename    string
+	Owner       *user.User // owner or manager of the space the file belongs to
+	Description string
+	Member      string // path of the infected file inside an archive, if any
+	Signatures  string
+	Scandate    time.Time
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (FileInfected) Unmarshal(v []byte) (interface{}, error) {
+	e := FileInfected{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
+
+// FileQuarantined is emitted when an infected upload was moved to the quarantine
+type FileQuarantined struct {
+	ItemID        string
+	UploadID      string
+	ResourceID    *provider.ResourceId
+	Filename      string
+	ExecutingUser *user.UserId
+	Description   string
+	Member        string // path of the infected file inside an archive, if any
+	Timestamp     time.Time
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (FileQuarantined) Unmarshal(v []byte) (interface{}, error) {
+	e := FileQuarantined{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
+
+// QuarantinedFileDownloaded is emitted when an admin downloaded a quarantined file
+type QuarantinedFileDownloaded struct {
+	Executant  *user.UserId // nil when done via cli
+	ItemID     string
+	ResourceID *provider.ResourceId
+	Filename   string
+	Timestamp  time.Time
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (QuarantinedFileDownloaded) Unmarshal(v []byte) (interface{}, error) {
+	e := QuarantinedFileDownloaded{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
+
+// QuarantinedFileReleased is emitted when an admin released a quarantined file into its original location
+type QuarantinedFileReleased struct {
+	Executant  *user.UserId // nil when done via cli
+	ItemID     string
+	ResourceID *provider.ResourceId
+	Filename   string
+	Timestamp  time.Time
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (QuarantinedFileReleased) Unmarshal(v []byte) (interface{}, error) {
+	e := QuarantinedFileReleased{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
+
+// QuarantinedFilePurged is emitted when an admin purged a quarantined file
+type QuarantinedFilePurged struct {
+	Executant  *user.UserId // nil when done via cli
+	ItemID     string
+	ResourceID *provider.ResourceId
+	Filename   string
+	Timestamp  time.Time
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (QuarantinedFilePurged) Unmarshal(v []byte) (interface{}, error) {
+	e := QuarantinedFilePurged{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
@@ -0,0 +1,78 @@
+package event
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+)
+
+// Reason explains why the policies denied an action
+type Reason struct {
+	Rule    string
+	Message string
+}
+
+// String returns the message of the reason prefixed with its rule
+func (r Reason) String() string {
+	if r.Rule == "" {
+		return r.Message
+	}
+	return r.Rule + ": " + r.Message
+}
+
+// PoliciesResult is the Result of the PostprocessingStepFinished event of the policies step. It
+// explains why the policies deleted the uploaded file.
+type PoliciesResult struct {
+	Reasons []Reason
+}
+
+// DecodePoliciesResult returns the policies result of a PostprocessingStepFinished event. Reva only
+// decodes the results it knows, so the result usually arrives as decoded json.
+func DecodePoliciesResult(result interface{}) (PoliciesResult, error) {
+	switch r := result.(type) {
+	case nil:
+		return PoliciesResult{}, nil
+	case PoliciesResult:
+		return r, nil
+	case *PoliciesResult:
+		return *r, nil
+	}
+
+	b, err := json.Marshal(result)
+	if err != nil {
+		return PoliciesResult{}, err
+	}
+
+	var res PoliciesResult
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&res); err != nil {
+		return PoliciesResult{}, fmt.Errorf("unexpected policies result: %w", err)
+	}
+	return res, nil
+}
+
+// PolicyDenied is emitted when the policies deny an action. In audit only mode the denial was not enforced.
+type PolicyDenied struct {
+	Query      string
+	Stage      string
+	Executant  *user.UserId
+	ResourceID *provider.ResourceId
+	Filename   string
+	Method     string
+	Path       string
+	Reasons    []Reason
+	AuditOnly  bool
+	Timestamp  time.Time
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (PolicyDenied) Unmarshal(v []byte) (interface{}, error) {
+	e := PolicyDenied{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
@@ -0,0 +1,47 @@
+package event
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDecodePoliciesResult(t *testing.T) {
+	want := PoliciesResult{Reasons: []Reason{{Rule: "no_executables", Message: "executables are not allowed"}}}
+
+	b, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, result := range map[string]interface{}{"typed": want, "pointer": &want, "decoded json": decoded} {
+		t.Run(name, func(t *testing.T) {
+			got, err := DecodePoliciesResult(result)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+
+	t.Run("no result", func(t *testing.T) {
+		got, err := DecodePoliciesResult(nil)
+		if err != nil || len(got.Reasons) != 0 {
+			t.Errorf("got %v, %v, want an empty result", got, err)
+		}
+	})
+
+	t.Run("unexpected result", func(t *testing.T) {
+		for _, result := range []interface{}{[]interface{}{"a reason"}, map[string]interface{}{"Infected": true}} {
+			if _, err := DecodePoliciesResult(result); err == nil {
+				t.Errorf("decoding %v: expected an error", result)
+			}
+		}
+	})
+}
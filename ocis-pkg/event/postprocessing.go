@@ -0,0 +1,46 @@
+package event
+
+import (
+	"encoding/json"
+	"time"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/v2/pkg/events"
+)
+
+// SpaceStepsKey is the arbitrary metadata key of a space root holding the postprocessing steps of the space
+// as a json encoded list. It is written by the graph service and read by the postprocessing service.
+const SpaceStepsKey = "postprocessing.steps"
+
+// PostprocessingFailed is emitted when a postprocessing step timed out too often and the upload was aborted
+type PostprocessingFailed struct {
+	UploadID      string
+	ExecutingUser *user.User
+	Filename      string
+	ResourceID    *provider.ResourceId
+	FailedStep    events.Postprocessingstep
+	Retries       int
+	Timestamp     time.Time
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (PostprocessingFailed) Unmarshal(v []byte) (interface{}, error) {
+	e := PostprocessingFailed{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
+
+// ForcePostprocessingOutcome finishes the postprocessing of an upload with the given outcome
+type ForcePostprocessingOutcome struct {
+	UploadID  string
+	Outcome   events.PostprocessingOutcome
+	Timestamp time.Time
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (ForcePostprocessingOutcome) Unmarshal(v []byte) (interface{}, error) {
+	e := ForcePostprocessingOutcome{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
@@ -0,0 +1,28 @@
+package event
+
+import (
+	"encoding/json"
+	"time"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+)
+
+// SavedSearchMatched is emitted when an uploaded or updated file newly matches a saved search
+type SavedSearchMatched struct {
+	Owner           *user.UserId // owner of the saved search
+	SavedSearchID   string
+	SavedSearchName string
+	ResourceID      *provider.ResourceId
+	Filename        string
+	// Email asks the notifications service to send an email about the match as well
+	Email     bool
+	Timestamp time.Time
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (SavedSearchMatched) Unmarshal(v []byte) (interface{}, error) {
+	e := SavedSearchMatched{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
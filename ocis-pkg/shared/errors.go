@@ -38,6 +38,14 @@ func MissingRevaTransferSecretError(service string) error {
 		service, defaults.BaseConfigPath())
 }
 
+func MissingWebhooksCallbackSecretError(service string) error {
+	return fmt.Errorf("The callback_secret has not been set properly in your config for %s. "+
+		"Make sure your %s config contains the proper values "+
+		"(e.g. by running ocis init or setting it manually in "+
+		"the config/corresponding environment variable).",
+		service, defaults.BaseConfigPath())
+}
+
 func MissingLDAPBindPassword(service string) error {
 	return fmt.Errorf("The ldap bind_password has not been set properly in your config for %s. "+
 		"Make sure your %s config contains the proper values "+
@@ -44,12 +44,13 @@ import (
 	web "github.com/owncloud/ocis/v2/services/web/pkg/command"
 	webdav "github.com/owncloud/ocis/v2/services/webdav/pkg/command"
 	webfinger "github.com/owncloud/ocis/v2/services/webfinger/pkg/command"
+	webhooks "github.com/owncloud/ocis/v2/services/webhooks/pkg/command"
 )
 
 var svccmds = []register.Command{
 	func(cfg *config.Config) *cli.Command {
 		return ServiceCommand(cfg, cfg.Antivirus.Service.Name, antivirus.GetCommands(cfg.Antivirus), func(c *config.Config) {
-			// cfg.Antivirus.Commons = cfg.Commons // antivirus needs no commons atm
+			cfg.Antivirus.Commons = cfg.Commons
 		})
 	},
 	func(cfg *config.Config) *cli.Command {
@@ -227,6 +228,11 @@ var svccmds = []register.Command{
 			cfg.Webfinger.Commons = cfg.Commons
 		})
 	},
+	func(cfg *config.Config) *cli.Command {
+		return ServiceCommand(cfg, cfg.Webhooks.Service.Name, webhooks.GetCommands(cfg.Webhooks), func(c *config.Config) {
+			cfg.Webhooks.Commons = cfg.Commons
+		})
+	},
 }
 
 // ServiceCommand is the entry point for the all service commands.
@@ -100,6 +100,10 @@ type ThumbnailService struct {
 	Thumbnail ThumbnailSettings
 }
 
+type WebhooksService struct {
+	CallbackSecret string `yaml:"callback_secret"`
+}
+
 type Search struct {
 	Events Events
 }
@@ -166,6 +170,7 @@ type OcisConfig struct {
 	Groups            UsersAndGroupsService
 	Ocdav             InsecureService
 	Thumbnails        ThumbnailService
+	Webhooks          WebhooksService
 	Search            Search
 	Audit             Audit
 	Sharing           Sharing
@@ -267,6 +272,11 @@ func CreateConfig(insecure, forceOverwrite bool, configPath, adminPassword strin
 		return fmt.Errorf("could not generate random password for thumbnailsTransferSecret: %s", err)
 	}
 
+	webhooksCallbackSecret, err := generators.GenerateRandomPassword(passwordLength)
+	if err != nil {
+		return fmt.Errorf("could not generate random password for webhooksCallbackSecret: %s", err)
+	}
+
 	cfg := OcisConfig{
 		TokenManager: TokenManager{
 			JWTSecret: tokenManagerJwtSecret,
@@ -325,6 +335,9 @@ func CreateConfig(insecure, forceOverwrite bool, configPath, adminPassword strin
 				TransferSecret: thumbnailsTransferSecret,
 			},
 		},
+		Webhooks: WebhooksService{
+			CallbackSecret: webhooksCallbackSecret,
+		},
 		Gateway: Gateway{
 			StorageRegistry: StorageRegistry{
 				StorageUsersMountID: storageUsersMountID,
@@ -53,6 +53,7 @@ import (
 	web "github.com/owncloud/ocis/v2/services/web/pkg/command"
 	webdav "github.com/owncloud/ocis/v2/services/webdav/pkg/command"
 	webfinger "github.com/owncloud/ocis/v2/services/webfinger/pkg/command"
+	webhooks "github.com/owncloud/ocis/v2/services/webhooks/pkg/command"
 	"github.com/thejerf/suture/v4"
 )
 
@@ -265,7 +266,7 @@ func NewService(options ...Option) (*Service, error) {
 	}
 	areg(opts.Config.Antivirus.Service.Name, func(ctx context.Context, cfg *ociscfg.Config) error {
 		cfg.Antivirus.Context = ctx
-		// cfg.Antivirus.Commons = cfg.Commons // antivirus holds no Commons atm
+		cfg.Antivirus.Commons = cfg.Commons
 		return antivirus.Execute(cfg.Antivirus)
 	})
 	areg(opts.Config.Audit.Service.Name, func(ctx context.Context, cfg *ociscfg.Config) error {
@@ -278,6 +279,11 @@ func NewService(options ...Option) (*Service, error) {
 		cfg.Policies.Commons = cfg.Commons
 		return policies.Execute(cfg.Policies)
 	})
+	areg(opts.Config.Webhooks.Service.Name, func(ctx context.Context, cfg *ociscfg.Config) error {
+		cfg.Webhooks.Context = ctx
+		cfg.Webhooks.Commons = cfg.Commons
+		return webhooks.Execute(cfg.Webhooks)
+	})
 
 	// populate delayed services
 	dreg := func(name string, exec func(context.Context, *ociscfg.Config) error) {
@@ -118,6 +118,186 @@ func (m *Entity) UnmarshalJSON(b []byte) error {
 
 var _ json.Unmarshaler = (*Entity)(nil)
 
+// AudioJSONMarshaler describes the default jsonpb.Marshaler used by all
+// instances of Audio. This struct is safe to replace or modify but
+// should not be done so concurrently.
+var AudioJSONMarshaler = new(jsonpb.Marshaler)
+
+// MarshalJSON satisfies the encoding/json Marshaler interface. This method
+// uses the more correct jsonpb package to correctly marshal the message.
+func (m *Audio) MarshalJSON() ([]byte, error) {
+	if m == nil {
+		return json.Marshal(nil)
+	}
+
+	buf := &bytes.Buffer{}
+
+	if err := AudioJSONMarshaler.Marshal(buf, m); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+var _ json.Marshaler = (*Audio)(nil)
+
+// AudioJSONUnmarshaler describes the default jsonpb.Unmarshaler used by all
+// instances of Audio. This struct is safe to replace or modify but
+// should not be done so concurrently.
+var AudioJSONUnmarshaler = new(jsonpb.Unmarshaler)
+
+// UnmarshalJSON satisfies the encoding/json Unmarshaler interface. This method
+// uses the more correct jsonpb package to correctly unmarshal the message.
+func (m *Audio) UnmarshalJSON(b []byte) error {
+	return AudioJSONUnmarshaler.Unmarshal(bytes.NewReader(b), m)
+}
+
+var _ json.Unmarshaler = (*Audio)(nil)
+
+// ImageJSONMarshaler describes the default jsonpb.Marshaler used by all
+// instances of Image. This struct is safe to replace or modify but
+// should not be done so concurrently.
+var ImageJSONMarshaler = new(jsonpb.Marshaler)
+
+// MarshalJSON satisfies the encoding/json Marshaler interface. This method
+// uses the more correct jsonpb package to correctly marshal the message.
+func (m *Image) MarshalJSON() ([]byte, error) {
+	if m == nil {
+		return json.Marshal(nil)
+	}
+
+	buf := &bytes.Buffer{}
+
+	if err := ImageJSONMarshaler.Marshal(buf, m); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+var _ json.Marshaler = (*Image)(nil)
+
+// ImageJSONUnmarshaler describes the default jsonpb.Unmarshaler used by all
+// instances of Image. This struct is safe to replace or modify but
+// should not be done so concurrently.
+var ImageJSONUnmarshaler = new(jsonpb.Unmarshaler)
+
+// UnmarshalJSON satisfies the encoding/json Unmarshaler interface. This method
+// uses the more correct jsonpb package to correctly unmarshal the message.
+func (m *Image) UnmarshalJSON(b []byte) error {
+	return ImageJSONUnmarshaler.Unmarshal(bytes.NewReader(b), m)
+}
+
+var _ json.Unmarshaler = (*Image)(nil)
+
+// GeoCoordinatesJSONMarshaler describes the default jsonpb.Marshaler used by all
+// instances of GeoCoordinates. This struct is safe to replace or modify but
+// should not be done so concurrently.
+var GeoCoordinatesJSONMarshaler = new(jsonpb.Marshaler)
+
+// MarshalJSON satisfies the encoding/json Marshaler interface. This method
+// uses the more correct jsonpb package to correctly marshal the message.
+func (m *GeoCoordinates) MarshalJSON() ([]byte, error) {
+	if m == nil {
+		return json.Marshal(nil)
+	}
+
+	buf := &bytes.Buffer{}
+
+	if err := GeoCoordinatesJSONMarshaler.Marshal(buf, m); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+var _ json.Marshaler = (*GeoCoordinates)(nil)
+
+// GeoCoordinatesJSONUnmarshaler describes the default jsonpb.Unmarshaler used by all
+// instances of GeoCoordinates. This struct is safe to replace or modify but
+// should not be done so concurrently.
+var GeoCoordinatesJSONUnmarshaler = new(jsonpb.Unmarshaler)
+
+// UnmarshalJSON satisfies the encoding/json Unmarshaler interface. This method
+// uses the more correct jsonpb package to correctly unmarshal the message.
+func (m *GeoCoordinates) UnmarshalJSON(b []byte) error {
+	return GeoCoordinatesJSONUnmarshaler.Unmarshal(bytes.NewReader(b), m)
+}
+
+var _ json.Unmarshaler = (*GeoCoordinates)(nil)
+
+// PhotoJSONMarshaler describes the default jsonpb.Marshaler used by all
+// instances of Photo. This struct is safe to replace or modify but
+// should not be done so concurrently.
+var PhotoJSONMarshaler = new(jsonpb.Marshaler)
+
+// MarshalJSON satisfies the encoding/json Marshaler interface. This method
+// uses the more correct jsonpb package to correctly marshal the message.
+func (m *Photo) MarshalJSON() ([]byte, error) {
+	if m == nil {
+		return json.Marshal(nil)
+	}
+
+	buf := &bytes.Buffer{}
+
+	if err := PhotoJSONMarshaler.Marshal(buf, m); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+var _ json.Marshaler = (*Photo)(nil)
+
+// PhotoJSONUnmarshaler describes the default jsonpb.Unmarshaler used by all
+// instances of Photo. This struct is safe to replace or modify but
+// should not be done so concurrently.
+var PhotoJSONUnmarshaler = new(jsonpb.Unmarshaler)
+
+// UnmarshalJSON satisfies the encoding/json Unmarshaler interface. This method
+// uses the more correct jsonpb package to correctly unmarshal the message.
+func (m *Photo) UnmarshalJSON(b []byte) error {
+	return PhotoJSONUnmarshaler.Unmarshal(bytes.NewReader(b), m)
+}
+
+var _ json.Unmarshaler = (*Photo)(nil)
+
+// OfficeJSONMarshaler describes the default jsonpb.Marshaler used by all
+// instances of Office. This struct is safe to replace or modify but
+// should not be done so concurrently.
+var OfficeJSONMarshaler = new(jsonpb.Marshaler)
+
+// MarshalJSON satisfies the encoding/json Marshaler interface. This method
+// uses the more correct jsonpb package to correctly marshal the message.
+func (m *Office) MarshalJSON() ([]byte, error) {
+	if m == nil {
+		return json.Marshal(nil)
+	}
+
+	buf := &bytes.Buffer{}
+
+	if err := OfficeJSONMarshaler.Marshal(buf, m); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+var _ json.Marshaler = (*Office)(nil)
+
+// OfficeJSONUnmarshaler describes the default jsonpb.Unmarshaler used by all
+// instances of Office. This struct is safe to replace or modify but
+// should not be done so concurrently.
+var OfficeJSONUnmarshaler = new(jsonpb.Unmarshaler)
+
+// UnmarshalJSON satisfies the encoding/json Unmarshaler interface. This method
+// uses the more correct jsonpb package to correctly unmarshal the message.
+func (m *Office) UnmarshalJSON(b []byte) error {
+	return OfficeJSONUnmarshaler.Unmarshal(bytes.NewReader(b), m)
+}
+
+var _ json.Unmarshaler = (*Office)(nil)
+
 // MatchJSONMarshaler describes the default jsonpb.Marshaler used by all
 // instances of Match. This struct is safe to replace or modify but
 // should not be done so concurrently.
@@ -153,3 +333,111 @@ func (m *Match) UnmarshalJSON(b []byte) error {
 }
 
 var _ json.Unmarshaler = (*Match)(nil)
+
+// FacetJSONMarshaler describes the default jsonpb.Marshaler used by all
+// instances of Facet. This struct is safe to replace or modify but
+// should not be done so concurrently.
+var FacetJSONMarshaler = new(jsonpb.Marshaler)
+
+// MarshalJSON satisfies the encoding/json Marshaler interface. This method
+// uses the more correct jsonpb package to correctly marshal the message.
+func (m *Facet) MarshalJSON() ([]byte, error) {
+	if m == nil {
+		return json.Marshal(nil)
+	}
+
+	buf := &bytes.Buffer{}
+
+	if err := FacetJSONMarshaler.Marshal(buf, m); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+var _ json.Marshaler = (*Facet)(nil)
+
+// FacetJSONUnmarshaler describes the default jsonpb.Unmarshaler used by all
+// instances of Facet. This struct is safe to replace or modify but
+// should not be done so concurrently.
+var FacetJSONUnmarshaler = new(jsonpb.Unmarshaler)
+
+// UnmarshalJSON satisfies the encoding/json Unmarshaler interface. This method
+// uses the more correct jsonpb package to correctly unmarshal the message.
+func (m *Facet) UnmarshalJSON(b []byte) error {
+	return FacetJSONUnmarshaler.Unmarshal(bytes.NewReader(b), m)
+}
+
+var _ json.Unmarshaler = (*Facet)(nil)
+
+// FacetValueJSONMarshaler describes the default jsonpb.Marshaler used by all
+// instances of FacetValue. This struct is safe to replace or modify but
+// should not be done so concurrently.
+var FacetValueJSONMarshaler = new(jsonpb.Marshaler)
+
+// MarshalJSON satisfies the encoding/json Marshaler interface. This method
+// uses the more correct jsonpb package to correctly marshal the message.
+func (m *FacetValue) MarshalJSON() ([]byte, error) {
+	if m == nil {
+		return json.Marshal(nil)
+	}
+
+	buf := &bytes.Buffer{}
+
+	if err := FacetValueJSONMarshaler.Marshal(buf, m); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+var _ json.Marshaler = (*FacetValue)(nil)
+
+// FacetValueJSONUnmarshaler describes the default jsonpb.Unmarshaler used by all
+// instances of FacetValue. This struct is safe to replace or modify but
+// should not be done so concurrently.
+var FacetValueJSONUnmarshaler = new(jsonpb.Unmarshaler)
+
+// UnmarshalJSON satisfies the encoding/json Unmarshaler interface. This method
+// uses the more correct jsonpb package to correctly unmarshal the message.
+func (m *FacetValue) UnmarshalJSON(b []byte) error {
+	return FacetValueJSONUnmarshaler.Unmarshal(bytes.NewReader(b), m)
+}
+
+var _ json.Unmarshaler = (*FacetValue)(nil)
+
+// SavedSearchJSONMarshaler describes the default jsonpb.Marshaler used by all
+// instances of SavedSearch. This struct is safe to replace or modify but
+// should not be done so concurrently.
+var SavedSearchJSONMarshaler = new(jsonpb.Marshaler)
+
+// MarshalJSON satisfies the encoding/json Marshaler interface. This method
+// uses the more correct jsonpb package to correctly marshal the message.
+func (m *SavedSearch) MarshalJSON() ([]byte, error) {
+	if m == nil {
+		return json.Marshal(nil)
+	}
+
+	buf := &bytes.Buffer{}
+
+	if err := SavedSearchJSONMarshaler.Marshal(buf, m); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+var _ json.Marshaler = (*SavedSearch)(nil)
+
+// SavedSearchJSONUnmarshaler describes the default jsonpb.Unmarshaler used by all
+// instances of SavedSearch. This struct is safe to replace or modify but
+// should not be done so concurrently.
+var SavedSearchJSONUnmarshaler = new(jsonpb.Unmarshaler)
+
+// UnmarshalJSON satisfies the encoding/json Unmarshaler interface. This method
+// uses the more correct jsonpb package to correctly unmarshal the message.
+func (m *SavedSearch) UnmarshalJSON(b []byte) error {
+	return SavedSearchJSONUnmarshaler.Unmarshal(bytes.NewReader(b), m)
+}
+
+var _ json.Unmarshaler = (*SavedSearch)(nil)
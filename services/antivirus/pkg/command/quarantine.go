@@ -0,0 +1,264 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	"github.com/cs3org/reva/v2/pkg/events/stream"
+	"github.com/cs3org/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/v2/pkg/storagespace"
+	"github.com/owncloud/ocis/v2/ocis-pkg/config/configlog"
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/ocis-pkg/registry"
+	ogrpc "github.com/owncloud/ocis/v2/ocis-pkg/service/grpc"
+	"github.com/owncloud/ocis/v2/services/antivirus/pkg/config"
+	"github.com/owncloud/ocis/v2/services/antivirus/pkg/config/parser"
+	"github.com/owncloud/ocis/v2/services/antivirus/pkg/quarantine"
+	"github.com/urfave/cli/v2"
+)
+
+// Quarantine is the entrypoint for the quarantine command.
+func Quarantine(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:     "quarantine",
+		Usage:    "manage quarantined files",
+		Category: "quarantine",
+		Subcommands: []*cli.Command{
+			ListQuarantine(cfg),
+			ShowQuarantine(cfg),
+			DownloadQuarantine(cfg),
+			ReleaseQuarantine(cfg),
+			PurgeQuarantine(cfg),
+		},
+	}
+}
+
+// ListQuarantine prints a list of all quarantined files
+func ListQuarantine(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "list",
+		Usage: "Print a list of all quarantined files",
+		Before: func(c *cli.Context) error {
+			return configlog.ReturnFatal(parser.ParseConfig(cfg))
+		},
+		Action: func(c *cli.Context) error {
+			q, err := newQuarantineManager(cfg, newLogger(cfg))
+			if err != nil {
+				return err
+			}
+
+			items, err := q.List()
+			if err != nil {
+				return err
+			}
+
+			fmt.Println("Quarantined files:")
+			for _, item := range items {
+				fmt.Printf(" - %s (%s, Size: %d, Virus: %s, Status: %s, Quarantined: %s)\n", item.ID, item.Filename, item.Size, item.Description, item.Status, item.Quarantined.Format(time.RFC3339))
+			}
+			return nil
+		},
+	}
+}
+
+// ShowQuarantine prints the details of a quarantined file
+func ShowQuarantine(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:      "show",
+		Usage:     "Print the details of a quarantined file",
+		ArgsUsage: "<id>",
+		Before: func(c *cli.Context) error {
+			return configlog.ReturnFatal(parser.ParseConfig(cfg))
+		},
+		Action: func(c *cli.Context) error {
+			q, err := newQuarantineManager(cfg, newLogger(cfg))
+			if err != nil {
+				return err
+			}
+
+			item, err := q.Get(c.Args().First())
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("ID:            %s\n", item.ID)
+			fmt.Printf("Status:        %s\n", item.Status)
+			fmt.Printf("Filename:      %s\n", item.Filename)
+			fmt.Printf("Size:          %d\n", item.Size)
+			fmt.Printf("Checksum:      %s\n", item.Checksum)
+			fmt.Printf("Virus:         %s\n", item.Description)
+			if item.Member != "" {
+				fmt.Printf("Member:        %s\n", item.Member)
+			}
+			fmt.Printf("Upload:        %s\n", item.UploadID)
+			fmt.Printf("Executant:     %s\n", item.ExecutingUser.GetOpaqueId())
+			if item.ResourceID != nil {
+				fmt.Printf("Resource:      %s\n", storagespace.FormatResourceID(*item.ResourceID))
+			}
+			if item.ParentID != nil {
+				fmt.Printf("Parent:        %s\n", storagespace.FormatResourceID(*item.ParentID))
+			}
+			fmt.Printf("Quarantined:   %s\n", item.Quarantined.Format(time.RFC3339))
+			return nil
+		},
+	}
+}
+
+// DownloadQuarantine writes the content of a quarantined file
+func DownloadQuarantine(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:      "download",
+		Usage:     "Write the content of a quarantined file to a local file",
+		ArgsUsage: "<id>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "output",
+				Aliases:  []string{"o"},
+				Usage:    "the file to write to",
+				Required: true,
+			},
+		},
+		Before: func(c *cli.Context) error {
+			return configlog.ReturnFatal(parser.ParseConfig(cfg))
+		},
+		Action: func(c *cli.Context) error {
+			q, err := newQuarantineManager(cfg, newLogger(cfg))
+			if err != nil {
+				return err
+			}
+
+			_, rc, err := q.Download(nil, c.Args().First())
+			if err != nil {
+				return err
+			}
+			defer rc.Close()
+
+			f, err := os.OpenFile(c.String("output"), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			if _, err := io.Copy(f, rc); err != nil {
+				return err
+			}
+
+			waitForEvents()
+			return nil
+		},
+	}
+}
+
+// ReleaseQuarantine uploads a quarantined file to its original location
+func ReleaseQuarantine(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:      "release",
+		Usage:     "Upload a quarantined file to its original location",
+		ArgsUsage: "<id>",
+		Before: func(c *cli.Context) error {
+			return configlog.ReturnFatal(parser.ParseConfig(cfg))
+		},
+		Action: func(c *cli.Context) error {
+			q, err := newQuarantineManager(cfg, newLogger(cfg))
+			if err != nil {
+				return err
+			}
+
+			if err := q.Release(nil, c.Args().First()); err != nil {
+				return err
+			}
+
+			waitForEvents()
+			return nil
+		},
+	}
+}
+
+// PurgeQuarantine removes a quarantined file
+func PurgeQuarantine(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:      "purge",
+		Usage:     "Remove a quarantined file",
+		ArgsUsage: "<id>",
+		Before: func(c *cli.Context) error {
+			return configlog.ReturnFatal(parser.ParseConfig(cfg))
+		},
+		Action: func(c *cli.Context) error {
+			q, err := newQuarantineManager(cfg, newLogger(cfg))
+			if err != nil {
+				return err
+			}
+
+			if err := q.Purge(nil, c.Args().First()); err != nil {
+				return err
+			}
+
+			waitForEvents()
+			return nil
+		},
+	}
+}
+
+func newLogger(cfg *config.Config) log.Logger {
+	return log.NewLogger(
+		log.Name(cfg.Service.Name),
+		log.Level(cfg.Log.Level),
+		log.Pretty(cfg.Log.Pretty),
+		log.Color(cfg.Log.Color),
+		log.File(cfg.Log.File),
+	)
+}
+
+func newGatewaySelector(cfg *config.Config) (pool.Selectable[gateway.GatewayAPIClient], error) {
+	if err := ogrpc.Configure(ogrpc.GetClientOptions(cfg.GRPCClientTLS)...); err != nil {
+		return nil, err
+	}
+
+	tm, err := pool.StringToTLSMode(cfg.GRPCClientTLS.Mode)
+	if err != nil {
+		return nil, err
+	}
+	gatewaySelector, err := pool.GatewaySelector(
+		cfg.Reva.Address,
+		pool.WithTLSCACert(cfg.GRPCClientTLS.CACert),
+		pool.WithTLSMode(tm),
+		pool.WithRegistry(registry.GetRegistry()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not get reva client selector: %s", err)
+	}
+	return gatewaySelector, nil
+}
+
+func newQuarantineManager(cfg *config.Config, logger log.Logger) (*quarantine.Manager, error) {
+	gatewaySelector, err := newGatewaySelector(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	publisher, err := stream.NatsFromConfig(stream.NatsConfig(cfg.Events))
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := quarantine.NewStore(cfg.Quarantine.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	return quarantine.NewManager(s, gatewaySelector, cfg.MachineAuthAPIKey, publisher, logger), nil
+}
+
+// waitForEvents waits for published events to be sent
+func waitForEvents() {
+	// go-micro nats implementation uses async publishing,
+	// therefore we need to manually wait.
+	//
+	// FIXME: upstream pr
+	//
+	// https://github.com/go-micro/plugins/blob/3e77393890683be4bacfb613bc5751867d584692/v4/events/natsjs/nats.go#L115
+	time.Sleep(5 * time.Second)
+}
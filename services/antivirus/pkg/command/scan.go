@@ -0,0 +1,70 @@
+package command
+
+import (
+	"time"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/v2/pkg/events"
+	"github.com/cs3org/reva/v2/pkg/events/stream"
+	"github.com/owncloud/ocis/v2/ocis-pkg/config/configlog"
+	"github.com/owncloud/ocis/v2/ocis-pkg/event"
+	"github.com/owncloud/ocis/v2/services/antivirus/pkg/config"
+	"github.com/owncloud/ocis/v2/services/antivirus/pkg/config/parser"
+	"github.com/urfave/cli/v2"
+)
+
+// Scan is the entrypoint for the scan command.
+func Scan(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:     "scan",
+		Usage:    "rescan existing files, the scan is executed by the running antivirus service",
+		Category: "scan",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "space",
+				Aliases: []string{"s"},
+				Usage:   "the id of the space to rescan, all spaces are rescanned if not set",
+			},
+			&cli.StringFlag{
+				Name:    "user",
+				Aliases: []string{"u"},
+				Usage:   "the id of the user listing the spaces, defaults to ANTIVIRUS_RESCAN_USER_ID",
+			},
+			&cli.BoolFlag{
+				Name:  "force",
+				Usage: "also rescan files which were scanned with the current signatures already, required for scanners without a signature version like icap",
+			},
+		},
+		Before: func(c *cli.Context) error {
+			return configlog.ReturnFatal(parser.ParseConfig(cfg))
+		},
+		Action: func(c *cli.Context) error {
+			s, err := stream.NatsFromConfig(stream.NatsConfig(cfg.Events))
+			if err != nil {
+				return err
+			}
+
+			ev := event.StartRescan{
+				SpaceID: c.String("space"),
+				Force:   c.Bool("force"),
+			}
+			if u := c.String("user"); u != "" {
+				ev.ExecutantID = &user.UserId{OpaqueId: u}
+			}
+
+			if err := events.Publish(s, ev); err != nil {
+				return err
+			}
+
+			// go-micro nats implementation uses async publishing,
+			// therefore we need to manually wait.
+			//
+			// FIXME: upstream pr
+			//
+			// https://github.com/go-micro/plugins/blob/3e77393890683be4bacfb613bc5751867d584692/v4/events/natsjs/nats.go#L115
+			time.Sleep(5 * time.Second)
+
+			return nil
+		},
+	}
+}
@@ -4,16 +4,19 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/cs3org/reva/v2/pkg/store"
 	"github.com/oklog/run"
 	"github.com/owncloud/ocis/v2/ocis-pkg/config/configlog"
 	"github.com/owncloud/ocis/v2/ocis-pkg/handlers"
-	"github.com/owncloud/ocis/v2/ocis-pkg/log"
 	"github.com/owncloud/ocis/v2/ocis-pkg/service/debug"
 	"github.com/owncloud/ocis/v2/ocis-pkg/version"
 	"github.com/owncloud/ocis/v2/services/antivirus/pkg/config"
 	"github.com/owncloud/ocis/v2/services/antivirus/pkg/config/parser"
+	"github.com/owncloud/ocis/v2/services/antivirus/pkg/quarantine"
+	"github.com/owncloud/ocis/v2/services/antivirus/pkg/server/http"
 	"github.com/owncloud/ocis/v2/services/antivirus/pkg/service"
 	"github.com/urfave/cli/v2"
+	microstore "go-micro.dev/v4/store"
 )
 
 // Server is the entrypoint for the server command.
@@ -34,18 +37,55 @@ func Server(cfg *config.Config) *cli.Command {
 					}
 					return context.WithCancel(cfg.Context)
 				}()
-				logger = log.NewLogger(
-					log.Name(cfg.Service.Name),
-					log.Level(cfg.Log.Level),
-					log.Pretty(cfg.Log.Pretty),
-					log.Color(cfg.Log.Color),
-					log.File(cfg.Log.File),
-				)
+				logger = newLogger(cfg)
 			)
 			defer cancel()
 
+			gatewaySelector, err := newGatewaySelector(cfg)
+			if err != nil {
+				return err
+			}
+
+			var q *quarantine.Manager
+			if cfg.InfectedFileHandling == "quarantine" {
+				q, err = newQuarantineManager(cfg, logger)
+				if err != nil {
+					return err
+				}
+
+				server, err := http.Server(
+					http.Logger(logger),
+					http.Context(ctx),
+					http.Config(cfg),
+					http.Quarantine(q),
+					http.GatewaySelector(gatewaySelector),
+				)
+				if err != nil {
+					logger.Info().Err(err).Str("transport", "http").Msg("Failed to initialize server")
+					return err
+				}
+
+				gr.Add(server.Run, func(err error) {
+					logger.Error().
+						Str("transport", "http").
+						Err(err).
+						Msg("Shutting down server")
+
+					cancel()
+				})
+			}
+
 			{
-				svc, err := service.NewAntivirus(cfg, logger)
+				st := store.Create(
+					store.Store(cfg.Store.Store),
+					store.TTL(cfg.Store.TTL),
+					store.Size(cfg.Store.Size),
+					microstore.Nodes(cfg.Store.Nodes...),
+					microstore.Database(cfg.Store.Database),
+					microstore.Table(cfg.Store.Table),
+				)
+
+				svc, err := service.NewAntivirus(cfg, logger, gatewaySelector, q, st)
 				if err != nil {
 					return err
 				}
@@ -2,10 +2,15 @@ package config
 
 import (
 	"context"
+	"time"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/shared"
 )
 
 // Config combines all available configuration parts.
 type Config struct {
+	Commons *shared.Commons `yaml:"-"` // don't use this directly as configuration for a service
+
 	File string
 	Log  *Log
 
@@ -13,10 +18,22 @@ type Config struct {
 
 	Service Service `yaml:"-"`
 
-	InfectedFileHandling string `yaml:"infected-file-handling" env:"ANTIVIRUS_INFECTED_FILE_HANDLING" desc:"Defines the behaviour when a virus has been found. Supported options are: 'delete', 'continue' and 'abort '. Delete will delete the file. Continue will mark the file as infected but continues further processing. Abort will keep the file in the uploads folder for further admin inspection and will not move it to its final destination."`
+	InfectedFileHandling string `yaml:"infected-file-handling" env:"ANTIVIRUS_INFECTED_FILE_HANDLING" desc:"Defines the behaviour when a virus has been found. Supported options are: 'delete', 'continue', 'abort' and 'quarantine'. Delete will delete the file. Continue will mark the file as infected but continues further processing. Abort will keep the file in the uploads folder for further admin inspection and will not move it to its final destination. Quarantine will move the file to the quarantine directory where admins can inspect, release or purge it."`
 	Events               Events
 	Scanner              Scanner
 	MaxScanSize          string `yaml:"max-scan-size" env:"ANTIVIRUS_MAX_SCAN_SIZE" desc:"The maximum scan size the virusscanner can handle. Only this many bytes of a file will be scanned. 0 means unlimited and is the default. Usable common abbreviations: [KB, KiB, GB, GiB, TB, TiB, PB, PiB, EB, EiB], example: 2GB."`
+	MaxScanSizeMode      string `yaml:"max-scan-size-mode" env:"ANTIVIRUS_MAX_SCAN_SIZE_MODE" desc:"Defines the behaviour for files exceeding ANTIVIRUS_MAX_SCAN_SIZE. Supported options are 'partial' and 'skip'. Partial will scan the first ANTIVIRUS_MAX_SCAN_SIZE bytes of the file, skip will not scan the file at all."`
+	Archives             Archives
+	Rescan               Rescan
+	Quarantine           Quarantine
+	Store                Store `yaml:"store"`
+
+	HTTP         HTTP          `yaml:"http"`
+	TokenManager *TokenManager `yaml:"token_manager"`
+
+	Reva              *shared.Reva          `yaml:"reva"`
+	GRPCClientTLS     *shared.GRPCClientTLS `yaml:"grpc_client_tls"`
+	MachineAuthAPIKey string                `yaml:"machine_auth_api_key" env:"OCIS_MACHINE_AUTH_API_KEY;ANTIVIRUS_MACHINE_AUTH_API_KEY" desc:"Machine auth API key used to validate internal requests necessary to access resources from other services. Only needed for rescanning existing files and for the quarantine."`
 
 	Context context.Context `yaml:"-" json:"-"`
 
@@ -55,10 +72,12 @@ type Events struct {
 
 // Scanner provides configuration options for the antivirusscanner
 type Scanner struct {
-	Type string `yaml:"type" env:"ANTIVIRUS_SCANNER_TYPE" desc:"The antivirus scanner to use. Supported values are 'clamav' and 'icap'."`
+	Type  string   `yaml:"type" env:"ANTIVIRUS_SCANNER_TYPE" desc:"The antivirus scanner to use. Supported values are 'clamav', 'icap', 'signatures' and 'chain'."`
+	Chain []string `yaml:"chain" env:"ANTIVIRUS_SCANNER_CHAIN" desc:"A comma separated list of scanner types which are run in sequence when ANTIVIRUS_SCANNER_TYPE is set to 'chain'. The first scanner reporting an infection wins. Supported values are 'clamav', 'icap' and 'signatures'."`
 
-	ClamAV ClamAV // only if Type == clamav
-	ICAP   ICAP   // only if Type == icap
+	ClamAV     ClamAV     // only if Type == clamav
+	ICAP       ICAP       // only if Type == icap
+	Signatures Signatures // only if Type == signatures
 }
 
 // ClamAV provides configuration option for clamav
@@ -72,3 +91,50 @@ type ICAP struct {
 	URL     string `yaml:"url" env:"ANTIVIRUS_ICAP_URL" desc:"URL of the ICAP server."`
 	Service string `yaml:"service" env:"ANTIVIRUS_ICAP_SERVICE" desc:"The name of the ICAP service."`
 }
+
+// Signatures provides configuration options for the offline signature scanner
+type Signatures struct {
+	Path string `yaml:"path" env:"ANTIVIRUS_SIGNATURES_PATH" desc:"The directory containing the signature rule files. Files ending in '.hdb', '.hsb' or '.hash' are read as hash databases using the clamav 'hash:size:name' format, files ending in '.yar' or '.yara' are read as YARA-style rules with text and hex strings. The files are read on startup, the service needs to be restarted after updating them. If not defined, the root directory derives from $OCIS_BASE_DATA_PATH:/antivirus/signatures."`
+}
+
+// Archives provides configuration options for scanning the members of archive files
+type Archives struct {
+	Enabled           bool `yaml:"enabled" env:"ANTIVIRUS_ARCHIVES_ENABLED" desc:"Unpack zip, tar and gzip archives and scan each member in addition to the archive itself."`
+	MaxDepth          int  `yaml:"max_depth" env:"ANTIVIRUS_ARCHIVES_MAX_DEPTH" desc:"The maximum nesting depth of archives which will be unpacked. Deeper nested archives are scanned as a whole."`
+	MaxEntries        int  `yaml:"max_entries" env:"ANTIVIRUS_ARCHIVES_MAX_ENTRIES" desc:"The maximum number of archive members, including the members of nested archives. The scan fails if an archive contains more members."`
+	MaxExpansionRatio int  `yaml:"max_expansion_ratio" env:"ANTIVIRUS_ARCHIVES_MAX_EXPANSION_RATIO" desc:"The maximum ratio between the unpacked size of all members and the size of the archive. The scan fails if the ratio is exceeded."`
+}
+
+// Rescan provides configuration options for rescanning existing files
+type Rescan struct {
+	Interval time.Duration `yaml:"interval" env:"ANTIVIRUS_RESCAN_INTERVAL" desc:"The interval in which existing files are rescanned if the scanner signatures changed since their last scan. 0 disables scheduled rescans. The duration can be set as number followed by a unit identifier like s, m or h."`
+	UserID   string        `yaml:"user_id" env:"OCIS_ADMIN_USER_ID;ANTIVIRUS_RESCAN_USER_ID" desc:"ID of the user who lists the spaces to rescan. The user needs the permission to list all spaces. Consider that the UUID can be encoded in some LDAP deployment configurations like in .ldif files. These need to be decoded beforehand."`
+}
+
+// Store configures the store used to coordinate the rescans of several instances of the service
+type Store struct {
+	Store    string        `yaml:"store" env:"OCIS_PERSISTENT_STORE;ANTIVIRUS_STORE" desc:"The type of the store. Supported values are: 'memory', 'ocmem', 'etcd', 'redis', 'redis-sentinel', 'nats-js', 'noop'. See the text description for details."`
+	Nodes    []string      `yaml:"nodes" env:"OCIS_PERSISTENT_STORE_NODES;ANTIVIRUS_STORE_NODES" desc:"A comma separated list of nodes to access the configured store. This has no effect when 'memory' or 'ocmem' stores are configured. Note that the behaviour how nodes are used is dependent on the library of the configured store."`
+	Database string        `yaml:"database" env:"ANTIVIRUS_STORE_DATABASE" desc:"The database name the configured store should use."`
+	Table    string        `yaml:"table" env:"ANTIVIRUS_STORE_TABLE" desc:"The database table the store should use."`
+	TTL      time.Duration `yaml:"ttl" env:"OCIS_PERSISTENT_STORE_TTL;ANTIVIRUS_STORE_TTL" desc:"Time to live for entries in the store. The duration can be set as number followed by a unit identifier like s, m or h."`
+	Size     int           `yaml:"size" env:"OCIS_PERSISTENT_STORE_SIZE;ANTIVIRUS_STORE_SIZE" desc:"The maximum quantity of items in the store. Only applies when store type 'ocmem' is configured. Defaults to 512."`
+}
+
+// Quarantine provides configuration options for quarantined files
+type Quarantine struct {
+	Path string `yaml:"path" env:"ANTIVIRUS_QUARANTINE_PATH" desc:"The directory where infected files are stored when ANTIVIRUS_INFECTED_FILE_HANDLING is set to 'quarantine'. It needs to be shared when running several instances of the service. If not defined, the root directory derives from $OCIS_BASE_DATA_PATH:/antivirus/quarantine."`
+}
+
+// HTTP defines the available http configuration.
+type HTTP struct {
+	Addr      string                `yaml:"addr" env:"ANTIVIRUS_HTTP_ADDR" desc:"The bind address of the HTTP service. The HTTP service is only started when ANTIVIRUS_INFECTED_FILE_HANDLING is set to 'quarantine'."`
+	Namespace string                `yaml:"-"`
+	Root      string                `yaml:"root" env:"ANTIVIRUS_HTTP_ROOT" desc:"Subdirectory that serves as the root for this HTTP service."`
+	TLS       shared.HTTPServiceTLS `yaml:"tls"`
+}
+
+// TokenManager is the config for using the reva token manager
+type TokenManager struct {
+	JWTSecret string `yaml:"jwt_secret" env:"OCIS_JWT_SECRET;ANTIVIRUS_JWT_SECRET" desc:"The secret to mint and validate jwt tokens."`
+}
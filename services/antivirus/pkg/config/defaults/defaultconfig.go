@@ -1,6 +1,12 @@
 package defaults
 
 import (
+	"path/filepath"
+	"strings"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/config/defaults"
+	"github.com/owncloud/ocis/v2/ocis-pkg/shared"
+	"github.com/owncloud/ocis/v2/ocis-pkg/structs"
 	"github.com/owncloud/ocis/v2/services/antivirus/pkg/config"
 )
 
@@ -22,11 +28,32 @@ func DefaultConfig() *config.Config {
 		Service: config.Service{
 			Name: "antivirus",
 		},
+		HTTP: config.HTTP{
+			Addr:      "127.0.0.1:9276",
+			Root:      "/api/v0/antivirus",
+			Namespace: "com.owncloud.web",
+		},
 		Events: config.Events{
 			Endpoint: "127.0.0.1:9233",
 			Cluster:  "ocis-cluster",
 		},
+		Reva:                 shared.DefaultRevaConfig(),
 		InfectedFileHandling: "delete",
+		MaxScanSizeMode:      "partial",
+		Archives: config.Archives{
+			Enabled:           false,
+			MaxDepth:          5,
+			MaxEntries:        10000,
+			MaxExpansionRatio: 100,
+		},
+		Store: config.Store{
+			Store:    "memory",
+			Database: "antivirus",
+			Table:    "antivirus",
+		},
+		Quarantine: config.Quarantine{
+			Path: filepath.Join(defaults.BaseDataPath(), "antivirus", "quarantine"),
+		},
 		Scanner: config.Scanner{
 			Type: "clamav",
 			ClamAV: config.ClamAV{
@@ -37,18 +64,63 @@ func DefaultConfig() *config.Config {
 				Service: "avscan",
 				Timeout: 300,
 			},
+			Signatures: config.Signatures{
+				Path: filepath.Join(defaults.BaseDataPath(), "antivirus", "signatures"),
+			},
 		},
 	}
 }
 
 // EnsureDefaults adds default values to the configuration if they are not set yet
 func EnsureDefaults(cfg *config.Config) {
-	if cfg.Log == nil {
+	if cfg.Log == nil && cfg.Commons != nil && cfg.Commons.Log != nil {
+		cfg.Log = &config.Log{
+			Level:  cfg.Commons.Log.Level,
+			Pretty: cfg.Commons.Log.Pretty,
+			Color:  cfg.Commons.Log.Color,
+			File:   cfg.Commons.Log.File,
+		}
+	} else if cfg.Log == nil {
 		cfg.Log = &config.Log{}
 	}
+
+	if cfg.MachineAuthAPIKey == "" && cfg.Commons != nil && cfg.Commons.MachineAuthAPIKey != "" {
+		cfg.MachineAuthAPIKey = cfg.Commons.MachineAuthAPIKey
+	}
+
+	if cfg.Reva == nil && cfg.Commons != nil && cfg.Commons.Reva != nil {
+		cfg.Reva = &shared.Reva{
+			Address: cfg.Commons.Reva.Address,
+			TLS:     cfg.Commons.Reva.TLS,
+		}
+	} else if cfg.Reva == nil {
+		cfg.Reva = &shared.Reva{}
+	}
+
+	if cfg.GRPCClientTLS == nil && cfg.Commons != nil {
+		cfg.GRPCClientTLS = structs.CopyOrZeroValue(cfg.Commons.GRPCClientTLS)
+	}
+
+	if cfg.Rescan.UserID == "" && cfg.Commons != nil {
+		cfg.Rescan.UserID = cfg.Commons.AdminUserID
+	}
+
+	if cfg.TokenManager == nil && cfg.Commons != nil && cfg.Commons.TokenManager != nil {
+		cfg.TokenManager = &config.TokenManager{
+			JWTSecret: cfg.Commons.TokenManager.JWTSecret,
+		}
+	} else if cfg.TokenManager == nil {
+		cfg.TokenManager = &config.TokenManager{}
+	}
+
+	if cfg.Commons != nil {
+		cfg.HTTP.TLS = cfg.Commons.HTTPServiceTLS
+	}
 }
 
 // Sanitize sanitizes the configuration
 func Sanitize(cfg *config.Config) {
-
+	if cfg.HTTP.Root != "/" {
+		cfg.HTTP.Root = strings.TrimSuffix(cfg.HTTP.Root, "/")
+	}
 }
@@ -0,0 +1,289 @@
+package quarantine
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/v2/pkg/errtypes"
+	"github.com/cs3org/reva/v2/pkg/events"
+	"github.com/cs3org/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/v2/pkg/rhttp"
+	"github.com/cs3org/reva/v2/pkg/utils"
+	"github.com/owncloud/ocis/v2/ocis-pkg/event"
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+)
+
+// Manager handles quarantined files
+type Manager struct {
+	store             *Store
+	gatewaySelector   pool.Selectable[gateway.GatewayAPIClient]
+	machineAuthAPIKey string
+	publisher         events.Publisher
+	client            *http.Client
+	l                 log.Logger
+}
+
+// NewManager returns a new quarantine manager
+func NewManager(s *Store, gatewaySelector pool.Selectable[gateway.GatewayAPIClient], machineAuthAPIKey string, publisher events.Publisher, l log.Logger) *Manager {
+	return &Manager{
+		store:             s,
+		gatewaySelector:   gatewaySelector,
+		machineAuthAPIKey: machineAuthAPIKey,
+		publisher:         publisher,
+		client:            rhttp.GetHTTPClient(rhttp.Insecure(true)),
+		l:                 l,
+	}
+}
+
+// Quarantine stores the content of an infected upload. member is the path of the infected
+// file inside an archive, if any.
+func (m *Manager) Quarantine(ev events.StartPostprocessingStep, description string, member string, r io.Reader) (Item, error) {
+	item := Item{
+		Status:        StatusQuarantined,
+		UploadID:      ev.UploadID,
+		ResourceID:    ev.ResourceID,
+		ParentID:      m.parentID(ev),
+		Filename:      ev.Filename,
+		ExecutingUser: ev.ExecutingUser.GetId(),
+		Description:   description,
+		Member:        member,
+		Quarantined:   time.Now(),
+	}
+
+	item, err := m.store.Add(item, r)
+	if err != nil {
+		return Item{}, err
+	}
+
+	return item, events.Publish(m.publisher, event.FileQuarantined{
+		ItemID:        item.ID,
+		UploadID:      item.UploadID,
+		ResourceID:    item.ResourceID,
+		Filename:      item.Filename,
+		ExecutingUser: item.ExecutingUser,
+		Description:   item.Description,
+		Member:        item.Member,
+		Timestamp:     item.Quarantined,
+	})
+}
+
+// Released checks if the upload is a released item. Only uploads with the exact content of the item
+// match, open is only called to compute the checksum of the upload when an item matches its file name,
+// size and user. The item is removed from the quarantine as the upload contains its content again.
+func (m *Manager) Released(ev events.StartPostprocessingStep, open func() (io.ReadCloser, error)) (Item, bool) {
+	items, err := m.store.List()
+	if err != nil {
+		m.l.Error().Err(err).Msg("cannot list quarantined items")
+		return Item{}, false
+	}
+
+	var checksum string
+	for _, item := range items {
+		if item.Status != StatusReleased ||
+			item.Filename != ev.Filename ||
+			item.Size != ev.Filesize ||
+			item.ExecutingUser.GetOpaqueId() != ev.ExecutingUser.GetId().GetOpaqueId() {
+			continue
+		}
+
+		if checksum == "" {
+			if checksum, err = sha256Checksum(open); err != nil {
+				m.l.Error().Err(err).Str("uploadid", ev.UploadID).Msg("cannot compute checksum of upload")
+				return Item{}, false
+			}
+		}
+		if item.Checksum != checksum {
+			continue
+		}
+
+		if err := m.store.Delete(item.ID); err != nil {
+			m.l.Error().Err(err).Str("item", item.ID).Msg("cannot remove released item")
+		}
+		return item, true
+	}
+
+	return Item{}, false
+}
+
+// List returns all quarantined items
+func (m *Manager) List() ([]Item, error) {
+	return m.store.List()
+}
+
+// Get returns a quarantined item
+func (m *Manager) Get(id string) (Item, error) {
+	return m.store.Get(id)
+}
+
+// Download returns the content of a quarantined item
+func (m *Manager) Download(executant *user.UserId, id string) (Item, io.ReadCloser, error) {
+	item, err := m.store.Get(id)
+	if err != nil {
+		return Item{}, nil, err
+	}
+
+	rc, err := m.store.Open(id)
+	if err != nil {
+		return Item{}, nil, err
+	}
+
+	if err := events.Publish(m.publisher, event.QuarantinedFileDownloaded{
+		Executant:  executant,
+		ItemID:     item.ID,
+		ResourceID: item.ResourceID,
+		Filename:   item.Filename,
+		Timestamp:  time.Now(),
+	}); err != nil {
+		rc.Close()
+		return Item{}, nil, err
+	}
+
+	return item, rc, nil
+}
+
+// Release uploads a quarantined item to its original location. The upload runs through
+// postprocessing again, the virus scan will let it pass.
+func (m *Manager) Release(executant *user.UserId, id string) error {
+	item, err := m.store.Get(id)
+	switch {
+	case err != nil:
+		return err
+	case item.Status != StatusQuarantined:
+		return fmt.Errorf("item '%s' was released already", id)
+	case item.ParentID == nil:
+		return fmt.Errorf("original location of item '%s' is unknown", id)
+	}
+
+	// the status needs to be set before uploading, the upload might be scanned before the request returns
+	item.Status = StatusReleased
+	if err := m.store.Update(item); err != nil {
+		return err
+	}
+
+	if err := m.upload(item); err != nil {
+		item.Status = StatusQuarantined
+		if uerr := m.store.Update(item); uerr != nil {
+			m.l.Error().Err(uerr).Str("item", item.ID).Msg("cannot reset item status")
+		}
+		return err
+	}
+
+	return events.Publish(m.publisher, event.QuarantinedFileReleased{
+		Executant:  executant,
+		ItemID:     item.ID,
+		ResourceID: item.ResourceID,
+		Filename:   item.Filename,
+		Timestamp:  time.Now(),
+	})
+}
+
+// Purge removes a quarantined item
+func (m *Manager) Purge(executant *user.UserId, id string) error {
+	item, err := m.store.Get(id)
+	if err != nil {
+		return err
+	}
+
+	if err := m.store.Delete(id); err != nil {
+		return err
+	}
+
+	return events.Publish(m.publisher, event.QuarantinedFilePurged{
+		Executant:  executant,
+		ItemID:     item.ID,
+		ResourceID: item.ResourceID,
+		Filename:   item.Filename,
+		Timestamp:  time.Now(),
+	})
+}
+
+// parentID looks up the folder the upload is going to. It is needed to release the file later on.
+func (m *Manager) parentID(ev events.StartPostprocessingStep) *provider.ResourceId {
+	gwc, err := m.gatewaySelector.Next()
+	if err != nil {
+		m.l.Error().Err(err).Str("uploadid", ev.UploadID).Msg("cannot get gateway client")
+		return nil
+	}
+
+	ctx, _, err := utils.Impersonate(ev.ExecutingUser.GetId(), gwc, m.machineAuthAPIKey)
+	if err != nil {
+		m.l.Error().Err(err).Str("uploadid", ev.UploadID).Msg("cannot impersonate uploading user")
+		return nil
+	}
+
+	res, err := gwc.Stat(ctx, &provider.StatRequest{Ref: &provider.Reference{ResourceId: ev.ResourceID}})
+	switch {
+	case err != nil:
+		m.l.Error().Err(err).Str("uploadid", ev.UploadID).Msg("cannot stat upload")
+		return nil
+	case res.GetStatus().GetCode() != rpc.Code_CODE_OK:
+		m.l.Error().Str("uploadid", ev.UploadID).Str("status", res.GetStatus().GetMessage()).Msg("cannot stat upload")
+		return nil
+	}
+
+	return res.GetInfo().GetParentId()
+}
+
+func (m *Manager) upload(item Item) error {
+	gwc, err := m.gatewaySelector.Next()
+	if err != nil {
+		return err
+	}
+
+	ctx, _, err := utils.Impersonate(item.ExecutingUser, gwc, m.machineAuthAPIKey)
+	if err != nil {
+		return err
+	}
+
+	uRes, err := gwc.InitiateFileUpload(ctx, &provider.InitiateFileUploadRequest{
+		Ref:    &provider.Reference{ResourceId: item.ParentID, Path: utils.MakeRelativePath(item.Filename)},
+		Opaque: utils.AppendPlainToOpaque(nil, "Upload-Length", strconv.FormatUint(item.Size, 10)),
+	})
+	switch {
+	case err != nil:
+		return err
+	case uRes.GetStatus().GetCode() != rpc.Code_CODE_OK:
+		return errtypes.NewErrtypeFromStatus(uRes.GetStatus())
+	}
+
+	var endpoint, token string
+	for _, p := range uRes.GetProtocols() {
+		if p.GetProtocol() == "simple" {
+			endpoint, token = p.GetUploadEndpoint(), p.GetToken()
+		}
+	}
+	if endpoint == "" {
+		return fmt.Errorf("no upload protocol available")
+	}
+
+	rc, err := m.store.Open(item.ID)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	req, err := rhttp.NewRequest(ctx, http.MethodPut, endpoint, rc)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Reva-Transfer", token)
+	req.ContentLength = int64(item.Size)
+
+	res, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code from upload %v", res.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package quarantine
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/v2/pkg/events"
+	"github.com/cs3org/reva/v2/pkg/rgrpc/todo/pool"
+	cs3mocks "github.com/cs3org/reva/v2/tests/cs3mocks/mocks"
+	"github.com/owncloud/ocis/v2/ocis-pkg/event"
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	microevents "go-micro.dev/v4/events"
+	"google.golang.org/grpc"
+)
+
+type publisher struct {
+	events []interface{}
+}
+
+func (p *publisher) Publish(_ string, ev interface{}, _ ...microevents.PublishOption) error {
+	p.events = append(p.events, ev)
+	return nil
+}
+
+var (
+	_uploader = &user.UserId{OpaqueId: "uploader"}
+	_parentID = &provider.ResourceId{StorageId: "storage", SpaceId: "space", OpaqueId: "parent"}
+)
+
+func newTestManager(t *testing.T) (*Manager, *cs3mocks.GatewayAPIClient, *publisher) {
+	s, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	gatewayClient := &cs3mocks.GatewayAPIClient{}
+	gatewayClient.On("GetUser", mock.Anything, mock.Anything).Return(&user.GetUserResponse{
+		Status: &rpc.Status{Code: rpc.Code_CODE_OK},
+		User:   &user.User{Id: _uploader},
+	}, nil)
+	gatewayClient.On("Authenticate", mock.Anything, mock.Anything).Return(&gateway.AuthenticateResponse{
+		Status: &rpc.Status{Code: rpc.Code_CODE_OK},
+		Token:  "token",
+	}, nil)
+	gatewayClient.On("Stat", mock.Anything, mock.Anything).Return(&provider.StatResponse{
+		Status: &rpc.Status{Code: rpc.Code_CODE_OK},
+		Info:   &provider.ResourceInfo{ParentId: _parentID},
+	}, nil)
+
+	pool.RemoveSelector("GatewaySelector" + "com.owncloud.api.gateway")
+	selector := pool.GetSelector[gateway.GatewayAPIClient](
+		"GatewaySelector",
+		"com.owncloud.api.gateway",
+		func(cc *grpc.ClientConn) gateway.GatewayAPIClient {
+			return gatewayClient
+		},
+	)
+
+	p := &publisher{}
+	return NewManager(s, selector, "secret", p, log.NopLogger()), gatewayClient, p
+}
+
+func upload(filename string, content string) events.StartPostprocessingStep {
+	return events.StartPostprocessingStep{
+		UploadID:      "upload-" + filename,
+		ResourceID:    &provider.ResourceId{StorageId: "storage", SpaceId: "space", OpaqueId: filename},
+		Filename:      filename,
+		Filesize:      uint64(len(content)),
+		ExecutingUser: &user.User{Id: _uploader},
+	}
+}
+
+func opener(content string, calls *int) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		*calls++
+		return io.NopCloser(strings.NewReader(content)), nil
+	}
+}
+
+func TestQuarantine(t *testing.T) {
+	m, _, p := newTestManager(t)
+
+	item, err := m.Quarantine(upload("evil.exe", "evil"), "Eicar-Signature", "docs.zip/evil.exe", strings.NewReader("evil"))
+	require.NoError(t, err)
+	assert.Equal(t, StatusQuarantined, item.Status)
+	assert.Equal(t, "evil.exe", item.Filename)
+	assert.Equal(t, uint64(4), item.Size)
+	assert.Equal(t, "docs.zip/evil.exe", item.Member)
+	assert.Equal(t, _parentID.GetOpaqueId(), item.ParentID.GetOpaqueId(), "the original location is remembered")
+
+	require.Len(t, p.events, 1)
+	ev, ok := p.events[0].(event.FileQuarantined)
+	require.True(t, ok)
+	assert.Equal(t, item.ID, ev.ItemID)
+	assert.Equal(t, "Eicar-Signature", ev.Description)
+	assert.Equal(t, "docs.zip/evil.exe", ev.Member)
+}
+
+func TestReleased(t *testing.T) {
+	tests := map[string]struct {
+		status       Status
+		upload       events.StartPostprocessingStep
+		content      string
+		wantReleased bool
+		wantOpened   int
+	}{
+		"same content": {
+			status:       StatusReleased,
+			upload:       upload("evil.exe", "evil"),
+			content:      "evil",
+			wantReleased: true,
+			wantOpened:   1,
+		},
+		"same metadata but other content": {
+			status:     StatusReleased,
+			upload:     upload("evil.exe", "EVIL"),
+			content:    "EVIL",
+			wantOpened: 1,
+		},
+		"item not released": {
+			status:  StatusQuarantined,
+			upload:  upload("evil.exe", "evil"),
+			content: "evil",
+		},
+		"other file name": {
+			status:  StatusReleased,
+			upload:  upload("other.exe", "evil"),
+			content: "evil",
+		},
+		"other size": {
+			status:  StatusReleased,
+			upload:  upload("evil.exe", "evil!"),
+			content: "evil!",
+		},
+		"other user": {
+			status: StatusReleased,
+			upload: func() events.StartPostprocessingStep {
+				ev := upload("evil.exe", "evil")
+				ev.ExecutingUser = &user.User{Id: &user.UserId{OpaqueId: "other"}}
+				return ev
+			}(),
+			content: "evil",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			m, _, _ := newTestManager(t)
+			item, err := m.Quarantine(upload("evil.exe", "evil"), "Eicar-Signature", "", strings.NewReader("evil"))
+			require.NoError(t, err)
+			item.Status = tt.status
+			require.NoError(t, m.store.Update(item))
+
+			var opened int
+			released, ok := m.Released(tt.upload, opener(tt.content, &opened))
+			assert.Equal(t, tt.wantReleased, ok)
+			assert.Equal(t, tt.wantOpened, opened, "the upload is only downloaded for candidates")
+
+			_, err = m.Get(item.ID)
+			if tt.wantReleased {
+				assert.Equal(t, item.ID, released.ID)
+				assert.ErrorIs(t, err, ErrNotFound, "a released item is removed once its upload passed")
+			} else {
+				assert.NoError(t, err, "the item stays in the quarantine")
+			}
+		})
+	}
+}
+
+func TestReleasedFailingDownload(t *testing.T) {
+	m, _, _ := newTestManager(t)
+	item, err := m.Quarantine(upload("evil.exe", "evil"), "Eicar-Signature", "", strings.NewReader("evil"))
+	require.NoError(t, err)
+	item.Status = StatusReleased
+	require.NoError(t, m.store.Update(item))
+
+	_, ok := m.Released(upload("evil.exe", "evil"), func() (io.ReadCloser, error) {
+		return nil, errors.New("download failed")
+	})
+	assert.False(t, ok)
+}
+
+func TestRelease(t *testing.T) {
+	m, gatewayClient, p := newTestManager(t)
+
+	var uploaded string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		uploaded = string(b)
+		assert.Equal(t, "upload-token", r.Header.Get("X-Reva-Transfer"))
+	}))
+	defer srv.Close()
+	gatewayClient.On("InitiateFileUpload", mock.Anything, mock.Anything).Return(&gateway.InitiateFileUploadResponse{
+		Status:    &rpc.Status{Code: rpc.Code_CODE_OK},
+		Protocols: []*gateway.FileUploadProtocol{{Protocol: "simple", UploadEndpoint: srv.URL, Token: "upload-token"}},
+	}, nil)
+
+	item, err := m.Quarantine(upload("evil.exe", "evil"), "Eicar-Signature", "", strings.NewReader("evil"))
+	require.NoError(t, err)
+
+	require.NoError(t, m.Release(_uploader, item.ID))
+	assert.Equal(t, "evil", uploaded)
+
+	item, err = m.Get(item.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusReleased, item.Status)
+	require.Len(t, p.events, 2)
+	assert.IsType(t, event.QuarantinedFileReleased{}, p.events[1])
+
+	assert.Error(t, m.Release(_uploader, item.ID), "an item can only be released once")
+
+	// the upload of the released content passes, other uploads don't
+	var opened int
+	_, ok := m.Released(upload("evil.exe", "evil"), opener("evil", &opened))
+	assert.True(t, ok)
+}
+
+func TestPurge(t *testing.T) {
+	m, _, p := newTestManager(t)
+	item, err := m.Quarantine(upload("evil.exe", "evil"), "Eicar-Signature", "", strings.NewReader("evil"))
+	require.NoError(t, err)
+
+	require.NoError(t, m.Purge(_uploader, item.ID))
+	_, err = m.Get(item.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	require.Len(t, p.events, 2)
+	assert.IsType(t, event.QuarantinedFilePurged{}, p.events[1])
+
+	assert.ErrorIs(t, m.Purge(_uploader, item.ID), ErrNotFound)
+}
@@ -0,0 +1,210 @@
+package quarantine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/google/uuid"
+)
+
+// ErrNotFound is returned when a quarantined item does not exist
+var ErrNotFound = errors.New("quarantined item not found")
+
+// Status of a quarantined item
+type Status string
+
+const (
+	// StatusQuarantined is the status of an item waiting for admin review
+	StatusQuarantined Status = "quarantined"
+	// StatusReleased is the status of an item which was uploaded to its original location again
+	// and waits for the virus scan of that upload
+	StatusReleased Status = "released"
+)
+
+// Item is a quarantined file
+type Item struct {
+	ID            string               `json:"id"`
+	Status        Status               `json:"status"`
+	UploadID      string               `json:"uploadId"`
+	ResourceID    *provider.ResourceId `json:"resourceId"`
+	ParentID      *provider.ResourceId `json:"parentId,omitempty"`
+	Filename      string               `json:"filename"`
+	Size          uint64               `json:"size"`
+	Checksum      string               `json:"checksum"`
+	ExecutingUser *user.UserId         `json:"executingUser"`
+	Description   string               `json:"description"`
+	Member        string               `json:"member,omitempty"`
+	Quarantined   time.Time            `json:"quarantined"`
+}
+
+// Store persists quarantined items in a local directory. Every item consists
+// of a '<id>.json' metadata file and a '<id>.blob' file holding the content.
+type Store struct {
+	root string
+}
+
+// NewStore returns a Store using the given directory
+func NewStore(root string) (*Store, error) {
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return nil, err
+	}
+	return &Store{root: root}, nil
+}
+
+// Add stores the content of r as a new item
+func (s *Store) Add(item Item, r io.Reader) (Item, error) {
+	item.ID = uuid.New().String()
+
+	f, err := os.OpenFile(s.blobPath(item.ID), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return Item{}, err
+	}
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, h), r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return Item{}, err
+	}
+
+	item.Size = uint64(n)
+	item.Checksum = formatChecksum(h)
+	if err := s.Update(item); err != nil {
+		_ = os.Remove(f.Name())
+		return Item{}, err
+	}
+
+	return item, nil
+}
+
+// Update writes the metadata of an item
+func (s *Store) Update(item Item) error {
+	b, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	tmp := s.metaPath(item.ID) + ".tmp"
+	if err := os.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.metaPath(item.ID))
+}
+
+// Get returns the item with the given id
+func (s *Store) Get(id string) (Item, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return Item{}, ErrNotFound
+	}
+
+	b, err := os.ReadFile(s.metaPath(id))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return Item{}, ErrNotFound
+	case err != nil:
+		return Item{}, err
+	}
+
+	var item Item
+	err = json.Unmarshal(b, &item)
+	return item, err
+}
+
+// List returns all items, oldest first
+func (s *Store) List() ([]Item, error) {
+	entries, err := os.ReadDir(s.root)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]Item, 0, len(entries))
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok {
+			continue
+		}
+
+		item, err := s.Get(id)
+		if err != nil {
+			continue
+		}
+		items = append(items, item)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Quarantined.Before(items[j].Quarantined)
+	})
+	return items, nil
+}
+
+// Open returns the content of an item
+func (s *Store) Open(id string) (io.ReadCloser, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrNotFound
+	}
+
+	f, err := os.Open(s.blobPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Delete removes an item and its content
+func (s *Store) Delete(id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrNotFound
+	}
+
+	if err := os.Remove(s.metaPath(id)); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if err := os.Remove(s.blobPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *Store) metaPath(id string) string {
+	return filepath.Join(s.root, id+".json")
+}
+
+func (s *Store) blobPath(id string) string {
+	return filepath.Join(s.root, id+".blob")
+}
+
+// sha256Checksum returns the checksum of the opened content in the format of Item.Checksum
+func sha256Checksum(open func() (io.ReadCloser, error)) (string, error) {
+	rc, err := open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, rc); err != nil {
+		return "", err
+	}
+	return formatChecksum(h), nil
+}
+
+func formatChecksum(h hash.Hash) string {
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
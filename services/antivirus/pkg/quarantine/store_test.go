@@ -0,0 +1,97 @@
+package quarantine
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	s, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	first, err := s.Add(Item{
+		Status:        StatusQuarantined,
+		Filename:      "first.exe",
+		ExecutingUser: &user.UserId{OpaqueId: "user"},
+		Description:   "Eicar-Signature",
+		Quarantined:   time.Now().Add(-time.Hour),
+	}, strings.NewReader("first content"))
+	require.NoError(t, err)
+	assert.NotEmpty(t, first.ID)
+	assert.Equal(t, uint64(len("first content")), first.Size)
+	assert.True(t, strings.HasPrefix(first.Checksum, "sha256:"))
+
+	second, err := s.Add(Item{Status: StatusQuarantined, Filename: "second.exe", Quarantined: time.Now()}, strings.NewReader("second content"))
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Checksum, second.Checksum)
+
+	got, err := s.Get(first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, first.Filename, got.Filename)
+	assert.Equal(t, first.Checksum, got.Checksum)
+	assert.Equal(t, "user", got.ExecutingUser.GetOpaqueId())
+
+	items, err := s.List()
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, first.ID, items[0].ID, "items are sorted oldest first")
+	assert.Equal(t, second.ID, items[1].ID)
+
+	rc, err := s.Open(first.ID)
+	require.NoError(t, err)
+	b, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	assert.Equal(t, "first content", string(b))
+
+	got.Status = StatusReleased
+	require.NoError(t, s.Update(got))
+	got, err = s.Get(first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusReleased, got.Status)
+
+	require.NoError(t, s.Delete(first.ID))
+	_, err = s.Get(first.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = s.Open(first.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, s.Delete(first.ID), ErrNotFound)
+
+	items, err = s.List()
+	require.NoError(t, err)
+	assert.Len(t, items, 1)
+}
+
+func TestStoreRejectsInvalidIDs(t *testing.T) {
+	s, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	for _, id := range []string{"", "../../etc/passwd", "not-a-uuid", "b2a4c7e2-6f43-4f6b-8f1e-2b0f7b1f4d0e"} {
+		_, err := s.Get(id)
+		assert.ErrorIs(t, err, ErrNotFound, id)
+		_, err = s.Open(id)
+		assert.ErrorIs(t, err, ErrNotFound, id)
+		assert.ErrorIs(t, s.Delete(id), ErrNotFound, id)
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	s, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	item, err := s.Add(Item{}, strings.NewReader("content"))
+	require.NoError(t, err)
+
+	checksum, err := sha256Checksum(func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("content")), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, item.Checksum, checksum, "the checksum of the same content matches the stored one")
+	assert.Equal(t, "sha256:ed7002b439e9ac845f22357d822bac1444730fbdb6016d3ec9432297b9ec9f73", checksum)
+}
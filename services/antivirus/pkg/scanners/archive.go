@@ -0,0 +1,282 @@
+package scanners
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrArchiveLimitExceeded is returned when an archive exceeds one of the configured unpack limits
+var ErrArchiveLimitExceeded = errors.New("archive exceeds unpack limits")
+
+type archiveKind int
+
+const (
+	noArchive archiveKind = iota
+	zipArchive
+	tarArchive
+	gzipArchive
+)
+
+// NewArchive returns a Scanner which unpacks zip, tar and gzip archives and scans every member
+// with the given scanner in addition to the archive itself.
+func NewArchive(s Scanner, maxDepth int, maxEntries int, maxExpansionRatio int) *Archive {
+	return &Archive{
+		scanner:           s,
+		maxDepth:          maxDepth,
+		maxEntries:        maxEntries,
+		maxExpansionRatio: int64(maxExpansionRatio),
+	}
+}
+
+// Archive is a Scanner looking into archives. Nesting depth, number of members and
+// the expansion ratio are limited to protect against decompression bombs.
+type Archive struct {
+	scanner           Scanner
+	maxDepth          int
+	maxEntries        int
+	maxExpansionRatio int64
+}
+
+// Scan to fulfill Scanner interface. Archives which can't be unpacked completely, e.g. because
+// only the beginning of a file is scanned, are scanned as far as possible and reported as partial.
+func (s Archive) Scan(file io.Reader) (ScanResult, error) {
+	u := &unpacker{Archive: s, budget: -1}
+	res, err := u.scan(file, "", 0)
+	if u.truncated && !res.Infected {
+		res.Partial = true
+	}
+	return res, err
+}
+
+// Version returns the version of the wrapped scanner
+func (s Archive) Version() (string, error) {
+	return Version(s.scanner)
+}
+
+// unpacker holds the state of a single archive scan
+type unpacker struct {
+	Archive
+
+	entries   int
+	budget    int64 // remaining bytes which may be unpacked, -1 means not yet known
+	exceeded  bool
+	truncated bool // an archive ended unexpectedly, only the raw content of the rest was scanned
+}
+
+func (u *unpacker) scan(r io.Reader, name string, depth int) (ScanResult, error) {
+	br := bufio.NewReaderSize(r, 512)
+	head, _ := br.Peek(512)
+
+	kind := detectArchive(head)
+	if kind == noArchive || depth >= u.maxDepth {
+		return u.scanMember(br, name)
+	}
+
+	// the archive needs to be scanned as a whole and read again for unpacking
+	tmp, err := os.CreateTemp("", "ocis-antivirus-*")
+	if err != nil {
+		return ScanResult{}, err
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+
+	size, err := io.Copy(tmp, br)
+	if err != nil {
+		return ScanResult{}, u.limitErr(err)
+	}
+	if u.budget < 0 {
+		u.budget = size * u.maxExpansionRatio
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return ScanResult{}, err
+	}
+	res, err := u.scanMember(tmp, name)
+	if err != nil || res.Infected {
+		return res, err
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return ScanResult{}, err
+	}
+
+	switch kind {
+	case zipArchive:
+		return u.unzip(tmp, size, name, depth)
+	case tarArchive:
+		return u.untar(tmp, name, depth)
+	default:
+		return u.gunzip(tmp, name, depth)
+	}
+}
+
+func (u *unpacker) scanMember(r io.Reader, name string) (ScanResult, error) {
+	res, err := u.scanner.Scan(r)
+	if u.exceeded {
+		return ScanResult{}, ErrArchiveLimitExceeded
+	}
+	if res.Infected {
+		res.Member = name
+	}
+	return res, err
+}
+
+func (u *unpacker) unzip(f *os.File, size int64, name string, depth int) (ScanResult, error) {
+	zr, err := zip.NewReader(f, size)
+	if err != nil {
+		// not a valid archive, e.g. because only a part of the file was read. The raw content was scanned already.
+		u.truncated = true
+		return ScanResult{Scantime: time.Now()}, nil
+	}
+
+	for _, zf := range zr.File {
+		if zf.FileInfo().IsDir() {
+			continue
+		}
+		if err := u.count(); err != nil {
+			return ScanResult{}, err
+		}
+
+		rc, err := zf.Open()
+		if err != nil {
+			continue
+		}
+		res, err := u.scan(u.limit(rc), path.Join(name, zf.Name), depth+1)
+		rc.Close()
+		if err != nil || res.Infected {
+			return res, err
+		}
+	}
+
+	return ScanResult{Scantime: time.Now()}, nil
+}
+
+func (u *unpacker) untar(f io.Reader, name string, depth int) (ScanResult, error) {
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			// io.EOF or a broken archive, the raw content was scanned already
+			if err != io.EOF {
+				u.truncated = true
+			}
+			return ScanResult{Scantime: time.Now()}, u.limitErr(nil)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := u.count(); err != nil {
+			return ScanResult{}, err
+		}
+
+		res, err := u.scan(u.limit(u.truncation(tr)), path.Join(name, hdr.Name), depth+1)
+		if err != nil || res.Infected {
+			return res, err
+		}
+	}
+}
+
+func (u *unpacker) gunzip(f io.Reader, name string, depth int) (ScanResult, error) {
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		u.truncated = true
+		return ScanResult{Scantime: time.Now()}, nil
+	}
+	defer gr.Close()
+
+	member := gr.Name
+	if member == "" {
+		member = strings.TrimSuffix(path.Base(name), ".gz")
+	}
+	if member == "" || member == "." {
+		member = "content"
+	}
+
+	if err := u.count(); err != nil {
+		return ScanResult{}, err
+	}
+	return u.scan(u.limit(u.truncation(gr)), path.Join(name, member), depth+1)
+}
+
+func (u *unpacker) count() error {
+	u.entries++
+	if u.entries > u.maxEntries {
+		return ErrArchiveLimitExceeded
+	}
+	return nil
+}
+
+func (u *unpacker) limitErr(err error) error {
+	if u.exceeded {
+		return ErrArchiveLimitExceeded
+	}
+	return err
+}
+
+func (u *unpacker) limit(r io.Reader) io.Reader {
+	return &budgetReader{r: r, u: u}
+}
+
+func (u *unpacker) truncation(r io.Reader) io.Reader {
+	return &truncatedReader{r: r, u: u}
+}
+
+// truncatedReader ends the content of a truncated archive member where the archive ends,
+// so the unpacked part of it is still scanned.
+type truncatedReader struct {
+	r io.Reader
+	u *unpacker
+}
+
+func (t *truncatedReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		t.u.truncated = true
+		err = io.EOF
+	}
+	return n, err
+}
+
+// budgetReader fails as soon as more bytes are unpacked than allowed by the expansion ratio
+type budgetReader struct {
+	r io.Reader
+	u *unpacker
+}
+
+func (b *budgetReader) Read(p []byte) (int, error) {
+	if b.u.exceeded {
+		return 0, ErrArchiveLimitExceeded
+	}
+
+	n, err := b.r.Read(p)
+	b.u.budget -= int64(n)
+	if b.u.budget < 0 {
+		b.u.exceeded = true
+		return n, ErrArchiveLimitExceeded
+	}
+	return n, err
+}
+
+func detectArchive(head []byte) archiveKind {
+	switch {
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")):
+		return zipArchive
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+		return gzipArchive
+	case len(head) >= 262 && bytes.Equal(head[257:262], []byte("ustar")):
+		return tarArchive
+	default:
+		return noArchive
+	}
+}
@@ -0,0 +1,231 @@
+package scanners
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// contentScanner reports an infection when the scanned content equals the infected content.
+// Unlike a signature it does not match the raw bytes of an archive containing that content.
+type contentScanner struct {
+	infected []byte
+}
+
+func (s contentScanner) Scan(file io.Reader) (ScanResult, error) {
+	b, err := io.ReadAll(file)
+	if err != nil {
+		return ScanResult{}, err
+	}
+	if bytes.Equal(b, s.infected) {
+		return ScanResult{Infected: true, Description: "Evil-Signature"}, nil
+	}
+	return ScanResult{}, nil
+}
+
+type archiveFile struct {
+	name    string
+	content []byte
+}
+
+func zipFiles(t *testing.T, method uint16, files ...archiveFile) []byte {
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for _, f := range files {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: method})
+		require.NoError(t, err)
+		_, err = w.Write(f.content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func tarFiles(t *testing.T, files ...archiveFile) []byte {
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for _, f := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0600, Size: int64(len(f.content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write(f.content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	return buf.Bytes()
+}
+
+func gzipFile(t *testing.T, name string, content []byte) []byte {
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	gw.Name = name
+	_, err := gw.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, gw.Close())
+	return buf.Bytes()
+}
+
+func TestArchiveScan(t *testing.T) {
+	evil := archiveFile{name: "evil.txt", content: []byte("some EVIL content")}
+	clean := archiveFile{name: "clean.txt", content: []byte("nothing to see here")}
+	nested := func(t *testing.T) []byte {
+		// zip > tar.gz > tar > evil.txt
+		return zipFiles(t, zip.Deflate, clean, archiveFile{
+			name:    "docs/inner.tar.gz",
+			content: gzipFile(t, "inner.tar", tarFiles(t, clean, evil)),
+		})
+	}
+
+	tests := map[string]struct {
+		data        func(t *testing.T) []byte
+		maxDepth    int
+		maxEntries  int
+		infected    func(t *testing.T) []byte // the content the scanner reports, defaults to evil.txt
+		want        string                    // infected member, empty for the scanned file itself
+		wantClean   bool
+		wantPartial bool
+		wantErr     error
+	}{
+		"plain file": {
+			data:      func(t *testing.T) []byte { return clean.content },
+			wantClean: true,
+		},
+		"infected plain file": {
+			data: func(t *testing.T) []byte { return evil.content },
+		},
+		"clean zip": {
+			data:      func(t *testing.T) []byte { return zipFiles(t, zip.Deflate, clean, clean) },
+			wantClean: true,
+		},
+		"infected zip member": {
+			data: func(t *testing.T) []byte { return zipFiles(t, zip.Deflate, clean, evil) },
+			want: "evil.txt",
+		},
+		"infected archive": {
+			data: func(t *testing.T) []byte { return zipFiles(t, zip.Store, evil) },
+			infected: func(t *testing.T) []byte {
+				return zipFiles(t, zip.Store, evil)
+			},
+		},
+		"infected tar member": {
+			data: func(t *testing.T) []byte { return tarFiles(t, clean, evil) },
+			want: "evil.txt",
+		},
+		"gzip uses the stored name": {
+			data: func(t *testing.T) []byte { return gzipFile(t, "evil.txt", evil.content) },
+			want: "evil.txt",
+		},
+		"nested archives": {
+			data:     nested,
+			maxDepth: 3,
+			want:     "docs/inner.tar.gz/inner.tar/evil.txt",
+		},
+		"nesting deeper than the max depth is scanned as a whole": {
+			data:      nested,
+			maxDepth:  2,
+			wantClean: true,
+		},
+		"broken archive": {
+			data:        func(t *testing.T) []byte { return []byte("PK\x03\x04 this is not a zip file") },
+			wantClean:   true,
+			wantPartial: true,
+		},
+		"truncated zip": {
+			data: func(t *testing.T) []byte {
+				b := zipFiles(t, zip.Deflate, clean, evil)
+				return b[:len(b)-30]
+			},
+			wantClean:   true,
+			wantPartial: true,
+		},
+		"truncated tar": {
+			data: func(t *testing.T) []byte {
+				b := tarFiles(t, clean, archiveFile{name: "big.bin", content: make([]byte, 4096)})
+				return b[:2048]
+			},
+			wantClean:   true,
+			wantPartial: true,
+		},
+		"truncated tar with an infected member before the end": {
+			data: func(t *testing.T) []byte {
+				b := tarFiles(t, evil, archiveFile{name: "big.bin", content: make([]byte, 4096)})
+				return b[:2048]
+			},
+			want: "evil.txt",
+		},
+		"truncated gzip": {
+			data: func(t *testing.T) []byte {
+				b := gzipFile(t, "clean.txt", bytes.Repeat(clean.content, 100))
+				return b[:len(b)/2]
+			},
+			wantClean:   true,
+			wantPartial: true,
+		},
+		"too many entries": {
+			data:       func(t *testing.T) []byte { return zipFiles(t, zip.Deflate, clean, clean, clean) },
+			maxEntries: 2,
+			wantErr:    ErrArchiveLimitExceeded,
+		},
+		"too many entries in nested archives": {
+			data:       nested,
+			maxDepth:   3,
+			maxEntries: 4,
+			wantErr:    ErrArchiveLimitExceeded,
+		},
+		"zip bomb": {
+			data: func(t *testing.T) []byte {
+				return zipFiles(t, zip.Deflate, archiveFile{name: "zeros", content: make([]byte, 10<<20)})
+			},
+			wantErr: ErrArchiveLimitExceeded,
+		},
+		"gzip bomb": {
+			data:    func(t *testing.T) []byte { return gzipFile(t, "zeros", make([]byte, 10<<20)) },
+			wantErr: ErrArchiveLimitExceeded,
+		},
+		"nested zip bomb": {
+			data: func(t *testing.T) []byte {
+				return tarFiles(t, archiveFile{name: "bomb.gz", content: gzipFile(t, "zeros", make([]byte, 10<<20))})
+			},
+			wantErr: ErrArchiveLimitExceeded,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if tt.maxDepth == 0 {
+				tt.maxDepth = 5
+			}
+			if tt.maxEntries == 0 {
+				tt.maxEntries = 100
+			}
+			infected := evil.content
+			if tt.infected != nil {
+				infected = tt.infected(t)
+			}
+			s := NewArchive(contentScanner{infected: infected}, tt.maxDepth, tt.maxEntries, 100)
+
+			res, err := s.Scan(bytes.NewReader(tt.data(t)))
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, !tt.wantClean, res.Infected)
+			assert.Equal(t, tt.want, res.Member)
+			assert.Equal(t, tt.wantPartial, res.Partial)
+			if res.Infected {
+				assert.Equal(t, "Evil-Signature", res.Description, "the description only contains the virus")
+			}
+		})
+	}
+}
+
+func TestArchiveVersion(t *testing.T) {
+	v, err := NewArchive(&fakeScanner{version: "1"}, 1, 1, 1).Version()
+	require.NoError(t, err)
+	assert.Equal(t, "1", v)
+}
@@ -0,0 +1,76 @@
+package scanners
+
+import (
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// NewChain returns a Scanner running the given scanners in sequence
+func NewChain(s ...Scanner) *Chain {
+	return &Chain{
+		scanners: s,
+	}
+}
+
+// Chain is a Scanner combining several scanners. They are run in the given order,
+// the first one reporting an infection wins and stops the chain.
+type Chain struct {
+	scanners []Scanner
+}
+
+// Scan to fulfill Scanner interface
+func (s Chain) Scan(file io.Reader) (ScanResult, error) {
+	if len(s.scanners) == 1 {
+		return s.scanners[0].Scan(file)
+	}
+
+	// every scanner needs to read the whole file, so we need something we can rewind
+	rs, ok := file.(io.ReadSeeker)
+	if !ok {
+		tmp, err := os.CreateTemp("", "ocis-antivirus-*")
+		if err != nil {
+			return ScanResult{}, err
+		}
+		defer func() {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}()
+
+		if _, err := io.Copy(tmp, file); err != nil {
+			return ScanResult{}, err
+		}
+		rs = tmp
+	}
+
+	for _, scanner := range s.scanners {
+		if _, err := rs.Seek(0, io.SeekStart); err != nil {
+			return ScanResult{}, err
+		}
+
+		res, err := scanner.Scan(rs)
+		if err != nil {
+			return res, err
+		}
+
+		if res.Infected {
+			return res, nil
+		}
+	}
+
+	return ScanResult{Scantime: time.Now()}, nil
+}
+
+// Version combines the versions of all chained scanners. It is empty if any of them can't tell its version.
+func (s Chain) Version() (string, error) {
+	versions := make([]string, 0, len(s.scanners))
+	for _, scanner := range s.scanners {
+		v, err := Version(scanner)
+		if err != nil || v == "" {
+			return "", err
+		}
+		versions = append(versions, v)
+	}
+	return strings.Join(versions, ";"), nil
+}
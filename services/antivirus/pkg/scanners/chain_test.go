@@ -0,0 +1,113 @@
+package scanners
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeScanner reports an infection when the scanned content contains its marker
+type fakeScanner struct {
+	marker  string
+	err     error
+	version string
+	seen    []string
+}
+
+func (s *fakeScanner) Scan(file io.Reader) (ScanResult, error) {
+	b, err := io.ReadAll(file)
+	if err != nil {
+		return ScanResult{}, err
+	}
+	s.seen = append(s.seen, string(b))
+	if s.err != nil {
+		return ScanResult{}, s.err
+	}
+	if s.marker != "" && strings.Contains(string(b), s.marker) {
+		return ScanResult{Infected: true, Description: s.marker}, nil
+	}
+	return ScanResult{}, nil
+}
+
+func (s *fakeScanner) Version() (string, error) {
+	return s.version, nil
+}
+
+// plainScanner does not implement Versioner
+type plainScanner struct{}
+
+func (plainScanner) Scan(io.Reader) (ScanResult, error) { return ScanResult{}, nil }
+
+func TestChainScan(t *testing.T) {
+	tests := map[string]struct {
+		content     string
+		want        string
+		wantErr     bool
+		wantScanned []int // number of scans per scanner
+	}{
+		"clean":              {content: "clean", wantScanned: []int{1, 1, 1}},
+		"first scanner wins": {content: "first second", want: "first", wantScanned: []int{1, 0, 0}},
+		"stops the chain":    {content: "second", want: "second", wantScanned: []int{1, 1, 0}},
+		"error aborts":       {content: "broken", wantErr: true, wantScanned: []int{1, 1, 0}},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			scanners := []*fakeScanner{{marker: "first"}, {marker: "second"}, {marker: "third"}}
+			if tt.wantErr {
+				scanners[1].err = errors.New("scanner failed")
+			}
+			c := NewChain(scanners[0], scanners[1], scanners[2])
+
+			// a plain reader needs to be buffered so that every scanner sees the full content
+			res, err := c.Scan(iotest.OneByteReader(strings.NewReader(tt.content)))
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.want != "", res.Infected)
+				assert.Equal(t, tt.want, res.Description)
+			}
+
+			for i, s := range scanners {
+				require.Len(t, s.seen, tt.wantScanned[i], "scanner %d", i)
+				for _, seen := range s.seen {
+					assert.Equal(t, tt.content, seen, "scanner %d", i)
+				}
+			}
+		})
+	}
+}
+
+func TestChainScanSeeker(t *testing.T) {
+	first, second := &fakeScanner{}, &fakeScanner{}
+	_, err := NewChain(first, second).Scan(strings.NewReader("content"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"content"}, first.seen)
+	assert.Equal(t, []string{"content"}, second.seen)
+}
+
+func TestChainVersion(t *testing.T) {
+	tests := map[string]struct {
+		scanners []Scanner
+		want     string
+	}{
+		"all versions":     {scanners: []Scanner{&fakeScanner{version: "a"}, &fakeScanner{version: "b"}}, want: "a;b"},
+		"empty version":    {scanners: []Scanner{&fakeScanner{version: "a"}, &fakeScanner{}}},
+		"no versioner":     {scanners: []Scanner{&fakeScanner{version: "a"}, plainScanner{}}},
+		"single versioned": {scanners: []Scanner{&fakeScanner{version: "a"}}, want: "a"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			v, err := NewChain(tt.scanners...).Version()
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, v)
+		})
+	}
+}
@@ -33,3 +33,14 @@ func (s ClamAV) Scan(file io.Reader) (ScanResult, error) {
 		Scantime:    time.Now(),
 	}, nil
 }
+
+// Version returns the clamav and signature database version
+func (s ClamAV) Version() (string, error) {
+	ch, err := s.clamd.Version()
+	if err != nil {
+		return "", err
+	}
+
+	r := <-ch
+	return r.Raw, nil
+}
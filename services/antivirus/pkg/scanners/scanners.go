@@ -13,6 +13,10 @@ type ScanResult struct {
 	Infected    bool
 	Scantime    time.Time
 	Description string
+	// Member is the path of the infected file inside an archive, if any
+	Member string
+	// Partial is set when only a part of the file could be scanned, e.g. because an archive was truncated
+	Partial bool
 }
 
 // Scanner is an abstraction for the actual virus scan
@@ -20,15 +24,63 @@ type Scanner interface {
 	Scan(file io.Reader) (ScanResult, error)
 }
 
+// Versioner is implemented by scanners which are able to tell the version of their signatures
+type Versioner interface {
+	Version() (string, error)
+}
+
+// Version returns the signature version of the given scanner. It is empty if the scanner can't tell.
+func Version(s Scanner) (string, error) {
+	v, ok := s.(Versioner)
+	if !ok {
+		return "", nil
+	}
+	return v.Version()
+}
+
 // New returns a new scanner from config
 func New(c *config.Config) (Scanner, error) {
-	switch c.Scanner.Type {
+	s, err := newChain(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Archives.Enabled {
+		return NewArchive(s, c.Archives.MaxDepth, c.Archives.MaxEntries, c.Archives.MaxExpansionRatio), nil
+	}
+	return s, nil
+}
+
+func newChain(c *config.Config) (Scanner, error) {
+	if c.Scanner.Type != "chain" {
+		return newScanner(c.Scanner.Type, c)
+	}
+
+	if len(c.Scanner.Chain) == 0 {
+		return nil, fmt.Errorf("av scanner chain is empty")
+	}
+
+	chain := make([]Scanner, 0, len(c.Scanner.Chain))
+	for _, t := range c.Scanner.Chain {
+		s, err := newScanner(t, c)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, s)
+	}
+
+	return NewChain(chain...), nil
+}
+
+func newScanner(t string, c *config.Config) (Scanner, error) {
+	switch t {
 	default:
-		return nil, fmt.Errorf("unknown av scanner: '%s'", c.Scanner.Type)
+		return nil, fmt.Errorf("unknown av scanner: '%s'", t)
 	case "clamav":
 		return NewClamAV(c.Scanner.ClamAV.Socket), nil
 	case "icap":
 		return NewICAP(c.Scanner.ICAP.URL, c.Scanner.ICAP.Service, time.Duration(c.Scanner.ICAP.Timeout)*time.Second)
+	case "signatures":
+		return NewSignatures(c.Scanner.Signatures.Path)
 	}
-
 }
@@ -0,0 +1,475 @@
+package scanners
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NewSignatures returns a Scanner matching files against the signature rule files found in dir
+func NewSignatures(dir string) (*Signatures, error) {
+	s := &Signatures{
+		hashes: make(map[string]hashSignature),
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read signatures directory: %w", err)
+	}
+
+	// the version is a digest over all loaded rule files
+	digest := sha256.New()
+
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+
+		p := filepath.Join(dir, e.Name())
+		switch strings.ToLower(filepath.Ext(e.Name())) {
+		case ".hdb", ".hsb", ".hash":
+			err = s.loadHashes(p)
+		case ".yar", ".yara":
+			err = s.loadRules(p)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		_, _ = digest.Write([]byte(e.Name()))
+		_, _ = digest.Write(b)
+	}
+	s.version = hex.EncodeToString(digest.Sum(nil))
+
+	for _, r := range s.rules {
+		for _, p := range r.patterns {
+			if len(p.value) > s.maxPattern {
+				s.maxPattern = len(p.value)
+			}
+		}
+	}
+
+	return s, nil
+}
+
+// Signatures is a Scanner matching files against local hash and byte signatures.
+// It does not need any external daemon, which makes it usable in air-gapped setups
+// or as cheap first member of a scanner chain.
+type Signatures struct {
+	hashes     map[string]hashSignature
+	algorithms map[string]struct{}
+	rules      []rule
+	maxPattern int
+	version    string
+}
+
+type hashSignature struct {
+	size int64 // -1 matches any size
+	name string
+}
+
+type rule struct {
+	name      string
+	patterns  []pattern
+	condition condition
+}
+
+type pattern struct {
+	id     string
+	value  []byte
+	mask   []bool // false marks a wildcard byte
+	nocase bool
+}
+
+type condition struct {
+	// min is the number of patterns which need to match, 0 means all of them
+	min int
+	// ids restricts the condition to the given patterns, empty means all of them
+	ids []string
+}
+
+// Scan to fulfill Scanner interface
+func (s Signatures) Scan(file io.Reader) (ScanResult, error) {
+	hashers := make(map[string]hash.Hash, len(s.algorithms))
+	writers := make([]io.Writer, 0, len(s.algorithms))
+	for algo := range s.algorithms {
+		h := newHash(algo)
+		hashers[algo] = h
+		writers = append(writers, h)
+	}
+
+	matched := make([]map[string]struct{}, len(s.rules))
+	for i := range matched {
+		matched[i] = make(map[string]struct{})
+	}
+
+	var (
+		size int64
+		tail []byte
+		buf  = make([]byte, 32*1024)
+	)
+	for {
+		n, err := file.Read(buf)
+		if n > 0 {
+			size += int64(n)
+			for _, w := range writers {
+				_, _ = w.Write(buf[:n])
+			}
+
+			if len(s.rules) > 0 {
+				// keep the end of the previous chunk to find patterns spanning two reads
+				data := append(tail, buf[:n]...)
+				lower := bytes.ToLower(data)
+				for i, r := range s.rules {
+					for _, p := range r.patterns {
+						if _, ok := matched[i][p.id]; ok {
+							continue
+						}
+						if p.match(data, lower) {
+							matched[i][p.id] = struct{}{}
+						}
+					}
+					if r.condition.satisfied(r.patterns, matched[i]) {
+						return ScanResult{Infected: true, Description: r.name, Scantime: time.Now()}, nil
+					}
+				}
+
+				keep := s.maxPattern - 1
+				if keep > len(data) {
+					keep = len(data)
+				}
+				tail = append(tail[:0], data[len(data)-keep:]...)
+			}
+		}
+
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return ScanResult{}, err
+		}
+	}
+
+	for algo, h := range hashers {
+		sig, ok := s.hashes[algo+":"+hex.EncodeToString(h.Sum(nil))]
+		if ok && (sig.size < 0 || sig.size == size) {
+			return ScanResult{Infected: true, Description: sig.name, Scantime: time.Now()}, nil
+		}
+	}
+
+	return ScanResult{Scantime: time.Now()}, nil
+}
+
+// Version returns a digest of the loaded rule files
+func (s Signatures) Version() (string, error) {
+	return s.version, nil
+}
+
+// loadHashes reads a hash database in the clamav 'hash:size:name' format
+func (s *Signatures) loadHashes(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if s.algorithms == nil {
+		s.algorithms = make(map[string]struct{})
+	}
+
+	sc := bufio.NewScanner(f)
+	for ln := 1; sc.Scan(); ln++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			return fmt.Errorf("%s:%d: expected 'hash:size:name'", file, ln)
+		}
+
+		sum := strings.ToLower(parts[0])
+		if _, err := hex.DecodeString(sum); err != nil {
+			return fmt.Errorf("%s:%d: invalid hash '%s'", file, ln, parts[0])
+		}
+
+		var algo string
+		switch len(sum) {
+		case 32:
+			algo = "md5"
+		case 40:
+			algo = "sha1"
+		case 64:
+			algo = "sha256"
+		default:
+			return fmt.Errorf("%s:%d: unsupported hash length %d", file, ln, len(sum))
+		}
+
+		size := int64(-1)
+		if parts[1] != "*" {
+			size, err = strconv.ParseInt(parts[1], 10, 64)
+			if err != nil {
+				return fmt.Errorf("%s:%d: invalid size '%s'", file, ln, parts[1])
+			}
+		}
+
+		s.algorithms[algo] = struct{}{}
+		s.hashes[algo+":"+sum] = hashSignature{size: size, name: parts[2]}
+	}
+
+	return sc.Err()
+}
+
+// loadRules reads YARA-style rules. Only a subset of the YARA language is supported:
+// text strings (optionally 'nocase'), hex strings with '??' wildcards and the
+// conditions 'any of them', 'all of them', '<n> of them' as well as pattern ids
+// combined with either 'and' or 'or'.
+func (s *Signatures) loadRules(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var (
+		current *rule
+		section string
+	)
+
+	sc := bufio.NewScanner(f)
+	for ln := 1; sc.Scan(); ln++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+
+		switch {
+		case current == nil:
+			if !strings.HasPrefix(line, "rule ") {
+				return fmt.Errorf("%s:%d: expected rule definition", file, ln)
+			}
+			name := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(line, "rule "), "{"))
+			name, _, _ = strings.Cut(name, ":")
+			current = &rule{name: strings.TrimSpace(name)}
+			section = ""
+		case line == "{":
+		case line == "}":
+			if len(current.patterns) == 0 {
+				return fmt.Errorf("%s:%d: rule '%s' has no strings", file, ln, current.name)
+			}
+			for _, id := range current.condition.ids {
+				if !current.hasPattern(id) {
+					return fmt.Errorf("%s:%d: rule '%s' references unknown string '%s'", file, ln, current.name, id)
+				}
+			}
+			s.rules = append(s.rules, *current)
+			current = nil
+		case line == "meta:" || line == "strings:" || line == "condition:":
+			section = strings.TrimSuffix(line, ":")
+		case section == "meta":
+		case section == "strings":
+			p, err := parsePattern(line)
+			if err != nil {
+				return fmt.Errorf("%s:%d: %w", file, ln, err)
+			}
+			current.patterns = append(current.patterns, p)
+		case section == "condition":
+			c, err := parseCondition(line)
+			if err != nil {
+				return fmt.Errorf("%s:%d: %w", file, ln, err)
+			}
+			current.condition = c
+		default:
+			return fmt.Errorf("%s:%d: unexpected '%s'", file, ln, line)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+
+	if current != nil {
+		return fmt.Errorf("%s: rule '%s' is not terminated", file, current.name)
+	}
+	return nil
+}
+
+func (r rule) hasPattern(id string) bool {
+	for _, p := range r.patterns {
+		if p.id == id {
+			return true
+		}
+	}
+	return false
+}
+
+func parsePattern(line string) (pattern, error) {
+	id, def, ok := strings.Cut(line, "=")
+	if !ok {
+		return pattern{}, fmt.Errorf("expected '$id = <string>'")
+	}
+	p := pattern{id: strings.TrimSpace(id)}
+	if !strings.HasPrefix(p.id, "$") {
+		return p, fmt.Errorf("invalid string id '%s'", p.id)
+	}
+
+	def = strings.TrimSpace(def)
+	switch {
+	case strings.HasPrefix(def, "\""):
+		end := strings.LastIndex(def, "\"")
+		if end == 0 {
+			return p, fmt.Errorf("unterminated text string")
+		}
+		v, err := strconv.Unquote(def[:end+1])
+		if err != nil {
+			return p, fmt.Errorf("invalid text string: %w", err)
+		}
+		p.value = []byte(v)
+		for _, mod := range strings.Fields(def[end+1:]) {
+			switch mod {
+			case "nocase":
+				p.nocase = true
+				p.value = bytes.ToLower(p.value)
+			case "ascii":
+			default:
+				return p, fmt.Errorf("unsupported string modifier '%s'", mod)
+			}
+		}
+	case strings.HasPrefix(def, "{") && strings.HasSuffix(def, "}"):
+		for _, b := range strings.Fields(strings.Trim(def, "{}")) {
+			if b == "??" {
+				p.value = append(p.value, 0)
+				p.mask = append(p.mask, false)
+				continue
+			}
+			v, err := hex.DecodeString(b)
+			if err != nil || len(v) != 1 {
+				return p, fmt.Errorf("invalid hex byte '%s'", b)
+			}
+			p.value = append(p.value, v[0])
+			p.mask = append(p.mask, true)
+		}
+	default:
+		return p, fmt.Errorf("unsupported string definition '%s'", def)
+	}
+
+	if len(p.value) == 0 {
+		return p, fmt.Errorf("empty string '%s'", p.id)
+	}
+	return p, nil
+}
+
+func parseCondition(line string) (condition, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 3 && fields[1] == "of" && fields[2] == "them" {
+		switch fields[0] {
+		case "any":
+			return condition{min: 1}, nil
+		case "all":
+			return condition{}, nil
+		default:
+			n, err := strconv.Atoi(fields[0])
+			if err != nil || n < 1 {
+				return condition{}, fmt.Errorf("invalid condition '%s'", line)
+			}
+			return condition{min: n}, nil
+		}
+	}
+
+	// '$a', '$a and $b and ...' or '$a or $b or ...'
+	var c condition
+	op := ""
+	for i, f := range fields {
+		if i%2 == 0 {
+			if !strings.HasPrefix(f, "$") {
+				return c, fmt.Errorf("invalid condition '%s'", line)
+			}
+			c.ids = append(c.ids, f)
+			continue
+		}
+		if (f != "and" && f != "or") || (op != "" && op != f) {
+			return c, fmt.Errorf("unsupported condition '%s'", line)
+		}
+		op = f
+	}
+	if len(c.ids) == 0 || len(fields)%2 == 0 {
+		return c, fmt.Errorf("invalid condition '%s'", line)
+	}
+	if op == "or" {
+		c.min = 1
+	}
+	return c, nil
+}
+
+func (c condition) satisfied(patterns []pattern, matched map[string]struct{}) bool {
+	ids := c.ids
+	if len(ids) == 0 {
+		ids = make([]string, 0, len(patterns))
+		for _, p := range patterns {
+			ids = append(ids, p.id)
+		}
+	}
+
+	n := 0
+	for _, id := range ids {
+		if _, ok := matched[id]; ok {
+			n++
+		}
+	}
+
+	if c.min == 0 {
+		return n == len(ids)
+	}
+	return n >= c.min
+}
+
+func (p pattern) match(data, lower []byte) bool {
+	if p.nocase {
+		data = lower
+	}
+	if p.mask == nil {
+		return bytes.Contains(data, p.value)
+	}
+
+	for i := 0; i+len(p.value) <= len(data); i++ {
+		found := true
+		for j, b := range p.value {
+			if p.mask[j] && data[i+j] != b {
+				found = false
+				break
+			}
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+func newHash(algo string) hash.Hash {
+	switch algo {
+	case "md5":
+		return md5.New()
+	case "sha1":
+		return sha1.New()
+	default:
+		return sha256.New()
+	}
+}
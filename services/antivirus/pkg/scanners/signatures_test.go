@@ -0,0 +1,256 @@
+package scanners
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeSignatures(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+	}
+	return dir
+}
+
+func TestParsePattern(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    pattern
+		wantErr bool
+	}{
+		{line: `$a = "evil"`, want: pattern{id: "$a", value: []byte("evil")}},
+		{line: `$a = "EviL" nocase`, want: pattern{id: "$a", value: []byte("evil"), nocase: true}},
+		{line: `$a = "evil" ascii`, want: pattern{id: "$a", value: []byte("evil")}},
+		{line: `$a = "tab\there"`, want: pattern{id: "$a", value: []byte("tab\there")}},
+		{line: `$h = { 4D 5a ?? 00 }`, want: pattern{id: "$h", value: []byte{0x4d, 0x5a, 0, 0}, mask: []bool{true, true, false, true}}},
+		{line: `$a "evil"`, wantErr: true},
+		{line: `a = "evil"`, wantErr: true},
+		{line: `$a = "evil`, wantErr: true},
+		{line: `$a = ""`, wantErr: true},
+		{line: `$a = "evil" wide`, wantErr: true},
+		{line: `$h = { 4D 5 }`, wantErr: true},
+		{line: `$h = { 4D5A }`, wantErr: true},
+		{line: `$h = { }`, wantErr: true},
+		{line: `$r = /evil/`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			p, err := parsePattern(tt.line)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, p)
+		})
+	}
+}
+
+func TestParseCondition(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    condition
+		wantErr bool
+	}{
+		{line: "any of them", want: condition{min: 1}},
+		{line: "all of them", want: condition{}},
+		{line: "2 of them", want: condition{min: 2}},
+		{line: "$a", want: condition{ids: []string{"$a"}}},
+		{line: "$a and $b", want: condition{ids: []string{"$a", "$b"}}},
+		{line: "$a or $b or $c", want: condition{min: 1, ids: []string{"$a", "$b", "$c"}}},
+		{line: "0 of them", wantErr: true},
+		{line: "some of them", wantErr: true},
+		{line: "$a and $b or $c", wantErr: true},
+		{line: "$a and", wantErr: true},
+		{line: "$a not $b", wantErr: true},
+		{line: "a and b", wantErr: true},
+		{line: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			c, err := parseCondition(tt.line)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, c)
+		})
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	tests := map[string]struct {
+		rules   string
+		wantErr string
+	}{
+		"valid": {rules: `
+// comment
+rule Test_Rule : tag
+{
+    meta:
+        author = "someone"
+    strings:
+        $a = "evil"
+        $b = { 4D 5A }
+    condition:
+        $a or $b
+}
+rule Other {
+    strings:
+        $a = "other"
+    condition:
+        any of them
+}`},
+		"no strings":         {rules: "rule A {\ncondition:\nany of them\n}", wantErr: "has no strings"},
+		"unknown id":         {rules: "rule A {\nstrings:\n$a = \"x\"\ncondition:\n$b\n}", wantErr: "unknown string '$b'"},
+		"not terminated":     {rules: "rule A {\nstrings:\n$a = \"x\"", wantErr: "is not terminated"},
+		"garbage":            {rules: "import \"pe\"", wantErr: "expected rule definition"},
+		"unexpected section": {rules: "rule A {\nfoo\n}", wantErr: "unexpected 'foo'"},
+		"invalid string":     {rules: "rule A {\nstrings:\n$a = /x/\n}", wantErr: "rules.yar:3"},
+		"invalid condition":  {rules: "rule A {\nstrings:\n$a = \"x\"\ncondition:\n$a xor $a\n}", wantErr: "rules.yar:5"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			s, err := NewSignatures(writeSignatures(t, map[string]string{"rules.yar": tt.rules}))
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, s.rules, 2)
+			assert.Equal(t, "Test_Rule", s.rules[0].name)
+			assert.Equal(t, len("other"), s.maxPattern)
+		})
+	}
+}
+
+func TestLoadHashes(t *testing.T) {
+	tests := map[string]struct {
+		hashes  string
+		wantErr string
+	}{
+		"valid":          {hashes: "# comment\n44d88612fea8a8f36de82e1278abb02f:68:Eicar\n\n" + strings.Repeat("a", 64) + ":*:Any-Size"},
+		"missing fields": {hashes: "44d88612fea8a8f36de82e1278abb02f:68", wantErr: "expected 'hash:size:name'"},
+		"invalid hash":   {hashes: "xyz:68:Name", wantErr: "invalid hash"},
+		"invalid length": {hashes: "abcd:68:Name", wantErr: "unsupported hash length"},
+		"invalid size":   {hashes: "44d88612fea8a8f36de82e1278abb02f:big:Name", wantErr: "invalid size"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			s, err := NewSignatures(writeSignatures(t, map[string]string{"db.hdb": tt.hashes}))
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, s.hashes, 2)
+			assert.Contains(t, s.algorithms, "md5")
+			assert.Contains(t, s.algorithms, "sha256")
+		})
+	}
+}
+
+func TestSignaturesScan(t *testing.T) {
+	eicar := []byte(`X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`)
+	md5Sum := md5.Sum(eicar)
+	shaSum := sha256.Sum256([]byte("exact size"))
+
+	dir := writeSignatures(t, map[string]string{
+		"db.hdb": hex.EncodeToString(md5Sum[:]) + ":*:Eicar-Hash\n" +
+			hex.EncodeToString(shaSum[:]) + ":999:Wrong-Size\n",
+		"rules.yara": `
+rule Text_All {
+    strings:
+        $a = "first part"
+        $b = "second part"
+    condition:
+        all of them
+}
+rule Nocase {
+    strings:
+        $a = "ShOuTiNg" nocase
+    condition:
+        $a
+}
+rule Hex_Wildcard {
+    strings:
+        $h = { DE AD ?? EF }
+    condition:
+        $h
+}
+rule Two_Of_Three {
+    strings:
+        $a = "alpha"
+        $b = "beta"
+        $c = "gamma"
+    condition:
+        2 of them
+}`,
+		"ignored.txt": "not a signature file",
+	})
+	s, err := NewSignatures(dir)
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		data []byte
+		want string
+	}{
+		"clean":                {data: []byte("nothing to see here")},
+		"hash":                 {data: eicar, want: "Eicar-Hash"},
+		"hash with wrong size": {data: []byte("exact size")},
+		"all strings":          {data: []byte("the first part and the second part"), want: "Text_All"},
+		"only one string":      {data: []byte("only the first part")},
+		"nocase":               {data: []byte("stop shouting"), want: "Nocase"},
+		"hex wildcard":         {data: []byte{0x00, 0xde, 0xad, 0x42, 0xef, 0x00}, want: "Hex_Wildcard"},
+		"hex mismatch":         {data: []byte{0xde, 0xad, 0x42, 0xee}},
+		"two of three":         {data: []byte("alpha and gamma"), want: "Two_Of_Three"},
+		"one of three":         {data: []byte("beta only")},
+		// the pattern spans two reads of the scanner
+		"across chunks": {data: append(bytes.Repeat([]byte("x"), 32*1024-3), []byte("shouting")...), want: "Nocase"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			res, err := s.Scan(iotest.HalfReader(bytes.NewReader(tt.data)))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want != "", res.Infected)
+			assert.Equal(t, tt.want, res.Description)
+		})
+	}
+}
+
+func TestSignaturesVersion(t *testing.T) {
+	files := map[string]string{"db.hdb": "44d88612fea8a8f36de82e1278abb02f:68:Eicar"}
+	s1, err := NewSignatures(writeSignatures(t, files))
+	require.NoError(t, err)
+	s2, err := NewSignatures(writeSignatures(t, files))
+	require.NoError(t, err)
+	files["db.hdb"] += "\n44d88612fea8a8f36de82e1278abb02e:68:Other"
+	s3, err := NewSignatures(writeSignatures(t, files))
+	require.NoError(t, err)
+
+	v1, _ := s1.Version()
+	v2, _ := s2.Version()
+	v3, _ := s3.Version()
+	assert.NotEmpty(t, v1)
+	assert.Equal(t, v1, v2, "the same files have the same version")
+	assert.NotEqual(t, v1, v3, "changed files change the version")
+}
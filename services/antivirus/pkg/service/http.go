@@ -0,0 +1,171 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	permissionsapi "github.com/cs3org/go-cs3apis/cs3/permissions/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	revactx "github.com/cs3org/reva/v2/pkg/ctx"
+	"github.com/cs3org/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/go-chi/chi/v5"
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/services/antivirus/pkg/quarantine"
+	"github.com/owncloud/ocis/v2/services/settings/pkg/store/defaults"
+)
+
+// QuarantineHandler serves the admin API for quarantined files
+type QuarantineHandler struct {
+	m               *chi.Mux
+	l               log.Logger
+	q               *quarantine.Manager
+	gatewaySelector pool.Selectable[gateway.GatewayAPIClient]
+}
+
+// NewQuarantineHandler returns the http handler for the quarantine API
+func NewQuarantineHandler(mux *chi.Mux, root string, l log.Logger, q *quarantine.Manager, gatewaySelector pool.Selectable[gateway.GatewayAPIClient]) *QuarantineHandler {
+	h := &QuarantineHandler{
+		m:               mux,
+		l:               l,
+		q:               q,
+		gatewaySelector: gatewaySelector,
+	}
+
+	h.m.Route(root, func(r chi.Router) {
+		r.Route("/quarantine", func(r chi.Router) {
+			r.Use(h.requirePermission)
+			r.Get("/", h.HandleList)
+			r.Get("/{id}", h.HandleGet)
+			r.Get("/{id}/content", h.HandleDownload)
+			r.Post("/{id}/release", h.HandleRelease)
+			r.Delete("/{id}", h.HandlePurge)
+		})
+	})
+
+	return h
+}
+
+// ServeHTTP fulfills Handler interface
+func (h *QuarantineHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.m.ServeHTTP(w, r)
+}
+
+// HandleList is the GET handler listing all quarantined items
+func (h *QuarantineHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	items, err := h.q.List()
+	if err != nil {
+		h.l.Error().Err(err).Int("returned statuscode", http.StatusInternalServerError).Msg("listing quarantined items failed")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	b, _ := json.Marshal(items)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+// HandleGet is the GET handler for a single quarantined item
+func (h *QuarantineHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
+	item, err := h.q.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, err, "getting quarantined item failed")
+		return
+	}
+
+	b, _ := json.Marshal(item)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+// HandleDownload is the GET handler for the content of a quarantined item
+func (h *QuarantineHandler) HandleDownload(w http.ResponseWriter, r *http.Request) {
+	u := revactx.ContextMustGetUser(r.Context())
+
+	item, rc, err := h.q.Download(u.GetId(), chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, err, "downloading quarantined item failed")
+		return
+	}
+	defer rc.Close()
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", "attachment; filename="+strconv.Quote(item.Filename))
+	w.Header().Set("Content-Length", strconv.FormatUint(item.Size, 10))
+	if _, err := io.Copy(w, rc); err != nil {
+		h.l.Error().Err(err).Str("item", item.ID).Msg("sending quarantined item failed")
+	}
+}
+
+// HandleRelease is the POST handler releasing a quarantined item to its original location
+func (h *QuarantineHandler) HandleRelease(w http.ResponseWriter, r *http.Request) {
+	u := revactx.ContextMustGetUser(r.Context())
+
+	if err := h.q.Release(u.GetId(), chi.URLParam(r, "id")); err != nil {
+		h.writeError(w, err, "releasing quarantined item failed")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandlePurge is the DELETE handler removing a quarantined item
+func (h *QuarantineHandler) HandlePurge(w http.ResponseWriter, r *http.Request) {
+	u := revactx.ContextMustGetUser(r.Context())
+
+	if err := h.q.Purge(u.GetId(), chi.URLParam(r, "id")); err != nil {
+		h.writeError(w, err, "purging quarantined item failed")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requirePermission only lets users with the quarantine management permission pass
+func (h *QuarantineHandler) requirePermission(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u, ok := revactx.ContextGetUser(r.Context())
+		if !ok {
+			h.l.Error().Int("returned statuscode", http.StatusUnauthorized).Msg("user unauthorized")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		gatewayClient, err := h.gatewaySelector.Next()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		rsp, err := gatewayClient.CheckPermission(r.Context(), &permissionsapi.CheckPermissionRequest{
+			Permission: defaults.QuarantineManagementPermissionName,
+			SubjectRef: &permissionsapi.SubjectReference{
+				Spec: &permissionsapi.SubjectReference_UserId{
+					UserId: u.GetId(),
+				},
+			},
+		})
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if rsp.GetStatus().GetCode() != rpc.Code_CODE_OK {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *QuarantineHandler) writeError(w http.ResponseWriter, err error, msg string) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, quarantine.ErrNotFound) {
+		status = http.StatusNotFound
+	}
+	h.l.Error().Err(err).Int("returned statuscode", status).Msg(msg)
+	w.WriteHeader(status)
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	microstore "go-micro.dev/v4/store"
+)
+
+const (
+	// _rescanLockKey is the key of the rescan lock in the store
+	_rescanLockKey = "rescan-lock"
+	// _rescanLease is the time a rescan lock is valid without being refreshed
+	_rescanLease = 2 * time.Minute
+	// _rescanLockSettle is the time to wait for concurrent writes of the rescan lock before reading it back
+	_rescanLockSettle = 2 * time.Second
+)
+
+// rescanLock coordinates the rescans of several instances of the service sharing the same store.
+// The store has no compare-and-set operation. An instance writes the lock, waits until concurrent
+// writes of other instances have landed and reads it back, only the last writer owns the lock.
+// This requires writes to the store to take less than the settle time.
+type rescanLock struct {
+	Instance string
+	Started  time.Time
+	Expires  time.Time // refreshed while the rescan is running
+	Finished time.Time
+}
+
+// held returns true while the rescan of the lock is running
+func (l rescanLock) held(now time.Time) bool {
+	return l.Finished.IsZero() && l.Expires.After(now)
+}
+
+// ownedBy returns true if the lock belongs to the rescan the instance started at the given time
+func (l rescanLock) ownedBy(instance string, started time.Time) bool {
+	return l.Instance == instance && l.Started.Equal(started)
+}
+
+// acquireRescanLock takes the rescan lock for the instance and returns the start time of the rescan.
+// A scheduled rescan is skipped as well when another instance started one less than the rescan interval ago.
+func (av Antivirus) acquireRescanLock(scheduled bool) (time.Time, bool, error) {
+	now := time.Now()
+	l, err := av.readRescanLock()
+	switch {
+	case err != nil:
+		return now, false, err
+	case l.held(now):
+		return now, false, nil
+	case scheduled && l.Instance != av.instance && now.Sub(l.Started) < av.c.Rescan.Interval*9/10:
+		// the instances tick at different times, the rescan of another instance covers this tick
+		return now, false, nil
+	}
+
+	if err := av.writeRescanLock(rescanLock{Instance: av.instance, Started: now, Expires: now.Add(_rescanLease)}); err != nil {
+		return now, false, err
+	}
+
+	// instances which found the lock free at the same time have written it by now, the last one wins
+	time.Sleep(av.lockSettle)
+
+	l, err = av.readRescanLock()
+	if err != nil {
+		return now, false, err
+	}
+	return now, l.ownedBy(av.instance, now), nil
+}
+
+// refreshRescanLock extends the lease of the lock until done is closed. It calls lost and stops
+// when the lock was taken over by another instance, e.g. because it could not be refreshed in time.
+func (av Antivirus) refreshRescanLock(started time.Time, done <-chan struct{}, lost func()) {
+	t := time.NewTicker(_rescanLease / 3)
+	defer t.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-t.C:
+			owned, err := av.extendRescanLock(started)
+			switch {
+			case err != nil:
+				av.l.Error().Err(err).Msg("cannot refresh rescan lock")
+			case !owned:
+				av.l.Error().Msg("the rescan lock was taken over by another instance, stopping the rescan")
+				lost()
+				return
+			}
+		}
+	}
+}
+
+// extendRescanLock extends the lease of the lock if the instance still owns it
+func (av Antivirus) extendRescanLock(started time.Time) (bool, error) {
+	l, err := av.readRescanLock()
+	if err != nil {
+		return false, err
+	}
+	if !l.ownedBy(av.instance, started) {
+		return false, nil
+	}
+
+	l.Expires = time.Now().Add(_rescanLease)
+	return true, av.writeRescanLock(l)
+}
+
+// releaseRescanLock marks the rescan of the instance as finished unless the lock was taken over meanwhile
+func (av Antivirus) releaseRescanLock(started time.Time) {
+	l, err := av.readRescanLock()
+	switch {
+	case err != nil:
+		av.l.Error().Err(err).Msg("cannot release rescan lock")
+		return
+	case !l.ownedBy(av.instance, started):
+		return
+	}
+
+	now := time.Now()
+	l.Expires, l.Finished = now, now
+	if err := av.writeRescanLock(l); err != nil {
+		av.l.Error().Err(err).Msg("cannot release rescan lock")
+	}
+}
+
+func (av Antivirus) readRescanLock() (rescanLock, error) {
+	var l rescanLock
+	recs, err := av.store.Read(_rescanLockKey)
+	switch {
+	case errors.Is(err, microstore.ErrNotFound):
+		return l, nil
+	case err != nil:
+		return l, err
+	case len(recs) == 0:
+		return l, nil
+	}
+	return l, json.Unmarshal(recs[0].Value, &l)
+}
+
+func (av Antivirus) writeRescanLock(l rescanLock) error {
+	b, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	return av.store.Write(&microstore.Record{Key: _rescanLockKey, Value: b})
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/event"
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/services/antivirus/pkg/config"
+	"github.com/owncloud/ocis/v2/services/antivirus/pkg/scanners"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	microstore "go-micro.dev/v4/store"
+)
+
+type versionedScanner struct {
+	version string
+}
+
+func (versionedScanner) Scan(io.Reader) (scanners.ScanResult, error) {
+	return scanners.ScanResult{}, nil
+}
+
+func (s versionedScanner) Version() (string, error) {
+	return s.version, nil
+}
+
+func newTestAntivirus(st microstore.Store, instance string, version string) Antivirus {
+	return Antivirus{
+		c:          &config.Config{Rescan: config.Rescan{Interval: time.Hour}},
+		l:          log.NopLogger(),
+		s:          versionedScanner{version: version},
+		store:      st,
+		instance:   instance,
+		lockSettle: 10 * time.Millisecond,
+		rescanning: &atomic.Bool{},
+	}
+}
+
+func TestRescanLock(t *testing.T) {
+	st := microstore.NewMemoryStore()
+	av1 := newTestAntivirus(st, "instance-1", "1")
+	av2 := newTestAntivirus(st, "instance-2", "1")
+
+	started, ok, err := av1.acquireRescanLock(true)
+	require.NoError(t, err)
+	require.True(t, ok, "a free lock can be acquired")
+
+	for _, scheduled := range []bool{true, false} {
+		_, ok, err = av2.acquireRescanLock(scheduled)
+		require.NoError(t, err)
+		assert.False(t, ok, "a held lock can't be acquired by another instance")
+		_, ok, err = av1.acquireRescanLock(scheduled)
+		require.NoError(t, err)
+		assert.False(t, ok, "a held lock can't be acquired again")
+	}
+
+	av1.releaseRescanLock(started)
+
+	_, ok, err = av2.acquireRescanLock(true)
+	require.NoError(t, err)
+	assert.False(t, ok, "a scheduled rescan is skipped when another instance rescanned within the interval")
+
+	started, ok, err = av2.acquireRescanLock(false)
+	require.NoError(t, err)
+	assert.True(t, ok, "a triggered rescan runs after the last one finished")
+	av2.releaseRescanLock(started)
+
+	_, ok, err = av2.acquireRescanLock(true)
+	require.NoError(t, err)
+	assert.True(t, ok, "a scheduled rescan runs when the last rescan was done by the same instance")
+}
+
+func TestRescanLockExpires(t *testing.T) {
+	st := microstore.NewMemoryStore()
+	av1 := newTestAntivirus(st, "instance-1", "1")
+	av2 := newTestAntivirus(st, "instance-2", "1")
+
+	// the lock of a crashed instance which was not refreshed
+	require.NoError(t, av1.writeRescanLock(rescanLock{
+		Instance: "instance-1",
+		Started:  time.Now().Add(-2 * time.Hour),
+		Expires:  time.Now().Add(-time.Minute),
+	}))
+
+	_, ok, err := av2.acquireRescanLock(true)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	l, err := av2.readRescanLock()
+	require.NoError(t, err)
+	assert.Equal(t, "instance-2", l.Instance)
+	assert.True(t, l.held(time.Now()))
+}
+
+func TestRescanLockConcurrentAcquire(t *testing.T) {
+	st := microstore.NewMemoryStore()
+	instances := []Antivirus{
+		newTestAntivirus(st, "instance-1", "1"),
+		newTestAntivirus(st, "instance-2", "1"),
+		newTestAntivirus(st, "instance-3", "1"),
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		owner []string
+	)
+	for _, av := range instances {
+		wg.Add(1)
+		go func(av Antivirus) {
+			defer wg.Done()
+			_, ok, err := av.acquireRescanLock(false)
+			assert.NoError(t, err)
+			if ok {
+				mu.Lock()
+				owner = append(owner, av.instance)
+				mu.Unlock()
+			}
+		}(av)
+	}
+	wg.Wait()
+
+	require.Len(t, owner, 1, "only one instance may acquire the lock")
+	l, err := instances[0].readRescanLock()
+	require.NoError(t, err)
+	assert.Equal(t, owner[0], l.Instance)
+}
+
+func TestRescanLockTakenOver(t *testing.T) {
+	st := microstore.NewMemoryStore()
+	av1 := newTestAntivirus(st, "instance-1", "1")
+	av2 := newTestAntivirus(st, "instance-2", "1")
+
+	started, ok, err := av1.acquireRescanLock(false)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	owned, err := av1.extendRescanLock(started)
+	require.NoError(t, err)
+	assert.True(t, owned, "the owner extends its lock")
+
+	// the lease expired, e.g. because the instance was stalled, and another instance took over
+	now := time.Now()
+	require.NoError(t, av2.writeRescanLock(rescanLock{Instance: "instance-2", Started: now, Expires: now.Add(_rescanLease)}))
+
+	owned, err = av1.extendRescanLock(started)
+	require.NoError(t, err)
+	assert.False(t, owned, "a lock taken over by another instance is not extended")
+
+	av1.releaseRescanLock(started)
+	l, err := av2.readRescanLock()
+	require.NoError(t, err)
+	assert.Equal(t, "instance-2", l.Instance, "a lock taken over by another instance is not released")
+	assert.True(t, l.held(time.Now()))
+}
+
+func TestStartRescanSkipsLockedRescan(t *testing.T) {
+	st := microstore.NewMemoryStore()
+	av1 := newTestAntivirus(st, "instance-1", "1")
+	av2 := newTestAntivirus(st, "instance-2", "1")
+
+	_, ok, err := av1.acquireRescanLock(false)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	av2.startRescan(nil, event.StartRescan{}, false)
+	assert.False(t, av2.rescanning.Load(), "no rescan should be started")
+}
+
+func TestRescanWithoutVersion(t *testing.T) {
+	av := newTestAntivirus(microstore.NewMemoryStore(), "instance-1", "")
+
+	err := av.Rescan(context.Background(), nil, event.StartRescan{})
+	assert.ErrorIs(t, err, ErrNoSignatureVersion)
+
+	// a forced rescan continues, it fails here because there is no gateway
+	err = av.Rescan(context.Background(), nil, event.StartRescan{Force: true})
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrNoSignatureVersion)
+}
@@ -0,0 +1,272 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	ctxpkg "github.com/cs3org/reva/v2/pkg/ctx"
+	"github.com/cs3org/reva/v2/pkg/errtypes"
+	"github.com/cs3org/reva/v2/pkg/events"
+	"github.com/cs3org/reva/v2/pkg/storage/utils/walker"
+	"github.com/cs3org/reva/v2/pkg/utils"
+	"github.com/owncloud/ocis/v2/ocis-pkg/event"
+	"github.com/owncloud/ocis/v2/services/antivirus/pkg/scanners"
+	"google.golang.org/grpc/metadata"
+)
+
+// arbitrary metadata keys used to remember the last scan of a file
+const (
+	ScanDateKey   = "antivirus.scandate"
+	SignaturesKey = "antivirus.signatures"
+	VirusKey      = "antivirus.virus"
+	MemberKey     = "antivirus.member"
+)
+
+// ErrNoSignatureVersion is returned when a rescan which is not forced is started with a scanner
+// which does not report a signature version
+var ErrNoSignatureVersion = errors.New("the scanner does not report a signature version, only forced rescans are possible")
+
+// startRescan runs a rescan in the background unless another one is still running on this
+// or another instance of the service
+func (av Antivirus) startRescan(stream events.Publisher, ev event.StartRescan, scheduled bool) {
+	if !av.rescanning.CompareAndSwap(false, true) {
+		av.l.Info().Msg("a rescan is already running, skipping")
+		return
+	}
+
+	started, ok, err := av.acquireRescanLock(scheduled)
+	switch {
+	case err != nil:
+		av.rescanning.Store(false)
+		av.l.Error().Err(err).Msg("cannot acquire rescan lock")
+		return
+	case !ok:
+		av.rescanning.Store(false)
+		av.l.Info().Bool("scheduled", scheduled).Msg("a rescan is running or was run recently by another instance, skipping")
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go av.refreshRescanLock(started, done, cancel)
+		defer func() {
+			close(done)
+			cancel()
+			av.releaseRescanLock(started)
+			av.rescanning.Store(false)
+		}()
+
+		av.l.Info().Str("space", ev.SpaceID).Bool("force", ev.Force).Msg("starting rescan")
+		if err := av.Rescan(ctx, stream, ev); err != nil {
+			av.l.Error().Err(err).Msg("rescan failed")
+			return
+		}
+		av.l.Info().Str("space", ev.SpaceID).Msg("rescan finished")
+	}()
+}
+
+// Rescan scans existing files. Files already scanned with the current signature version are skipped
+// unless the rescan is forced. Newly found infections are published as event.FileInfected.
+// The rescan stops when the context is canceled.
+func (av Antivirus) Rescan(ctx context.Context, stream events.Publisher, ev event.StartRescan) error {
+	version, err := scanners.Version(av.s)
+	switch {
+	case err != nil:
+		return fmt.Errorf("cannot get signature version: %w", err)
+	case version == "" && !ev.Force:
+		return ErrNoSignatureVersion
+	}
+
+	if av.gatewaySelector == nil {
+		return fmt.Errorf("no gateway configured")
+	}
+
+	gwc, err := av.gatewaySelector.Next()
+	if err != nil {
+		return err
+	}
+
+	executantID := ev.ExecutantID
+	if executantID == nil {
+		executantID = &user.UserId{OpaqueId: av.c.Rescan.UserID}
+	}
+
+	ictx, _, err := utils.Impersonate(executantID, gwc, av.c.MachineAuthAPIKey)
+	if err != nil {
+		return err
+	}
+
+	for _, spaceType := range []string{"personal", "project"} {
+		filters := []*provider.ListStorageSpacesRequest_Filter{
+			{
+				Type: provider.ListStorageSpacesRequest_Filter_TYPE_SPACE_TYPE,
+				Term: &provider.ListStorageSpacesRequest_Filter_SpaceType{SpaceType: spaceType},
+			},
+		}
+		if ev.SpaceID != "" {
+			filters = append(filters, &provider.ListStorageSpacesRequest_Filter{
+				Type: provider.ListStorageSpacesRequest_Filter_TYPE_ID,
+				Term: &provider.ListStorageSpacesRequest_Filter_Id{Id: &provider.StorageSpaceId{OpaqueId: ev.SpaceID}},
+			})
+		}
+
+		res, err := gwc.ListStorageSpaces(ictx, &provider.ListStorageSpacesRequest{Filters: filters})
+		switch {
+		case err != nil:
+			return err
+		case res.GetStatus().GetCode() != rpc.Code_CODE_OK:
+			return errtypes.NewErrtypeFromStatus(res.GetStatus())
+		}
+
+		for _, space := range res.GetStorageSpaces() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := av.rescanSpace(ctx, stream, gwc, space, version, ev.Force); err != nil {
+				av.l.Error().Err(err).Str("space", space.GetId().GetOpaqueId()).Msg("cannot rescan space")
+			}
+		}
+	}
+
+	return nil
+}
+
+func (av Antivirus) rescanSpace(stop context.Context, stream events.Publisher, gwc gateway.GatewayAPIClient, space *provider.StorageSpace, version string, force bool) error {
+	var userID *user.UserId
+	switch space.GetSpaceType() {
+	case "personal":
+		userID = space.GetOwner().GetId()
+	default:
+		var grants map[string]*provider.ResourcePermissions
+		if err := utils.ReadJSONFromOpaque(space.GetOpaque(), "grants", &grants); err != nil {
+			return err
+		}
+		for id, permissions := range grants {
+			// we need a manager to be able to store the scan results
+			if permissions.GetRemoveGrant() {
+				userID = &user.UserId{OpaqueId: id}
+				break
+			}
+		}
+	}
+	if userID == nil {
+		return fmt.Errorf("can't impersonate space user")
+	}
+
+	ctx, usr, err := utils.Impersonate(userID, gwc, av.c.MachineAuthAPIKey)
+	if err != nil {
+		return err
+	}
+
+	w := walker.NewWalker(av.gatewaySelector)
+	return w.Walk(ctx, space.GetRoot(), func(wd string, info *provider.ResourceInfo, err error) error {
+		if err := stop.Err(); err != nil {
+			return err
+		}
+		if err != nil {
+			av.l.Error().Err(err).Str("path", wd).Msg("error walking the tree")
+			return nil
+		}
+
+		if info == nil || info.GetType() != provider.ResourceType_RESOURCE_TYPE_FILE {
+			return nil
+		}
+
+		md := info.GetArbitraryMetadata().GetMetadata()
+		if !force && version != "" && md[SignaturesKey] == version {
+			return nil
+		}
+
+		if err := av.rescanFile(ctx, stream, gwc, usr, info, version); err != nil {
+			av.l.Error().Err(err).Str("path", filepath.Join(wd, info.GetPath())).Interface("resourceID", info.GetId()).Msg("cannot rescan file")
+		}
+		return nil
+	})
+}
+
+func (av Antivirus) rescanFile(ctx context.Context, stream events.Publisher, gwc gateway.GatewayAPIClient, owner *user.User, info *provider.ResourceInfo, version string) error {
+	ref := &provider.Reference{ResourceId: info.GetId()}
+
+	dRes, err := gwc.InitiateFileDownload(ctx, &provider.InitiateFileDownloadRequest{Ref: ref})
+	switch {
+	case err != nil:
+		return err
+	case dRes.GetStatus().GetCode() != rpc.Code_CODE_OK:
+		return errtypes.NewErrtypeFromStatus(dRes.GetStatus())
+	case len(dRes.GetProtocols()) == 0:
+		return fmt.Errorf("no download protocol available")
+	}
+
+	ep, tk := dRes.GetProtocols()[0].GetDownloadEndpoint(), dRes.GetProtocols()[0].GetToken()
+	for _, p := range dRes.GetProtocols() {
+		if p.GetProtocol() == "spaces" {
+			ep, tk = p.GetDownloadEndpoint(), p.GetToken()
+			break
+		}
+	}
+
+	// an empty upload id makes process download the file via reva
+	res, err := av.process(events.StartPostprocessingStep{
+		URL:        ep,
+		Token:      tk,
+		RevaToken:  revaToken(ctx),
+		ResourceID: info.GetId(),
+		Filename:   info.GetName(),
+		Filesize:   info.GetSize(),
+	})
+	if err != nil {
+		return err
+	}
+
+	scandate := time.Now()
+	mRes, err := gwc.SetArbitraryMetadata(ctx, &provider.SetArbitraryMetadataRequest{
+		Ref: ref,
+		ArbitraryMetadata: &provider.ArbitraryMetadata{
+			Metadata: map[string]string{
+				ScanDateKey:   scandate.Format(time.RFC3339Nano),
+				SignaturesKey: version,
+				VirusKey:      res.Description,
+				MemberKey:     res.Member,
+			},
+		},
+	})
+	switch {
+	case err != nil:
+		return err
+	case mRes.GetStatus().GetCode() != rpc.Code_CODE_OK:
+		return errtypes.NewErrtypeFromStatus(mRes.GetStatus())
+	}
+
+	md := info.GetArbitraryMetadata().GetMetadata()
+	if !res.Infected || (md[VirusKey] == res.Description && md[MemberKey] == res.Member) {
+		return nil
+	}
+
+	av.l.Info().Interface("resourceID", info.GetId()).Str("virus", res.Description).Str("member", res.Member).Str("filename", info.GetName()).Msg("Infection found in existing file")
+	return events.Publish(stream, event.FileInfected{
+		ResourceID:  info.GetId(),
+		Filename:    info.GetName(),
+		Owner:       owner,
+		Description: res.Description,
+		Member:      res.Member,
+		Signatures:  version,
+		Scandate:    scandate,
+	})
+}
+
+// revaToken extracts the reva token from an impersonated context
+func revaToken(ctx context.Context) string {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if t := md.Get(ctxpkg.TokenHeader); len(t) > 0 {
+		return t[0]
+	}
+	return ""
+}
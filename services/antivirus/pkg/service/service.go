@@ -8,16 +8,24 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"sync/atomic"
 	"time"
 
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/v2/pkg/bytesize"
 	ctxpkg "github.com/cs3org/reva/v2/pkg/ctx"
 	"github.com/cs3org/reva/v2/pkg/events"
 	"github.com/cs3org/reva/v2/pkg/events/stream"
+	"github.com/cs3org/reva/v2/pkg/rgrpc/todo/pool"
 	"github.com/cs3org/reva/v2/pkg/rhttp"
+	"github.com/google/uuid"
+	"github.com/owncloud/ocis/v2/ocis-pkg/event"
 	"github.com/owncloud/ocis/v2/ocis-pkg/log"
 	"github.com/owncloud/ocis/v2/services/antivirus/pkg/config"
+	"github.com/owncloud/ocis/v2/services/antivirus/pkg/quarantine"
 	"github.com/owncloud/ocis/v2/services/antivirus/pkg/scanners"
+	microstore "go-micro.dev/v4/store"
 )
 
 // Scanner is an abstraction for the actual virus scan
@@ -25,9 +33,19 @@ type Scanner interface {
 	Scan(file io.Reader) (scanners.ScanResult, error)
 }
 
-// NewAntivirus returns a service implementation for Service.
-func NewAntivirus(c *config.Config, l log.Logger) (Antivirus, error) {
-	av := Antivirus{c: c, l: l, client: rhttp.GetHTTPClient(rhttp.Insecure(true))}
+// NewAntivirus returns a service implementation for Service. The quarantine manager is only needed
+// when infected files are quarantined. The store coordinates the rescans of several instances.
+func NewAntivirus(c *config.Config, l log.Logger, gatewaySelector pool.Selectable[gateway.GatewayAPIClient], q *quarantine.Manager, st microstore.Store) (Antivirus, error) {
+	av := Antivirus{
+		c:               c,
+		l:               l,
+		client:          rhttp.GetHTTPClient(rhttp.Insecure(true)),
+		gatewaySelector: gatewaySelector,
+		rescanning:      &atomic.Bool{},
+		store:           st,
+		instance:        uuid.New().String(),
+		lockSettle:      _rescanLockSettle,
+	}
 
 	var err error
 	av.s, err = scanners.New(c)
@@ -38,10 +56,23 @@ func NewAntivirus(c *config.Config, l log.Logger) (Antivirus, error) {
 	switch o := events.PostprocessingOutcome(c.InfectedFileHandling); o {
 	case events.PPOutcomeContinue, events.PPOutcomeAbort, events.PPOutcomeDelete:
 		av.o = o
+	case "quarantine":
+		if q == nil {
+			return av, fmt.Errorf("quarantine is not configured")
+		}
+		// the upload is deleted once its content is in the quarantine
+		av.o = events.PPOutcomeDelete
+		av.q = q
 	default:
 		return av, fmt.Errorf("unknown infected file handling '%s'", o)
 	}
 
+	switch c.MaxScanSizeMode {
+	case "partial", "skip":
+	default:
+		return av, fmt.Errorf("unknown max scan size mode '%s'", c.MaxScanSizeMode)
+	}
+
 	if c.MaxScanSize != "" {
 		b, err := bytesize.Parse(c.MaxScanSize)
 		if err != nil {
@@ -63,6 +94,13 @@ type Antivirus struct {
 	m uint64
 
 	client *http.Client
+
+	gatewaySelector pool.Selectable[gateway.GatewayAPIClient]
+	rescanning      *atomic.Bool
+	store           microstore.Store
+	instance        string // identifies the instance in the rescan lock
+	lockSettle      time.Duration
+	q               *quarantine.Manager
 }
 
 // Run runs the service
@@ -91,12 +129,30 @@ func (av Antivirus) Run() error {
 		return err
 	}
 
-	ch, err := events.Consume(stream, "antivirus", events.StartPostprocessingStep{})
+	ch, err := events.Consume(stream, "antivirus", events.StartPostprocessingStep{}, event.StartRescan{})
 	if err != nil {
 		return err
 	}
 
+	if av.c.Rescan.Interval > 0 {
+		if v, err := scanners.Version(av.s); err == nil && v == "" {
+			// without a version every file would be scanned again on every run
+			av.l.Warn().Msg("the scanner does not report a signature version, scheduled rescans are disabled")
+		} else {
+			go func() {
+				for range time.Tick(av.c.Rescan.Interval) {
+					av.startRescan(stream, event.StartRescan{}, true)
+				}
+			}()
+		}
+	}
+
 	for e := range ch {
+		if ev, ok := e.Event.(event.StartRescan); ok {
+			av.startRescan(stream, ev, false)
+			continue
+		}
+
 		ev := e.Event.(events.StartPostprocessingStep)
 		if ev.StepToStart != events.PPStepAntivirus {
 			continue
@@ -131,8 +187,22 @@ func (av Antivirus) Run() error {
 			errmsg = err.Error()
 		}
 
+		var released bool
+		if av.q != nil && res.Infected && err == nil {
+			var item quarantine.Item
+			// the checksum covers the whole upload, also when only a part of it was scanned
+			open := func() (io.ReadCloser, error) { return av.downloadViaToken(ev.URL) }
+			if item, released = av.q.Released(ev, open); released {
+				av.l.Info().Str("uploadid", ev.UploadID).Str("item", item.ID).Msg("Upload of released quarantine item, skipping infected file handling")
+			}
+		}
+
 		var outcome events.PostprocessingOutcome
 		switch {
+		case res.Infected && released:
+			outcome = events.PPOutcomeContinue
+		case res.Infected && av.q != nil:
+			outcome = av.quarantine(ev, res)
 		case res.Infected:
 			outcome = av.o
 		case !res.Infected && err == nil:
@@ -141,20 +211,17 @@ func (av Antivirus) Run() error {
 			outcome = events.PPOutcomeAbort
 		}
 
-		av.l.Info().Str("uploadid", ev.UploadID).Interface("resourceID", ev.ResourceID).Str("virus", res.Description).Str("outcome", string(outcome)).Str("filename", ev.Filename).Str("user", ev.ExecutingUser.GetId().GetOpaqueId()).Bool("infected", res.Infected).Msg("File scanned")
+		av.l.Info().Str("uploadid", ev.UploadID).Interface("resourceID", ev.ResourceID).Str("virus", res.Description).Str("member", res.Member).Str("outcome", string(outcome)).Str("filename", ev.Filename).Str("user", ev.ExecutingUser.GetId().GetOpaqueId()).Bool("infected", res.Infected).Bool("partial", res.Partial).Msg("File scanned")
+		if res.Partial && !res.Infected && err == nil {
+			av.l.Warn().Str("uploadid", ev.UploadID).Interface("resourceID", ev.ResourceID).Str("filename", ev.Filename).Msg("File was only partially scanned, it may still be infected")
+		}
 		if err := events.Publish(stream, events.PostprocessingStepFinished{
 			FinishedStep:  events.PPStepAntivirus,
 			Outcome:       outcome,
 			UploadID:      ev.UploadID,
 			ExecutingUser: ev.ExecutingUser,
 			Filename:      ev.Filename,
-			Result: events.VirusscanResult{
-				Infected:    res.Infected,
-				Description: res.Description,
-				Scandate:    time.Now(),
-				ResourceID:  ev.ResourceID,
-				ErrorMsg:    errmsg,
-			},
+			Result:        virusscanResult(res, ev.ResourceID, errmsg),
 		}); err != nil {
 			av.l.Fatal().Err(err).Str("uploadid", ev.UploadID).Interface("resourceID", ev.ResourceID).Msg("cannot publish events - exiting")
 			return err
@@ -164,9 +231,55 @@ func (av Antivirus) Run() error {
 	return nil
 }
 
+// virusscanResult converts a scan result into the result of the PostprocessingStepFinished event.
+// The event has no fields for the infected archive member and partial scans, they are added to the description.
+func virusscanResult(res scanners.ScanResult, rid *provider.ResourceId, errmsg string) events.VirusscanResult {
+	description := res.Description
+	switch {
+	case res.Infected && res.Member != "":
+		description = fmt.Sprintf("%s (%s)", res.Description, res.Member)
+	case !res.Infected && res.Partial:
+		description = "partially scanned"
+	}
+
+	return events.VirusscanResult{
+		Infected:    res.Infected,
+		Description: description,
+		Scandate:    time.Now(),
+		ResourceID:  rid,
+		ErrorMsg:    errmsg,
+	}
+}
+
+// quarantine moves an infected upload into the quarantine. The upload is kept if that fails.
+func (av Antivirus) quarantine(ev events.StartPostprocessingStep, res scanners.ScanResult) events.PostprocessingOutcome {
+	rc, err := av.downloadViaToken(ev.URL)
+	if err != nil {
+		av.l.Error().Err(err).Str("uploadid", ev.UploadID).Msg("cannot download file for quarantine, keeping upload")
+		return events.PPOutcomeAbort
+	}
+	defer rc.Close()
+
+	item, err := av.q.Quarantine(ev, res.Description, res.Member, rc)
+	if err != nil {
+		av.l.Error().Err(err).Str("uploadid", ev.UploadID).Msg("cannot quarantine file, keeping upload")
+		return events.PPOutcomeAbort
+	}
+
+	av.l.Info().Str("uploadid", ev.UploadID).Str("item", item.ID).Msg("File quarantined")
+	return av.o
+}
+
 // process the scan
 func (av Antivirus) process(ev events.StartPostprocessingStep) (scanners.ScanResult, error) {
-	if ev.Filesize == 0 || (0 < av.m && av.m < ev.Filesize) {
+	if ev.Filesize == 0 {
+		return scanners.ScanResult{
+			Scantime: time.Now(),
+		}, nil
+	}
+
+	partial := 0 < av.m && av.m < ev.Filesize
+	if partial && av.c.MaxScanSizeMode == "skip" {
 		av.l.Info().Str("uploadid", ev.UploadID).Uint64("limit", av.m).Uint64("filesize", ev.Filesize).Msg("Skipping file to be virus scanned because its file size is higher than the defined limit.")
 		return scanners.ScanResult{
 			Scantime: time.Now(),
@@ -189,10 +302,19 @@ func (av Antivirus) process(ev events.StartPostprocessingStep) (scanners.ScanRes
 	defer rrc.Close()
 	av.l.Debug().Str("uploadid", ev.UploadID).Msg("Downloaded file successfully, starting virusscan")
 
-	res, err := av.s.Scan(rrc)
+	var r io.Reader = rrc
+	if partial {
+		av.l.Info().Str("uploadid", ev.UploadID).Uint64("limit", av.m).Uint64("filesize", ev.Filesize).Msg("Scanning only the beginning of the file because its file size is higher than the defined limit.")
+		r = io.LimitReader(rrc, int64(av.m))
+	}
+
+	res, err := av.s.Scan(r)
 	if err != nil {
 		av.l.Error().Err(err).Str("uploadid", ev.UploadID).Msg("error scanning file")
 	}
+	if partial && !res.Infected {
+		res.Partial = true
+	}
 
 	return res, err
 
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"testing"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/owncloud/ocis/v2/services/antivirus/pkg/scanners"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVirusscanResult(t *testing.T) {
+	rid := &provider.ResourceId{StorageId: "storage", SpaceId: "space", OpaqueId: "file"}
+
+	tests := map[string]struct {
+		res      scanners.ScanResult
+		errmsg   string
+		infected bool
+		want     string
+	}{
+		"clean": {
+			res: scanners.ScanResult{},
+		},
+		"infected file": {
+			res:      scanners.ScanResult{Infected: true, Description: "Evil-Signature"},
+			infected: true,
+			want:     "Evil-Signature",
+		},
+		"infected archive member": {
+			res:      scanners.ScanResult{Infected: true, Description: "Evil-Signature", Member: "docs/evil.txt"},
+			infected: true,
+			want:     "Evil-Signature (docs/evil.txt)",
+		},
+		"partially scanned": {
+			res:  scanners.ScanResult{Partial: true},
+			want: "partially scanned",
+		},
+		"infected in the scanned part": {
+			res:      scanners.ScanResult{Infected: true, Description: "Evil-Signature", Partial: true},
+			infected: true,
+			want:     "Evil-Signature",
+		},
+		"failed scan": {
+			res:    scanners.ScanResult{},
+			errmsg: "scanner not reachable",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := virusscanResult(tt.res, rid, tt.errmsg)
+			assert.Equal(t, tt.infected, r.Infected)
+			assert.Equal(t, tt.want, r.Description)
+			assert.Equal(t, tt.errmsg, r.ErrorMsg)
+			assert.Equal(t, rid, r.ResourceID)
+			assert.False(t, r.Scandate.IsZero())
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 
 	"github.com/cs3org/reva/v2/pkg/events"
+	ocisevent "github.com/owncloud/ocis/v2/ocis-pkg/event"
 	"github.com/owncloud/ocis/v2/ocis-pkg/log"
 	"github.com/owncloud/ocis/v2/services/audit/pkg/config"
 	"github.com/owncloud/ocis/v2/services/audit/pkg/types"
@@ -79,6 +80,18 @@ func StartAuditLogger(ctx context.Context, ch <-chan events.Event, log log.Logge
 				auditEvent = types.ItemRestored(ev)
 			case events.FileVersionRestored:
 				auditEvent = types.FileVersionRestored(ev)
+			case ocisevent.FileInfected:
+				auditEvent = types.FileInfected(ev)
+			case ocisevent.FileQuarantined:
+				auditEvent = types.FileQuarantined(ev)
+			case ocisevent.QuarantinedFileDownloaded:
+				auditEvent = types.QuarantinedFileDownloaded(ev)
+			case ocisevent.QuarantinedFileReleased:
+				auditEvent = types.QuarantinedFileReleased(ev)
+			case ocisevent.QuarantinedFilePurged:
+				auditEvent = types.QuarantinedFilePurged(ev)
+			case ocisevent.PolicyDenied:
+				auditEvent = types.PolicyDenied(ev)
 			case events.SpaceCreated:
 				auditEvent = types.SpaceCreated(ev)
 			case events.SpaceRenamed:
@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/cs3org/reva/v2/pkg/events"
+	ocisevent "github.com/owncloud/ocis/v2/ocis-pkg/event"
 	"github.com/owncloud/ocis/v2/ocis-pkg/log"
 	"github.com/owncloud/ocis/v2/services/audit/pkg/types"
 	"github.com/test-go/testify/require"
@@ -354,6 +356,107 @@ var testCases = []struct {
 			// AuditEventSharing fields
 			checkFilesAuditEvent(t, ev.AuditEventFiles, "pro-1$sto-123!iid-123/item", "uid-123", "./item")
 		},
+	}, {
+		Alias: "File infected",
+		SystemEvent: events.Event{
+			Event: ocisevent.FileInfected{
+				ResourceID:  resourceID("pro-1", "sto-123", "iid-123"),
+				Filename:    "item",
+				Owner:       &user.User{Id: userID("uid-123")},
+				Description: "Eicar-Test-Signature",
+				Member:      "docs.zip/invoice.exe",
+				Signatures:  "0.103.8/26870",
+				Scandate:    time.Unix(10e8, 0),
+			},
+		},
+		CheckAuditEvent: func(t *testing.T, b []byte) {
+			ev := types.AuditEventFileInfected{}
+			require.NoError(t, json.Unmarshal(b, &ev))
+
+			// AuditEvent fields
+			checkBaseAuditEvent(t, ev.AuditEvent, "uid-123", "2001-09-09T01:46:40Z", "virus 'Eicar-Test-Signature' found in file 'pro-1$sto-123!iid-123'", "file_infected")
+			// AuditEventFiles fields
+			checkFilesAuditEvent(t, ev.AuditEventFiles, "pro-1$sto-123!iid-123", "uid-123", "item")
+			// AuditEventFileInfected fields
+			require.Equal(t, "Eicar-Test-Signature", ev.Virus)
+			require.Equal(t, "docs.zip/invoice.exe", ev.Member)
+			require.Equal(t, "0.103.8/26870", ev.Signatures)
+		},
+	}, {
+		Alias: "File quarantined",
+		SystemEvent: events.Event{
+			Event: ocisevent.FileQuarantined{
+				ItemID:        "quarantine-1",
+				UploadID:      "upload-1",
+				ResourceID:    resourceID("pro-1", "sto-123", "iid-123"),
+				Filename:      "item",
+				ExecutingUser: userID("uid-123"),
+				Description:   "Eicar-Test-Signature",
+				Timestamp:     time.Unix(10e8, 0),
+			},
+		},
+		CheckAuditEvent: func(t *testing.T, b []byte) {
+			ev := types.AuditEventFileQuarantined{}
+			require.NoError(t, json.Unmarshal(b, &ev))
+
+			// AuditEvent fields
+			checkBaseAuditEvent(t, ev.AuditEvent, "uid-123", "2001-09-09T01:46:40Z", "file 'pro-1$sto-123!iid-123' infected with virus 'Eicar-Test-Signature' was quarantined as 'quarantine-1'", "file_quarantined")
+			// AuditEventFiles fields
+			checkFilesAuditEvent(t, ev.AuditEventFiles, "pro-1$sto-123!iid-123", "uid-123", "item")
+			// AuditEventFileQuarantined fields
+			require.Equal(t, "quarantine-1", ev.QuarantineID)
+			require.Equal(t, "Eicar-Test-Signature", ev.Virus)
+		},
+	}, {
+		Alias: "Policy denied",
+		SystemEvent: events.Event{
+			Event: ocisevent.PolicyDenied{
+				Query:      "data.postprocessing.granted",
+				Stage:      "pp",
+				Executant:  userID("uid-123"),
+				ResourceID: resourceID("pro-1", "sto-123", "iid-123"),
+				Filename:   "run.exe",
+				Reasons:    []ocisevent.Reason{{Rule: "no_executables", Message: "executables are not allowed"}},
+				AuditOnly:  true,
+				Timestamp:  time.Unix(10e8, 0),
+			},
+		},
+		CheckAuditEvent: func(t *testing.T, b []byte) {
+			ev := types.AuditEventPolicyDenied{}
+			require.NoError(t, json.Unmarshal(b, &ev))
+
+			// AuditEvent fields
+			checkBaseAuditEvent(t, ev.AuditEvent, "uid-123", "2001-09-09T01:46:40Z", "policy query 'data.postprocessing.granted' denied user 'uid-123' access to 'run.exe': no_executables: executables are not allowed (audit only, not enforced)", "policy_denied")
+			// AuditEventFiles fields
+			checkFilesAuditEvent(t, ev.AuditEventFiles, "pro-1$sto-123!iid-123", "uid-123", "run.exe")
+			// AuditEventPolicyDenied fields
+			require.Equal(t, "data.postprocessing.granted", ev.Query)
+			require.Equal(t, "pp", ev.Stage)
+			require.Equal(t, []string{"no_executables: executables are not allowed"}, ev.Reasons)
+			require.True(t, ev.AuditOnly)
+		},
+	}, {
+		Alias: "Quarantined file released",
+		SystemEvent: events.Event{
+			Event: ocisevent.QuarantinedFileReleased{
+				Executant:  userID("admin-uid"),
+				ItemID:     "quarantine-1",
+				ResourceID: resourceID("pro-1", "sto-123", "iid-123"),
+				Filename:   "item",
+				Timestamp:  time.Unix(10e8, 0),
+			},
+		},
+		CheckAuditEvent: func(t *testing.T, b []byte) {
+			ev := types.AuditEventQuarantinedFileReleased{}
+			require.NoError(t, json.Unmarshal(b, &ev))
+
+			// AuditEvent fields
+			checkBaseAuditEvent(t, ev.AuditEvent, "admin-uid", "2001-09-09T01:46:40Z", "user 'admin-uid' released quarantined file 'quarantine-1'", "quarantined_file_release")
+			// AuditEventFiles fields
+			checkFilesAuditEvent(t, ev.AuditEventFiles, "pro-1$sto-123!iid-123", "", "item")
+			// AuditEventQuarantinedFileReleased fields
+			require.Equal(t, "quarantine-1", ev.QuarantineID)
+		},
 	}, {
 		Alias: "File trashed",
 		SystemEvent: events.Event{
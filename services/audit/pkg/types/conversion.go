@@ -13,6 +13,7 @@ import (
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	sdk "github.com/cs3org/reva/v2/pkg/sdk/common"
+	ocisevent "github.com/owncloud/ocis/v2/ocis-pkg/event"
 )
 
 const _linktype = "link"
@@ -323,6 +324,87 @@ func FileVersionRestored(ev events.FileVersionRestored) AuditEventFileVersionRes
 	}
 }
 
+// FileInfected converts a FileInfected event to an AuditEventFileInfected
+func FileInfected(ev ocisevent.FileInfected) AuditEventFileInfected {
+	iid, uid := formatResourceID(ev.ResourceID), ev.Owner.GetId().GetOpaqueId()
+	base := BasicAuditEvent(uid, ev.Scandate.UTC().Format(time.RFC3339), MessageFileInfected(iid, ev.Description), ActionFileInfected)
+	return AuditEventFileInfected{
+		AuditEventFiles: FilesAuditEvent(base, iid, uid, ev.Filename),
+		Virus:           ev.Description,
+		Member:          ev.Member,
+		Signatures:      ev.Signatures,
+	}
+}
+
+// FileQuarantined converts a FileQuarantined event to an AuditEventFileQuarantined
+func FileQuarantined(ev ocisevent.FileQuarantined) AuditEventFileQuarantined {
+	iid, uid := formatResourceID(ev.ResourceID), ev.ExecutingUser.GetOpaqueId()
+	base := BasicAuditEvent(uid, ev.Timestamp.UTC().Format(time.RFC3339), MessageFileQuarantined(iid, ev.Description, ev.ItemID), ActionFileQuarantined)
+	return AuditEventFileQuarantined{
+		AuditEventFiles: FilesAuditEvent(base, iid, uid, ev.Filename),
+		QuarantineID:    ev.ItemID,
+		Virus:           ev.Description,
+		Member:          ev.Member,
+	}
+}
+
+// QuarantinedFileDownloaded converts a QuarantinedFileDownloaded event to an AuditEventQuarantinedFileDownloaded
+func QuarantinedFileDownloaded(ev ocisevent.QuarantinedFileDownloaded) AuditEventQuarantinedFileDownloaded {
+	uid := ev.Executant.GetOpaqueId()
+	base := BasicAuditEvent(uid, ev.Timestamp.UTC().Format(time.RFC3339), MessageQuarantinedFileDownloaded(uid, ev.ItemID), ActionQuarantinedFileDownloaded)
+	base.CLI = ev.Executant == nil
+	return AuditEventQuarantinedFileDownloaded{
+		AuditEventFiles: FilesAuditEvent(base, formatResourceID(ev.ResourceID), "", ev.Filename),
+		QuarantineID:    ev.ItemID,
+	}
+}
+
+// QuarantinedFileReleased converts a QuarantinedFileReleased event to an AuditEventQuarantinedFileReleased
+func QuarantinedFileReleased(ev ocisevent.QuarantinedFileReleased) AuditEventQuarantinedFileReleased {
+	uid := ev.Executant.GetOpaqueId()
+	base := BasicAuditEvent(uid, ev.Timestamp.UTC().Format(time.RFC3339), MessageQuarantinedFileReleased(uid, ev.ItemID), ActionQuarantinedFileReleased)
+	base.CLI = ev.Executant == nil
+	return AuditEventQuarantinedFileReleased{
+		AuditEventFiles: FilesAuditEvent(base, formatResourceID(ev.ResourceID), "", ev.Filename),
+		QuarantineID:    ev.ItemID,
+	}
+}
+
+// QuarantinedFilePurged converts a QuarantinedFilePurged event to an AuditEventQuarantinedFilePurged
+func QuarantinedFilePurged(ev ocisevent.QuarantinedFilePurged) AuditEventQuarantinedFilePurged {
+	uid := ev.Executant.GetOpaqueId()
+	base := BasicAuditEvent(uid, ev.Timestamp.UTC().Format(time.RFC3339), MessageQuarantinedFilePurged(uid, ev.ItemID), ActionQuarantinedFilePurged)
+	base.CLI = ev.Executant == nil
+	return AuditEventQuarantinedFilePurged{
+		AuditEventFiles: FilesAuditEvent(base, formatResourceID(ev.ResourceID), "", ev.Filename),
+		QuarantineID:    ev.ItemID,
+	}
+}
+
+// PolicyDenied converts a PolicyDenied event to an AuditEventPolicyDenied
+func PolicyDenied(ev ocisevent.PolicyDenied) AuditEventPolicyDenied {
+	iid, uid := formatResourceID(ev.ResourceID), ev.Executant.GetOpaqueId()
+	item := ev.Filename
+	if item == "" {
+		item = ev.Path
+	}
+
+	reasons := make([]string, 0, len(ev.Reasons))
+	for _, r := range ev.Reasons {
+		reasons = append(reasons, r.String())
+	}
+
+	base := BasicAuditEvent(uid, ev.Timestamp.UTC().Format(time.RFC3339), MessagePolicyDenied(uid, ev.Query, item, reasons, ev.AuditOnly), ActionPolicyDenied)
+	return AuditEventPolicyDenied{
+		AuditEventFiles: FilesAuditEvent(base, iid, uid, item),
+		Query:           ev.Query,
+		Stage:           ev.Stage,
+		Method:          ev.Method,
+		Reasons:         reasons,
+		AuditOnly:       ev.AuditOnly,
+	}
+}
+
 // SpacesAuditEvent creates an AuditEventSpaces from the given values
 func SpacesAuditEvent(base AuditEvent, spaceID string) AuditEventSpaces {
 	return AuditEventSpaces{
@@ -529,6 +611,13 @@ func extractFileDetails(ref *provider.Reference, owner *user.UserId) (string, st
 	return id, path, uid
 }
 
+func formatResourceID(id *provider.ResourceId) string {
+	if id == nil {
+		return ""
+	}
+	return storagespace.FormatResourceID(*id)
+}
+
 func formatTime(t *types.Timestamp) string {
 	if t == nil {
 		return ""
@@ -2,6 +2,7 @@ package types
 
 import (
 	"github.com/cs3org/reva/v2/pkg/events"
+	ocisevent "github.com/owncloud/ocis/v2/ocis-pkg/event"
 )
 
 // RegisteredEvents returns the events the service is registered for
@@ -24,6 +25,12 @@ func RegisteredEvents() []events.Unmarshaller {
 		events.ItemPurged{},
 		events.ItemRestored{},
 		events.FileVersionRestored{},
+		ocisevent.FileInfected{},
+		ocisevent.FileQuarantined{},
+		ocisevent.QuarantinedFileDownloaded{},
+		ocisevent.QuarantinedFileReleased{},
+		ocisevent.QuarantinedFilePurged{},
+		ocisevent.PolicyDenied{},
 		events.SpaceCreated{},
 		events.SpaceRenamed{},
 		events.SpaceEnabled{},
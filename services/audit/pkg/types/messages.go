@@ -30,6 +30,16 @@ const (
 	ActionFilePurged          = "file_trash_delete"
 	ActionFileRestored        = "file_trash_restore"
 	ActionFileVersionRestored = "file_version_restore"
+	ActionFileInfected        = "file_infected"
+
+	// Quarantine
+	ActionFileQuarantined           = "file_quarantined"
+	ActionQuarantinedFileDownloaded = "quarantined_file_download"
+	ActionQuarantinedFileReleased   = "quarantined_file_release"
+	ActionQuarantinedFilePurged     = "quarantined_file_purge"
+
+	// Policies
+	ActionPolicyDenied = "policy_denied"
 
 	// Spaces
 	ActionSpaceCreated  = "space_created"
@@ -138,6 +148,43 @@ func MessageFileVersionRestored(executant, item, version string) string {
 	return fmt.Sprintf("user '%s' restored file '%s' in version '%s'", executant, item, version)
 }
 
+// MessageFileInfected returns the human readable string that describes the action
+func MessageFileInfected(item, virus string) string {
+	return fmt.Sprintf("virus '%s' found in file '%s'", virus, item)
+}
+
+// MessageFileQuarantined returns the human readable string that describes the action
+func MessageFileQuarantined(item, virus, quarantineID string) string {
+	return fmt.Sprintf("file '%s' infected with virus '%s' was quarantined as '%s'", item, virus, quarantineID)
+}
+
+// MessageQuarantinedFileDownloaded returns the human readable string that describes the action
+func MessageQuarantinedFileDownloaded(executant, quarantineID string) string {
+	return fmt.Sprintf("user '%s' downloaded quarantined file '%s'", executant, quarantineID)
+}
+
+// MessageQuarantinedFileReleased returns the human readable string that describes the action
+func MessageQuarantinedFileReleased(executant, quarantineID string) string {
+	return fmt.Sprintf("user '%s' released quarantined file '%s'", executant, quarantineID)
+}
+
+// MessageQuarantinedFilePurged returns the human readable string that describes the action
+func MessageQuarantinedFilePurged(executant, quarantineID string) string {
+	return fmt.Sprintf("user '%s' purged quarantined file '%s'", executant, quarantineID)
+}
+
+// MessagePolicyDenied returns the human readable string that describes the action
+func MessagePolicyDenied(executant, query, item string, reasons []string, auditOnly bool) string {
+	msg := fmt.Sprintf("policy query '%s' denied user '%s' access to '%s'", query, executant, item)
+	if len(reasons) > 0 {
+		msg += fmt.Sprintf(": %s", strings.Join(reasons, "; "))
+	}
+	if auditOnly {
+		msg += " (audit only, not enforced)"
+	}
+	return msg
+}
+
 // MessageSpaceCreated returns the human readable string that describes the action
 func MessageSpaceCreated(executant, spaceID, name string) string {
 	storagID, spaceID := storagespace.SplitStorageID(spaceID)
@@ -156,6 +156,56 @@ type AuditEventFileVersionRestored struct {
 	Key string
 }
 
+// AuditEventFileInfected is the event logged when a virus was found in an existing file
+type AuditEventFileInfected struct {
+	AuditEventFiles
+
+	Virus      string
+	Member     string
+	Signatures string
+}
+
+// AuditEventFileQuarantined is the event logged when an infected upload was quarantined
+type AuditEventFileQuarantined struct {
+	AuditEventFiles
+
+	QuarantineID string
+	Virus        string
+	Member       string
+}
+
+// AuditEventQuarantinedFileDownloaded is the event logged when a quarantined file was downloaded
+type AuditEventQuarantinedFileDownloaded struct {
+	AuditEventFiles
+
+	QuarantineID string
+}
+
+// AuditEventQuarantinedFileReleased is the event logged when a quarantined file was released
+type AuditEventQuarantinedFileReleased struct {
+	AuditEventFiles
+
+	QuarantineID string
+}
+
+// AuditEventQuarantinedFilePurged is the event logged when a quarantined file was purged
+type AuditEventQuarantinedFilePurged struct {
+	AuditEventFiles
+
+	QuarantineID string
+}
+
+// AuditEventPolicyDenied is the event logged when the policies denied an action
+type AuditEventPolicyDenied struct {
+	AuditEventFiles
+
+	Query     string
+	Stage     string
+	Method    string
+	Reasons   []string
+	AuditOnly bool
+}
+
 // AuditEventFileVersionDeleted is the event logged when a file version is deleted
 // TODO: is this even possible?
 type AuditEventFileVersionDeleted struct {
@@ -1161,4 +1161,84 @@ var _ = Describe("Graph", func() {
 			}))
 		})
 	})
+
+	Describe("Drive postprocessing", func() {
+		newRequest := func(method string, body io.Reader) *http.Request {
+			r := httptest.NewRequest(method, "/graph/v1.0/drives/{driveID}/postprocessing", body)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("driveID", "pro-1$spaceid")
+			return r.WithContext(context.WithValue(revactx.ContextSetUser(ctx, currentUser), chi.RouteCtxKey, rctx))
+		}
+
+		It("returns the steps of the drive", func() {
+			gatewayClient.On("Stat", mock.Anything, mock.Anything).Return(&provider.StatResponse{
+				Status: status.NewOK(ctx),
+				Info: &provider.ResourceInfo{
+					Id: &provider.ResourceId{StorageId: "pro-1", SpaceId: "spaceid", OpaqueId: "spaceid"},
+					ArbitraryMetadata: &provider.ArbitraryMetadata{
+						Metadata: map[string]string{"postprocessing.steps": `["virusscan","policies"]`},
+					},
+				},
+			}, nil)
+
+			svc.GetDrivePostprocessing(rr, newRequest(http.MethodGet, nil))
+			Expect(rr.Code).To(Equal(http.StatusOK))
+
+			var pp service.DrivePostprocessing
+			Expect(json.Unmarshal(rr.Body.Bytes(), &pp)).To(Succeed())
+			Expect(pp.Steps).To(Equal([]string{"virusscan", "policies"}))
+
+			gatewayClient.AssertCalled(GinkgoT(), "Stat", mock.Anything, mock.MatchedBy(func(req *provider.StatRequest) bool {
+				return req.GetRef().GetResourceId().GetOpaqueId() == "spaceid"
+			}))
+		})
+
+		It("returns not found when the drive has no steps", func() {
+			gatewayClient.On("Stat", mock.Anything, mock.Anything).Return(&provider.StatResponse{
+				Status: status.NewOK(ctx),
+				Info:   &provider.ResourceInfo{},
+			}, nil)
+
+			svc.GetDrivePostprocessing(rr, newRequest(http.MethodGet, nil))
+			Expect(rr.Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("does not let non managers set the steps", func() {
+			gatewayClient.On("Stat", mock.Anything, mock.Anything).Return(&provider.StatResponse{
+				Status: status.NewOK(ctx),
+				Info: &provider.ResourceInfo{
+					PermissionSet: &provider.ResourcePermissions{InitiateFileUpload: true},
+				},
+			}, nil)
+
+			svc.SetDrivePostprocessing(rr, newRequest(http.MethodPut, bytes.NewBufferString(`{"steps":[]}`)))
+			Expect(rr.Code).To(Equal(http.StatusForbidden))
+			gatewayClient.AssertNotCalled(GinkgoT(), "SetArbitraryMetadata", mock.Anything, mock.Anything)
+		})
+
+		It("sets the steps", func() {
+			gatewayClient.On("Stat", mock.Anything, mock.Anything).Return(&provider.StatResponse{
+				Status: status.NewOK(ctx),
+				Info: &provider.ResourceInfo{
+					Id:            &provider.ResourceId{StorageId: "pro-1", SpaceId: "spaceid", OpaqueId: "spaceid"},
+					PermissionSet: &provider.ResourcePermissions{AddGrant: true},
+				},
+			}, nil)
+			gatewayClient.On("SetArbitraryMetadata", mock.Anything, mock.Anything).Return(&provider.SetArbitraryMetadataResponse{
+				Status: status.NewOK(ctx),
+			}, nil)
+
+			svc.SetDrivePostprocessing(rr, newRequest(http.MethodPut, bytes.NewBufferString(`{"steps":["virusscan", " policies "]}`)))
+			Expect(rr.Code).To(Equal(http.StatusOK))
+
+			gatewayClient.AssertCalled(GinkgoT(), "SetArbitraryMetadata", mock.Anything, mock.MatchedBy(func(req *provider.SetArbitraryMetadataRequest) bool {
+				return req.GetArbitraryMetadata().GetMetadata()["postprocessing.steps"] == `["virusscan","policies"]`
+			}))
+		})
+
+		It("rejects empty step names", func() {
+			svc.SetDrivePostprocessing(rr, newRequest(http.MethodPut, bytes.NewBufferString(`{"steps":[""]}`)))
+			Expect(rr.Code).To(Equal(http.StatusBadRequest))
+		})
+	})
 })
@@ -294,6 +294,21 @@ func (i instrument) UnassignTags(w http.ResponseWriter, r *http.Request) {
 	i.next.UnassignTags(w, r)
 }
 
+// GetDrivePostprocessing implements the Service interface.
+func (i instrument) GetDrivePostprocessing(w http.ResponseWriter, r *http.Request) {
+	i.next.GetDrivePostprocessing(w, r)
+}
+
+// SetDrivePostprocessing implements the Service interface.
+func (i instrument) SetDrivePostprocessing(w http.ResponseWriter, r *http.Request) {
+	i.next.SetDrivePostprocessing(w, r)
+}
+
+// DeleteDrivePostprocessing implements the Service interface.
+func (i instrument) DeleteDrivePostprocessing(w http.ResponseWriter, r *http.Request) {
+	i.next.DeleteDrivePostprocessing(w, r)
+}
+
 // GetEducationClassTeachers implements the Service interface.
 func (i instrument) GetEducationClassTeachers(w http.ResponseWriter, r *http.Request) {
 	i.next.UnassignTags(w, r)
@@ -294,6 +294,21 @@ func (l logging) UnassignTags(w http.ResponseWriter, r *http.Request) {
 	l.next.UnassignTags(w, r)
 }
 
+// GetDrivePostprocessing implements the Service interface.
+func (l logging) GetDrivePostprocessing(w http.ResponseWriter, r *http.Request) {
+	l.next.GetDrivePostprocessing(w, r)
+}
+
+// SetDrivePostprocessing implements the Service interface.
+func (l logging) SetDrivePostprocessing(w http.ResponseWriter, r *http.Request) {
+	l.next.SetDrivePostprocessing(w, r)
+}
+
+// DeleteDrivePostprocessing implements the Service interface.
+func (l logging) DeleteDrivePostprocessing(w http.ResponseWriter, r *http.Request) {
+	l.next.DeleteDrivePostprocessing(w, r)
+}
+
 // GetEducationClassTeachers implements the Service interface.
 func (l logging) GetEducationClassTeachers(w http.ResponseWriter, r *http.Request) {
 	l.next.UnassignTags(w, r)
@@ -0,0 +1,184 @@
+package svc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/v2/pkg/storagespace"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/owncloud/ocis/v2/ocis-pkg/event"
+	"github.com/owncloud/ocis/v2/services/graph/pkg/service/v0/errorcode"
+)
+
+// DrivePostprocessing holds the postprocessing steps of a drive
+type DrivePostprocessing struct {
+	Steps []string `json:"steps"`
+}
+
+// GetDrivePostprocessing returns the postprocessing steps configured for a drive
+func (g Graph) GetDrivePostprocessing(w http.ResponseWriter, r *http.Request) {
+	info, ok := g.statDriveRoot(w, r)
+	if !ok {
+		return
+	}
+
+	v, ok := info.GetArbitraryMetadata().GetMetadata()[event.SpaceStepsKey]
+	if !ok {
+		errorcode.ItemNotFound.Render(w, r, http.StatusNotFound, "no postprocessing steps configured for the drive")
+		return
+	}
+
+	var pp DrivePostprocessing
+	if err := json.Unmarshal([]byte(v), &pp.Steps); err != nil {
+		g.logger.Error().Err(err).Msg("could not decode postprocessing steps of the drive")
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "invalid postprocessing steps stored")
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, pp)
+}
+
+// SetDrivePostprocessing sets the postprocessing steps of a drive. Only space managers may do this. Disallowed
+// and missing mandatory steps are handled by the postprocessing service when the steps are applied.
+func (g Graph) SetDrivePostprocessing(w http.ResponseWriter, r *http.Request) {
+	var pp DrivePostprocessing
+	if err := StrictJSONUnmarshal(r.Body, &pp); err != nil {
+		g.logger.Debug().Err(err).Interface("body", r.Body).Msg("could not decode postprocessing steps request")
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "invalid body schema definition")
+		return
+	}
+
+	if pp.Steps == nil {
+		pp.Steps = []string{}
+	}
+	for i, s := range pp.Steps {
+		pp.Steps[i] = strings.TrimSpace(s)
+		if pp.Steps[i] == "" {
+			errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "postprocessing steps must not be empty")
+			return
+		}
+	}
+
+	info, ok := g.statDriveRoot(w, r)
+	if !ok {
+		return
+	}
+
+	if !info.GetPermissionSet().GetAddGrant() {
+		g.logger.Debug().Msg("no permission to set postprocessing steps")
+		errorcode.AccessDenied.Render(w, r, http.StatusForbidden, "only space managers can set postprocessing steps")
+		return
+	}
+
+	b, _ := json.Marshal(pp.Steps)
+
+	client, err := g.gatewaySelector.Next()
+	if err != nil {
+		g.logger.Error().Err(err).Msg("error selecting next gateway client")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := client.SetArbitraryMetadata(r.Context(), &provider.SetArbitraryMetadataRequest{
+		Ref: &provider.Reference{ResourceId: info.GetId()},
+		ArbitraryMetadata: &provider.ArbitraryMetadata{
+			Metadata: map[string]string{
+				event.SpaceStepsKey: string(b),
+			},
+		},
+	})
+	if err != nil || resp.GetStatus().GetCode() != rpc.Code_CODE_OK {
+		g.logger.Error().Err(err).Msg("error setting postprocessing steps")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, pp)
+}
+
+// DeleteDrivePostprocessing removes the postprocessing steps of a drive. Uploads to the drive use the
+// steps configured in the postprocessing service again. Only space managers may do this.
+func (g Graph) DeleteDrivePostprocessing(w http.ResponseWriter, r *http.Request) {
+	info, ok := g.statDriveRoot(w, r)
+	if !ok {
+		return
+	}
+
+	if !info.GetPermissionSet().GetAddGrant() {
+		g.logger.Debug().Msg("no permission to delete postprocessing steps")
+		errorcode.AccessDenied.Render(w, r, http.StatusForbidden, "only space managers can delete postprocessing steps")
+		return
+	}
+
+	client, err := g.gatewaySelector.Next()
+	if err != nil {
+		g.logger.Error().Err(err).Msg("error selecting next gateway client")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := client.UnsetArbitraryMetadata(r.Context(), &provider.UnsetArbitraryMetadataRequest{
+		Ref:                   &provider.Reference{ResourceId: info.GetId()},
+		ArbitraryMetadataKeys: []string{event.SpaceStepsKey},
+	})
+	if err != nil || resp.GetStatus().GetCode() != rpc.Code_CODE_OK {
+		g.logger.Error().Err(err).Msg("error deleting postprocessing steps")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// statDriveRoot returns the root of the drive given in the url. It renders an error if the root can't be found.
+func (g Graph) statDriveRoot(w http.ResponseWriter, r *http.Request) (*provider.ResourceInfo, bool) {
+	driveID, err := url.PathUnescape(chi.URLParam(r, "driveID"))
+	if err != nil {
+		g.logger.Debug().Err(err).Str("id", chi.URLParam(r, "driveID")).Msg("unescaping drive id failed")
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "unescaping drive id failed")
+		return nil, false
+	}
+
+	rid, err := storagespace.ParseID(driveID)
+	if err != nil || rid.GetSpaceId() == "" {
+		g.logger.Debug().Err(err).Str("id", driveID).Msg("invalid drive id")
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "invalid drive id")
+		return nil, false
+	}
+	// the drive id points to the space root
+	rid.OpaqueId = rid.GetSpaceId()
+
+	client, err := g.gatewaySelector.Next()
+	if err != nil {
+		g.logger.Error().Err(err).Msg("error selecting next gateway client")
+		w.WriteHeader(http.StatusInternalServerError)
+		return nil, false
+	}
+
+	sres, err := client.Stat(r.Context(), &provider.StatRequest{
+		Ref:                   &provider.Reference{ResourceId: &rid},
+		ArbitraryMetadataKeys: []string{event.SpaceStepsKey},
+	})
+	switch {
+	case err != nil:
+		g.logger.Error().Err(err).Msg("error stating drive root")
+		w.WriteHeader(http.StatusInternalServerError)
+		return nil, false
+	case sres.GetStatus().GetCode() == rpc.Code_CODE_NOT_FOUND:
+		errorcode.ItemNotFound.Render(w, r, http.StatusNotFound, "drive not found")
+		return nil, false
+	case sres.GetStatus().GetCode() != rpc.Code_CODE_OK:
+		g.logger.Debug().Str("grpc", sres.GetStatus().GetMessage()).Msg("error stating drive root")
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "grpc error")
+		return nil, false
+	}
+
+	return sres.GetInfo(), true
+}
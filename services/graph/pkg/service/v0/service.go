@@ -105,6 +105,10 @@ type Service interface {
 	GetTags(w http.ResponseWriter, r *http.Request)
 	AssignTags(w http.ResponseWriter, r *http.Request)
 	UnassignTags(w http.ResponseWriter, r *http.Request)
+
+	GetDrivePostprocessing(w http.ResponseWriter, r *http.Request)
+	SetDrivePostprocessing(w http.ResponseWriter, r *http.Request)
+	DeleteDrivePostprocessing(w http.ResponseWriter, r *http.Request)
 }
 
 // NewService returns a service implementation for Service.
@@ -252,6 +256,11 @@ func NewService(opts ...Option) (Graph, error) {
 					r.Patch("/", svc.UpdateDrive)
 					r.Get("/", svc.GetSingleDrive)
 					r.Delete("/", svc.DeleteDrive)
+					r.Route("/postprocessing", func(r chi.Router) {
+						r.Get("/", svc.GetDrivePostprocessing)
+						r.Put("/", svc.SetDrivePostprocessing)
+						r.Delete("/", svc.DeleteDrivePostprocessing)
+					})
 				})
 			})
 			r.With(requireAdmin).Route("/education", func(r chi.Router) {
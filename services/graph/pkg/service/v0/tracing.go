@@ -290,6 +290,21 @@ func (t tracing) UnassignTags(w http.ResponseWriter, r *http.Request) {
 	t.next.UnassignTags(w, r)
 }
 
+// GetDrivePostprocessing implements the Service interface.
+func (t tracing) GetDrivePostprocessing(w http.ResponseWriter, r *http.Request) {
+	t.next.GetDrivePostprocessing(w, r)
+}
+
+// SetDrivePostprocessing implements the Service interface.
+func (t tracing) SetDrivePostprocessing(w http.ResponseWriter, r *http.Request) {
+	t.next.SetDrivePostprocessing(w, r)
+}
+
+// DeleteDrivePostprocessing implements the Service interface.
+func (t tracing) DeleteDrivePostprocessing(w http.ResponseWriter, r *http.Request) {
+	t.next.DeleteDrivePostprocessing(w, r)
+}
+
 // GetEducationClassTeachers implements the Service interface.
 func (t tracing) GetEducationClassTeachers(w http.ResponseWriter, r *http.Request) {
 	t.next.UnassignTags(w, r)
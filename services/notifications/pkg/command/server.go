@@ -14,6 +14,7 @@ import (
 	"github.com/oklog/run"
 	"github.com/owncloud/ocis/v2/ocis-pkg/config/configlog"
 	"github.com/owncloud/ocis/v2/ocis-pkg/crypto"
+	ocisevent "github.com/owncloud/ocis/v2/ocis-pkg/event"
 	"github.com/owncloud/ocis/v2/ocis-pkg/handlers"
 	"github.com/owncloud/ocis/v2/ocis-pkg/registry"
 	"github.com/owncloud/ocis/v2/ocis-pkg/service/debug"
@@ -82,6 +83,7 @@ func Server(cfg *config.Config) *cli.Command {
 				events.SpaceShared{},
 				events.SpaceUnshared{},
 				events.SpaceMembershipExpired{},
+				ocisevent.SavedSearchMatched{},
 			}
 
 			evtsCfg := cfg.Notifications.Events
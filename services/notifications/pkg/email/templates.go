@@ -73,6 +73,20 @@ You might still have access through your other groups or direct membership.`),
 
 Even though this membership has expired you still might have access through other shares and/or space memberships`),
 	}
+
+	// Search templates
+	SavedSearchMatched = MessageTemplate{
+		textTemplate: "templates/text/email.text.tmpl",
+		htmlTemplate: "templates/html/email.html.tmpl",
+		// SavedSearchMatched email template, Subject field (resolves directly)
+		Subject: Template(`'{FileName}' matches your saved search '{SearchName}'`),
+		// SavedSearchMatched email template, resolves via {{ .Greeting }}
+		Greeting: Template(`Hello {SearchOwner},`),
+		// SavedSearchMatched email template, resolves via {{ .MessageBody }}
+		MessageBody: Template(`The file "{FileName}" is a new result of your saved search "{SearchName}".`),
+		// SavedSearchMatched email template, resolves via {{ .CallToAction }}
+		CallToAction: Template(`Click here to view it: {FileLink}`),
+	}
 )
 
 // holds the information to turn the raw template into a parseable go template
@@ -85,6 +99,10 @@ var _placeholders = map[string]string{
 	"{SpaceGrantee}": "{{ .SpaceGrantee }}",
 	"{SpaceSharer}":  "{{ .SpaceSharer }}",
 	"{ExpiredAt}":    "{{ .ExpiredAt }}",
+	"{SearchName}":   "{{ .SearchName }}",
+	"{SearchOwner}":  "{{ .SearchOwner }}",
+	"{FileName}":     "{{ .FileName }}",
+	"{FileLink}":     "{{ .FileLink }}",
 }
 
 // MessageTemplate is the data structure for the email
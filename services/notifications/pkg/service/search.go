@@ -0,0 +1,57 @@
+package service
+
+import (
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/v2/pkg/storagespace"
+	"github.com/cs3org/reva/v2/pkg/utils"
+	ocisevent "github.com/owncloud/ocis/v2/ocis-pkg/event"
+	"github.com/owncloud/ocis/v2/services/notifications/pkg/email"
+)
+
+func (s eventsNotifier) handleSavedSearchMatched(e ocisevent.SavedSearchMatched) {
+	if !e.Email {
+		return
+	}
+
+	logger := s.logger.With().
+		Str("event", "SavedSearchMatched").
+		Str("savedsearch", e.SavedSearchID).
+		Logger()
+
+	gatewayClient, err := s.gatewaySelector.Next()
+	if err != nil {
+		logger.Error().Err(err).Msg("could not select next gateway client")
+		return
+	}
+
+	ownerCtx, owner, err := utils.Impersonate(e.Owner, gatewayClient, s.machineAuthAPIKey)
+	if err != nil {
+		logger.Error().Err(err).Msg("could not impersonate the owner of the saved search")
+		return
+	}
+
+	if s.disableEmails(ownerCtx, owner.GetId()) {
+		return
+	}
+
+	fileLink, err := urlJoinPath(s.ocisURL, "f", storagespace.FormatResourceID(*e.ResourceID))
+	if err != nil {
+		logger.Error().
+			Err(err).
+			Msg("could not create link to the file")
+		return
+	}
+
+	recipientList, err := s.render(ownerCtx, email.SavedSearchMatched,
+		"SearchOwner",
+		map[string]string{
+			"SearchName": e.SavedSearchName,
+			"FileName":   e.Filename,
+			"FileLink":   fileLink,
+		}, []*user.User{owner}, "")
+	if err != nil {
+		logger.Error().Err(err).Msg("could not get render the email")
+		return
+	}
+	s.send(ownerCtx, recipientList)
+}
@@ -17,6 +17,7 @@ import (
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/v2/pkg/events"
 	"github.com/cs3org/reva/v2/pkg/rgrpc/todo/pool"
+	ocisevent "github.com/owncloud/ocis/v2/ocis-pkg/event"
 	"github.com/owncloud/ocis/v2/ocis-pkg/log"
 	"github.com/owncloud/ocis/v2/ocis-pkg/middleware"
 	settingssvc "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/settings/v0"
@@ -88,6 +89,8 @@ func (s eventsNotifier) Run() error {
 					s.handleShareCreated(e)
 				case events.ShareExpired:
 					s.handleShareExpired(e)
+				case ocisevent.SavedSearchMatched:
+					s.handleSavedSearchMatched(e)
 				}
 			}()
 		case <-s.signals:
@@ -0,0 +1,159 @@
+package command
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/config/configlog"
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/services/policies/pkg/config"
+	"github.com/owncloud/ocis/v2/services/policies/pkg/config/parser"
+	"github.com/owncloud/ocis/v2/services/policies/pkg/engine"
+	"github.com/owncloud/ocis/v2/services/policies/pkg/metrics"
+	"github.com/urfave/cli/v2"
+)
+
+// Eval is the entrypoint for the eval command.
+func Eval(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:      "eval",
+		Usage:     "Evaluate a query against environments recorded as json files",
+		ArgsUsage: "<environment file or directory>...",
+		Category:  "policies",
+		Flags: append(engineFlags(),
+			&cli.StringFlag{
+				Name:    "query",
+				Aliases: []string{"q"},
+				Usage:   "the query to evaluate, defaults to the postprocessing query",
+			},
+		),
+		Before: func(c *cli.Context) error {
+			return configlog.ReturnFatal(parser.ParseConfig(cfg))
+		},
+		Action: func(c *cli.Context) error {
+			qs := c.String("query")
+			if qs == "" {
+				qs = cfg.Postprocessing.Query
+			}
+			if qs == "" {
+				return errors.New("no query given")
+			}
+			if c.NArg() == 0 {
+				return errors.New("no environment given")
+			}
+
+			files, err := environmentFiles(c.Args().Slice())
+			if err != nil {
+				return err
+			}
+
+			o, err := newEngine(c, cfg.Engine)
+			if err != nil {
+				return err
+			}
+
+			for _, f := range files {
+				env, err := readEnvironment(f)
+				if err != nil {
+					return err
+				}
+
+				d, err := o.Evaluate(c.Context, qs, env)
+				switch {
+				case err != nil:
+					fmt.Printf("%s: error: %s\n", f, err)
+					continue
+				case !d.Denied():
+					fmt.Printf("%s: granted\n", f)
+					continue
+				case d.AuditOnly:
+					fmt.Printf("%s: denied (audit only, not enforced)\n", f)
+				default:
+					fmt.Printf("%s: denied\n", f)
+				}
+				for _, r := range d.Reasons {
+					fmt.Printf("  - %s\n", r)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// engineFlags returns the flags of the commands evaluating the policies locally
+func engineFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:    "policy",
+			Aliases: []string{"p"},
+			Usage:   "policy files or directories to load instead of the configured ones",
+		},
+		&cli.StringFlag{
+			Name:  "resource",
+			Usage: "a local file returned by ocis_get_resource for every url, urls are read as local paths otherwise",
+		},
+	}
+}
+
+// newEngine returns an engine for local evaluations. The ocis_get_resource builtin reads local files
+// instead of downloading the resources.
+func newEngine(c *cli.Context, conf config.Engine, paths ...string) (*engine.OPA, error) {
+	if policies := c.StringSlice("policy"); len(policies) > 0 {
+		conf.Policies = policies
+	}
+	conf.Policies = append(conf.Policies, paths...)
+	if len(conf.Policies) == 0 {
+		return nil, errors.New("no policies configured")
+	}
+
+	resource := c.String("resource")
+	return engine.NewOPA(conf.Timeout, log.NopLogger(), conf, metrics.New(), engine.ResourceLoader(func(url string) ([]byte, error) {
+		if resource != "" {
+			return os.ReadFile(resource)
+		}
+		if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+			return nil, fmt.Errorf("remote resource '%s' is not downloaded, use a local path or --resource", url)
+		}
+		return os.ReadFile(strings.TrimPrefix(url, "file://"))
+	}))
+}
+
+// environmentFiles returns the json files of the given files and directories
+func environmentFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, p := range paths {
+		err := filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
+			switch {
+			case err != nil:
+				return err
+			case d.IsDir():
+				return nil
+			case path == p || filepath.Ext(path) == ".json":
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+func readEnvironment(path string) (engine.Environment, error) {
+	var env engine.Environment
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return env, err
+	}
+	if err := json.Unmarshal(b, &env); err != nil {
+		return env, fmt.Errorf("invalid environment '%s': %w", path, err)
+	}
+	return env, nil
+}
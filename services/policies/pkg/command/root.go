@@ -12,6 +12,8 @@ import (
 func GetCommands(cfg *config.Config) cli.Commands {
 	return []*cli.Command{
 		Server(cfg),
+		Eval(cfg),
+		Test(cfg),
 		Health(cfg),
 		Version(cfg),
 	}
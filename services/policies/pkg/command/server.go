@@ -10,18 +10,22 @@ import (
 	"os"
 
 	"github.com/cs3org/reva/v2/pkg/events/stream"
+	"github.com/cs3org/reva/v2/pkg/rgrpc/todo/pool"
 	"github.com/go-micro/plugins/v4/events/natsjs"
 	"github.com/oklog/run"
 	"github.com/owncloud/ocis/v2/ocis-pkg/config/configlog"
 	ociscrypto "github.com/owncloud/ocis/v2/ocis-pkg/crypto"
 	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/ocis-pkg/registry"
 	"github.com/owncloud/ocis/v2/ocis-pkg/service/debug"
 	"github.com/owncloud/ocis/v2/ocis-pkg/service/grpc"
 	"github.com/owncloud/ocis/v2/ocis-pkg/version"
 	svcProtogen "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/policies/v0"
+	settingssvc "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/settings/v0"
 	"github.com/owncloud/ocis/v2/services/policies/pkg/config"
 	"github.com/owncloud/ocis/v2/services/policies/pkg/config/parser"
 	"github.com/owncloud/ocis/v2/services/policies/pkg/engine"
+	"github.com/owncloud/ocis/v2/services/policies/pkg/metrics"
 	svcEvent "github.com/owncloud/ocis/v2/services/policies/pkg/service/event"
 	svcGRPC "github.com/owncloud/ocis/v2/services/policies/pkg/service/grpc"
 	"github.com/urfave/cli/v2"
@@ -55,7 +59,49 @@ func Server(cfg *config.Config) *cli.Command {
 			)
 			defer cancel()
 
-			e, err := engine.NewOPA(cfg.Engine.Timeout, cfg.Engine)
+			mtrcs := metrics.New()
+			mtrcs.BuildInfo.WithLabelValues(version.GetString()).Set(1)
+
+			e, err := engine.NewOPA(cfg.Engine.Timeout, logger, cfg.Engine, mtrcs)
+			if err != nil {
+				return err
+			}
+
+			if cfg.Engine.Watch && len(cfg.Engine.Policies) > 0 {
+				gr.Add(func() error {
+					return e.Watch(ctx)
+				}, func(_ error) {
+					cancel()
+				})
+			}
+
+			var tlsConf *tls.Config
+
+			if cfg.Events.EnableTLS {
+				var rootCAPool *x509.CertPool
+				if cfg.Events.TLSRootCACertificate != "" {
+					rootCrtFile, err := os.Open(cfg.Events.TLSRootCACertificate)
+					if err != nil {
+						return err
+					}
+
+					rootCAPool, err = ociscrypto.NewCertPoolFromPEM(rootCrtFile)
+					if err != nil {
+						return err
+					}
+					cfg.Events.TLSInsecure = false
+				}
+
+				tlsConf = &tls.Config{
+					RootCAs: rootCAPool,
+				}
+			}
+
+			bus, err := stream.Nats(
+				natsjs.TLSConfig(tlsConf),
+				natsjs.Address(cfg.Events.Endpoint),
+				natsjs.ClusterID(cfg.Events.Cluster),
+			)
 			if err != nil {
 				return err
 			}
@@ -83,7 +129,7 @@ func Server(cfg *config.Config) *cli.Command {
 					return err
 				}
 
-				grpcSvc, err := svcGRPC.New(e)
+				grpcSvc, err := svcGRPC.New(e, bus, logger)
 				if err != nil {
 					return err
 				}
@@ -101,38 +147,23 @@ func Server(cfg *config.Config) *cli.Command {
 			}
 
 			{
-				var tlsConf *tls.Config
-
-				if cfg.Events.EnableTLS {
-					var rootCAPool *x509.CertPool
-					if cfg.Events.TLSRootCACertificate != "" {
-						rootCrtFile, err := os.Open(cfg.Events.TLSRootCACertificate)
-						if err != nil {
-							return err
-						}
-
-						rootCAPool, err = ociscrypto.NewCertPoolFromPEM(rootCrtFile)
-						if err != nil {
-							return err
-						}
-						cfg.Events.TLSInsecure = false
-					}
-
-					tlsConf = &tls.Config{
-						RootCAs: rootCAPool,
-					}
+				tm, err := pool.StringToTLSMode(cfg.GRPCClientTLS.Mode)
+				if err != nil {
+					return err
 				}
-
-				bus, err := stream.Nats(
-					natsjs.TLSConfig(tlsConf),
-					natsjs.Address(cfg.Events.Endpoint),
-					natsjs.ClusterID(cfg.Events.Cluster),
+				gatewaySelector, err := pool.GatewaySelector(
+					cfg.Reva.Address,
+					pool.WithTLSCACert(cfg.GRPCClientTLS.CACert),
+					pool.WithTLSMode(tm),
+					pool.WithRegistry(registry.GetRegistry()),
 				)
 				if err != nil {
-					return err
+					return fmt.Errorf("could not get reva client selector: %s", err)
 				}
 
-				eventSvc, err := svcEvent.New(bus, logger, e, cfg.Postprocessing.Query)
+				roleService := settingssvc.NewRoleService("com.owncloud.api.settings", grpc.DefaultClient())
+
+				eventSvc, err := svcEvent.New(bus, logger, e, cfg.Postprocessing.Query, gatewaySelector, roleService, cfg.MachineAuthAPIKey)
 				if err != nil {
 					return err
 				}
@@ -0,0 +1,55 @@
+package command
+
+import (
+	"fmt"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/config/configlog"
+	"github.com/owncloud/ocis/v2/services/policies/pkg/config"
+	"github.com/owncloud/ocis/v2/services/policies/pkg/config/parser"
+	"github.com/urfave/cli/v2"
+)
+
+// Test is the entrypoint for the test command.
+func Test(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:      "test",
+		Usage:     "Run the rego unit tests of the policies, rules starting with test_ are tests",
+		ArgsUsage: "[test file or directory]...",
+		Category:  "policies",
+		Flags:     engineFlags(),
+		Before: func(c *cli.Context) error {
+			return configlog.ReturnFatal(parser.ParseConfig(cfg))
+		},
+		Action: func(c *cli.Context) error {
+			o, err := newEngine(c, cfg.Engine, c.Args().Slice()...)
+			if err != nil {
+				return err
+			}
+
+			results, err := o.Test(c.Context)
+			if err != nil {
+				return err
+			}
+
+			failed := 0
+			for _, r := range results {
+				switch {
+				case r.Err != nil:
+					failed++
+					fmt.Printf("ERROR %s: %s\n", r.Name, r.Err)
+				case !r.Passed:
+					failed++
+					fmt.Printf("FAIL  %s (%s)\n", r.Name, r.Duration)
+				default:
+					fmt.Printf("PASS  %s (%s)\n", r.Name, r.Duration)
+				}
+			}
+
+			fmt.Printf("%d of %d tests passed\n", len(results)-failed, len(results))
+			if failed > 0 {
+				return fmt.Errorf("%d tests failed", failed)
+			}
+			return nil
+		},
+	}
+}
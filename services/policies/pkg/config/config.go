@@ -45,6 +45,11 @@ type TokenManager struct {
 type Engine struct {
 	Timeout  time.Duration `yaml:"timeout" env:"POLICIES_ENGINE_TIMEOUT" desc:"Sets the timeout the rego expression evaluation can take. The timeout can be set as number followed by a unit identifier like ms, s, etc. Rules default to deny if the timeout was reached."`
 	Policies []string      `yaml:"policies"`
+	Watch    bool          `yaml:"watch" env:"POLICIES_ENGINE_WATCH" desc:"Reload the policy files when they change without restarting the service. If the changed policies can't be compiled, the previous policies stay in use."`
+
+	AuditOnly            bool     `yaml:"audit_only" env:"POLICIES_ENGINE_AUDIT_ONLY" desc:"Evaluate all queries in audit only mode. Denials are logged and reported to the audit service but not enforced."`
+	AuditOnlyQueries     []string `yaml:"audit_only_queries" env:"POLICIES_ENGINE_AUDIT_ONLY_QUERIES" desc:"A comma separated list of queries which are evaluated in audit only mode, e.g. 'data.proxy.granted'. Denials of these queries are logged and reported to the audit service but not enforced."`
+	AuditOnlyGrantErrors bool     `yaml:"audit_only_grant_errors" env:"POLICIES_ENGINE_AUDIT_ONLY_GRANT_ERRORS" desc:"Grant the action if a query in audit only mode can't be evaluated. The failure is reported to the audit service. If not set, evaluation errors deny the action in audit only mode too."`
 }
 
 // Postprocessing defines the config options for the postprocessing policy handling.
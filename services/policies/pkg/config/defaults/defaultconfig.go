@@ -40,6 +40,7 @@ func DefaultConfig() *config.Config {
 		},
 		Engine: config.Engine{
 			Timeout: 10 * time.Second,
+			Watch:   true,
 		},
 	}
 }
@@ -2,17 +2,30 @@ package engine
 
 import (
 	"context"
-	"encoding/json"
 
 	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/owncloud/ocis/v2/ocis-pkg/event"
 	"github.com/owncloud/ocis/v2/protogen/gen/ocis/messages/policies/v0"
-	"google.golang.org/protobuf/encoding/protojson"
 )
 
 // Engine defines the granted handlers.
 type Engine interface {
-	Evaluate(ctx context.Context, query string, env Environment) (bool, error)
+	Evaluate(ctx context.Context, query string, env Environment) (Decision, error)
+}
+
+// Decision is the result of a policy evaluation.
+type Decision struct {
+	// Granted tells if the action is allowed. Denials of queries in audit only mode are granted.
+	Granted bool `json:"granted"`
+	// AuditOnly is set if the policies denied the action but the denial is not enforced.
+	AuditOnly bool           `json:"audit_only"`
+	Reasons   []event.Reason `json:"reasons"`
+}
+
+// Denied tells if the policies denied the action, no matter if the denial is enforced.
+func (d Decision) Denied() bool {
+	return !d.Granted || d.AuditOnly
 }
 
 type (
@@ -28,42 +41,97 @@ var (
 	StageHTTP Stage = "http"
 )
 
+// User contains user information and is used as part of the evaluated environment.
+type User struct {
+	user.User
+	Roles []string `json:"roles"`
+}
+
 // Resource contains resource information and is used as part of the evaluated environment.
 type Resource struct {
-	ID   provider.ResourceId `json:"resource_id"`
-	Name string              `json:"name"`
-	URL  string              `json:"url"`
-	Size uint64              `json:"size"`
+	ID       provider.ResourceId `json:"resource_id"`
+	Name     string              `json:"name"`
+	URL      string              `json:"url"`
+	Size     uint64              `json:"size"`
+	Path     string              `json:"path"`
+	MimeType string              `json:"mime_type"`
+}
+
+// Space contains information about the space of the resource and is used as part of the evaluated environment.
+type Space struct {
+	ID    string `json:"id"`
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Quota uint64 `json:"quota"`
 }
 
 // Request contains request information and is used as part of the evaluated environment.
 type Request struct {
-	Method string `json:"method"`
-	Path   string `json:"path"`
+	Method     string `json:"method"`
+	Path       string `json:"path"`
+	ClientIP   string `json:"client_ip"`
+	UserAgent  string `json:"user_agent"`
+	PublicLink bool   `json:"public_link"`
+	Share      bool   `json:"share"`
 }
 
 // Environment contains every data that is needed to decide if the request should pass or not
 type Environment struct {
-	Stage    Stage     `json:"stage"`
-	User     user.User `json:"user"`
-	Request  Request   `json:"request"`
-	Resource Resource  `json:"resource"`
+	Stage    Stage    `json:"stage"`
+	User     User     `json:"user"`
+	Request  Request  `json:"request"`
+	Resource Resource `json:"resource"`
+	Space    Space    `json:"space"`
 }
 
 // NewEnvironmentFromPB converts a PBEnvironment to Environment.
 func NewEnvironmentFromPB(pEnv *v0.Environment) (Environment, error) {
-	env := Environment{}
+	env := Environment{
+		Request: Request{
+			Method:     pEnv.GetRequest().GetMethod(),
+			Path:       pEnv.GetRequest().GetPath(),
+			ClientIP:   pEnv.GetRequest().GetClientIp(),
+			UserAgent:  pEnv.GetRequest().GetUserAgent(),
+			PublicLink: pEnv.GetRequest().GetPublicLink(),
+			Share:      pEnv.GetRequest().GetShare(),
+		},
+		Resource: Resource{
+			Name:     pEnv.GetResource().GetName(),
+			URL:      pEnv.GetResource().GetUrl(),
+			Size:     pEnv.GetResource().GetSize(),
+			Path:     pEnv.GetResource().GetPath(),
+			MimeType: pEnv.GetResource().GetMimeType(),
+		},
+		Space: Space{
+			ID:    pEnv.GetSpace().GetId(),
+			Type:  pEnv.GetSpace().GetType(),
+			Name:  pEnv.GetSpace().GetName(),
+			Quota: pEnv.GetSpace().GetQuota(),
+		},
+	}
 
-	rData, err := protojson.Marshal(pEnv)
-	if err != nil {
-		return env, err
+	if pUser := pEnv.GetUser(); pUser != nil {
+		env.User.User = user.User{
+			Username:    pUser.GetUsername(),
+			Mail:        pUser.GetMail(),
+			DisplayName: pUser.GetDisplayName(),
+			Groups:      pUser.GetGroups(),
+		}
+		if pUser.GetId() != nil {
+			env.User.Id = &user.UserId{OpaqueId: pUser.GetId().GetOpaqueId()}
+		}
+		env.User.Roles = pUser.GetRoles()
 	}
 
-	if err := json.Unmarshal(rData, &env); err != nil {
-		return env, err
+	if id := pEnv.GetResource().GetId(); id != nil {
+		env.Resource.ID = provider.ResourceId{
+			StorageId: id.GetStorageId(),
+			SpaceId:   id.GetSpaceId(),
+			OpaqueId:  id.GetOpaqueId(),
+		}
 	}
 
-	switch pEnv.Stage {
+	switch pEnv.GetStage() {
 	case v0.Stage_STAGE_HTTP:
 		env.Stage = StageHTTP
 	case v0.Stage_STAGE_PP:
@@ -22,4 +22,43 @@ var _ = Describe("Engine", func() {
 		Entry("http stage", pMessage.Stage_STAGE_HTTP, engine.StageHTTP),
 		Entry("pp stage", pMessage.Stage_STAGE_PP, engine.StagePP),
 	)
+
+	It("converts all fields of the environment", func() {
+		env, err := engine.NewEnvironmentFromPB(&pMessage.Environment{
+			User: &pMessage.User{
+				Id:          &pMessage.User_ID{OpaqueId: "user"},
+				DisplayName: "User",
+				Roles:       []string{"role"},
+			},
+			Request: &pMessage.Request{
+				ClientIp:   "10.0.0.1",
+				UserAgent:  "agent",
+				PublicLink: true,
+			},
+			Resource: &pMessage.Resource{
+				Id:       &pMessage.Resource_ID{StorageId: "storage", SpaceId: "space", OpaqueId: "file"},
+				Path:     "/folder/file.exe",
+				MimeType: "application/x-ms-dos-executable",
+			},
+			Space: &pMessage.Space{
+				Id:    "storage$space",
+				Type:  "project",
+				Quota: 1024,
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(env.User.GetId().GetOpaqueId()).To(Equal("user"))
+		Expect(env.User.GetDisplayName()).To(Equal("User"))
+		Expect(env.User.Roles).To(Equal([]string{"role"}))
+		Expect(env.Request.ClientIP).To(Equal("10.0.0.1"))
+		Expect(env.Request.UserAgent).To(Equal("agent"))
+		Expect(env.Request.PublicLink).To(BeTrue())
+		Expect(env.Resource.ID.GetOpaqueId()).To(Equal("file"))
+		Expect(env.Resource.Path).To(Equal("/folder/file.exe"))
+		Expect(env.Resource.MimeType).To(Equal("application/x-ms-dos-executable"))
+		Expect(env.Space.ID).To(Equal("storage$space"))
+		Expect(env.Space.Type).To(Equal("project"))
+		Expect(env.Space.Quota).To(Equal(uint64(1024)))
+	})
 })
@@ -5,43 +5,227 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/cs3org/reva/v2/pkg/rhttp"
+	"github.com/fsnotify/fsnotify"
 	"github.com/gabriel-vasile/mimetype"
 	"github.com/open-policy-agent/opa/ast"
 	"github.com/open-policy-agent/opa/rego"
 	"github.com/open-policy-agent/opa/types"
+	"github.com/owncloud/ocis/v2/ocis-pkg/event"
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
 	"github.com/owncloud/ocis/v2/services/policies/pkg/config"
+	"github.com/owncloud/ocis/v2/services/policies/pkg/metrics"
+)
+
+const (
+	// watchDelay is the time to wait for further changes before reloading the policies, editors tend to
+	// write files in several steps.
+	watchDelay = 500 * time.Millisecond
+
+	// ReasonsRule is the rule next to a query explaining its denials, e.g. data.proxy.reasons for data.proxy.granted.
+	ReasonsRule = "reasons"
+
+	// EvaluationErrorRule is the rule of the reason reported for granted evaluation errors in audit only mode.
+	EvaluationErrorRule = "evaluation_error"
 )
 
 // OPA wraps open policy agent makes it possible to ask if an action is granted.
 type OPA struct {
-	policies []string
-	timeout  time.Duration
+	policies         []string
+	timeout          time.Duration
+	logger           log.Logger
+	metrics          *metrics.Metrics
+	auditOnly        bool
+	auditOnlyQueries map[string]struct{}
+	grantErrors      bool
+	getResource      func(*rego.Rego)
+
+	mu         sync.RWMutex
+	queries    map[string]rego.PreparedEvalQuery
+	generation uint64
+}
+
+// Option configures the opa engine.
+type Option func(o *OPA)
+
+// ResourceLoader replaces the download of the ocis_get_resource builtin, e.g. to read local files
+// when testing policies.
+func ResourceLoader(load func(url string) ([]byte, error)) Option {
+	return func(o *OPA) {
+		o.getResource = NewGetResource(load)
+	}
 }
 
 // NewOPA returns a ready to use opa engine.
-func NewOPA(timeout time.Duration, conf config.Engine) (OPA, error) {
-	return OPA{
-			policies: conf.Policies,
-			timeout:  timeout,
-		},
-		nil
+func NewOPA(timeout time.Duration, logger log.Logger, conf config.Engine, m *metrics.Metrics, opts ...Option) (*OPA, error) {
+	o := &OPA{
+		policies:         conf.Policies,
+		timeout:          timeout,
+		logger:           logger,
+		metrics:          m,
+		auditOnly:        conf.AuditOnly,
+		auditOnlyQueries: make(map[string]struct{}, len(conf.AuditOnlyQueries)),
+		grantErrors:      conf.AuditOnlyGrantErrors,
+		queries:          make(map[string]rego.PreparedEvalQuery),
+		getResource:      GetResource,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	for _, qs := range conf.AuditOnlyQueries {
+		o.auditOnlyQueries[qs] = struct{}{}
+	}
+
+	// compile the policies once to report errors early, evaluations are denied until they are fixed
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if _, err := o.prepare(ctx, "data"); err != nil {
+		logger.Error().Err(err).Msg("policies could not be compiled")
+	}
+
+	return o, nil
 }
 
-// Evaluate evaluates the opa policies and returns the result.
-func (o OPA) Evaluate(ctx context.Context, qs string, env Environment) (bool, error) {
+// Evaluate evaluates the opa policies and returns the decision. Denials are explained by the reasons rule
+// next to the query. Denials of queries in audit only mode are granted and only reported. Evaluation errors
+// of these queries are only granted if enabled, the decision then carries the error as reason.
+func (o *OPA) Evaluate(ctx context.Context, qs string, env Environment) (Decision, error) {
+	defer func(start time.Time) {
+		o.metrics.EvaluationDuration.WithLabelValues(qs).Observe(time.Since(start).Seconds())
+	}(time.Now())
+
 	ctx, cancel := context.WithTimeout(ctx, o.timeout)
 	defer cancel()
 
-	q, err := rego.New(
-		rego.Query(qs),
-		rego.Load(o.policies, nil),
-		GetMimetype,
-		GetResource,
-	).PrepareForEval(ctx)
+	auditOnly := o.isAuditOnly(qs)
+
+	granted, err := o.eval(ctx, qs, env)
+	if err != nil {
+		if !auditOnly || !o.grantErrors {
+			return Decision{}, err
+		}
+		o.logger.Error().Err(err).Str("query", qs).Msg("policies could not be evaluated, granting the action in audit only mode")
+		return Decision{
+			Granted:   true,
+			AuditOnly: true,
+			Reasons:   []event.Reason{{Rule: EvaluationErrorRule, Message: err.Error()}},
+		}, nil
+	}
+
+	if granted {
+		o.logger.Debug().Str("query", qs).Str("stage", string(env.Stage)).Msg("policies granted the action")
+		return Decision{Granted: true}, nil
+	}
+
+	d := Decision{
+		Granted:   auditOnly,
+		AuditOnly: auditOnly,
+		Reasons:   o.reasons(ctx, qs, env),
+	}
+	mode := "enforced"
+	if auditOnly {
+		mode = "audit_only"
+	}
+	o.metrics.Denials.WithLabelValues(qs, mode).Inc()
+	o.logger.Info().Str("query", qs).Str("stage", string(env.Stage)).Bool("auditOnly", auditOnly).Interface("reasons", d.Reasons).Msg("policies denied the action")
+
+	return d, nil
+}
+
+// Reload compiles the cached queries from the policy files again. The previous queries stay in use
+// if the policies can't be compiled.
+func (o *OPA) Reload(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, o.timeout)
+	defer cancel()
+
+	o.mu.RLock()
+	qss := make([]string, 0, len(o.queries))
+	for qs := range o.queries {
+		qss = append(qss, qs)
+	}
+	o.mu.RUnlock()
+
+	queries := make(map[string]rego.PreparedEvalQuery, len(qss))
+	if _, err := o.prepare(ctx, "data"); err != nil {
+		o.metrics.Reloads.WithLabelValues("error").Inc()
+		return err
+	}
+	for _, qs := range qss {
+		q, err := o.prepare(ctx, qs)
+		if err != nil {
+			o.metrics.Reloads.WithLabelValues("error").Inc()
+			return err
+		}
+		queries[qs] = q
+	}
+
+	o.mu.Lock()
+	o.queries = queries
+	o.generation++
+	o.mu.Unlock()
+
+	o.metrics.Reloads.WithLabelValues("success").Inc()
+	return nil
+}
+
+// Watch reloads the policies whenever one of the policy files changes until the context is done.
+func (o *OPA) Watch(ctx context.Context) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	// directories are watched to notice files being replaced
+	dirs := make(map[string]struct{})
+	for _, p := range o.policies {
+		if fi, err := os.Stat(p); err != nil || !fi.IsDir() {
+			p = filepath.Dir(p)
+		}
+		dirs[filepath.Clean(p)] = struct{}{}
+	}
+	for dir := range dirs {
+		if err := w.Add(dir); err != nil {
+			return fmt.Errorf("cannot watch policies in '%s': %w", dir, err)
+		}
+	}
+
+	var reload <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+			if ev.Op != fsnotify.Chmod && o.isPolicy(ev.Name) {
+				reload = time.After(watchDelay)
+			}
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+			o.logger.Error().Err(err).Msg("watching the policy files failed")
+		case <-reload:
+			reload = nil
+			if err := o.Reload(ctx); err != nil {
+				o.logger.Error().Err(err).Msg("policies could not be reloaded, keeping the previous policies")
+				continue
+			}
+			o.logger.Info().Msg("policies reloaded")
+		}
+	}
+}
+
+func (o *OPA) eval(ctx context.Context, qs string, env Environment) (bool, error) {
+	q, err := o.query(ctx, qs)
 	if err != nil {
 		return false, err
 	}
@@ -54,49 +238,169 @@ func (o OPA) Evaluate(ctx context.Context, qs string, env Environment) (bool, er
 	return result.Allowed(), nil
 }
 
-var GetResource = rego.Function1(
-	&rego.Function{
-		Name:             "ocis_get_resource",
-		Decl:             types.NewFunction(types.Args(types.S), types.A),
-		Memoize:          true,
-		Nondeterministic: true,
-	},
-	func(_ rego.BuiltinContext, a *ast.Term) (*ast.Term, error) {
-		var url string
+// reasons evaluates the reasons rule next to the query. A reason is either a string or an object with
+// a rule and a message. Errors are only logged, the decision stands without reasons.
+func (o *OPA) reasons(ctx context.Context, qs string, env Environment) []event.Reason {
+	ref, err := ast.ParseRef(qs)
+	if err != nil || len(ref) < 2 || !ref.HasPrefix(ast.DefaultRootRef) {
+		return nil
+	}
+	rqs := ref[:len(ref)-1].Append(ast.StringTerm(ReasonsRule)).String()
+	if rqs == qs {
+		return nil
+	}
 
-		if err := ast.As(a.Value, &url); err != nil {
-			return nil, err
-		}
+	q, err := o.query(ctx, rqs)
+	if err != nil {
+		o.logger.Error().Err(err).Str("query", rqs).Msg("policy reasons could not be prepared")
+		return nil
+	}
 
-		req, err := http.NewRequest(http.MethodGet, url, nil)
-		if err != nil {
-			return nil, err
-		}
+	rs, err := q.Eval(ctx, rego.EvalInput(env))
+	if err != nil {
+		o.logger.Error().Err(err).Str("query", rqs).Msg("policy reasons could not be evaluated")
+		return nil
+	}
+	if len(rs) == 0 || len(rs[0].Expressions) == 0 {
+		return nil
+	}
 
-		client := rhttp.GetHTTPClient(rhttp.Insecure(true))
-		res, err := client.Do(req)
-		if err != nil {
-			return nil, err
-		}
-		defer res.Body.Close()
+	values, ok := rs[0].Expressions[0].Value.([]interface{})
+	if !ok {
+		values = []interface{}{rs[0].Expressions[0].Value}
+	}
 
-		if res.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("unexpected status code from Download %v", res.StatusCode)
+	reasons := make([]event.Reason, 0, len(values))
+	for _, v := range values {
+		switch r := v.(type) {
+		case string:
+			reasons = append(reasons, event.Reason{Message: r})
+		case map[string]interface{}:
+			rule, _ := r["rule"].(string)
+			message, _ := r["message"].(string)
+			reasons = append(reasons, event.Reason{Rule: rule, Message: message})
 		}
+	}
+	return reasons
+}
 
-		buf := new(bytes.Buffer)
-		if _, err := buf.ReadFrom(res.Body); err != nil {
-			return nil, err
-		}
+// isAuditOnly checks if denials of the query are only logged instead of enforced
+func (o *OPA) isAuditOnly(qs string) bool {
+	if o.auditOnly {
+		return true
+	}
+	_, ok := o.auditOnlyQueries[qs]
+	return ok
+}
 
-		v, err := ast.InterfaceToValue(buf.Bytes())
-		if err != nil {
-			return nil, err
+// query returns the prepared query for the query string, it is prepared on first use
+func (o *OPA) query(ctx context.Context, qs string) (rego.PreparedEvalQuery, error) {
+	o.mu.RLock()
+	q, ok := o.queries[qs]
+	generation := o.generation
+	o.mu.RUnlock()
+	if ok {
+		return q, nil
+	}
+
+	q, err := o.prepare(ctx, qs)
+	if err != nil {
+		return q, err
+	}
+
+	o.mu.Lock()
+	// don't cache queries prepared from policies which were reloaded in the meantime
+	if generation == o.generation {
+		o.queries[qs] = q
+	}
+	o.mu.Unlock()
+
+	return q, nil
+}
+
+func (o *OPA) prepare(ctx context.Context, qs string) (rego.PreparedEvalQuery, error) {
+	q, err := rego.New(
+		rego.Query(qs),
+		rego.Load(o.policies, nil),
+		GetMimetype,
+		o.getResource,
+	).PrepareForEval(ctx)
+	if err != nil {
+		o.metrics.CompileErrors.Inc()
+	}
+	return q, err
+}
+
+// isPolicy checks if the path is one of the policy files or inside of one of the policy directories
+func (o *OPA) isPolicy(path string) bool {
+	path = filepath.Clean(path)
+	for _, p := range o.policies {
+		p = filepath.Clean(p)
+		if path == p || strings.HasPrefix(path, p+string(filepath.Separator)) {
+			return true
 		}
+	}
+	return false
+}
 
-		return ast.NewTerm(v), nil
-	},
-)
+// GetResource is the ocis_get_resource builtin, it downloads the resource from the given url.
+var GetResource = NewGetResource(downloadResource)
+
+// NewGetResource returns the ocis_get_resource builtin loading the resource with the given function.
+func NewGetResource(load func(url string) ([]byte, error)) func(*rego.Rego) {
+	return rego.Function1(
+		&rego.Function{
+			Name:             "ocis_get_resource",
+			Decl:             types.NewFunction(types.Args(types.S), types.A),
+			Memoize:          true,
+			Nondeterministic: true,
+		},
+		func(_ rego.BuiltinContext, a *ast.Term) (*ast.Term, error) {
+			var url string
+
+			if err := ast.As(a.Value, &url); err != nil {
+				return nil, err
+			}
+
+			b, err := load(url)
+			if err != nil {
+				return nil, err
+			}
+
+			v, err := ast.InterfaceToValue(b)
+			if err != nil {
+				return nil, err
+			}
+
+			return ast.NewTerm(v), nil
+		},
+	)
+}
+
+func downloadResource(url string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client := rhttp.GetHTTPClient(rhttp.Insecure(true))
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code from Download %v", res.StatusCode)
+	}
+
+	buf := new(bytes.Buffer)
+	if _, err := buf.ReadFrom(res.Body); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
 
 var GetMimetype = rego.Function1(
 	&rego.Function{
@@ -5,14 +5,207 @@ import (
 	"encoding/base64"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/open-policy-agent/opa/rego"
+	"github.com/owncloud/ocis/v2/ocis-pkg/event"
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/services/policies/pkg/config"
 	"github.com/owncloud/ocis/v2/services/policies/pkg/engine"
+	"github.com/owncloud/ocis/v2/services/policies/pkg/metrics"
 )
 
 var _ = Describe("Opa", func() {
+	Describe("Policies", func() {
+		var (
+			policy string
+			o      *engine.OPA
+		)
+
+		writePolicy := func(allowed string) {
+			Expect(os.WriteFile(policy, []byte("package test\n\ndefault granted := "+allowed+"\n"), 0600)).To(Succeed())
+		}
+
+		granted := func() bool {
+			d, err := o.Evaluate(context.Background(), "data.test.granted", engine.Environment{})
+			Expect(err).ToNot(HaveOccurred())
+			return d.Granted
+		}
+
+		BeforeEach(func() {
+			policy = filepath.Join(GinkgoT().TempDir(), "test.rego")
+			writePolicy("true")
+
+			var err error
+			o, err = engine.NewOPA(10*time.Second, log.NopLogger(), config.Engine{Policies: []string{policy}}, metrics.New())
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("evaluates the cached query until the policies are reloaded", func() {
+			Expect(granted()).To(BeTrue())
+
+			writePolicy("false")
+			Expect(granted()).To(BeTrue())
+
+			Expect(o.Reload(context.Background())).To(Succeed())
+			Expect(granted()).To(BeFalse())
+		})
+
+		It("keeps the previous policies if the changed policies can't be compiled", func() {
+			Expect(granted()).To(BeTrue())
+
+			writePolicy("{")
+			Expect(o.Reload(context.Background())).ToNot(Succeed())
+			Expect(granted()).To(BeTrue())
+		})
+
+		It("reloads changed policy files", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go o.Watch(ctx)
+
+			Expect(granted()).To(BeTrue())
+			// give the watcher some time to start
+			time.Sleep(100 * time.Millisecond)
+			writePolicy("false")
+			Eventually(func() bool {
+				d, _ := o.Evaluate(context.Background(), "data.test.granted", engine.Environment{})
+				return d.Granted
+			}, 5*time.Second, 100*time.Millisecond).Should(BeFalse())
+		})
+	})
+
+	Describe("Decisions", func() {
+		var policy string
+
+		newOPA := func(conf config.Engine) *engine.OPA {
+			conf.Policies = []string{policy}
+			o, err := engine.NewOPA(10*time.Second, log.NopLogger(), conf, metrics.New())
+			Expect(err).ToNot(HaveOccurred())
+			return o
+		}
+
+		BeforeEach(func() {
+			policy = filepath.Join(GinkgoT().TempDir(), "test.rego")
+			Expect(os.WriteFile(policy, []byte(`package test
+
+import future.keywords.if
+import future.keywords.contains
+
+default granted := true
+
+granted := false if {
+	endswith(input.resource.name, ".exe")
+}
+
+reasons contains {"rule": "no_executables", "message": "executables are not allowed"} if {
+	endswith(input.resource.name, ".exe")
+}
+
+reasons contains "no programs at all" if {
+	endswith(input.resource.name, ".exe")
+}
+
+broken := true if {
+	input.resource.name != ""
+}
+
+broken := false if {
+	input.resource.name != ""
+}
+`), 0600)).To(Succeed())
+		})
+
+		It("explains denials with the reasons rule", func() {
+			d, err := newOPA(config.Engine{}).Evaluate(context.Background(), "data.test.granted", engine.Environment{Resource: engine.Resource{Name: "run.exe"}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(d.Granted).To(BeFalse())
+			Expect(d.AuditOnly).To(BeFalse())
+			Expect(d.Reasons).To(ConsistOf(
+				event.Reason{Rule: "no_executables", Message: "executables are not allowed"},
+				event.Reason{Message: "no programs at all"},
+			))
+		})
+
+		It("has no reasons for granted actions", func() {
+			d, err := newOPA(config.Engine{}).Evaluate(context.Background(), "data.test.granted", engine.Environment{Resource: engine.Resource{Name: "doc.pdf"}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(d.Granted).To(BeTrue())
+			Expect(d.Denied()).To(BeFalse())
+			Expect(d.Reasons).To(BeEmpty())
+		})
+
+		It("doesn't enforce denials in audit only mode", func() {
+			for _, conf := range []config.Engine{{AuditOnly: true}, {AuditOnlyQueries: []string{"data.test.granted"}}} {
+				d, err := newOPA(conf).Evaluate(context.Background(), "data.test.granted", engine.Environment{Resource: engine.Resource{Name: "run.exe"}})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(d.Granted).To(BeTrue())
+				Expect(d.AuditOnly).To(BeTrue())
+				Expect(d.Denied()).To(BeTrue())
+				Expect(d.Reasons).To(HaveLen(2))
+			}
+		})
+
+		It("denies actions which can't be evaluated in audit only mode", func() {
+			_, err := newOPA(config.Engine{AuditOnly: true}).Evaluate(context.Background(), "data.test.broken", engine.Environment{Resource: engine.Resource{Name: "run.exe"}})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("grants and reports actions which can't be evaluated in audit only mode if enabled", func() {
+			d, err := newOPA(config.Engine{AuditOnly: true, AuditOnlyGrantErrors: true}).Evaluate(context.Background(), "data.test.broken", engine.Environment{Resource: engine.Resource{Name: "run.exe"}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(d.Granted).To(BeTrue())
+			Expect(d.Denied()).To(BeTrue())
+			Expect(d.Reasons).To(HaveLen(1))
+			Expect(d.Reasons[0].Rule).To(Equal(engine.EvaluationErrorRule))
+
+			_, err = newOPA(config.Engine{AuditOnlyGrantErrors: true}).Evaluate(context.Background(), "data.test.broken", engine.Environment{Resource: engine.Resource{Name: "run.exe"}})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("enforces denials of other queries", func() {
+			d, err := newOPA(config.Engine{AuditOnlyQueries: []string{"data.other.granted"}}).Evaluate(context.Background(), "data.test.granted", engine.Environment{Resource: engine.Resource{Name: "run.exe"}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(d.Granted).To(BeFalse())
+			Expect(d.AuditOnly).To(BeFalse())
+		})
+	})
+
+	Describe("Unit tests", func() {
+		It("runs the test rules with the local resource loader", func() {
+			dir := GinkgoT().TempDir()
+			Expect(os.WriteFile(filepath.Join(dir, "test.rego"), []byte(`package test
+
+test_resource {
+	ocis_get_mimetype(ocis_get_resource("local")) == "text/plain"
+}
+
+test_fails {
+	false
+}
+`), 0600)).To(Succeed())
+
+			o, err := engine.NewOPA(10*time.Second, log.NopLogger(), config.Engine{Policies: []string{dir}}, metrics.New(), engine.ResourceLoader(func(url string) ([]byte, error) {
+				Expect(url).To(Equal("local"))
+				return []byte("plain text"), nil
+			}))
+			Expect(err).ToNot(HaveOccurred())
+
+			results, err := o.Test(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(HaveLen(2))
+			Expect(results[0].Name).To(Equal("data.test.test_fails"))
+			Expect(results[0].Passed).To(BeFalse())
+			Expect(results[1].Name).To(Equal("data.test.test_resource"))
+			Expect(results[1].Err).ToNot(HaveOccurred())
+			Expect(results[1].Passed).To(BeTrue())
+		})
+	})
+
 	Describe("Custom OPA function", func() {
 		Describe("GetResource", func() {
 			It("loads reva resources", func() {
@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/loader"
+)
+
+// TestPrefix is the prefix of the rules which are run as rego unit tests.
+const TestPrefix = "test_"
+
+// TestResult is the result of a rego unit test.
+type TestResult struct {
+	Name     string
+	Passed   bool
+	Err      error
+	Duration time.Duration
+}
+
+// Test runs the rego unit tests of the policies. Every rule starting with test_ is a test, it passes if it
+// evaluates to true. The custom builtins are available to the tests.
+func (o *OPA) Test(ctx context.Context) ([]TestResult, error) {
+	res, err := loader.AllRegos(o.policies)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	seen := make(map[string]struct{})
+	for _, m := range res.ParsedModules() {
+		for _, r := range m.Rules {
+			name := r.Head.Name.String()
+			if !strings.HasPrefix(name, TestPrefix) {
+				continue
+			}
+			// rules can be defined incrementally, the test is run once
+			qs := m.Package.Path.Append(ast.StringTerm(name)).String()
+			if _, ok := seen[qs]; ok {
+				continue
+			}
+			seen[qs] = struct{}{}
+			names = append(names, qs)
+		}
+	}
+	sort.Strings(names)
+
+	results := make([]TestResult, 0, len(names))
+	for _, qs := range names {
+		results = append(results, o.test(ctx, qs))
+	}
+	return results, nil
+}
+
+func (o *OPA) test(ctx context.Context, qs string) TestResult {
+	start := time.Now()
+	tr := TestResult{Name: qs}
+
+	ctx, cancel := context.WithTimeout(ctx, o.timeout)
+	defer cancel()
+
+	q, err := o.query(ctx, qs)
+	if err != nil {
+		tr.Err = err
+		return tr
+	}
+
+	rs, err := q.Eval(ctx)
+	tr.Duration = time.Since(start)
+	if err != nil {
+		tr.Err = err
+		return tr
+	}
+
+	tr.Passed = rs.Allowed()
+	return tr
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	// Namespace defines the namespace for the defines metrics.
+	Namespace = "ocis"
+
+	// Subsystem defines the subsystem for the defines metrics.
+	Subsystem = "policies"
+)
+
+// Metrics defines the available metrics of this service.
+type Metrics struct {
+	BuildInfo          *prometheus.GaugeVec
+	EvaluationDuration *prometheus.HistogramVec
+	CompileErrors      prometheus.Counter
+	Reloads            *prometheus.CounterVec
+	Denials            *prometheus.CounterVec
+}
+
+// New initializes the available metrics.
+func New() *Metrics {
+	m := &Metrics{
+		BuildInfo: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: Subsystem,
+			Name:      "build_info",
+			Help:      "Build information",
+		}, []string{"version"}),
+		EvaluationDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Subsystem: Subsystem,
+			Name:      "evaluation_duration_seconds",
+			Help:      "Time it took to evaluate a policy query in seconds",
+		}, []string{"query"}),
+		CompileErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: Subsystem,
+			Name:      "compile_errors_total",
+			Help:      "How many times the policies could not be compiled",
+		}),
+		Reloads: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: Subsystem,
+			Name:      "reloads_total",
+			Help:      "How many times the policy files were reloaded",
+		}, []string{"status"}),
+		Denials: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: Subsystem,
+			Name:      "denials_total",
+			Help:      "How many actions were denied by a policy query, in enforced or audit only mode",
+		}, []string{"query", "mode"}),
+	}
+
+	_ = prometheus.Register(m.BuildInfo)
+	_ = prometheus.Register(m.EvaluationDuration)
+	_ = prometheus.Register(m.CompileErrors)
+	_ = prometheus.Register(m.Reloads)
+	_ = prometheus.Register(m.Denials)
+
+	return m
+}
@@ -0,0 +1,119 @@
+package eventSVC
+
+import (
+	"context"
+	"fmt"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/v2/pkg/errtypes"
+	"github.com/cs3org/reva/v2/pkg/events"
+	"github.com/cs3org/reva/v2/pkg/storagespace"
+	"github.com/cs3org/reva/v2/pkg/utils"
+	settingssvc "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/settings/v0"
+	"github.com/owncloud/ocis/v2/services/policies/pkg/engine"
+)
+
+// environment returns the evaluated environment of an upload. It is enriched with the resource path,
+// the mime type, the space and the roles of the uploading user as far as they can be looked up.
+func (s Service) environment(ctx context.Context, ev events.StartPostprocessingStep) engine.Environment {
+	env := engine.Environment{
+		Stage: engine.StagePP,
+		Resource: engine.Resource{
+			Name: ev.Filename,
+			URL:  ev.URL,
+			Size: ev.Filesize,
+		},
+	}
+
+	if ev.ExecutingUser != nil {
+		env.User.User = *ev.ExecutingUser
+	}
+
+	if ev.ResourceID != nil {
+		env.Resource.ID = *ev.ResourceID
+	}
+
+	if s.roleService != nil && ev.ExecutingUser.GetId().GetOpaqueId() != "" {
+		res, err := s.roleService.ListRoleAssignments(ctx, &settingssvc.ListRoleAssignmentsRequest{
+			AccountUuid: ev.ExecutingUser.GetId().GetOpaqueId(),
+		})
+		if err != nil {
+			s.log.Error().Err(err).Str("uploadid", ev.UploadID).Msg("cannot get roles of the uploading user")
+		}
+		for _, a := range res.GetAssignments() {
+			env.User.Roles = append(env.User.Roles, a.GetRoleId())
+		}
+	}
+
+	if s.gatewaySelector == nil || s.machineAuthAPIKey == "" || ev.ResourceID == nil {
+		return env
+	}
+
+	if err := s.addResourceInfo(ctx, ev, &env); err != nil {
+		s.log.Error().Err(err).Str("uploadid", ev.UploadID).Msg("cannot get resource information of the upload")
+	}
+
+	return env
+}
+
+// addResourceInfo adds the information the gateway knows about the uploaded resource and its space
+func (s Service) addResourceInfo(ctx context.Context, ev events.StartPostprocessingStep, env *engine.Environment) error {
+	gwc, err := s.gatewaySelector.Next()
+	if err != nil {
+		return err
+	}
+
+	ctx, _, err = utils.Impersonate(ev.ExecutingUser.GetId(), gwc, s.machineAuthAPIKey)
+	if err != nil {
+		return err
+	}
+
+	sRes, err := gwc.Stat(ctx, &provider.StatRequest{Ref: &provider.Reference{ResourceId: ev.ResourceID}})
+	switch {
+	case err != nil:
+		return err
+	case sRes.GetStatus().GetCode() != rpc.Code_CODE_OK:
+		return errtypes.NewErrtypeFromStatus(sRes.GetStatus())
+	}
+	env.Resource.MimeType = sRes.GetInfo().GetMimeType()
+
+	pRes, err := gwc.GetPath(ctx, &provider.GetPathRequest{ResourceId: ev.ResourceID})
+	switch {
+	case err != nil:
+		return err
+	case pRes.GetStatus().GetCode() != rpc.Code_CODE_OK:
+		return errtypes.NewErrtypeFromStatus(pRes.GetStatus())
+	}
+	env.Resource.Path = pRes.GetPath()
+
+	spaceID := storagespace.FormatStorageID(ev.ResourceID.GetStorageId(), ev.ResourceID.GetSpaceId())
+	lRes, err := gwc.ListStorageSpaces(ctx, &provider.ListStorageSpacesRequest{
+		Filters: []*provider.ListStorageSpacesRequest_Filter{
+			{
+				Type: provider.ListStorageSpacesRequest_Filter_TYPE_ID,
+				Term: &provider.ListStorageSpacesRequest_Filter_Id{Id: &provider.StorageSpaceId{OpaqueId: spaceID}},
+			},
+		},
+	})
+	switch {
+	case err != nil:
+		return err
+	case lRes.GetStatus().GetCode() != rpc.Code_CODE_OK:
+		return errtypes.NewErrtypeFromStatus(lRes.GetStatus())
+	case len(lRes.GetStorageSpaces()) != 1:
+		return fmt.Errorf("expected one space with id '%s', got %d", spaceID, len(lRes.GetStorageSpaces()))
+	}
+
+	space := lRes.GetStorageSpaces()[0]
+	env.Space = engine.Space{
+		ID:    spaceID,
+		Type:  space.GetSpaceType(),
+		Name:  space.GetName(),
+		Quota: space.GetQuota().GetQuotaMaxBytes(),
+	}
+	// uploads to the personal space of another user can only happen through a share
+	env.Request.Share = space.GetSpaceType() == "personal" && !utils.UserIDEqual(space.GetOwner().GetId(), ev.ExecutingUser.GetId())
+
+	return nil
+}
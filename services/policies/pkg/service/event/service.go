@@ -2,9 +2,14 @@ package eventSVC
 
 import (
 	"context"
+	"time"
 
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
 	"github.com/cs3org/reva/v2/pkg/events"
+	"github.com/cs3org/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/owncloud/ocis/v2/ocis-pkg/event"
 	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	settingssvc "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/settings/v0"
 	"github.com/owncloud/ocis/v2/services/policies/pkg/engine"
 )
 
@@ -14,15 +19,23 @@ type Service struct {
 	log    log.Logger
 	stream events.Stream
 	engine engine.Engine
+
+	gatewaySelector   pool.Selectable[gateway.GatewayAPIClient]
+	roleService       settingssvc.RoleService
+	machineAuthAPIKey string
 }
 
-// New returns a service implementation for Service.
-func New(stream events.Stream, logger log.Logger, engine engine.Engine, query string) (Service, error) {
+// New returns a service implementation for Service. The gateway selector and the role service are used
+// to enrich the evaluated environment, they are optional.
+func New(stream events.Stream, logger log.Logger, engine engine.Engine, query string, gatewaySelector pool.Selectable[gateway.GatewayAPIClient], roleService settingssvc.RoleService, machineAuthAPIKey string) (Service, error) {
 	svc := Service{
-		log:    logger,
-		query:  query,
-		engine: engine,
-		stream: stream,
+		log:               logger,
+		query:             query,
+		engine:            engine,
+		stream:            stream,
+		gatewaySelector:   gatewaySelector,
+		roleService:       roleService,
+		machineAuthAPIKey: machineAuthAPIKey,
 	}
 
 	return svc, nil
@@ -43,32 +56,34 @@ func (s Service) Run() error {
 			}
 
 			outcome := events.PPOutcomeContinue
+			var reasons []event.Reason
 
 			if s.query != "" {
-				env := engine.Environment{
-					Stage: engine.StagePP,
-					Resource: engine.Resource{
-						Name: ev.Filename,
-						URL:  ev.URL,
-						Size: ev.Filesize,
-					},
-				}
+				env := s.environment(context.TODO(), ev)
 
-				if ev.ExecutingUser != nil {
-					env.User = *ev.ExecutingUser
-				}
-
-				if ev.ResourceID != nil {
-					env.Resource.ID = *ev.ResourceID
-				}
-
-				result, err := s.engine.Evaluate(context.TODO(), s.query, env)
-				if err != nil {
+				decision, err := s.engine.Evaluate(context.TODO(), s.query, env)
+				switch {
+				case err != nil:
 					s.log.Error().Err(err).Msg("unable evaluate policy")
-				}
-
-				if !result {
 					outcome = events.PPOutcomeDelete
+				case decision.Denied():
+					if !decision.Granted {
+						outcome = events.PPOutcomeDelete
+						reasons = decision.Reasons
+					}
+
+					if err := events.Publish(s.stream, event.PolicyDenied{
+						Query:      s.query,
+						Stage:      string(env.Stage),
+						Executant:  ev.ExecutingUser.GetId(),
+						ResourceID: ev.ResourceID,
+						Filename:   ev.Filename,
+						Reasons:    decision.Reasons,
+						AuditOnly:  decision.AuditOnly,
+						Timestamp:  time.Now(),
+					}); err != nil {
+						s.log.Error().Err(err).Str("uploadID", ev.UploadID).Msg("could not publish policy denial")
+					}
 				}
 			}
 
@@ -78,6 +93,8 @@ func (s Service) Run() error {
 				ExecutingUser: ev.ExecutingUser,
 				Filename:      ev.Filename,
 				FinishedStep:  ev.StepToStart,
+				// the reasons are shown to the user in the notification about the deleted file
+				Result: event.PoliciesResult{Reasons: reasons},
 			}); err != nil {
 				return err
 			}
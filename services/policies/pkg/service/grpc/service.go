@@ -2,20 +2,30 @@ package grpcSVC
 
 import (
 	"context"
+	"time"
 
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/v2/pkg/events"
+	"github.com/owncloud/ocis/v2/ocis-pkg/event"
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	pMessage "github.com/owncloud/ocis/v2/protogen/gen/ocis/messages/policies/v0"
 	"github.com/owncloud/ocis/v2/protogen/gen/ocis/services/policies/v0"
 	"github.com/owncloud/ocis/v2/services/policies/pkg/engine"
 )
 
 // Service defines the service handlers.
 type Service struct {
-	engine engine.Engine
+	engine    engine.Engine
+	publisher events.Publisher
+	log       log.Logger
 }
 
-// New returns a service implementation for Service.
-func New(engine engine.Engine) (Service, error) {
+// New returns a service implementation for Service. Denials are published to the publisher if it is set.
+func New(engine engine.Engine, publisher events.Publisher, logger log.Logger) (Service, error) {
 	svc := Service{
-		engine: engine,
+		engine:    engine,
+		publisher: publisher,
+		log:       logger,
 	}
 
 	return svc, nil
@@ -28,8 +38,38 @@ func (s Service) Evaluate(ctx context.Context, request *v0.EvaluateRequest, resp
 		return err
 	}
 
-	result, err := s.engine.Evaluate(ctx, request.Query, env)
-	response.Result = result
+	decision, err := s.engine.Evaluate(ctx, request.Query, env)
+	if err != nil {
+		return err
+	}
+
+	response.Result = decision.Granted
+	response.AuditOnly = decision.AuditOnly
+	for _, r := range decision.Reasons {
+		response.Reasons = append(response.Reasons, &pMessage.Reason{Rule: r.Rule, Message: r.Message})
+	}
+
+	if decision.Denied() && s.publisher != nil {
+		var rid *provider.ResourceId
+		if env.Resource.ID.GetOpaqueId() != "" {
+			rid = &env.Resource.ID
+		}
+
+		if err := events.Publish(s.publisher, event.PolicyDenied{
+			Query:      request.Query,
+			Stage:      string(env.Stage),
+			Executant:  env.User.GetId(),
+			ResourceID: rid,
+			Filename:   env.Resource.Name,
+			Method:     env.Request.Method,
+			Path:       env.Request.Path,
+			Reasons:    decision.Reasons,
+			AuditOnly:  decision.AuditOnly,
+			Timestamp:  time.Now(),
+		}); err != nil {
+			s.log.Error().Err(err).Str("query", request.Query).Msg("could not publish policy denial")
+		}
+	}
 
-	return err
+	return nil
 }
@@ -1,56 +1,289 @@
 package command
 
 import (
+	"errors"
+	"fmt"
+	"sort"
 	"time"
 
 	"github.com/cs3org/reva/v2/pkg/events"
+	"github.com/cs3org/reva/v2/pkg/store"
 	"github.com/cs3org/reva/v2/pkg/utils"
 	"github.com/owncloud/ocis/v2/ocis-pkg/config/configlog"
+	"github.com/owncloud/ocis/v2/ocis-pkg/event"
 	"github.com/owncloud/ocis/v2/services/postprocessing/pkg/config"
 	"github.com/owncloud/ocis/v2/services/postprocessing/pkg/config/parser"
+	"github.com/owncloud/ocis/v2/services/postprocessing/pkg/postprocessing"
+	"github.com/owncloud/ocis/v2/services/postprocessing/pkg/service"
 	"github.com/urfave/cli/v2"
+	microstore "go-micro.dev/v4/store"
 )
 
+// ListPostprocessing cli command to list uploads in postprocessing
+func ListPostprocessing(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "list",
+		Usage: "list uploads known to the postprocessing service with their current step",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "step",
+				Aliases: []string{"s"},
+				Usage:   "only list uploads in the given step",
+			},
+		},
+		Before: func(c *cli.Context) error {
+			return configlog.ReturnFatal(parser.ParseConfig(cfg))
+		},
+		Action: func(c *cli.Context) error {
+			st, err := sharedStore(cfg.Store)
+			if err != nil {
+				return err
+			}
+
+			pps, err := service.ListPostprocessings(st)
+			if err != nil {
+				return err
+			}
+
+			sort.Slice(pps, func(i, j int) bool {
+				return pps[i].Created.Before(pps[j].Created)
+			})
+
+			fmt.Println("Uploads in postprocessing:")
+			for _, pp := range pps {
+				if s := c.String("step"); s != "" && string(pp.Status.CurrentStep) != s {
+					continue
+				}
+				fmt.Printf(" - %s (%s, Step: %s, Status: %s, Age: %s)\n", pp.ID, pp.Filename, pp.Status.CurrentStep, status(pp), age(pp.Created))
+			}
+			return nil
+		},
+	}
+}
+
+// ShowPostprocessing cli command to show the state of an upload
+func ShowPostprocessing(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "show",
+		Usage: "show the postprocessing state of an upload",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "upload-id",
+				Aliases:  []string{"u"},
+				Required: true,
+				Usage:    "the uploadid to show",
+			},
+		},
+		Before: func(c *cli.Context) error {
+			return configlog.ReturnFatal(parser.ParseConfig(cfg))
+		},
+		Action: func(c *cli.Context) error {
+			st, err := sharedStore(cfg.Store)
+			if err != nil {
+				return err
+			}
+
+			pp, err := service.GetPostprocessing(st, c.String("upload-id"))
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Upload:        %s\n", pp.ID)
+			fmt.Printf("Filename:      %s\n", pp.Filename)
+			fmt.Printf("Filesize:      %d\n", pp.Filesize)
+			fmt.Printf("User:          %s\n", pp.User.GetId().GetOpaqueId())
+			fmt.Printf("Steps:         %v\n", pp.Steps)
+			fmt.Printf("Current step:  %s\n", pp.Status.CurrentStep)
+			fmt.Printf("Status:        %s\n", status(pp))
+			if len(postprocessing.GroupSteps(pp.Status.CurrentStep)) > 1 {
+				fmt.Printf("Finished:      %v\n", pp.Status.Finished)
+			}
+			fmt.Printf("Step started:  %s\n", formatTime(pp.Status.StepStarted))
+			fmt.Printf("Retries:       %d\n", pp.Status.Retries)
+			fmt.Printf("Created:       %s\n", formatTime(pp.Created))
+			return nil
+		},
+	}
+}
+
 // RestartPostprocessing cli command to restart postprocessing
 func RestartPostprocessing(cfg *config.Config) *cli.Command {
 	return &cli.Command{
-		Name:  "restart",
-		Usage: "restart postprocessing for an uploadID",
+		Name:    "resume",
+		Aliases: []string{"restart"},
+		Usage:   "resume postprocessing for an uploadID, for all uploads in a step or for all failed uploads",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "upload-id",
+				Aliases: []string{"u"},
+				Usage:   "the uploadid to resume",
+			},
+			&cli.StringFlag{
+				Name:    "step",
+				Aliases: []string{"s"},
+				Usage:   "resume all uploads which are in the given step",
+			},
+			&cli.BoolFlag{
+				Name:    "failed",
+				Aliases: []string{"f"},
+				Usage:   "resume failed uploads instead of running ones, the failed step is started again. Can be combined with --step",
+			},
+		},
+		Before: func(c *cli.Context) error {
+			return configlog.ReturnFatal(parser.ParseConfig(cfg))
+		},
+		Action: func(c *cli.Context) error {
+			uploadID, step, failed := c.String("upload-id"), c.String("step"), c.Bool("failed")
+			if (uploadID == "") == (step == "" && !failed) {
+				return errors.New("either --upload-id or --step or --failed needs to be set")
+			}
+
+			uploadIDs := []string{uploadID}
+			if uploadID == "" {
+				st, err := sharedStore(cfg.Store)
+				if err != nil {
+					return err
+				}
+
+				pps, err := service.ListPostprocessings(st)
+				if err != nil {
+					return err
+				}
+				uploadIDs = resumable(pps, step, failed)
+			}
+
+			stream, err := getEventBus(cfg.Postprocessing.Events)
+			if err != nil {
+				return err
+			}
+
+			for _, id := range uploadIDs {
+				ev := events.ResumePostprocessing{
+					UploadID:  id,
+					Timestamp: utils.TSNow(),
+				}
+
+				if err := events.Publish(stream, ev); err != nil {
+					return err
+				}
+				fmt.Printf("Resumed postprocessing of %s\n", id)
+			}
+
+			waitForEvents()
+			return nil
+		},
+	}
+}
+
+// AbortPostprocessing cli command to finish postprocessing with a given outcome
+func AbortPostprocessing(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "abort",
+		Usage: "finish postprocessing for an uploadID with the given outcome",
 		Flags: []cli.Flag{
 			&cli.StringFlag{
 				Name:     "upload-id",
 				Aliases:  []string{"u"},
 				Required: true,
-				Usage:    "the uploadid to restart",
+				Usage:    "the uploadid to abort",
+			},
+			&cli.StringFlag{
+				Name:    "outcome",
+				Aliases: []string{"o"},
+				Value:   string(events.PPOutcomeAbort),
+				Usage:   "the outcome of the postprocessing, one of 'abort' (keep the upload), 'delete' (delete the upload) or 'continue' (make the file available)",
 			},
 		},
 		Before: func(c *cli.Context) error {
 			return configlog.ReturnFatal(parser.ParseConfig(cfg))
 		},
 		Action: func(c *cli.Context) error {
+			outcome := events.PostprocessingOutcome(c.String("outcome"))
+			switch outcome {
+			case events.PPOutcomeAbort, events.PPOutcomeDelete, events.PPOutcomeContinue:
+			default:
+				return fmt.Errorf("unknown outcome '%s'", outcome)
+			}
+
 			stream, err := getEventBus(cfg.Postprocessing.Events)
 			if err != nil {
 				return err
 			}
 
-			ev := events.ResumePostprocessing{
+			ev := event.ForcePostprocessingOutcome{
 				UploadID:  c.String("upload-id"),
-				Timestamp: utils.TSNow(),
+				Outcome:   outcome,
+				Timestamp: time.Now(),
 			}
 
 			if err := events.Publish(stream, ev); err != nil {
 				return err
 			}
 
-			// go-micro nats implementation uses async publishing,
-			// therefore we need to manually wait.
-			//
-			// FIXME: upstream pr
-			//
-			// https://github.com/go-micro/plugins/blob/3e77393890683be4bacfb613bc5751867d584692/v4/events/natsjs/nats.go#L115
-			time.Sleep(5 * time.Second)
-
+			waitForEvents()
 			return nil
 		},
 	}
 }
+
+// resumable returns the ids of the uploads to resume. Without failed, only uploads which are still
+// processing are returned. An empty step matches all steps.
+func resumable(pps []*postprocessing.Postprocessing, step string, failed bool) []string {
+	var ids []string
+	for _, pp := range pps {
+		if step != "" && string(pp.Status.CurrentStep) != step {
+			continue
+		}
+		if (failed && pp.Status.Failed) || (!failed && pp.Status.Outcome == "") {
+			ids = append(ids, pp.ID)
+		}
+	}
+	return ids
+}
+
+// sharedStore returns the store of the postprocessing service. The commands run in their own process,
+// they can only read the state of the service when it is kept in a shared store.
+func sharedStore(storeCfg config.Store) (microstore.Store, error) {
+	switch storeCfg.Store {
+	case store.TypeMemory, "mem", "", store.TypeOCMem, store.TypeNoop:
+		return nil, fmt.Errorf("the '%s' store is local to the postprocessing service and can't be read by this command, configure a shared store like 'nats-js' or 'redis' with POSTPROCESSING_STORE", storeCfg.Store)
+	default:
+		return getStore(storeCfg), nil
+	}
+}
+
+func status(pp *postprocessing.Postprocessing) string {
+	switch {
+	case pp.Status.Failed:
+		return "failed"
+	case pp.Status.Outcome != "":
+		return "finished (" + string(pp.Status.Outcome) + ")"
+	default:
+		return "processing"
+	}
+}
+
+func age(t time.Time) string {
+	if t.IsZero() {
+		return "unknown"
+	}
+	return time.Since(t).Round(time.Second).String()
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "unknown"
+	}
+	return t.Format(time.RFC3339)
+}
+
+// waitForEvents waits for published events to be sent
+func waitForEvents() {
+	// go-micro nats implementation uses async publishing,
+	// therefore we need to manually wait.
+	//
+	// FIXME: upstream pr
+	//
+	// https://github.com/go-micro/plugins/blob/3e77393890683be4bacfb613bc5751867d584692/v4/events/natsjs/nats.go#L115
+	time.Sleep(5 * time.Second)
+}
@@ -0,0 +1,45 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/cs3org/reva/v2/pkg/events"
+	"github.com/owncloud/ocis/v2/services/postprocessing/pkg/config"
+	"github.com/owncloud/ocis/v2/services/postprocessing/pkg/postprocessing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResumable(t *testing.T) {
+	pps := []*postprocessing.Postprocessing{
+		{ID: "running", Status: postprocessing.Status{CurrentStep: "virusscan"}},
+		{ID: "running-policies", Status: postprocessing.Status{CurrentStep: "policies"}},
+		{ID: "failed", Status: postprocessing.Status{CurrentStep: "virusscan", Outcome: events.PPOutcomeAbort, Failed: true}},
+		{ID: "failed-policies", Status: postprocessing.Status{CurrentStep: "policies", Outcome: events.PPOutcomeAbort, Failed: true}},
+		{ID: "aborted", Status: postprocessing.Status{CurrentStep: "virusscan", Outcome: events.PPOutcomeAbort}},
+	}
+
+	tests := []struct {
+		name   string
+		step   string
+		failed bool
+		want   []string
+	}{
+		{name: "step", step: "virusscan", want: []string{"running"}},
+		{name: "failed in step", step: "virusscan", failed: true, want: []string{"failed"}},
+		{name: "all failed", failed: true, want: []string{"failed", "failed-policies"}},
+		{name: "unknown step", step: "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, resumable(pps, tt.step, tt.failed))
+		})
+	}
+}
+
+func TestSharedStore(t *testing.T) {
+	for _, s := range []string{"", "memory", "mem", "ocmem", "noop"} {
+		_, err := sharedStore(config.Store{Store: s})
+		assert.ErrorContains(t, err, "POSTPROCESSING_STORE", "store %q", s)
+	}
+}
@@ -15,7 +15,10 @@ func GetCommands(cfg *config.Config) cli.Commands {
 		Server(cfg),
 
 		// interaction with this service
+		ListPostprocessing(cfg),
+		ShowPostprocessing(cfg),
 		RestartPostprocessing(cfg),
+		AbortPostprocessing(cfg),
 
 		// infos about this service
 		Health(cfg),
@@ -7,14 +7,18 @@ import (
 	"fmt"
 	"os"
 
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
 	"github.com/cs3org/reva/v2/pkg/events"
 	"github.com/cs3org/reva/v2/pkg/events/stream"
+	"github.com/cs3org/reva/v2/pkg/rgrpc/todo/pool"
 	"github.com/cs3org/reva/v2/pkg/store"
 	"github.com/go-micro/plugins/v4/events/natsjs"
 	"github.com/oklog/run"
 	ociscrypto "github.com/owncloud/ocis/v2/ocis-pkg/crypto"
 	"github.com/owncloud/ocis/v2/ocis-pkg/handlers"
+	"github.com/owncloud/ocis/v2/ocis-pkg/registry"
 	"github.com/owncloud/ocis/v2/ocis-pkg/service/debug"
+	ogrpc "github.com/owncloud/ocis/v2/ocis-pkg/service/grpc"
 	"github.com/owncloud/ocis/v2/ocis-pkg/version"
 	"github.com/owncloud/ocis/v2/services/postprocessing/pkg/config"
 	"github.com/owncloud/ocis/v2/services/postprocessing/pkg/config/parser"
@@ -58,16 +62,17 @@ func Server(cfg *config.Config) *cli.Command {
 					return err
 				}
 
-				st := store.Create(
-					store.Store(cfg.Store.Store),
-					store.TTL(cfg.Store.TTL),
-					store.Size(cfg.Store.Size),
-					microstore.Nodes(cfg.Store.Nodes...),
-					microstore.Database(cfg.Store.Database),
-					microstore.Table(cfg.Store.Table),
-				)
+				st := getStore(cfg.Store)
+
+				var gatewaySelector pool.Selectable[gateway.GatewayAPIClient]
+				if cfg.Postprocessing.SpaceSteps || len(cfg.Postprocessing.SpaceTypeSteps) > 0 {
+					gatewaySelector, err = getGatewaySelector(cfg)
+					if err != nil {
+						return err
+					}
+				}
 
-				svc, err := service.NewPostprocessingService(bus, logger, st, cfg.Postprocessing)
+				svc, err := service.NewPostprocessingService(bus, logger, st, gatewaySelector, cfg.MachineAuthAPIKey, cfg.Postprocessing)
 				if err != nil {
 					return err
 				}
@@ -111,6 +116,38 @@ func Server(cfg *config.Config) *cli.Command {
 	}
 }
 
+func getGatewaySelector(cfg *config.Config) (pool.Selectable[gateway.GatewayAPIClient], error) {
+	if err := ogrpc.Configure(ogrpc.GetClientOptions(cfg.GRPCClientTLS)...); err != nil {
+		return nil, err
+	}
+
+	tm, err := pool.StringToTLSMode(cfg.GRPCClientTLS.Mode)
+	if err != nil {
+		return nil, err
+	}
+	gatewaySelector, err := pool.GatewaySelector(
+		cfg.Reva.Address,
+		pool.WithTLSCACert(cfg.GRPCClientTLS.CACert),
+		pool.WithTLSMode(tm),
+		pool.WithRegistry(registry.GetRegistry()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not get reva client selector: %s", err)
+	}
+	return gatewaySelector, nil
+}
+
+func getStore(storeCfg config.Store) microstore.Store {
+	return store.Create(
+		store.Store(storeCfg.Store),
+		store.TTL(storeCfg.TTL),
+		store.Size(storeCfg.Size),
+		microstore.Nodes(storeCfg.Nodes...),
+		microstore.Database(storeCfg.Database),
+		microstore.Table(storeCfg.Table),
+	)
+}
+
 func getEventBus(evtsCfg config.Events) (events.Stream, error) {
 	var tlsConf *tls.Config
 	if evtsCfg.EnableTLS {
@@ -20,15 +20,34 @@ type Config struct {
 	Store          Store          `yaml:"store"`
 	Postprocessing Postprocessing `yaml:"postprocessing"`
 
+	Reva              *shared.Reva          `yaml:"reva"`
+	GRPCClientTLS     *shared.GRPCClientTLS `yaml:"grpc_client_tls"`
+	MachineAuthAPIKey string                `yaml:"machine_auth_api_key" env:"OCIS_MACHINE_AUTH_API_KEY;POSTPROCESSING_MACHINE_AUTH_API_KEY" desc:"Machine auth API key used to validate internal requests necessary to access resources from other services. Only needed when postprocessing steps are configured per space or per space type."`
+
 	Context context.Context `yaml:"-"`
 }
 
 // Postprocessing defines the config options for the postprocessing service.
 type Postprocessing struct {
-	Events          Events        `yaml:"events"`
-	Steps           []string      `yaml:"steps" env:"POSTPROCESSING_STEPS" desc:"A comma separated list of postprocessing steps, processed in order of their appearance. Currently supported values by the system are: 'virusscan', 'policies' and 'delay'. Custom steps are allowed. See the documentation for instructions."`
-	Virusscan       bool          `yaml:"virusscan" env:"POSTPROCESSING_VIRUSSCAN" desc:"After uploading a file but before making it available for download, virus scanning the file can be enabled. Needs as prerequisite the antivirus service to be enabled and configured." deprecationVersion:"master" removalVersion:"master" deprecationVersion:"3.0" removalVersion:"4.0.0" deprecationInfo:"POSTPROCESSING_VIRUSSCAN is not longer necessary and is replaced by POSTPROCESSING_STEPS which also holds information about the order of steps" deprecationReplacement:"POSTPROCESSING_STEPS"`
-	Delayprocessing time.Duration `yaml:"delayprocessing" env:"POSTPROCESSING_DELAY" desc:"After uploading a file but before making it available for download, a delay step can be added. Intended for developing purposes only. The duration can be set as number followed by a unit identifier like s, m or h. If a duration is set but the keyword 'delay' is not explicitely added to 'POSTPROCESSING_STEPS', the delay step will be processed as last step. In such a case, a log entry will be written on service startup to remind the admin about that situation."`
+	Events          Events                `yaml:"events"`
+	Steps           []string              `yaml:"steps" env:"POSTPROCESSING_STEPS" desc:"A comma separated list of postprocessing steps, processed in order of their appearance. Currently supported values by the system are: 'virusscan', 'policies' and 'delay'. Custom steps are allowed. Steps joined with '+' like 'virusscan+policies' are started in parallel. See the documentation for instructions."`
+	Virusscan       bool                  `yaml:"virusscan" env:"POSTPROCESSING_VIRUSSCAN" desc:"After uploading a file but before making it available for download, virus scanning the file can be enabled. Needs as prerequisite the antivirus service to be enabled and configured." deprecationVersion:"master" removalVersion:"master" deprecationVersion:"3.0" removalVersion:"4.0.0" deprecationInfo:"POSTPROCESSING_VIRUSSCAN is not longer necessary and is replaced by POSTPROCESSING_STEPS which also holds information about the order of steps" deprecationReplacement:"POSTPROCESSING_STEPS"`
+	Delayprocessing time.Duration         `yaml:"delayprocessing" env:"POSTPROCESSING_DELAY" desc:"After uploading a file but before making it available for download, a delay step can be added. Intended for developing purposes only. The duration can be set as number followed by a unit identifier like s, m or h. If a duration is set but the keyword 'delay' is not explicitely added to 'POSTPROCESSING_STEPS', the delay step will be processed as last step. In such a case, a log entry will be written on service startup to remind the admin about that situation."`
+	StepTimeout     time.Duration         `yaml:"step_timeout" env:"POSTPROCESSING_STEP_TIMEOUT" desc:"The time a postprocessing step may take before it is started again. 0 disables timeouts, postprocessing then waits forever for a step to finish. The duration can be set as number followed by a unit identifier like s, m or h. Can be overwritten per step in the 'step_configs' section of the yaml configuration."`
+	StepRetries     int                   `yaml:"step_retries" env:"POSTPROCESSING_STEP_RETRIES" desc:"How often a postprocessing step is started again after a timeout. When all retries timed out, postprocessing of the upload fails and the uploading user is notified. Can be overwritten per step in the 'step_configs' section of the yaml configuration."`
+	StepConfigs     map[string]StepConfig `yaml:"step_configs"`
+	SpaceSteps      bool                  `yaml:"space_steps" env:"POSTPROCESSING_SPACE_STEPS" desc:"Allow space managers to define the postprocessing steps of their spaces via the Graph API. The steps of a space take precedence over the steps configured for its space type and over POSTPROCESSING_STEPS."`
+	SpaceTypeSteps  map[string][]string   `yaml:"space_type_steps"`
+
+	AllowedSpaceSteps []string `yaml:"allowed_space_steps" env:"POSTPROCESSING_ALLOWED_SPACE_STEPS" desc:"A comma separated list of steps space managers may use in the postprocessing steps of their spaces in addition to the steps of POSTPROCESSING_STEPS and POSTPROCESSING_MANDATORY_STEPS. Other steps set by space managers are ignored."`
+	MandatorySteps    []string `yaml:"mandatory_steps" env:"POSTPROCESSING_MANDATORY_STEPS" desc:"A comma separated list of steps which always run for uploads to spaces whose postprocessing steps were set by a space manager. Mandatory steps missing in the steps of a space are run before them."`
+}
+
+// StepConfig overwrites the timeout settings for a single postprocessing step. A zero timeout and unset retries
+// fall back to the global settings.
+type StepConfig struct {
+	Timeout time.Duration `yaml:"timeout"`
+	Retries *int          `yaml:"retries"`
 }
 
 // Events combines the configuration options for the event bus.
@@ -7,8 +7,10 @@ import (
 
 	"github.com/cs3org/reva/v2/pkg/events"
 	ociscfg "github.com/owncloud/ocis/v2/ocis-pkg/config"
+	"github.com/owncloud/ocis/v2/ocis-pkg/shared"
 	"github.com/owncloud/ocis/v2/services/postprocessing/pkg/config"
 	"github.com/owncloud/ocis/v2/services/postprocessing/pkg/config/defaults"
+	"github.com/owncloud/ocis/v2/services/postprocessing/pkg/postprocessing"
 
 	"github.com/owncloud/ocis/v2/ocis-pkg/config/envdecode"
 )
@@ -54,13 +56,19 @@ func Validate(cfg *config.Config) error {
 			cfg.Postprocessing.Steps = append(cfg.Postprocessing.Steps, string(events.PPStepDelay))
 		}
 	}
+
+	if (cfg.Postprocessing.SpaceSteps || len(cfg.Postprocessing.SpaceTypeSteps) > 0) && cfg.MachineAuthAPIKey == "" {
+		return shared.MissingMachineAuthApiKeyError(cfg.Service.Name)
+	}
 	return nil
 }
 
 func contains(all []string, candidate events.Postprocessingstep) bool {
 	for _, s := range all {
-		if s == string(candidate) {
-			return true
+		for _, step := range postprocessing.GroupSteps(events.Postprocessingstep(s)) {
+			if step == candidate {
+				return true
+			}
 		}
 	}
 	return false
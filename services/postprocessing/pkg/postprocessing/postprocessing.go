@@ -1,6 +1,7 @@
 package postprocessing
 
 import (
+	"strings"
 	"time"
 
 	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
@@ -8,6 +9,10 @@ import (
 	"github.com/cs3org/reva/v2/pkg/events"
 )
 
+// StepGroupSeparator separates the steps of a step group. The steps of a group, e.g. 'virusscan+classification',
+// are started at the same time. Postprocessing continues when all of them finished.
+const StepGroupSeparator = "+"
+
 // Postprocessing handles postprocessing of a file
 type Postprocessing struct {
 	ID         string
@@ -19,12 +24,19 @@ type Postprocessing struct {
 	Steps      []events.Postprocessingstep
 	Status     Status
 	PPDelay    time.Duration
+	Created    time.Time
 }
 
 // Status is helper struct to show current postprocessing status
 type Status struct {
 	CurrentStep events.Postprocessingstep
 	Outcome     events.PostprocessingOutcome
+	StepStarted time.Time // when the current step was started the last time
+	Retries     int       // how often the current step was restarted after a timeout
+	Failed      bool      // the current step timed out too often, the upload is dead-lettered
+
+	Finished    []events.Postprocessingstep  // steps of the current step group which finished already
+	StepOutcome events.PostprocessingOutcome // merged outcome of the finished steps of the current step group
 }
 
 // New returns a new postprocessing instance
@@ -38,6 +50,7 @@ func New(uploadID string, uploadURL string, user *user.User, filename string, fi
 		ResourceID: resourceID,
 		Steps:      steps,
 		PPDelay:    delay,
+		Created:    time.Now(),
 	}
 }
 
@@ -50,14 +63,26 @@ func (pp *Postprocessing) Init(ev events.BytesReceived) interface{} {
 	return pp.step(pp.Steps[0])
 }
 
-// NextStep returns the next postprocessing step
+// NextStep returns the next postprocessing step. When the current step is a step group, the outcomes
+// of its steps are merged and nil is returned until all of them finished.
 func (pp *Postprocessing) NextStep(ev events.PostprocessingStepFinished) interface{} {
-	switch ev.Outcome {
+	if pp.Status.Outcome != "" || !pp.running(ev.FinishedStep) {
+		// a late or duplicate event, e.g. from a step which was restarted after a timeout
+		return nil
+	}
+
+	pp.Status.Finished = append(pp.Status.Finished, ev.FinishedStep)
+	pp.Status.StepOutcome = mergeOutcomes(pp.Status.StepOutcome, ev.Outcome)
+	if len(pp.Status.Finished) < len(GroupSteps(pp.Status.CurrentStep)) {
+		// wait for the other steps of the group
+		return nil
+	}
+
+	switch pp.Status.StepOutcome {
 	case events.PPOutcomeContinue:
-		return pp.next(ev.FinishedStep)
+		return pp.next(pp.Status.CurrentStep)
 	default:
-		return pp.finished(ev.Outcome)
-
+		return pp.finished(pp.Status.StepOutcome)
 	}
 }
 
@@ -69,10 +94,59 @@ func (pp *Postprocessing) CurrentStep() interface{} {
 	return pp.step(pp.Status.CurrentStep)
 }
 
-// Delay will sleep the configured time then continue
+// Resume continues the postprocessing. A dead-lettered upload restarts the step which failed,
+// a step group only the steps which did not finish yet.
+func (pp *Postprocessing) Resume() interface{} {
+	if pp.Status.Failed {
+		pp.Status.Failed = false
+		pp.Status.Outcome = ""
+		pp.Status.Retries = 0
+		return pp.step(pp.Status.CurrentStep)
+	}
+	return pp.CurrentStep()
+}
+
+// Retry restarts the current step after it timed out
+func (pp *Postprocessing) Retry() interface{} {
+	pp.Status.Retries++
+	return pp.step(pp.Status.CurrentStep)
+}
+
+// Fail aborts the postprocessing after the current step timed out too often
+func (pp *Postprocessing) Fail() interface{} {
+	pp.Status.Failed = true
+	return pp.finished(events.PPOutcomeAbort)
+}
+
+// Force finishes the postprocessing with the given outcome
+func (pp *Postprocessing) Force(outcome events.PostprocessingOutcome) interface{} {
+	return pp.finished(outcome)
+}
+
+// TimedOut checks if the current step runs longer than the given timeout
+func (pp *Postprocessing) TimedOut(timeout time.Duration) bool {
+	if timeout <= 0 || pp.Status.Outcome != "" || pp.Status.StepStarted.IsZero() {
+		return false
+	}
+	return time.Since(pp.Status.StepStarted) > timeout
+}
+
+// Delay finishes the delay step and continues. The caller is responsible for waiting PPDelay before.
 func (pp *Postprocessing) Delay(ev events.StartPostprocessingStep) interface{} {
-	time.Sleep(pp.PPDelay)
-	return pp.next(events.PPStepDelay)
+	return pp.NextStep(events.PostprocessingStepFinished{
+		UploadID:     pp.ID,
+		FinishedStep: events.PPStepDelay,
+		Outcome:      events.PPOutcomeContinue,
+	})
+}
+
+// GroupSteps returns the steps of a step group. A single step is returned as group of one.
+func GroupSteps(step events.Postprocessingstep) []events.Postprocessingstep {
+	var steps []events.Postprocessingstep
+	for _, s := range strings.Split(string(step), StepGroupSeparator) {
+		steps = append(steps, events.Postprocessingstep(strings.TrimSpace(s)))
+	}
+	return steps
 }
 
 func (pp *Postprocessing) next(current events.Postprocessingstep) interface{} {
@@ -85,8 +159,32 @@ func (pp *Postprocessing) next(current events.Postprocessingstep) interface{} {
 	return pp.finished(events.PPOutcomeContinue)
 }
 
-func (pp *Postprocessing) step(next events.Postprocessingstep) events.StartPostprocessingStep {
+// step starts a step or all steps of a step group which did not finish yet. A single step returns
+// an events.StartPostprocessingStep, a step group a []events.StartPostprocessingStep.
+func (pp *Postprocessing) step(next events.Postprocessingstep) interface{} {
+	if pp.Status.CurrentStep != next {
+		pp.Status.Retries = 0
+		pp.Status.Finished = nil
+		pp.Status.StepOutcome = ""
+	}
 	pp.Status.CurrentStep = next
+	pp.Status.StepStarted = time.Now()
+
+	steps := GroupSteps(next)
+	if len(steps) == 1 {
+		return pp.start(next)
+	}
+
+	evs := make([]events.StartPostprocessingStep, 0, len(steps))
+	for _, s := range steps {
+		if pp.running(s) {
+			evs = append(evs, pp.start(s))
+		}
+	}
+	return evs
+}
+
+func (pp *Postprocessing) start(step events.Postprocessingstep) events.StartPostprocessingStep {
 	return events.StartPostprocessingStep{
 		UploadID:      pp.ID,
 		URL:           pp.URL,
@@ -94,8 +192,42 @@ func (pp *Postprocessing) step(next events.Postprocessingstep) events.StartPostp
 		Filename:      pp.Filename,
 		Filesize:      pp.Filesize,
 		ResourceID:    pp.ResourceID,
-		StepToStart:   next,
+		StepToStart:   step,
+	}
+}
+
+// running checks if the step belongs to the current step group and did not finish yet
+func (pp *Postprocessing) running(step events.Postprocessingstep) bool {
+	for _, s := range pp.Status.Finished {
+		if s == step {
+			return false
+		}
+	}
+	for _, s := range GroupSteps(pp.Status.CurrentStep) {
+		if s == step {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeOutcomes returns the stronger of two outcomes: delete wins over abort, abort wins over continue
+func mergeOutcomes(a, b events.PostprocessingOutcome) events.PostprocessingOutcome {
+	rank := func(o events.PostprocessingOutcome) int {
+		switch o {
+		case events.PPOutcomeDelete:
+			return 2
+		case events.PPOutcomeAbort:
+			return 1
+		default:
+			return 0
+		}
+	}
+
+	if a == "" || rank(b) > rank(a) {
+		return b
 	}
+	return a
 }
 
 func (pp *Postprocessing) finished(outcome events.PostprocessingOutcome) events.PostprocessingFinished {
@@ -0,0 +1,159 @@
+package postprocessing
+
+import (
+	"testing"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/v2/pkg/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPP(steps ...events.Postprocessingstep) *Postprocessing {
+	return New("upload", "url", &user.User{Id: &user.UserId{OpaqueId: "user"}}, "file.txt", 10, nil, steps, 0)
+}
+
+func finished(step events.Postprocessingstep, outcome events.PostprocessingOutcome) events.PostprocessingStepFinished {
+	return events.PostprocessingStepFinished{UploadID: "upload", FinishedStep: step, Outcome: outcome}
+}
+
+func startedSteps(t *testing.T, next interface{}) []events.Postprocessingstep {
+	var steps []events.Postprocessingstep
+	switch ev := next.(type) {
+	case events.StartPostprocessingStep:
+		steps = append(steps, ev.StepToStart)
+	case []events.StartPostprocessingStep:
+		for _, e := range ev {
+			steps = append(steps, e.StepToStart)
+		}
+	default:
+		t.Fatalf("expected started steps, got %T", next)
+	}
+	return steps
+}
+
+func TestGroupSteps(t *testing.T) {
+	tests := []struct {
+		step events.Postprocessingstep
+		want []events.Postprocessingstep
+	}{
+		{step: "virusscan", want: []events.Postprocessingstep{"virusscan"}},
+		{step: "virusscan+policies", want: []events.Postprocessingstep{"virusscan", "policies"}},
+		{step: " virusscan + policies ", want: []events.Postprocessingstep{"virusscan", "policies"}},
+		{step: "a+b+c", want: []events.Postprocessingstep{"a", "b", "c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.step), func(t *testing.T) {
+			assert.Equal(t, tt.want, GroupSteps(tt.step))
+		})
+	}
+}
+
+func TestMergeOutcomes(t *testing.T) {
+	tests := []struct {
+		a, b events.PostprocessingOutcome
+		want events.PostprocessingOutcome
+	}{
+		{a: "", b: events.PPOutcomeContinue, want: events.PPOutcomeContinue},
+		{a: "", b: events.PPOutcomeAbort, want: events.PPOutcomeAbort},
+		{a: events.PPOutcomeContinue, b: events.PPOutcomeContinue, want: events.PPOutcomeContinue},
+		{a: events.PPOutcomeContinue, b: events.PPOutcomeAbort, want: events.PPOutcomeAbort},
+		{a: events.PPOutcomeAbort, b: events.PPOutcomeContinue, want: events.PPOutcomeAbort},
+		{a: events.PPOutcomeAbort, b: events.PPOutcomeDelete, want: events.PPOutcomeDelete},
+		{a: events.PPOutcomeDelete, b: events.PPOutcomeAbort, want: events.PPOutcomeDelete},
+		{a: events.PPOutcomeDelete, b: events.PPOutcomeContinue, want: events.PPOutcomeDelete},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.a)+"/"+string(tt.b), func(t *testing.T) {
+			assert.Equal(t, tt.want, mergeOutcomes(tt.a, tt.b))
+		})
+	}
+}
+
+func TestStepGroup(t *testing.T) {
+	pp := newPP("virusscan+policies+custom", "next")
+	assert.Equal(t, []events.Postprocessingstep{"virusscan", "policies", "custom"}, startedSteps(t, pp.Init(events.BytesReceived{})))
+
+	// partial completion waits for the other steps
+	assert.Nil(t, pp.NextStep(finished("policies", events.PPOutcomeContinue)))
+	assert.Equal(t, []events.Postprocessingstep{"policies"}, pp.Status.Finished)
+
+	// a restart only starts the steps which did not finish yet
+	assert.Equal(t, []events.Postprocessingstep{"virusscan", "custom"}, startedSteps(t, pp.Retry()))
+
+	assert.Nil(t, pp.NextStep(finished("custom", events.PPOutcomeContinue)))
+	assert.Equal(t, []events.Postprocessingstep{"next"}, startedSteps(t, pp.NextStep(finished("virusscan", events.PPOutcomeContinue))))
+	assert.Empty(t, pp.Status.Finished, "the next step starts with a clean state")
+	assert.Equal(t, 0, pp.Status.Retries)
+}
+
+func TestStepGroupOutcomes(t *testing.T) {
+	tests := []struct {
+		name     string
+		outcomes []events.PostprocessingOutcome
+		want     events.PostprocessingOutcome
+	}{
+		{name: "all continue", outcomes: []events.PostprocessingOutcome{events.PPOutcomeContinue, events.PPOutcomeContinue, events.PPOutcomeContinue}},
+		{name: "abort wins over continue", outcomes: []events.PostprocessingOutcome{events.PPOutcomeContinue, events.PPOutcomeAbort, events.PPOutcomeContinue}, want: events.PPOutcomeAbort},
+		{name: "delete wins over abort", outcomes: []events.PostprocessingOutcome{events.PPOutcomeDelete, events.PPOutcomeAbort, events.PPOutcomeContinue}, want: events.PPOutcomeDelete},
+		{name: "delete wins when finishing last", outcomes: []events.PostprocessingOutcome{events.PPOutcomeAbort, events.PPOutcomeContinue, events.PPOutcomeDelete}, want: events.PPOutcomeDelete},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pp := newPP("a+b+c", "next")
+			pp.Init(events.BytesReceived{})
+
+			var next interface{}
+			for i, s := range GroupSteps("a+b+c") {
+				next = pp.NextStep(finished(s, tt.outcomes[i]))
+				if i < 2 {
+					require.Nil(t, next, "the group did not finish yet")
+				}
+			}
+
+			if tt.want == "" {
+				assert.Equal(t, []events.Postprocessingstep{"next"}, startedSteps(t, next))
+				return
+			}
+			ev, ok := next.(events.PostprocessingFinished)
+			require.True(t, ok, "postprocessing finishes without starting the next step")
+			assert.Equal(t, tt.want, ev.Outcome)
+			assert.Equal(t, tt.want, pp.Status.Outcome)
+		})
+	}
+}
+
+func TestDuplicateFinishEvents(t *testing.T) {
+	pp := newPP("a+b", "next")
+	pp.Init(events.BytesReceived{})
+
+	require.Nil(t, pp.NextStep(finished("a", events.PPOutcomeContinue)))
+	// a duplicate event of a finished step neither counts nor changes the outcome
+	assert.Nil(t, pp.NextStep(finished("a", events.PPOutcomeDelete)))
+	assert.Equal(t, []events.Postprocessingstep{"a"}, pp.Status.Finished)
+	assert.Equal(t, events.PPOutcomeContinue, pp.Status.StepOutcome)
+
+	// events of steps outside the current group are ignored
+	assert.Nil(t, pp.NextStep(finished("next", events.PPOutcomeContinue)))
+
+	assert.Equal(t, []events.Postprocessingstep{"next"}, startedSteps(t, pp.NextStep(finished("b", events.PPOutcomeContinue))))
+	// a late event of the previous group
+	assert.Nil(t, pp.NextStep(finished("b", events.PPOutcomeContinue)))
+
+	ev, ok := pp.NextStep(finished("next", events.PPOutcomeContinue)).(events.PostprocessingFinished)
+	require.True(t, ok)
+	assert.Equal(t, events.PPOutcomeContinue, ev.Outcome)
+
+	// nothing happens after postprocessing finished
+	assert.Nil(t, pp.NextStep(finished("next", events.PPOutcomeDelete)))
+	assert.Equal(t, events.PPOutcomeContinue, pp.Status.Outcome)
+}
+
+func TestNoSteps(t *testing.T) {
+	ev, ok := newPP().Init(events.BytesReceived{}).(events.PostprocessingFinished)
+	require.True(t, ok)
+	assert.Equal(t, events.PPOutcomeContinue, ev.Outcome)
+}
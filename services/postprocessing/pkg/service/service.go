@@ -3,8 +3,13 @@ package service
 import (
 	"encoding/json"
 	"fmt"
+	"sync"
+	"time"
 
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
 	"github.com/cs3org/reva/v2/pkg/events"
+	"github.com/cs3org/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/owncloud/ocis/v2/ocis-pkg/event"
 	"github.com/owncloud/ocis/v2/ocis-pkg/log"
 	"github.com/owncloud/ocis/v2/services/postprocessing/pkg/config"
 	"github.com/owncloud/ocis/v2/services/postprocessing/pkg/postprocessing"
@@ -19,16 +24,22 @@ type PostprocessingService struct {
 	steps  []events.Postprocessingstep
 	store  store.Store
 	c      config.Postprocessing
+	mu     sync.Mutex // serializes the changes of the events and the timeout checks
+
+	gatewaySelector   pool.Selectable[gateway.GatewayAPIClient]
+	machineAuthAPIKey string
 }
 
-// NewPostprocessingService returns a new instance of a postprocessing service
-func NewPostprocessingService(stream events.Stream, logger log.Logger, sto store.Store, c config.Postprocessing) (*PostprocessingService, error) {
+// NewPostprocessingService returns a new instance of a postprocessing service. The gateway selector
+// is only needed when postprocessing steps are configured per space or per space type.
+func NewPostprocessingService(stream events.Stream, logger log.Logger, sto store.Store, gatewaySelector pool.Selectable[gateway.GatewayAPIClient], machineAuthAPIKey string, c config.Postprocessing) (*PostprocessingService, error) {
 	evs, err := events.Consume(stream, "postprocessing",
 		events.BytesReceived{},
 		events.StartPostprocessingStep{},
 		events.UploadReady{},
 		events.PostprocessingStepFinished{},
 		events.ResumePostprocessing{},
+		event.ForcePostprocessingOutcome{},
 	)
 	if err != nil {
 		return nil, err
@@ -41,87 +52,335 @@ func NewPostprocessingService(stream events.Stream, logger log.Logger, sto store
 		steps:  getSteps(c),
 		store:  sto,
 		c:      c,
+
+		gatewaySelector:   gatewaySelector,
+		machineAuthAPIKey: machineAuthAPIKey,
 	}, nil
 }
 
 // Run to fulfil Runner interface
 func (pps *PostprocessingService) Run() error {
-	for e := range pps.events {
-		var (
-			next interface{}
-			pp   *postprocessing.Postprocessing
-			err  error
-		)
-
-		switch ev := e.Event.(type) {
-		case events.BytesReceived:
-			pp = postprocessing.New(ev.UploadID, ev.URL, ev.ExecutingUser, ev.Filename, ev.Filesize, ev.ResourceID, pps.steps, pps.c.Delayprocessing)
-			next = pp.Init(ev)
-		case events.PostprocessingStepFinished:
-			if ev.UploadID == "" {
-				// no current upload - this was an on demand scan
-				continue
-			}
-			pp, err = getPP(pps.store, ev.UploadID)
-			if err != nil {
-				pps.log.Error().Str("uploadID", ev.UploadID).Err(err).Msg("cannot get upload")
-				continue
-			}
-			next = pp.NextStep(ev)
-		case events.StartPostprocessingStep:
-			if ev.StepToStart != events.PPStepDelay {
-				continue
-			}
-			pp, err = getPP(pps.store, ev.UploadID)
-			if err != nil {
-				pps.log.Error().Str("uploadID", ev.UploadID).Err(err).Msg("cannot get upload")
-				continue
-			}
-			next = pp.Delay(ev)
-		case events.UploadReady:
-			// the storage provider thinks the upload is done - so no need to keep it any more
-			if err := pps.store.Delete(ev.UploadID); err != nil {
-				pps.log.Error().Str("uploadID", ev.UploadID).Err(err).Msg("cannot delete upload")
-				continue
+	// timeouts are checked in the background, reading the whole store must not block the events
+	errs := make(chan error, 1)
+	if interval := pps.timeoutCheckInterval(); interval > 0 {
+		done := make(chan struct{})
+		defer close(done)
+		go pps.checkTimeouts(interval, done, errs)
+	}
+
+	for {
+		select {
+		case err := <-errs:
+			return err
+		case e, ok := <-pps.events:
+			if !ok {
+				return nil
 			}
-		case events.ResumePostprocessing:
-			pp, err = getPP(pps.store, ev.UploadID)
-			if err != nil {
-				pps.log.Error().Str("uploadID", ev.UploadID).Err(err).Msg("cannot get upload")
-				continue
+			if err := pps.handleEvent(e); err != nil {
+				return err
 			}
-			next = pp.CurrentStep()
 		}
+	}
+}
 
-		if pp != nil {
-			if err := storePP(pps.store, pp); err != nil {
-				pps.log.Error().Str("uploadID", pp.ID).Err(err).Msg("cannot store upload")
-				continue // TODO: should we really continue here?
-			}
+// handleEvent processes a single event. It only returns an error when publishing fails.
+func (pps *PostprocessingService) handleEvent(e events.Event) error {
+	if ev, ok := e.Event.(events.StartPostprocessingStep); ok && ev.StepToStart == events.PPStepDelay {
+		// waiting must not block the timeout checks
+		pps.delay(ev.UploadID)
+	}
+
+	// the state of an upload is read, changed and written by the events and the timeout checks
+	pps.mu.Lock()
+	defer pps.mu.Unlock()
+
+	var (
+		next interface{}
+		pp   *postprocessing.Postprocessing
+		err  error
+	)
+
+	switch ev := e.Event.(type) {
+	case events.BytesReceived:
+		pp = postprocessing.New(ev.UploadID, ev.URL, ev.ExecutingUser, ev.Filename, ev.Filesize, ev.ResourceID, pps.stepsFor(ev), pps.c.Delayprocessing)
+		next = pp.Init(ev)
+	case events.PostprocessingStepFinished:
+		if ev.UploadID == "" {
+			// no current upload - this was an on demand scan
+			return nil
+		}
+		pp, err = getPP(pps.store, ev.UploadID)
+		if err != nil {
+			pps.log.Error().Str("uploadID", ev.UploadID).Err(err).Msg("cannot get upload")
+			return nil
+		}
+		next = pp.NextStep(ev)
+	case events.StartPostprocessingStep:
+		if ev.StepToStart != events.PPStepDelay {
+			return nil
+		}
+		pp, err = getPP(pps.store, ev.UploadID)
+		if err != nil {
+			pps.log.Error().Str("uploadID", ev.UploadID).Err(err).Msg("cannot get upload")
+			return nil
+		}
+		next = pp.Delay(ev)
+	case events.UploadReady:
+		if pp, err := getPP(pps.store, ev.UploadID); err == nil && pp.Status.Failed {
+			// keep dead-lettered uploads for admin inspection
+			return nil
 		}
-		if next != nil {
-			if err := events.Publish(pps.pub, next); err != nil {
-				pps.log.Error().Err(err).Msg("unable to publish event")
-				return err // we can't publish -> we are screwed
+		// the storage provider thinks the upload is done - so no need to keep it any more
+		if err := pps.store.Delete(ev.UploadID); err != nil {
+			pps.log.Error().Str("uploadID", ev.UploadID).Err(err).Msg("cannot delete upload")
+			return nil
+		}
+	case events.ResumePostprocessing:
+		pp, err = getPP(pps.store, ev.UploadID)
+		if err != nil {
+			pps.log.Error().Str("uploadID", ev.UploadID).Err(err).Msg("cannot get upload")
+			return nil
+		}
+		next = pp.Resume()
+	case event.ForcePostprocessingOutcome:
+		pp, err = getPP(pps.store, ev.UploadID)
+		if err != nil {
+			pps.log.Error().Str("uploadID", ev.UploadID).Err(err).Msg("cannot get upload")
+			return nil
+		}
+		if pp.Status.Outcome != "" {
+			pps.log.Info().Str("uploadID", ev.UploadID).Str("outcome", string(pp.Status.Outcome)).Msg("postprocessing finished already")
+			return nil
+		}
+		next = pp.Force(ev.Outcome)
+	}
+
+	if pp != nil {
+		if err := storePP(pps.store, pp); err != nil {
+			pps.log.Error().Str("uploadID", pp.ID).Err(err).Msg("cannot store upload")
+			return nil // TODO: should we really continue here?
+		}
+	}
+	if next != nil {
+		if err := pps.publish(next); err != nil {
+			pps.log.Error().Err(err).Msg("unable to publish event")
+			return err // we can't publish -> we are screwed
+		}
+	}
+	return nil
+}
+
+// delay waits the configured postprocessing delay of an upload
+func (pps *PostprocessingService) delay(uploadID string) {
+	pp, err := getPP(pps.store, uploadID)
+	if err != nil {
+		// logged when the event is handled
+		return
+	}
+	time.Sleep(pp.PPDelay)
+}
+
+// checkTimeouts handles the timeouts of running steps in the given interval until done is closed.
+// A publishing error is sent to errs.
+func (pps *PostprocessingService) checkTimeouts(interval time.Duration, done <-chan struct{}, errs chan<- error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := pps.handleTimeouts(); err != nil {
+				errs <- err
+				return
 			}
 		}
+	}
+}
+
+// publish publishes an event, the steps of a step group are published one by one
+func (pps *PostprocessingService) publish(ev interface{}) error {
+	evs, ok := ev.([]events.StartPostprocessingStep)
+	if !ok {
+		return events.Publish(pps.pub, ev)
+	}
+
+	for _, e := range evs {
+		if err := events.Publish(pps.pub, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleTimeouts restarts steps which are running longer than their timeout. Uploads are
+// dead-lettered when all retries of a step timed out. The checks are not coordinated between
+// several instances of the service, each of them may restart the same timed out step.
+func (pps *PostprocessingService) handleTimeouts() error {
+	uploads, err := ListPostprocessings(pps.store)
+	if err != nil {
+		pps.log.Error().Err(err).Msg("cannot list uploads")
+		return nil
+	}
+
+	for _, pp := range uploads {
+		timeout, _ := pps.stepTimeout(pp.Status.CurrentStep)
+		if !pp.TimedOut(timeout) {
+			continue
+		}
 
+		if err := pps.handleTimeout(pp.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleTimeout restarts or fails the current step of an upload if it is still timed out
+func (pps *PostprocessingService) handleTimeout(uploadID string) error {
+	pps.mu.Lock()
+	defer pps.mu.Unlock()
+
+	// the step might have finished since the store was listed
+	pp, err := getPP(pps.store, uploadID)
+	if err != nil {
+		return nil
+	}
+	timeout, retries := pps.stepTimeout(pp.Status.CurrentStep)
+	if !pp.TimedOut(timeout) {
+		return nil
+	}
+
+	var next interface{}
+	if pp.Status.Retries < retries {
+		pps.log.Info().Str("uploadID", pp.ID).Str("step", string(pp.Status.CurrentStep)).Int("retry", pp.Status.Retries+1).Msg("postprocessing step timed out, restarting it")
+		next = pp.Retry()
+	} else {
+		pps.log.Error().Str("uploadID", pp.ID).Str("step", string(pp.Status.CurrentStep)).Int("retries", pp.Status.Retries).Msg("postprocessing step timed out too often, aborting postprocessing")
+		next = pp.Fail()
+	}
+
+	if err := storePP(pps.store, pp); err != nil {
+		pps.log.Error().Str("uploadID", pp.ID).Err(err).Msg("cannot store upload")
+		return nil
+	}
+
+	if err := pps.publish(next); err != nil {
+		pps.log.Error().Err(err).Msg("unable to publish event")
+		return err
+	}
+
+	if !pp.Status.Failed {
+		return nil
+	}
+	if err := events.Publish(pps.pub, event.PostprocessingFailed{
+		UploadID:      pp.ID,
+		ExecutingUser: pp.User,
+		Filename:      pp.Filename,
+		ResourceID:    pp.ResourceID,
+		FailedStep:    pp.Status.CurrentStep,
+		Retries:       pp.Status.Retries,
+		Timestamp:     time.Now(),
+	}); err != nil {
+		pps.log.Error().Err(err).Msg("unable to publish event")
+		return err
 	}
 	return nil
 }
 
+// stepTimeout returns the timeout and the number of retries for a step. Step groups use
+// the longest timeout and the most retries of their steps.
+func (pps *PostprocessingService) stepTimeout(step events.Postprocessingstep) (time.Duration, int) {
+	var timeout time.Duration
+	var retries int
+	for _, s := range postprocessing.GroupSteps(step) {
+		t, r := pps.singleStepTimeout(s)
+		if t > timeout {
+			timeout = t
+		}
+		if r > retries {
+			retries = r
+		}
+	}
+	return timeout, retries
+}
+
+func (pps *PostprocessingService) singleStepTimeout(step events.Postprocessingstep) (time.Duration, int) {
+	if step == events.PPStepDelay {
+		// the delay step is executed by this service
+		return 0, 0
+	}
+
+	timeout, retries := pps.c.StepTimeout, pps.c.StepRetries
+	if sc, ok := pps.c.StepConfigs[string(step)]; ok {
+		if sc.Timeout > 0 {
+			timeout = sc.Timeout
+		}
+		if sc.Retries != nil {
+			retries = *sc.Retries
+		}
+	}
+	return timeout, retries
+}
+
+// timeoutCheckInterval returns how often running steps are checked for timeouts, 0 if no timeouts are configured
+func (pps *PostprocessingService) timeoutCheckInterval() time.Duration {
+	shortest := pps.c.StepTimeout
+	for _, sc := range pps.c.StepConfigs {
+		if sc.Timeout > 0 && (shortest == 0 || sc.Timeout < shortest) {
+			shortest = sc.Timeout
+		}
+	}
+
+	if shortest == 0 {
+		return 0
+	}
+
+	// check often enough to detect timeouts in time but don't read the store all the time
+	interval := shortest / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+	return interval
+}
+
 func getSteps(c config.Postprocessing) []events.Postprocessingstep {
-	// NOTE: improved version only allows configuring order of postprocessing steps
-	// But we aim for a system where postprocessing steps can be configured per space, ideally by the spaceadmin itself
-	// We need to iterate over configuring PP service when we see fit
-	var steps []events.Postprocessingstep
-	for _, s := range c.Steps {
+	return toSteps(c.Steps)
+}
+
+func toSteps(names []string) []events.Postprocessingstep {
+	steps := make([]events.Postprocessingstep, 0, len(names))
+	for _, s := range names {
 		steps = append(steps, events.Postprocessingstep(s))
 	}
 
 	return steps
 }
 
+// ListPostprocessings returns the postprocessing state of all uploads in the store
+func ListPostprocessings(sto store.Store) ([]*postprocessing.Postprocessing, error) {
+	keys, err := sto.List()
+	if err != nil {
+		return nil, err
+	}
+
+	pps := make([]*postprocessing.Postprocessing, 0, len(keys))
+	for _, key := range keys {
+		pp, err := getPP(sto, key)
+		if err != nil {
+			// the upload might have been finished in the meantime
+			continue
+		}
+		pps = append(pps, pp)
+	}
+	return pps, nil
+}
+
+// GetPostprocessing returns the postprocessing state of an upload
+func GetPostprocessing(sto store.Store, uploadID string) (*postprocessing.Postprocessing, error) {
+	return getPP(sto, uploadID)
+}
+
 func storePP(sto store.Store, pp *postprocessing.Postprocessing) error {
 	b, err := json.Marshal(pp)
 	if err != nil {
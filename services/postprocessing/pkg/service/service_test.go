@@ -0,0 +1,267 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/v2/pkg/events"
+	"github.com/owncloud/ocis/v2/ocis-pkg/event"
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/services/postprocessing/pkg/config"
+	"github.com/owncloud/ocis/v2/services/postprocessing/pkg/postprocessing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	microevents "go-micro.dev/v4/events"
+	"go-micro.dev/v4/store"
+)
+
+type publisher struct {
+	events []interface{}
+}
+
+func (p *publisher) Publish(_ string, ev interface{}, _ ...microevents.PublishOption) error {
+	p.events = append(p.events, ev)
+	return nil
+}
+
+func (p *publisher) take() []interface{} {
+	evs := p.events
+	p.events = nil
+	return evs
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func newTestService(c config.Postprocessing) (*PostprocessingService, *publisher) {
+	pub := &publisher{}
+	return &PostprocessingService{
+		log:   log.NopLogger(),
+		pub:   pub,
+		steps: getSteps(c),
+		store: store.NewMemoryStore(),
+		c:     c,
+	}, pub
+}
+
+func TestStepTimeout(t *testing.T) {
+	pps, _ := newTestService(config.Postprocessing{
+		StepTimeout: time.Minute,
+		StepRetries: 3,
+		StepConfigs: map[string]config.StepConfig{
+			"virusscan": {Timeout: time.Hour},
+			"policies":  {Retries: intPtr(0)},
+			"slow":      {Timeout: 2 * time.Hour, Retries: intPtr(1)},
+		},
+	})
+
+	tests := []struct {
+		step        events.Postprocessingstep
+		wantTimeout time.Duration
+		wantRetries int
+	}{
+		{step: "custom", wantTimeout: time.Minute, wantRetries: 3},
+		{step: "virusscan", wantTimeout: time.Hour, wantRetries: 3},
+		{step: "policies", wantTimeout: time.Minute, wantRetries: 0},
+		{step: "slow", wantTimeout: 2 * time.Hour, wantRetries: 1},
+		{step: events.PPStepDelay},
+		// groups use the longest timeout and the most retries
+		{step: "policies+slow", wantTimeout: 2 * time.Hour, wantRetries: 1},
+		{step: "virusscan+policies", wantTimeout: time.Hour, wantRetries: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.step), func(t *testing.T) {
+			timeout, retries := pps.stepTimeout(tt.step)
+			assert.Equal(t, tt.wantTimeout, timeout)
+			assert.Equal(t, tt.wantRetries, retries)
+		})
+	}
+}
+
+func TestTimeoutCheckInterval(t *testing.T) {
+	tests := map[string]struct {
+		c    config.Postprocessing
+		want time.Duration
+	}{
+		"no timeouts":     {},
+		"global timeout":  {c: config.Postprocessing{StepTimeout: time.Minute}, want: 30 * time.Second},
+		"step timeout":    {c: config.Postprocessing{StepConfigs: map[string]config.StepConfig{"a": {Timeout: time.Hour}}}, want: 30 * time.Minute},
+		"shortest wins":   {c: config.Postprocessing{StepTimeout: time.Hour, StepConfigs: map[string]config.StepConfig{"a": {Timeout: time.Minute}}}, want: 30 * time.Second},
+		"at least second": {c: config.Postprocessing{StepTimeout: time.Millisecond}, want: time.Second},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			pps, _ := newTestService(tt.c)
+			assert.Equal(t, tt.want, pps.timeoutCheckInterval())
+		})
+	}
+}
+
+// startUpload stores an upload whose current step was started at the given time
+func startUpload(t *testing.T, pps *PostprocessingService, id string, step events.Postprocessingstep, started time.Time) {
+	pp := postprocessing.New(id, "url", &user.User{Id: &user.UserId{OpaqueId: "user"}}, "file.txt", 10, nil, []events.Postprocessingstep{step}, 0)
+	pp.Init(events.BytesReceived{})
+	pp.Status.StepStarted = started
+	require.NoError(t, storePP(pps.store, pp))
+}
+
+func TestHandleTimeouts(t *testing.T) {
+	pps, pub := newTestService(config.Postprocessing{StepTimeout: time.Minute, StepRetries: 2})
+	startUpload(t, pps, "timed-out", "virusscan", time.Now().Add(-2*time.Minute))
+	startUpload(t, pps, "running", "virusscan", time.Now())
+
+	for retry := 1; retry <= 2; retry++ {
+		require.NoError(t, pps.handleTimeouts())
+
+		evs := pub.take()
+		require.Len(t, evs, 1, "only the timed out step is restarted")
+		ev, ok := evs[0].(events.StartPostprocessingStep)
+		require.True(t, ok)
+		assert.Equal(t, "timed-out", ev.UploadID)
+		assert.Equal(t, events.Postprocessingstep("virusscan"), ev.StepToStart)
+
+		pp, err := GetPostprocessing(pps.store, "timed-out")
+		require.NoError(t, err)
+		assert.Equal(t, retry, pp.Status.Retries)
+
+		// nothing happens until the restarted step times out again
+		require.NoError(t, pps.handleTimeouts())
+		assert.Empty(t, pub.take())
+
+		pp.Status.StepStarted = time.Now().Add(-2 * time.Minute)
+		require.NoError(t, storePP(pps.store, pp))
+	}
+
+	// all retries timed out
+	require.NoError(t, pps.handleTimeouts())
+	evs := pub.take()
+	require.Len(t, evs, 2)
+	finished, ok := evs[0].(events.PostprocessingFinished)
+	require.True(t, ok)
+	assert.Equal(t, events.PPOutcomeAbort, finished.Outcome)
+	failed, ok := evs[1].(event.PostprocessingFailed)
+	require.True(t, ok)
+	assert.Equal(t, "timed-out", failed.UploadID)
+	assert.Equal(t, events.Postprocessingstep("virusscan"), failed.FailedStep)
+	assert.Equal(t, 2, failed.Retries)
+
+	pp, err := GetPostprocessing(pps.store, "timed-out")
+	require.NoError(t, err)
+	assert.True(t, pp.Status.Failed, "the upload is dead-lettered")
+
+	// a failed upload doesn't time out again
+	require.NoError(t, pps.handleTimeouts())
+	assert.Empty(t, pub.take())
+}
+
+func TestHandleTimeoutsWithoutRetries(t *testing.T) {
+	pps, pub := newTestService(config.Postprocessing{
+		StepTimeout: time.Minute,
+		StepRetries: 3,
+		StepConfigs: map[string]config.StepConfig{"policies": {Retries: intPtr(0)}},
+	})
+	startUpload(t, pps, "upload", "policies", time.Now().Add(-2*time.Minute))
+
+	require.NoError(t, pps.handleTimeouts())
+	evs := pub.take()
+	require.Len(t, evs, 2, "a step without retries fails at the first timeout")
+	assert.IsType(t, events.PostprocessingFinished{}, evs[0])
+	assert.IsType(t, event.PostprocessingFailed{}, evs[1])
+}
+
+func TestHandleTimeoutOfFinishedStep(t *testing.T) {
+	pps, pub := newTestService(config.Postprocessing{StepTimeout: time.Minute, StepRetries: 3})
+	startUpload(t, pps, "upload", "virusscan", time.Now().Add(-2*time.Minute))
+
+	// the step finishes after the timeouts were listed but before the upload is handled
+	require.NoError(t, pps.handleEvent(events.Event{Event: events.PostprocessingStepFinished{
+		UploadID:     "upload",
+		FinishedStep: "virusscan",
+		Outcome:      events.PPOutcomeContinue,
+	}}))
+	pub.take()
+
+	require.NoError(t, pps.handleTimeout("upload"))
+	assert.Empty(t, pub.take(), "a finished step is not restarted")
+}
+
+func TestLateStepFinishedAfterRetry(t *testing.T) {
+	pps, pub := newTestService(config.Postprocessing{StepTimeout: time.Minute, StepRetries: 3})
+	startUpload(t, pps, "upload", "virusscan", time.Now().Add(-2*time.Minute))
+
+	require.NoError(t, pps.handleTimeouts())
+	require.Len(t, pub.take(), 1)
+
+	finished := events.Event{Event: events.PostprocessingStepFinished{
+		UploadID:     "upload",
+		FinishedStep: "virusscan",
+		Outcome:      events.PPOutcomeContinue,
+	}}
+	require.NoError(t, pps.handleEvent(finished))
+	evs := pub.take()
+	require.Len(t, evs, 1)
+	assert.IsType(t, events.PostprocessingFinished{}, evs[0])
+
+	// the event of the first run arrives late
+	require.NoError(t, pps.handleEvent(finished))
+	assert.Empty(t, pub.take(), "duplicate finish events are ignored")
+}
+
+func TestDelayDoesNotBlockTimeoutChecks(t *testing.T) {
+	pps, pub := newTestService(config.Postprocessing{StepTimeout: time.Minute})
+	pp := postprocessing.New("upload", "url", &user.User{Id: &user.UserId{OpaqueId: "user"}}, "file.txt", 10, nil, []events.Postprocessingstep{events.PPStepDelay}, 200*time.Millisecond)
+	pp.Init(events.BytesReceived{})
+	require.NoError(t, storePP(pps.store, pp))
+
+	done := make(chan error)
+	go func() {
+		done <- pps.handleEvent(events.Event{Event: events.StartPostprocessingStep{UploadID: "upload", StepToStart: events.PPStepDelay}})
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	require.True(t, pps.mu.TryLock(), "the timeout checks can run while waiting")
+	pps.mu.Unlock()
+
+	require.NoError(t, <-done)
+	evs := pub.take()
+	require.Len(t, evs, 1)
+	finished, ok := evs[0].(events.PostprocessingFinished)
+	require.True(t, ok, "the delay is finished after waiting")
+	assert.Equal(t, events.PPOutcomeContinue, finished.Outcome)
+}
+
+func TestResumeFailedUpload(t *testing.T) {
+	pps, pub := newTestService(config.Postprocessing{StepTimeout: time.Minute})
+	startUpload(t, pps, "upload", "virusscan", time.Now().Add(-2*time.Minute))
+
+	require.NoError(t, pps.handleTimeouts())
+	require.Len(t, pub.take(), 2)
+
+	require.NoError(t, pps.handleEvent(events.Event{Event: events.ResumePostprocessing{UploadID: "upload"}}))
+	evs := pub.take()
+	require.Len(t, evs, 1)
+	ev, ok := evs[0].(events.StartPostprocessingStep)
+	require.True(t, ok, "the failed step is started again")
+	assert.Equal(t, events.Postprocessingstep("virusscan"), ev.StepToStart)
+
+	pp, err := GetPostprocessing(pps.store, "upload")
+	require.NoError(t, err)
+	assert.False(t, pp.Status.Failed)
+	assert.Empty(t, pp.Status.Outcome)
+	assert.Equal(t, 0, pp.Status.Retries)
+
+	require.NoError(t, pps.handleEvent(events.Event{Event: events.PostprocessingStepFinished{
+		UploadID:     "upload",
+		FinishedStep: "virusscan",
+		Outcome:      events.PPOutcomeContinue,
+	}}))
+	evs = pub.take()
+	require.Len(t, evs, 1)
+	finished, ok := evs[0].(events.PostprocessingFinished)
+	require.True(t, ok)
+	assert.Equal(t, events.PPOutcomeContinue, finished.Outcome)
+}
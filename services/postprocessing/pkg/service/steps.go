@@ -0,0 +1,148 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/v2/pkg/errtypes"
+	"github.com/cs3org/reva/v2/pkg/events"
+	"github.com/cs3org/reva/v2/pkg/storagespace"
+	"github.com/cs3org/reva/v2/pkg/utils"
+	"github.com/owncloud/ocis/v2/ocis-pkg/event"
+	"github.com/owncloud/ocis/v2/services/postprocessing/pkg/postprocessing"
+)
+
+// stepsFor returns the postprocessing steps of an upload. Steps configured on the space take precedence
+// over the steps configured for the space type, which take precedence over the global steps.
+// The global steps are used when the space can't be looked up.
+func (pps *PostprocessingService) stepsFor(ev events.BytesReceived) []events.Postprocessingstep {
+	if !pps.c.SpaceSteps && len(pps.c.SpaceTypeSteps) == 0 {
+		return pps.steps
+	}
+
+	steps, err := pps.spaceSteps(ev)
+	if err != nil {
+		pps.log.Error().Err(err).Str("uploadID", ev.UploadID).Msg("cannot get postprocessing steps of the space, using the default steps")
+		return pps.steps
+	}
+	if steps == nil {
+		return pps.steps
+	}
+	return steps
+}
+
+// spaceSteps returns the steps configured for the space of the upload, nil if there are none
+func (pps *PostprocessingService) spaceSteps(ev events.BytesReceived) ([]events.Postprocessingstep, error) {
+	if pps.gatewaySelector == nil {
+		return nil, fmt.Errorf("no gateway configured")
+	}
+
+	gwc, err := pps.gatewaySelector.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, _, err := utils.Impersonate(ev.ExecutingUser.GetId(), gwc, pps.machineAuthAPIKey)
+	if err != nil {
+		return nil, err
+	}
+
+	root := &provider.ResourceId{
+		StorageId: ev.ResourceID.GetStorageId(),
+		SpaceId:   ev.ResourceID.GetSpaceId(),
+		OpaqueId:  ev.ResourceID.GetSpaceId(),
+	}
+
+	if pps.c.SpaceSteps {
+		res, err := gwc.Stat(ctx, &provider.StatRequest{
+			Ref:                   &provider.Reference{ResourceId: root},
+			ArbitraryMetadataKeys: []string{event.SpaceStepsKey},
+		})
+		switch {
+		case err != nil:
+			return nil, err
+		case res.GetStatus().GetCode() != rpc.Code_CODE_OK:
+			return nil, errtypes.NewErrtypeFromStatus(res.GetStatus())
+		}
+
+		if v, ok := res.GetInfo().GetArbitraryMetadata().GetMetadata()[event.SpaceStepsKey]; ok {
+			var names []string
+			if err := json.Unmarshal([]byte(v), &names); err != nil {
+				return nil, fmt.Errorf("invalid postprocessing steps on space '%s': %w", root.GetSpaceId(), err)
+			}
+			return pps.restrictSpaceSteps(names, root.GetSpaceId()), nil
+		}
+	}
+
+	if len(pps.c.SpaceTypeSteps) == 0 {
+		return nil, nil
+	}
+
+	res, err := gwc.ListStorageSpaces(ctx, &provider.ListStorageSpacesRequest{
+		Filters: []*provider.ListStorageSpacesRequest_Filter{
+			{
+				Type: provider.ListStorageSpacesRequest_Filter_TYPE_ID,
+				Term: &provider.ListStorageSpacesRequest_Filter_Id{Id: &provider.StorageSpaceId{OpaqueId: storagespace.FormatStorageID(root.GetStorageId(), root.GetSpaceId())}},
+			},
+		},
+	})
+	switch {
+	case err != nil:
+		return nil, err
+	case res.GetStatus().GetCode() != rpc.Code_CODE_OK:
+		return nil, errtypes.NewErrtypeFromStatus(res.GetStatus())
+	case len(res.GetStorageSpaces()) != 1:
+		return nil, fmt.Errorf("expected one space with id '%s', got %d", root.GetSpaceId(), len(res.GetStorageSpaces()))
+	}
+
+	if names, ok := pps.c.SpaceTypeSteps[res.GetStorageSpaces()[0].GetSpaceType()]; ok {
+		return toSteps(names), nil
+	}
+	return nil, nil
+}
+
+// restrictSpaceSteps applies the admin settings to the steps set by a space manager. Steps which are not
+// allowed are dropped, missing mandatory steps are added in front of the remaining steps.
+func (pps *PostprocessingService) restrictSpaceSteps(names []string, spaceID string) []events.Postprocessingstep {
+	allowed := map[events.Postprocessingstep]bool{}
+	for _, s := range pps.steps {
+		for _, gs := range postprocessing.GroupSteps(s) {
+			allowed[gs] = true
+		}
+	}
+	for _, s := range pps.c.AllowedSpaceSteps {
+		allowed[events.Postprocessingstep(s)] = true
+	}
+	for _, s := range pps.c.MandatorySteps {
+		allowed[events.Postprocessingstep(s)] = true
+	}
+
+	present := map[events.Postprocessingstep]bool{}
+	steps := make([]events.Postprocessingstep, 0, len(names)+len(pps.c.MandatorySteps))
+	for _, name := range names {
+		var group []string
+		for _, s := range postprocessing.GroupSteps(events.Postprocessingstep(name)) {
+			if !allowed[s] {
+				pps.log.Warn().Str("spaceID", spaceID).Str("step", string(s)).Msg("ignoring postprocessing step of the space which is not allowed")
+				continue
+			}
+			present[s] = true
+			group = append(group, string(s))
+		}
+		if len(group) > 0 {
+			steps = append(steps, events.Postprocessingstep(strings.Join(group, postprocessing.StepGroupSeparator)))
+		}
+	}
+
+	var mandatory []events.Postprocessingstep
+	for _, s := range toSteps(pps.c.MandatorySteps) {
+		if !present[s] {
+			present[s] = true
+			mandatory = append(mandatory, s)
+		}
+	}
+	return append(mandatory, steps...)
+}
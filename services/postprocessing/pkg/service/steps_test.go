@@ -0,0 +1,125 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/v2/pkg/events"
+	"github.com/cs3org/reva/v2/pkg/rgrpc/todo/pool"
+	cs3mocks "github.com/cs3org/reva/v2/tests/cs3mocks/mocks"
+	"github.com/owncloud/ocis/v2/ocis-pkg/event"
+	"github.com/owncloud/ocis/v2/services/postprocessing/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+)
+
+func TestRestrictSpaceSteps(t *testing.T) {
+	pps, _ := newTestService(config.Postprocessing{
+		Steps:             []string{"virusscan+policies"},
+		AllowedSpaceSteps: []string{"classification"},
+		MandatorySteps:    []string{"virusscan"},
+	})
+
+	tests := []struct {
+		name  string
+		steps []string
+		want  []events.Postprocessingstep
+	}{
+		{name: "allowed", steps: []string{"virusscan", "policies", "classification"}, want: []events.Postprocessingstep{"virusscan", "policies", "classification"}},
+		{name: "empty list keeps mandatory steps", steps: []string{}, want: []events.Postprocessingstep{"virusscan"}},
+		{name: "missing mandatory step runs first", steps: []string{"policies"}, want: []events.Postprocessingstep{"virusscan", "policies"}},
+		{name: "disallowed step is dropped", steps: []string{"virusscan", "unknown"}, want: []events.Postprocessingstep{"virusscan"}},
+		{name: "disallowed group member is dropped", steps: []string{"classification+unknown"}, want: []events.Postprocessingstep{"virusscan", "classification"}},
+		{name: "mandatory step in group", steps: []string{"policies+virusscan"}, want: []events.Postprocessingstep{"policies+virusscan"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, pps.restrictSpaceSteps(tt.steps, "space"))
+		})
+	}
+}
+
+// newSpaceStepsService returns a service whose gateway returns the given steps on the space root
+// and the given space type
+func newSpaceStepsService(c config.Postprocessing, spaceSteps *string, spaceType string, statErr error) *PostprocessingService {
+	gatewayClient := &cs3mocks.GatewayAPIClient{}
+	gatewayClient.On("GetUser", mock.Anything, mock.Anything).Return(&user.GetUserResponse{
+		Status: &rpc.Status{Code: rpc.Code_CODE_OK},
+		User:   &user.User{Id: &user.UserId{OpaqueId: "user"}},
+	}, nil)
+	gatewayClient.On("Authenticate", mock.Anything, mock.Anything).Return(&gateway.AuthenticateResponse{
+		Status: &rpc.Status{Code: rpc.Code_CODE_OK},
+		Token:  "token",
+	}, nil)
+
+	metadata := map[string]string{}
+	if spaceSteps != nil {
+		metadata[event.SpaceStepsKey] = *spaceSteps
+	}
+	gatewayClient.On("Stat", mock.Anything, mock.Anything).Return(&provider.StatResponse{
+		Status: &rpc.Status{Code: rpc.Code_CODE_OK},
+		Info:   &provider.ResourceInfo{ArbitraryMetadata: &provider.ArbitraryMetadata{Metadata: metadata}},
+	}, statErr)
+	gatewayClient.On("ListStorageSpaces", mock.Anything, mock.Anything).Return(&provider.ListStorageSpacesResponse{
+		Status:        &rpc.Status{Code: rpc.Code_CODE_OK},
+		StorageSpaces: []*provider.StorageSpace{{SpaceType: spaceType}},
+	}, nil)
+
+	pool.RemoveSelector("GatewaySelector" + "com.owncloud.api.gateway")
+	selector := pool.GetSelector[gateway.GatewayAPIClient](
+		"GatewaySelector",
+		"com.owncloud.api.gateway",
+		func(cc *grpc.ClientConn) gateway.GatewayAPIClient {
+			return gatewayClient
+		},
+	)
+
+	pps, _ := newTestService(c)
+	pps.gatewaySelector = selector
+	return pps
+}
+
+func TestStepsFor(t *testing.T) {
+	strPtr := func(s string) *string { return &s }
+	c := config.Postprocessing{
+		Steps:          []string{"virusscan", "policies"},
+		SpaceSteps:     true,
+		SpaceTypeSteps: map[string][]string{"project": {"virusscan"}},
+		MandatorySteps: []string{"virusscan"},
+	}
+	ev := events.BytesReceived{
+		UploadID:      "upload",
+		ExecutingUser: &user.User{Id: &user.UserId{OpaqueId: "user"}},
+		ResourceID:    &provider.ResourceId{StorageId: "storage", SpaceId: "space", OpaqueId: "file"},
+	}
+
+	tests := []struct {
+		name       string
+		c          config.Postprocessing
+		spaceSteps *string
+		spaceType  string
+		statErr    error
+		want       []events.Postprocessingstep
+	}{
+		{name: "space steps", c: c, spaceSteps: strPtr(`["policies","virusscan"]`), spaceType: "project", want: []events.Postprocessingstep{"policies", "virusscan"}},
+		{name: "space steps can't drop mandatory steps", c: c, spaceSteps: strPtr(`[]`), spaceType: "project", want: []events.Postprocessingstep{"virusscan"}},
+		{name: "space type steps", c: c, spaceType: "project", want: []events.Postprocessingstep{"virusscan"}},
+		{name: "global steps", c: c, spaceType: "personal", want: []events.Postprocessingstep{"virusscan", "policies"}},
+		{name: "invalid space steps", c: c, spaceSteps: strPtr(`virusscan`), spaceType: "project", want: []events.Postprocessingstep{"virusscan", "policies"}},
+		{name: "stat error", c: c, spaceType: "project", statErr: errors.New("unavailable"), want: []events.Postprocessingstep{"virusscan", "policies"}},
+		{name: "space steps disabled", c: config.Postprocessing{Steps: []string{"policies"}}, spaceSteps: strPtr(`[]`), want: []events.Postprocessingstep{"policies"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pps := newSpaceStepsService(tt.c, tt.spaceSteps, tt.spaceType, tt.statErr)
+			assert.Equal(t, tt.want, pps.stepsFor(ev))
+		})
+	}
+}
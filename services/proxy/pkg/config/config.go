@@ -89,7 +89,8 @@ type AuthMiddleware struct {
 
 // PoliciesMiddleware configures the proxy policies middleware.
 type PoliciesMiddleware struct {
-	Query string `yaml:"query" env:"PROXY_POLICIES_QUERY" desc:"Defines the 'Complete Rules' variable defined in the rego rule set this step uses for its evaluation. Rules default to deny if the variable was not found."`
+	Query         string        `yaml:"query" env:"PROXY_POLICIES_QUERY" desc:"Defines the 'Complete Rules' variable defined in the rego rule set this step uses for its evaluation. Rules default to deny if the variable was not found."`
+	SpaceCacheTTL time.Duration `yaml:"space_cache_ttl" env:"PROXY_POLICIES_SPACE_CACHE_TTL" desc:"Time the type, name and quota of a space are cached for the evaluation of the policies. The duration can be set as number followed by a unit identifier like s, m or h."`
 }
 
 const (
@@ -73,6 +73,9 @@ func DefaultConfig() *config.Config {
 			AllowedHTTPMethods: []string{"GET"},
 			Enabled:            true,
 		},
+		PoliciesMiddleware: config.PoliciesMiddleware{
+			SpaceCacheTTL: time.Minute,
+		},
 		AccountBackend:        "cs3",
 		UserOIDCClaim:         "preferred_username",
 		UserCS3Claim:          "username",
@@ -175,6 +178,10 @@ func DefaultPolicies() []config.Policy {
 					Endpoint: "/webdav/?preview=1",
 					Service:  "com.owncloud.web.webdav",
 				},
+				{
+					Endpoint: "/dav/saved-searches",
+					Service:  "com.owncloud.web.webdav",
+				},
 				{
 					Endpoint: "/remote.php/",
 					Service:  "com.owncloud.web.ocdav",
@@ -231,6 +238,16 @@ func DefaultPolicies() []config.Policy {
 					Endpoint: "/api/v0/settings",
 					Service:  "com.owncloud.web.settings",
 				},
+				{
+					Endpoint: "/api/v0/antivirus",
+					Service:  "com.owncloud.web.antivirus",
+				},
+				{
+					// the callbacks are authenticated by the token in the callback url
+					Endpoint:    "/api/v0/webhooks",
+					Service:     "com.owncloud.web.webhooks",
+					Unprotected: true,
+				},
 			},
 		},
 	}
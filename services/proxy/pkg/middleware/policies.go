@@ -1,19 +1,32 @@
 package middleware
 
 import (
+	"fmt"
+	gonet "net"
 	"net/http"
 	"path"
+	"strings"
 	"time"
 
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	revactx "github.com/cs3org/reva/v2/pkg/ctx"
+	"github.com/cs3org/reva/v2/pkg/errtypes"
+	"github.com/cs3org/reva/v2/pkg/mime"
+	"github.com/cs3org/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/v2/pkg/storagespace"
+	"github.com/cs3org/reva/v2/pkg/utils"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
+	"github.com/jellydator/ttlcache/v2"
 	"github.com/owncloud/ocis/v2/ocis-pkg/log"
 	"github.com/owncloud/ocis/v2/ocis-pkg/service/grpc"
 	pMessage "github.com/owncloud/ocis/v2/protogen/gen/ocis/messages/policies/v0"
 	pService "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/policies/v0"
 	"github.com/owncloud/ocis/v2/services/webdav/pkg/net"
 	tusd "github.com/tus/tusd/pkg/handler"
+	"google.golang.org/grpc/metadata"
 )
 
 type (
@@ -33,9 +46,11 @@ type (
 
 const DeniedMessage = "Operation denied due to security policies"
 
-// Policies verifies if a request is granted or not.
-func Policies(logger log.Logger, qs string) func(next http.Handler) http.Handler {
+// Policies verifies if a request is granted or not. The gateway is used to look up the type, name and
+// quota of the space a request targets, they are cached for the given time.
+func Policies(logger log.Logger, qs string, gatewaySelector pool.Selectable[gateway.GatewayAPIClient], spaceCacheTTL time.Duration) func(next http.Handler) http.Handler {
 	pClient := pService.NewPoliciesProviderService("com.owncloud.api.policies", grpc.DefaultClient())
+	spaces := newSpaceLookup(gatewaySelector, spaceCacheTTL)
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -44,32 +59,20 @@ func Policies(logger log.Logger, qs string) func(next http.Handler) http.Handler
 				return
 			}
 
-			req := &pService.EvaluateRequest{
-				Query: qs,
-				Environment: &pMessage.Environment{
-					Request: &pMessage.Request{
-						Method: r.Method,
-						Path:   r.URL.Path,
-					},
-					Stage: pMessage.Stage_STAGE_HTTP,
-				},
-			}
-
-			meta := tusd.ParseMetadataHeader(r.Header.Get(net.HeaderUploadMetadata))
-			req.Environment.Resource = &pMessage.Resource{
-				Name: meta["filename"],
+			env := policiesEnvironment(r)
+			if env.GetSpace().GetId() != "" {
+				space, err := spaces.lookup(r, env.GetSpace().GetId())
+				switch {
+				case err != nil:
+					logger.Debug().Err(err).Str("space", env.GetSpace().GetId()).Msg("cannot look up the space of the request")
+				case space != nil:
+					env.Space = space
+				}
 			}
 
-			if user, ok := revactx.ContextGetUser(r.Context()); ok {
-				req.Environment.User = &pMessage.User{
-					Id: &pMessage.User_ID{
-						OpaqueId: user.GetId().GetOpaqueId(),
-					},
-					Username:    user.GetUsername(),
-					Mail:        user.GetMail(),
-					DisplayName: user.GetDisplayName(),
-					Groups:      user.GetGroups(),
-				}
+			req := &pService.EvaluateRequest{
+				Query:       qs,
+				Environment: env,
 			}
 
 			rsp, err := pClient.Evaluate(r.Context(), req)
@@ -80,17 +83,173 @@ func Policies(logger log.Logger, qs string) func(next http.Handler) http.Handler
 			}
 
 			if !rsp.Result {
-				RenderError(w, r, req, http.StatusForbidden, DeniedMessage)
+				RenderError(w, r, req, rsp.GetReasons(), http.StatusForbidden, DeniedMessage)
 				return
 			}
+			if rsp.AuditOnly {
+				logger.Debug().Str("path", r.URL.Path).Msg("request denied by policies in audit only mode, not enforced")
+			}
 
 			next.ServeHTTP(w, r)
 		})
 	}
 }
 
+// policiesEnvironment returns the environment of the request evaluated by the policies service.
+// Only information which can be derived from the request itself is added, the space is looked up separately.
+func policiesEnvironment(r *http.Request) *pMessage.Environment {
+	env := &pMessage.Environment{
+		Stage: pMessage.Stage_STAGE_HTTP,
+		Request: &pMessage.Request{
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			ClientIp:   clientIP(r),
+			UserAgent:  r.UserAgent(),
+			PublicLink: isPublicLinkRequest(r),
+		},
+		Resource: &pMessage.Resource{},
+	}
+
+	var spaceID string
+	spaceID, env.Resource.Path = spaceFromPath(r.URL.Path)
+	if spaceID != "" {
+		env.Space = &pMessage.Space{Id: spaceID}
+		if rid, err := storagespace.ParseID(spaceID); err == nil {
+			env.Request.Share = rid.GetStorageId() == utils.ShareStorageProviderID
+		}
+	}
+
+	meta := tusd.ParseMetadataHeader(r.Header.Get(net.HeaderUploadMetadata))
+	switch {
+	case meta["filename"] != "":
+		// tus creation requests target the parent folder
+		env.Resource.Name = meta["filename"]
+		if env.Resource.Path != "" {
+			env.Resource.Path = path.Join(env.Resource.Path, env.Resource.Name)
+		}
+	case r.Method == http.MethodPut && env.Resource.Path != "":
+		env.Resource.Name = path.Base(env.Resource.Path)
+	}
+	if env.Resource.Name != "" {
+		env.Resource.MimeType = mime.Detect(false, env.Resource.Name)
+	}
+
+	if user, ok := revactx.ContextGetUser(r.Context()); ok {
+		env.User = &pMessage.User{
+			Id: &pMessage.User_ID{
+				OpaqueId: user.GetId().GetOpaqueId(),
+			},
+			Username:    user.GetUsername(),
+			Mail:        user.GetMail(),
+			DisplayName: user.GetDisplayName(),
+			Groups:      user.GetGroups(),
+		}
+
+		// the account resolver adds the role ids of the user
+		var roleIDs []string
+		if err := utils.ReadJSONFromOpaque(user.GetOpaque(), "roles", &roleIDs); err == nil {
+			env.User.Roles = roleIDs
+		}
+	}
+
+	return env
+}
+
+// spaceLookup looks up the spaces requests target. Type, name and quota of a space rarely change,
+// they are cached to keep the proxy fast.
+type spaceLookup struct {
+	gatewaySelector pool.Selectable[gateway.GatewayAPIClient]
+	cache           *ttlcache.Cache
+}
+
+func newSpaceLookup(gatewaySelector pool.Selectable[gateway.GatewayAPIClient], ttl time.Duration) *spaceLookup {
+	cache := ttlcache.NewCache()
+	_ = cache.SetTTL(ttl)
+	cache.SkipTTLExtensionOnHit(true)
+	return &spaceLookup{
+		gatewaySelector: gatewaySelector,
+		cache:           cache,
+	}
+}
+
+// lookup returns the space with the given id as seen by the user of the request. It returns nil
+// if the space can't be looked up, e.g. because the request is not authenticated.
+func (l *spaceLookup) lookup(r *http.Request, spaceID string) (*pMessage.Space, error) {
+	if v, err := l.cache.Get(spaceID); err == nil {
+		return v.(*pMessage.Space), nil
+	}
+
+	token := r.Header.Get(revactx.TokenHeader)
+	if l.gatewaySelector == nil || token == "" {
+		return nil, nil
+	}
+
+	gwc, err := l.gatewaySelector.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := metadata.AppendToOutgoingContext(r.Context(), revactx.TokenHeader, token)
+	res, err := gwc.ListStorageSpaces(ctx, &provider.ListStorageSpacesRequest{
+		Filters: []*provider.ListStorageSpacesRequest_Filter{
+			{
+				Type: provider.ListStorageSpacesRequest_Filter_TYPE_ID,
+				Term: &provider.ListStorageSpacesRequest_Filter_Id{Id: &provider.StorageSpaceId{OpaqueId: spaceID}},
+			},
+		},
+	})
+	switch {
+	case err != nil:
+		return nil, err
+	case res.GetStatus().GetCode() != rpc.Code_CODE_OK:
+		return nil, errtypes.NewErrtypeFromStatus(res.GetStatus())
+	case len(res.GetStorageSpaces()) != 1:
+		return nil, fmt.Errorf("expected one space with id '%s', got %d", spaceID, len(res.GetStorageSpaces()))
+	}
+
+	s := res.GetStorageSpaces()[0]
+	space := &pMessage.Space{
+		Id:    spaceID,
+		Type:  s.GetSpaceType(),
+		Name:  s.GetName(),
+		Quota: s.GetQuota().GetQuotaMaxBytes(),
+	}
+	_ = l.cache.Set(spaceID, space)
+	return space, nil
+}
+
+// spaceFromPath returns the space id and the path inside of the space for webdav requests on spaces.
+func spaceFromPath(p string) (string, string) {
+	for _, prefix := range []string{"/remote.php/dav/spaces/", "/dav/spaces/"} {
+		if !strings.HasPrefix(p, prefix) {
+			continue
+		}
+
+		spaceID, rel, _ := strings.Cut(strings.TrimPrefix(p, prefix), "/")
+		return spaceID, path.Join("/", rel)
+	}
+	return "", ""
+}
+
+// isPublicLinkRequest checks if the request accesses a public link
+func isPublicLinkRequest(r *http.Request) bool {
+	if strings.HasPrefix(r.URL.Path, "/dav/public-files/") || strings.HasPrefix(r.URL.Path, "/remote.php/dav/public-files/") {
+		return true
+	}
+	return r.Header.Get(headerShareToken) != "" || r.URL.Query().Get(headerShareToken) != ""
+}
+
+// clientIP returns the ip of the client, the RealIP middleware already took care of proxy headers.
+func clientIP(r *http.Request) string {
+	host, _, err := gonet.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 // RenderError writes a Policies ErrorObject to the response writer
-func RenderError(w http.ResponseWriter, r *http.Request, evaluateReq *pService.EvaluateRequest, status int, msg string) {
+func RenderError(w http.ResponseWriter, r *http.Request, evaluateReq *pService.EvaluateRequest, reasons []*pMessage.Reason, status int, msg string) {
 	filename := evaluateReq.Environment.GetResource().GetName()
 	if filename == "" {
 		filename = path.Base(evaluateReq.Environment.GetRequest().GetPath())
@@ -104,6 +263,16 @@ func RenderError(w http.ResponseWriter, r *http.Request, evaluateReq *pService.E
 	innererror["method"] = evaluateReq.Environment.GetRequest().GetMethod()
 	innererror["filename"] = filename
 	innererror["path"] = evaluateReq.Environment.GetRequest().GetPath()
+	if len(reasons) > 0 {
+		rs := make([]map[string]string, 0, len(reasons))
+		for _, reason := range reasons {
+			rs = append(rs, map[string]string{
+				"rule":    reason.GetRule(),
+				"message": reason.GetMessage(),
+			})
+		}
+		innererror["reasons"] = rs
+	}
 
 	resp := &RequestDenied{
 		Error: RequestDeniedError{
@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userv1beta1 "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	revactx "github.com/cs3org/reva/v2/pkg/ctx"
+	"github.com/cs3org/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/v2/pkg/utils"
+	cs3mocks "github.com/cs3org/reva/v2/tests/cs3mocks/mocks"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	pMessage "github.com/owncloud/ocis/v2/protogen/gen/ocis/messages/policies/v0"
+	pService "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/policies/v0"
+	"github.com/owncloud/ocis/v2/services/webdav/pkg/net"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+)
+
+var _ = Describe("Policies environment", func() {
+	It("adds the space, the resource and the client of the request", func() {
+		req := httptest.NewRequest(http.MethodPut, "/remote.php/dav/spaces/storage$space/folder/file.pdf", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		req.Header.Set("User-Agent", "test-agent")
+
+		env := policiesEnvironment(req)
+		Expect(env.GetRequest().GetClientIp()).To(Equal("10.0.0.1"))
+		Expect(env.GetRequest().GetUserAgent()).To(Equal("test-agent"))
+		Expect(env.GetRequest().GetPublicLink()).To(BeFalse())
+		Expect(env.GetRequest().GetShare()).To(BeFalse())
+		Expect(env.GetSpace().GetId()).To(Equal("storage$space"))
+		Expect(env.GetResource().GetPath()).To(Equal("/folder/file.pdf"))
+		Expect(env.GetResource().GetName()).To(Equal("file.pdf"))
+		Expect(env.GetResource().GetMimeType()).To(Equal("application/pdf"))
+	})
+
+	It("uses the filename of tus uploads", func() {
+		req := httptest.NewRequest(http.MethodPost, "/dav/spaces/"+utils.ShareStorageProviderID+"$space!share/folder", nil)
+		// base64 of "run.exe"
+		req.Header.Set(net.HeaderUploadMetadata, "filename cnVuLmV4ZQ==")
+
+		env := policiesEnvironment(req)
+		Expect(env.GetRequest().GetShare()).To(BeTrue())
+		Expect(env.GetResource().GetPath()).To(Equal("/folder/run.exe"))
+		Expect(env.GetResource().GetName()).To(Equal("run.exe"))
+	})
+
+	It("detects public links", func() {
+		req := httptest.NewRequest(http.MethodGet, "/remote.php/dav/public-files/token/file.txt", nil)
+		Expect(policiesEnvironment(req).GetRequest().GetPublicLink()).To(BeTrue())
+	})
+
+	It("adds the roles of the user", func() {
+		user := &userv1beta1.User{
+			Id:     &userv1beta1.UserId{OpaqueId: "user"},
+			Opaque: utils.AppendJSONToOpaque(nil, "roles", []string{"role"}),
+		}
+		req := httptest.NewRequest(http.MethodGet, "/graph/v1.0/me", nil)
+		req = req.WithContext(revactx.ContextSetUser(context.Background(), user))
+
+		env := policiesEnvironment(req)
+		Expect(env.GetUser().GetId().GetOpaqueId()).To(Equal("user"))
+		Expect(env.GetUser().GetRoles()).To(Equal([]string{"role"}))
+	})
+})
+
+var _ = Describe("Policies space lookup", func() {
+	var (
+		gatewayClient *cs3mocks.GatewayAPIClient
+		spaces        *spaceLookup
+	)
+
+	BeforeEach(func() {
+		pool.RemoveSelector("GatewaySelector" + "com.owncloud.api.gateway")
+		gatewayClient = &cs3mocks.GatewayAPIClient{}
+		gatewaySelector := pool.GetSelector[gateway.GatewayAPIClient](
+			"GatewaySelector",
+			"com.owncloud.api.gateway",
+			func(cc *grpc.ClientConn) gateway.GatewayAPIClient {
+				return gatewayClient
+			},
+		)
+		spaces = newSpaceLookup(gatewaySelector, time.Minute)
+	})
+
+	It("adds the type, name and quota of the space and caches them", func() {
+		gatewayClient.On("ListStorageSpaces", mock.Anything, mock.MatchedBy(func(req *provider.ListStorageSpacesRequest) bool {
+			return req.GetFilters()[0].GetId().GetOpaqueId() == "storage$space"
+		})).Return(&provider.ListStorageSpacesResponse{
+			Status: &rpc.Status{Code: rpc.Code_CODE_OK},
+			StorageSpaces: []*provider.StorageSpace{{
+				Id:        &provider.StorageSpaceId{OpaqueId: "storage$space"},
+				SpaceType: "project",
+				Name:      "HR",
+				Quota:     &provider.Quota{QuotaMaxBytes: 1000},
+			}},
+		}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodPut, "/remote.php/dav/spaces/storage$space/run.exe", nil)
+		req.Header.Set(revactx.TokenHeader, "token")
+
+		for i := 0; i < 2; i++ {
+			space, err := spaces.lookup(req, "storage$space")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(space.GetId()).To(Equal("storage$space"))
+			Expect(space.GetType()).To(Equal("project"))
+			Expect(space.GetName()).To(Equal("HR"))
+			Expect(space.GetQuota()).To(Equal(uint64(1000)))
+		}
+		gatewayClient.AssertNumberOfCalls(GinkgoT(), "ListStorageSpaces", 1)
+	})
+
+	It("doesn't look up spaces of unauthenticated requests", func() {
+		req := httptest.NewRequest(http.MethodGet, "/remote.php/dav/spaces/storage$space/file.txt", nil)
+
+		space, err := spaces.lookup(req, "storage$space")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(space).To(BeNil())
+		gatewayClient.AssertNotCalled(GinkgoT(), "ListStorageSpaces", mock.Anything, mock.Anything)
+	})
+
+	It("fails when the space is not visible to the user", func() {
+		gatewayClient.On("ListStorageSpaces", mock.Anything, mock.Anything).Return(&provider.ListStorageSpacesResponse{
+			Status: &rpc.Status{Code: rpc.Code_CODE_OK},
+		}, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/remote.php/dav/spaces/storage$space/file.txt", nil)
+		req.Header.Set(revactx.TokenHeader, "token")
+
+		_, err := spaces.lookup(req, "storage$space")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Policies error", func() {
+	It("explains the denial", func() {
+		req := httptest.NewRequest(http.MethodPut, "/remote.php/dav/spaces/storage$space/run.exe", nil)
+		rec := httptest.NewRecorder()
+
+		RenderError(rec, req, &pService.EvaluateRequest{Environment: policiesEnvironment(req)}, []*pMessage.Reason{
+			{Rule: "no_executables", Message: "executables are not allowed"},
+		}, http.StatusForbidden, DeniedMessage)
+
+		var denied RequestDenied
+		Expect(rec.Code).To(Equal(http.StatusForbidden))
+		Expect(json.Unmarshal(rec.Body.Bytes(), &denied)).To(Succeed())
+		Expect(denied.Error.Innererror["filename"]).To(Equal("run.exe"))
+		Expect(denied.Error.Innererror["reasons"]).To(Equal([]interface{}{
+			map[string]interface{}{"rule": "no_executables", "message": "executables are not allowed"},
+		}))
+	})
+})
@@ -0,0 +1,88 @@
+package command
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/config/configlog"
+	"github.com/owncloud/ocis/v2/ocis-pkg/service/grpc"
+	searchsvc "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/search/v0"
+	"github.com/owncloud/ocis/v2/services/search/pkg/config"
+	"github.com/owncloud/ocis/v2/services/search/pkg/config/parser"
+)
+
+// Check is the entrypoint for the check command.
+func Check(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:     "check",
+		Usage:    "compare the index of one or all spaces with the storage and optionally repair it",
+		Category: "index management",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "space",
+				Aliases: []string{"s"},
+				Usage:   "the id of the space to check",
+			},
+			&cli.BoolFlag{
+				Name:  "all-spaces",
+				Usage: "check all personal and project spaces",
+			},
+			&cli.StringFlag{
+				Name:     "user",
+				Aliases:  []string{"u"},
+				Required: true,
+				Usage:    "the id of the user that shall be used to access the files. With --all-spaces the user lists the spaces, which requires the permission to list all spaces, the files are accessed as the owners or managers of the spaces",
+			},
+			&cli.BoolFlag{
+				Name:  "repair",
+				Usage: "reindex missing, stale and wrongly deleted resources and purge orphaned ones from the index",
+			},
+		},
+		Before: func(c *cli.Context) error {
+			return configlog.ReturnFatal(parser.ParseConfig(cfg))
+		},
+		Action: func(ctx *cli.Context) error {
+			spaces, err := spacesToProcess(ctx, cfg)
+			if err != nil {
+				fmt.Println("failed to list the spaces: " + err.Error())
+				return err
+			}
+
+			c := searchsvc.NewSearchProviderService("com.owncloud.api.search", grpc.DefaultClient())
+			var failed, checked, found, repaired int
+			for i, s := range spaces {
+				fmt.Printf("[%d/%d] checking space %s\n", i+1, len(spaces), s)
+				res, err := c.CheckIndex(context.Background(), &searchsvc.CheckIndexRequest{
+					SpaceId: s.id,
+					UserId:  s.userID,
+					Repair:  ctx.Bool("repair"),
+				}, spaceTimeout)
+				if err != nil {
+					fmt.Println("failed to check space: " + err.Error())
+					failed++
+					continue
+				}
+
+				for _, inc := range res.GetInconsistencies() {
+					status := ""
+					if inc.GetRepaired() {
+						status = " (repaired)"
+						repaired++
+					}
+					fmt.Printf("  %-8s %s %s%s\n", inc.GetKind(), inc.GetId(), inc.GetPath(), status)
+				}
+				checked += int(res.GetChecked())
+				found += len(res.GetInconsistencies())
+				fmt.Printf("[%d/%d] checked %d resources, found %d inconsistencies\n", i+1, len(spaces), res.GetChecked(), len(res.GetInconsistencies()))
+			}
+
+			fmt.Printf("checked %d resources in %d spaces, found %d inconsistencies, repaired %d\n", checked, len(spaces)-failed, found, repaired)
+			if failed > 0 {
+				return fmt.Errorf("failed to check %d of %d spaces", failed, len(spaces))
+			}
+			return nil
+		},
+	}
+}
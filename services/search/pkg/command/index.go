@@ -15,42 +15,66 @@ import (
 	"github.com/owncloud/ocis/v2/services/search/pkg/config/parser"
 )
 
+// spaceTimeout is the request timeout for indexing or checking a single space
+func spaceTimeout(opts *client.CallOptions) { opts.RequestTimeout = 10 * time.Minute }
+
 // Index is the entrypoint for the server command.
 func Index(cfg *config.Config) *cli.Command {
 	return &cli.Command{
 		Name:     "index",
-		Usage:    "index the files for one one more users",
+		Usage:    "index the files of one or all spaces",
 		Category: "index management",
 		Aliases:  []string{"i"},
 		Flags: []cli.Flag{
 			&cli.StringFlag{
-				Name:     "space",
-				Aliases:  []string{"s"},
-				Required: true,
-				Usage:    "the id of the space to travers and index the files of",
+				Name:    "space",
+				Aliases: []string{"s"},
+				Usage:   "the id of the space to travers and index the files of",
+			},
+			&cli.BoolFlag{
+				Name:  "all-spaces",
+				Usage: "index all personal and project spaces",
 			},
 			&cli.StringFlag{
 				Name:     "user",
 				Aliases:  []string{"u"},
 				Required: true,
-				Usage:    "the username of the user that shall be used to access the files",
+				Usage:    "the id of the user that shall be used to access the files. With --all-spaces the user lists the spaces, which requires the permission to list all spaces, the files are accessed as the owners or managers of the spaces",
 			},
 		},
 		Before: func(c *cli.Context) error {
 			return configlog.ReturnFatal(parser.ParseConfig(cfg))
 		},
 		Action: func(ctx *cli.Context) error {
-			grpcClient := grpc.DefaultClient()
-			grpcClient.Options()
-			c := searchsvc.NewSearchProviderService("com.owncloud.api.search", grpcClient)
-			_, err := c.IndexSpace(context.Background(), &searchsvc.IndexSpaceRequest{
-				SpaceId: ctx.String("space"),
-				UserId:  ctx.String("user"),
-			}, func(opts *client.CallOptions) { opts.RequestTimeout = 10 * time.Minute })
+			spaces, err := spacesToProcess(ctx, cfg)
 			if err != nil {
-				fmt.Println("failed to index space: " + err.Error())
+				fmt.Println("failed to list the spaces: " + err.Error())
 				return err
 			}
+
+			c := searchsvc.NewSearchProviderService("com.owncloud.api.search", grpc.DefaultClient())
+			failed := 0
+			for i, s := range spaces {
+				fmt.Printf("[%d/%d] indexing space %s\n", i+1, len(spaces), s)
+				start := time.Now()
+				_, err := c.IndexSpace(context.Background(), &searchsvc.IndexSpaceRequest{
+					SpaceId: s.id,
+					UserId:  s.userID,
+				}, spaceTimeout)
+				if err != nil {
+					fmt.Println("failed to index space: " + err.Error())
+					failed++
+					continue
+				}
+				fmt.Printf("[%d/%d] indexed space %s in %s\n", i+1, len(spaces), s, time.Since(start).Round(time.Millisecond))
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("failed to index %d of %d spaces", failed, len(spaces))
+			}
+			if len(spaces) > 1 {
+				fmt.Printf("indexed %d spaces\n", len(spaces))
+			}
 			return nil
 		},
 	}
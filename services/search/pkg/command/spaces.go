@@ -0,0 +1,108 @@
+package command
+
+import (
+	"fmt"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/v2/pkg/errtypes"
+	"github.com/cs3org/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/v2/pkg/utils"
+	"github.com/urfave/cli/v2"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/registry"
+	"github.com/owncloud/ocis/v2/services/search/pkg/config"
+)
+
+// space is a space to index or check and the user used to access its files
+type space struct {
+	id     string
+	name   string
+	userID string
+}
+
+// spacesToProcess returns the space given with the --space flag or all spaces if --all-spaces is set
+func spacesToProcess(ctx *cli.Context, cfg *config.Config) ([]space, error) {
+	switch {
+	case ctx.Bool("all-spaces") && ctx.String("space") != "":
+		return nil, fmt.Errorf("--space and --all-spaces can't be used together")
+	case ctx.Bool("all-spaces"):
+		return listSpaces(cfg, ctx.String("user"))
+	case ctx.String("space") != "":
+		return []space{{id: ctx.String("space"), userID: ctx.String("user")}}, nil
+	}
+	return nil, fmt.Errorf("either --space or --all-spaces is required")
+}
+
+// listSpaces lists all personal and project spaces as the given user. Personal spaces are accessed
+// by their owners, project spaces by one of their managers.
+func listSpaces(cfg *config.Config, userID string) ([]space, error) {
+	selector, err := pool.GatewaySelector(cfg.Reva.Address, pool.WithRegistry(registry.GetRegistry()))
+	if err != nil {
+		return nil, err
+	}
+	gwc, err := selector.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, _, err := utils.Impersonate(&user.UserId{OpaqueId: userID}, gwc, cfg.MachineAuthAPIKey)
+	if err != nil {
+		return nil, fmt.Errorf("can't impersonate user %s: %w", userID, err)
+	}
+
+	var spaces []space
+	for _, spaceType := range []string{"personal", "project"} {
+		res, err := gwc.ListStorageSpaces(ctx, &provider.ListStorageSpacesRequest{
+			Filters: []*provider.ListStorageSpacesRequest_Filter{
+				{
+					Type: provider.ListStorageSpacesRequest_Filter_TYPE_SPACE_TYPE,
+					Term: &provider.ListStorageSpacesRequest_Filter_SpaceType{SpaceType: spaceType},
+				},
+			},
+		})
+		switch {
+		case err != nil:
+			return nil, err
+		case res.GetStatus().GetCode() != rpc.Code_CODE_OK:
+			return nil, errtypes.NewErrtypeFromStatus(res.GetStatus())
+		}
+
+		for _, s := range res.GetStorageSpaces() {
+			spaceUserID, err := spaceUser(s)
+			if err != nil {
+				fmt.Printf("skipping space %s (%s): %s\n", s.GetName(), s.GetId().GetOpaqueId(), err)
+				continue
+			}
+			spaces = append(spaces, space{id: s.GetId().GetOpaqueId(), name: s.GetName(), userID: spaceUserID})
+		}
+	}
+	return spaces, nil
+}
+
+// spaceUser returns the id of the owner of a personal space or of a manager of a project space
+func spaceUser(s *provider.StorageSpace) (string, error) {
+	if s.GetSpaceType() == "personal" {
+		return s.GetOwner().GetId().GetOpaqueId(), nil
+	}
+
+	var grants map[string]*provider.ResourcePermissions
+	if err := utils.ReadJSONFromOpaque(s.GetOpaque(), "grants", &grants); err != nil {
+		return "", err
+	}
+	for id, permissions := range grants {
+		if permissions.GetRemoveGrant() {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("space has no manager")
+}
+
+// String returns the name and the id of the space
+func (s space) String() string {
+	if s.name == "" {
+		return s.id
+	}
+	return fmt.Sprintf("%s (%s)", s.name, s.id)
+}
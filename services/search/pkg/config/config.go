@@ -2,6 +2,7 @@ package config
 
 import (
 	"context"
+	"time"
 
 	"github.com/owncloud/ocis/v2/ocis-pkg/shared"
 )
@@ -25,9 +26,21 @@ type Config struct {
 	Events                     Events                `yaml:"events"`
 	Engine                     Engine                `yaml:"engine"`
 	Extractor                  Extractor             `yaml:"extractor"`
+	Store                      Store                 `yaml:"store"`
 	ContentExtractionSizeLimit uint64                `yaml:"content_extraction_size_limit" env:"SEARCH_CONTENT_EXTRACTION_SIZE_LIMIT" desc:"Maximum file size in bytes that is allowed for content extraction."`
 
 	MachineAuthAPIKey string `yaml:"machine_auth_api_key" env:"OCIS_MACHINE_AUTH_API_KEY;SEARCH_MACHINE_AUTH_API_KEY" desc:"Machine auth API key used to validate internal requests necessary for the access to resources from other services."`
 
 	Context context.Context `yaml:"-"`
 }
+
+// Store configures the store for the saved searches
+type Store struct {
+	Store        string        `yaml:"store" env:"OCIS_PERSISTENT_STORE;SEARCH_STORE" desc:"The type of the store. Supported values are: 'memory', 'ocmem', 'etcd', 'redis', 'redis-sentinel', 'nats-js', 'noop'. See the text description for details."`
+	Nodes        []string      `yaml:"nodes" env:"OCIS_PERSISTENT_STORE_NODES;SEARCH_STORE_NODES" desc:"A comma separated list of nodes to access the configured store. This has no effect when 'memory' or 'ocmem' stores are configured. Note that the behaviour how nodes are used is dependent on the library of the configured store."`
+	Database     string        `yaml:"database" env:"SEARCH_STORE_DATABASE" desc:"The database name the configured store should use."`
+	Table        string        `yaml:"table" env:"SEARCH_STORE_TABLE" desc:"The database table the store should use."`
+	MatchesTable string        `yaml:"matches_table" env:"SEARCH_STORE_MATCHES_TABLE" desc:"The database table the store should use for the files the owners of saved searches were notified about. Only the latest 1000 matches per saved search are kept."`
+	TTL          time.Duration `yaml:"ttl" env:"OCIS_PERSISTENT_STORE_TTL;SEARCH_STORE_TTL" desc:"Time to live for saved searches in the store. The duration can be set as number followed by a unit identifier like s, m or h. Defaults to no expiry."`
+	Size         int           `yaml:"size" env:"OCIS_PERSISTENT_STORE_SIZE;SEARCH_STORE_SIZE" desc:"The maximum quantity of items in the store. Only applies when store type 'ocmem' is configured. Defaults to 512."`
+}
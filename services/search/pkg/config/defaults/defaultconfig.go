@@ -38,6 +38,10 @@ func DefaultConfig() *config.Config {
 			Bleve: config.EngineBleve{
 				Datapath: filepath.Join(defaults.BaseDataPath(), "search"),
 			},
+			OpenSearch: config.EngineOpenSearch{
+				URL:   "http://127.0.0.1:9200",
+				Index: "ocis-resources",
+			},
 		},
 		Extractor: config.Extractor{
 			Type:             "basic",
@@ -46,6 +50,12 @@ func DefaultConfig() *config.Config {
 				TikaURL: "http://127.0.0.1:9998",
 			},
 		},
+		Store: config.Store{
+			Store:        "memory",
+			Database:     "search",
+			Table:        "saved-searches",
+			MatchesTable: "saved-search-matches",
+		},
 		Events: config.Events{
 			Endpoint:         "127.0.0.1:9233",
 			Cluster:          "ocis-cluster",
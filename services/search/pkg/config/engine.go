@@ -2,11 +2,21 @@ package config
 
 // Engine defines which search engine to use
 type Engine struct {
-	Type  string      `yaml:"type" env:"SEARCH_ENGINE_TYPE" desc:"Defines which search engine to use. Defaults to 'bleve'. Supported values are: 'bleve'."`
-	Bleve EngineBleve `yaml:"bleve"`
+	Type       string           `yaml:"type" env:"SEARCH_ENGINE_TYPE" desc:"Defines which search engine to use. Defaults to 'bleve'. Supported values are: 'bleve' and 'opensearch'."`
+	Bleve      EngineBleve      `yaml:"bleve"`
+	OpenSearch EngineOpenSearch `yaml:"opensearch"`
 }
 
 // EngineBleve configures the bleve engine
 type EngineBleve struct {
 	Datapath string `yaml:"data_path" env:"SEARCH_ENGINE_BLEVE_DATA_PATH" desc:"The directory where the filesystem will store search data. If not defined, the root directory derives from $OCIS_BASE_DATA_PATH:/search."`
 }
+
+// EngineOpenSearch configures the OpenSearch engine
+type EngineOpenSearch struct {
+	URL      string `yaml:"url" env:"SEARCH_ENGINE_OPENSEARCH_URL" desc:"The URL of the OpenSearch or Elasticsearch cluster."`
+	Index    string `yaml:"index" env:"SEARCH_ENGINE_OPENSEARCH_INDEX" desc:"The name of the index storing the resources. All search services sharing the index must use the same name. The index is created if it does not exist."`
+	Username string `yaml:"username" env:"SEARCH_ENGINE_OPENSEARCH_USERNAME" desc:"The username used for basic authentication with the cluster. Leave empty if the cluster does not require authentication."`
+	Password string `yaml:"password" env:"SEARCH_ENGINE_OPENSEARCH_PASSWORD" desc:"The password used for basic authentication with the cluster."`
+	Insecure bool   `yaml:"insecure" env:"OCIS_INSECURE;SEARCH_ENGINE_OPENSEARCH_INSECURE" desc:"Ignore untrusted SSL certificates when connecting to the cluster."`
+}
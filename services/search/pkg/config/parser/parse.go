@@ -2,6 +2,7 @@ package parser
 
 import (
 	"errors"
+	"fmt"
 
 	ociscfg "github.com/owncloud/ocis/v2/ocis-pkg/config"
 	"github.com/owncloud/ocis/v2/ocis-pkg/shared"
@@ -37,5 +38,11 @@ func Validate(cfg *config.Config) error {
 	if cfg.MachineAuthAPIKey == "" {
 		return shared.MissingMachineAuthApiKeyError(cfg.Service.Name)
 	}
+
+	if cfg.Engine.Type == "opensearch" && (cfg.Engine.OpenSearch.URL == "" || cfg.Engine.OpenSearch.Index == "") {
+		return fmt.Errorf("The OpenSearch url or index has not been configured for %s. "+
+			"Make sure SEARCH_ENGINE_OPENSEARCH_URL and SEARCH_ENGINE_OPENSEARCH_INDEX are set.",
+			cfg.Service.Name)
+	}
 	return nil
 }
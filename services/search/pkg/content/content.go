@@ -10,4 +10,61 @@ type Document struct {
 	Mtime    string
 	MimeType string
 	Tags     []string
+	// Language is the ISO 639-1 code of the language of the content
+	Language string `json:",omitempty"`
+
+	// the metadata facets are only set if the file contains the metadata
+	Audio    *Audio          `json:",omitempty"`
+	Image    *Image          `json:",omitempty"`
+	Location *GeoCoordinates `json:",omitempty"`
+	Photo    *Photo          `json:",omitempty"`
+	Office   *Office         `json:",omitempty"`
+}
+
+// AnalyzedLanguages are the languages whose content is indexed with a language specific analysis,
+// which stems the words and removes the stop words of the language.
+var AnalyzedLanguages = []string{"de", "en", "es", "fr", "it"}
+
+// IsAnalyzedLanguage returns true if the content of a language gets a language specific analysis
+func IsAnalyzedLanguage(lang string) bool {
+	for _, l := range AnalyzedLanguages {
+		if l == lang {
+			return true
+		}
+	}
+	return false
+}
+
+// Audio holds the metadata of audio files
+type Audio struct {
+	Album  string `json:",omitempty"`
+	Artist string `json:",omitempty"`
+	// Duration is the duration in milliseconds
+	Duration uint64 `json:",omitempty"`
+}
+
+// Image holds the dimensions of images in pixels
+type Image struct {
+	Width  uint64 `json:",omitempty"`
+	Height uint64 `json:",omitempty"`
+}
+
+// GeoCoordinates holds the location where a file, e.g. a photo, was created
+type GeoCoordinates struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Photo holds the EXIF metadata of photos
+type Photo struct {
+	CameraMake  string `json:",omitempty"`
+	CameraModel string `json:",omitempty"`
+	// TakenDateTime is the capture date, formatted like the Mtime of the Document
+	TakenDateTime string `json:",omitempty"`
+}
+
+// Office holds the metadata of office documents and PDFs
+type Office struct {
+	Author    string `json:",omitempty"`
+	PageCount uint64 `json:",omitempty"`
 }
@@ -3,7 +3,9 @@ package content
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/bbalet/stopwords"
 	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
@@ -76,7 +78,12 @@ func (t Tika) Extract(ctx context.Context, ri *provider.ResourceInfo) (Document,
 		return doc, err
 	}
 
-	for _, meta := range metas {
+	for i, meta := range metas {
+		// the first metadata belongs to the file itself, the others to embedded resources
+		if i == 0 {
+			addMetadata(&doc, meta)
+		}
+
 		if title, err := getFirstValue(meta, "title"); err == nil {
 			doc.Title = strings.TrimSpace(fmt.Sprintf("%s %s", doc.Title, title))
 		}
@@ -87,8 +94,88 @@ func (t Tika) Extract(ctx context.Context, ri *provider.ResourceInfo) (Document,
 	}
 
 	if lang, _ := t.tika.LanguageString(ctx, doc.Content); lang != "" {
-		doc.Content = stopwords.CleanString(doc.Content, lang, true)
+		doc.Language = lang
+		// the stop words of analyzed languages are removed when the content is indexed
+		if !IsAnalyzedLanguage(lang) {
+			doc.Content = stopwords.CleanString(doc.Content, lang, true)
+		}
 	}
 
 	return doc, nil
 }
+
+// addMetadata adds the image, photo, location, audio and office metadata reported by tika to the document
+func addMetadata(doc *Document, meta map[string][]string) {
+	if width, height := parseUint(firstValue(meta, "tiff:ImageWidth")), parseUint(firstValue(meta, "tiff:ImageLength")); width > 0 || height > 0 {
+		doc.Image = &Image{Width: width, Height: height}
+	}
+
+	photo := Photo{
+		CameraMake:    firstValue(meta, "tiff:Make"),
+		CameraModel:   firstValue(meta, "tiff:Model"),
+		TakenDateTime: parseDateTime(firstValue(meta, "exif:DateTimeOriginal")),
+	}
+	if photo != (Photo{}) {
+		doc.Photo = &photo
+	}
+
+	lat, latErr := strconv.ParseFloat(firstValue(meta, "geo:lat"), 64)
+	long, longErr := strconv.ParseFloat(firstValue(meta, "geo:long"), 64)
+	if latErr == nil && longErr == nil {
+		doc.Location = &GeoCoordinates{Latitude: lat, Longitude: long}
+	}
+
+	if strings.HasPrefix(doc.MimeType, "audio/") {
+		audio := Audio{
+			Album:  firstValue(meta, "xmpDM:album"),
+			Artist: firstValue(meta, "xmpDM:artist"),
+		}
+		// tika reports the duration in seconds
+		if seconds, err := strconv.ParseFloat(firstValue(meta, "xmpDM:duration"), 64); err == nil && seconds > 0 {
+			audio.Duration = uint64(seconds * 1000)
+		}
+		if audio != (Audio{}) {
+			doc.Audio = &audio
+		}
+	}
+
+	office := Office{
+		Author:    firstValue(meta, "dc:creator", "meta:author"),
+		PageCount: parseUint(firstValue(meta, "xmpTPg:NPages", "meta:page-count")),
+	}
+	if office != (Office{}) {
+		doc.Office = &office
+	}
+}
+
+// firstValue returns the first non empty value of the first key which has one
+func firstValue(meta map[string][]string, keys ...string) string {
+	for _, key := range keys {
+		for _, v := range meta[key] {
+			if v = strings.TrimSpace(v); v != "" {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+// parseUint parses values like "4032" or "4032 pixels", invalid values are 0
+func parseUint(v string) uint64 {
+	if fields := strings.Fields(v); len(fields) > 0 {
+		v = fields[0]
+	}
+	n, _ := strconv.ParseUint(v, 10, 64)
+	return n
+}
+
+// parseDateTime formats a date reported by tika like the mtime of a document. Dates without
+// a time zone are assumed to be UTC, invalid dates are empty.
+func parseDateTime(v string) string {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006:01:02 15:04:05"} {
+		if t, err := time.ParseInLocation(layout, v, time.UTC); err == nil {
+			return t.UTC().Format(time.RFC3339Nano)
+		}
+	}
+	return ""
+}
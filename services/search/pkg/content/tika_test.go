@@ -22,6 +22,7 @@ var _ = Describe("Tika", func() {
 	Describe("extract", func() {
 		var (
 			body     string
+			meta     string
 			language string
 			version  string
 			srv      *httptest.Server
@@ -30,6 +31,7 @@ var _ = Describe("Tika", func() {
 
 		BeforeEach(func() {
 			body = ""
+			meta = ""
 			language = ""
 			version = ""
 			srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
@@ -40,7 +42,7 @@ var _ = Describe("Tika", func() {
 				case "/language/string":
 					out = language
 				case "/rmeta/text":
-					out = fmt.Sprintf(`[{"X-TIKA:content":"%s"}]`, body)
+					out = fmt.Sprintf(`[{"X-TIKA:content":"%s"%s}]`, body, meta)
 				}
 
 				_, _ = w.Write([]byte(out))
@@ -81,7 +83,51 @@ var _ = Describe("Tika", func() {
 			Expect(doc.Content).To(Equal(body))
 		})
 
-		It("removes stop words", func() {
+		It("adds photo metadata", func() {
+			meta = `,"tiff:ImageWidth":"4032","tiff:ImageLength":"3024 pixels","tiff:Make":"Canon","tiff:Model":"EOS 5D",` +
+				`"exif:DateTimeOriginal":"2022-05-12T14:03:22","geo:lat":"52.5200","geo:long":"13.4050"`
+
+			doc, err := tika.Extract(context.TODO(), &provider.ResourceInfo{
+				Type:     provider.ResourceType_RESOURCE_TYPE_FILE,
+				MimeType: "image/jpeg",
+				Size:     1,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(doc.Image).To(Equal(&content.Image{Width: 4032, Height: 3024}))
+			Expect(doc.Photo).To(Equal(&content.Photo{CameraMake: "Canon", CameraModel: "EOS 5D", TakenDateTime: "2022-05-12T14:03:22Z"}))
+			Expect(doc.Location).To(Equal(&content.GeoCoordinates{Latitude: 52.52, Longitude: 13.405}))
+			Expect(doc.Audio).To(BeNil())
+			Expect(doc.Office).To(BeNil())
+		})
+
+		It("adds audio metadata", func() {
+			meta = `,"xmpDM:album":"Greatest Hits","xmpDM:artist":["Queen"],"xmpDM:duration":"219.5"`
+
+			doc, err := tika.Extract(context.TODO(), &provider.ResourceInfo{
+				Type:     provider.ResourceType_RESOURCE_TYPE_FILE,
+				MimeType: "audio/mpeg",
+				Size:     1,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(doc.Audio).To(Equal(&content.Audio{Album: "Greatest Hits", Artist: "Queen", Duration: 219500}))
+			Expect(doc.Image).To(BeNil())
+			Expect(doc.Photo).To(BeNil())
+		})
+
+		It("adds office metadata", func() {
+			meta = `,"meta:author":"Alice","xmpTPg:NPages":"12"`
+
+			doc, err := tika.Extract(context.TODO(), &provider.ResourceInfo{
+				Type:     provider.ResourceType_RESOURCE_TYPE_FILE,
+				MimeType: "application/pdf",
+				Size:     1,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(doc.Office).To(Equal(&content.Office{Author: "Alice", PageCount: 12}))
+			Expect(doc.Location).To(BeNil())
+		})
+
+		It("detects the language and keeps the content of analyzed languages", func() {
 			body = "body to test stop words!!! I, you, he, she, it, we, you, they, stay"
 			language = "en"
 
@@ -90,7 +136,21 @@ var _ = Describe("Tika", func() {
 				Size: 1,
 			})
 			Expect(err).ToNot(HaveOccurred())
-			Expect(doc.Content).To(Equal("body test stop words i stay "))
+			Expect(doc.Language).To(Equal("en"))
+			Expect(doc.Content).To(Equal(body))
+		})
+
+		It("removes stop words of other languages", func() {
+			body = "de tekst om de stopwoorden te testen, ik en jij blijven"
+			language = "nl"
+
+			doc, err := tika.Extract(context.TODO(), &provider.ResourceInfo{
+				Type: provider.ResourceType_RESOURCE_TYPE_FILE,
+				Size: 1,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(doc.Language).To(Equal("nl"))
+			Expect(doc.Content).To(Equal(" tekst stopwoorden te testen blijven "))
 		})
 	})
 })
@@ -6,7 +6,6 @@ import (
 	"math"
 	"path"
 	"path/filepath"
-	"regexp"
 	"strings"
 	"time"
 
@@ -16,9 +15,16 @@ import (
 	"github.com/blevesearch/bleve/v2"
 	"github.com/blevesearch/bleve/v2/analysis/analyzer/custom"
 	"github.com/blevesearch/bleve/v2/analysis/analyzer/keyword"
+	// register the analyzers of the analyzed languages
+	_ "github.com/blevesearch/bleve/v2/analysis/lang/de"
+	_ "github.com/blevesearch/bleve/v2/analysis/lang/en"
+	_ "github.com/blevesearch/bleve/v2/analysis/lang/es"
+	_ "github.com/blevesearch/bleve/v2/analysis/lang/fr"
+	_ "github.com/blevesearch/bleve/v2/analysis/lang/it"
 	"github.com/blevesearch/bleve/v2/analysis/token/lowercase"
 	"github.com/blevesearch/bleve/v2/analysis/tokenizer/single"
 	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search"
 	"github.com/blevesearch/bleve/v2/search/query"
 	storageProvider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/v2/pkg/storagespace"
@@ -26,6 +32,7 @@ import (
 	searchMessage "github.com/owncloud/ocis/v2/protogen/gen/ocis/messages/search/v0"
 	searchService "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/search/v0"
 	"github.com/owncloud/ocis/v2/services/search/pkg/content"
+	"github.com/owncloud/ocis/v2/services/search/pkg/query/kql"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -64,25 +71,13 @@ func NewBleveEngine(index bleve.Index) *Bleve {
 
 // BuildBleveMapping builds a bleve index mapping which can be used for indexing
 func BuildBleveMapping() (mapping.IndexMapping, error) {
-	nameMapping := bleve.NewTextFieldMapping()
-	nameMapping.Analyzer = "lowercaseKeyword"
-
-	lowercaseMapping := bleve.NewTextFieldMapping()
-	lowercaseMapping.IncludeInAll = false
-	lowercaseMapping.Analyzer = "lowercaseKeyword"
-
-	fulltextFieldMapping := bleve.NewTextFieldMapping()
-	fulltextFieldMapping.Analyzer = "fulltext"
-	fulltextFieldMapping.IncludeInAll = false
-
-	docMapping := bleve.NewDocumentMapping()
-	docMapping.AddFieldMappingsAt("Name", nameMapping)
-	docMapping.AddFieldMappingsAt("Tags", lowercaseMapping)
-	docMapping.AddFieldMappingsAt("Content", fulltextFieldMapping)
-
 	indexMapping := bleve.NewIndexMapping()
 	indexMapping.DefaultAnalyzer = keyword.Name
-	indexMapping.DefaultMapping = docMapping
+	indexMapping.DefaultMapping = buildBleveDocumentMapping("fulltext")
+	// the content of analyzed languages is indexed with the analyzer of the language, see Resource.BleveType
+	for _, lang := range content.AnalyzedLanguages {
+		indexMapping.AddDocumentMapping(lang, buildBleveDocumentMapping(lang))
+	}
 	err := indexMapping.AddCustomAnalyzer("lowercaseKeyword",
 		map[string]interface{}{
 			"type":      custom.Name,
@@ -113,18 +108,54 @@ func BuildBleveMapping() (mapping.IndexMapping, error) {
 	return indexMapping, nil
 }
 
+// buildBleveDocumentMapping builds the mapping of resources whose content is indexed with the given analyzer
+func buildBleveDocumentMapping(contentAnalyzer string) *mapping.DocumentMapping {
+	nameMapping := bleve.NewTextFieldMapping()
+	nameMapping.Analyzer = "lowercaseKeyword"
+
+	lowercaseMapping := bleve.NewTextFieldMapping()
+	lowercaseMapping.IncludeInAll = false
+	lowercaseMapping.Analyzer = "lowercaseKeyword"
+
+	fulltextFieldMapping := bleve.NewTextFieldMapping()
+	fulltextFieldMapping.Analyzer = contentAnalyzer
+	fulltextFieldMapping.IncludeInAll = false
+
+	docMapping := bleve.NewDocumentMapping()
+	docMapping.AddFieldMappingsAt("Name", nameMapping)
+	docMapping.AddFieldMappingsAt("Tags", lowercaseMapping)
+	docMapping.AddFieldMappingsAt("Content", fulltextFieldMapping)
+
+	// text metadata is matched case insensitive, the other metadata fields are mapped dynamically
+	for facet, fields := range map[string][]string{
+		"Audio":  {"Album", "Artist"},
+		"Photo":  {"CameraMake", "CameraModel"},
+		"Office": {"Author"},
+	} {
+		facetMapping := bleve.NewDocumentMapping()
+		for _, field := range fields {
+			facetMapping.AddFieldMappingsAt(field, lowercaseMapping)
+		}
+		docMapping.AddSubDocumentMapping(facet, facetMapping)
+	}
+	return docMapping
+}
+
 // Search executes a search request operation within the index.
 // Returns a SearchIndexResponse object or an error.
-func (b *Bleve) Search(_ context.Context, sir *searchService.SearchIndexRequest) (*searchService.SearchIndexResponse, error) {
+func (b *Bleve) Search(_ context.Context, n kql.Node, sir *searchService.SearchIndexRequest) (*searchService.SearchIndexResponse, error) {
+	uq, err := buildBleveQuery(n, sir.Query)
+	if err != nil {
+		return nil, err
+	}
+
 	q := bleve.NewConjunctionQuery(
 		// Skip documents that have been marked as deleted
 		&query.BoolFieldQuery{
 			Bool:     false,
 			FieldVal: "Deleted",
 		},
-		&query.QueryStringQuery{
-			Query: formatQuery(sir.Query),
-		},
+		uq,
 	)
 
 	if sir.Ref != nil {
@@ -141,20 +172,47 @@ func (b *Bleve) Search(_ context.Context, sir *searchService.SearchIndexRequest)
 				),
 			},
 		)
+
+		// restrict the query instead of filtering the hits, otherwise the total and the pages are off
+		if sir.Ref.Path != "" {
+			pq := bleve.NewPrefixQuery(utils.MakeRelativePath(path.Join(sir.Ref.Path, "/")))
+			pq.SetField("Path")
+			q.Conjuncts = append(q.Conjuncts, pq)
+		}
+	}
+
+	from, err := ParsePageToken(sir.PageToken)
+	if err != nil {
+		return nil, err
 	}
 
 	bleveReq := bleve.NewSearchRequest(q)
+	bleveReq.From = from
+	// sort by id on equal scores to keep the pages stable
+	bleveReq.SortBy([]string{"-_score", "_id"})
 	bleveReq.Highlight = bleve.NewHighlight()
+	bleveReq.Highlight.AddField("Content")
 
 	switch {
 	case sir.PageSize == -1:
-		bleveReq.Size = math.MaxInt
+		bleveReq.Size = math.MaxInt - from
 	case sir.PageSize == 0:
 		bleveReq.Size = 200
 	default:
 		bleveReq.Size = int(sir.PageSize)
 	}
 
+	if sir.Facets {
+		bleveReq.AddFacet(FacetMimeType, bleve.NewFacetRequest("MimeType", FacetShardSize))
+		bleveReq.AddFacet(FacetTags, bleve.NewFacetRequest("Tags", FacetShardSize))
+		buckets := mtimeBuckets(time.Now())
+		mtimeFacet := bleve.NewFacetRequest("Mtime", len(buckets))
+		for _, b := range buckets {
+			mtimeFacet.AddDateTimeRange(b.name, b.start, b.end)
+		}
+		bleveReq.AddFacet(FacetMtime, mtimeFacet)
+	}
+
 	bleveReq.Fields = []string{"*"}
 	res, err := b.index.Search(bleveReq)
 	if err != nil {
@@ -163,10 +221,6 @@ func (b *Bleve) Search(_ context.Context, sir *searchService.SearchIndexRequest)
 
 	matches := make([]*searchMessage.Match, 0, len(res.Hits))
 	for _, hit := range res.Hits {
-		if sir.Ref != nil && !strings.HasPrefix(getValue[string](hit.Fields, "Path"), utils.MakeRelativePath(path.Join(sir.Ref.Path, "/"))) {
-			continue
-		}
-
 		rootID, err := storagespace.ParseID(getValue[string](hit.Fields, "RootID"))
 		if err != nil {
 			return nil, err
@@ -194,7 +248,9 @@ func (b *Bleve) Search(_ context.Context, sir *searchService.SearchIndexRequest)
 				Deleted:  getValue[bool](hit.Fields, "Deleted"),
 				Tags:     getSliceValue[string](hit.Fields, "Tags"),
 			},
+			Highlights: hit.Fragments["Content"],
 		}
+		setMetadata(match.Entity, resourceFromFields(hit.Fields).Document)
 
 		if mtime, err := time.Parse(time.RFC3339, getValue[string](hit.Fields, "Mtime")); err == nil {
 			match.Entity.LastModifiedTime = &timestamppb.Timestamp{Seconds: mtime.Unix(), Nanos: int32(mtime.Nanosecond())}
@@ -204,8 +260,10 @@ func (b *Bleve) Search(_ context.Context, sir *searchService.SearchIndexRequest)
 	}
 
 	return &searchService.SearchIndexResponse{
-		Matches:      matches,
-		TotalMatches: int32(res.Total),
+		Matches:       matches,
+		TotalMatches:  int32(res.Total),
+		NextPageToken: NextPageToken(from, len(matches), res.Total),
+		Facets:        bleveFacets(res.Facets),
 	}, nil
 }
 
@@ -282,6 +340,25 @@ func (b *Bleve) DocCount() (uint64, error) {
 	return b.index.DocCount()
 }
 
+// List returns all resources of a space, including the deleted ones
+func (b *Bleve) List(rootID string) ([]*Resource, error) {
+	q := bleve.NewTermQuery(rootID)
+	q.SetField("RootID")
+	req := bleve.NewSearchRequest(q)
+	req.Size = math.MaxInt
+	req.Fields = []string{"*"}
+	res, err := b.index.Search(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]*Resource, 0, len(res.Hits))
+	for _, h := range res.Hits {
+		resources = append(resources, resourceFromFields(h.Fields))
+	}
+	return resources, nil
+}
+
 func (b *Bleve) getResource(id string) (*Resource, error) {
 	req := bleve.NewSearchRequest(bleve.NewDocIDQuery([]string{id}))
 	req.Fields = []string{"*"}
@@ -293,15 +370,18 @@ func (b *Bleve) getResource(id string) (*Resource, error) {
 		return nil, errors.New("entity not found")
 	}
 
-	fields := res.Hits[0].Fields
+	return resourceFromFields(res.Hits[0].Fields), nil
+}
 
-	return &Resource{
+func resourceFromFields(fields map[string]interface{}) *Resource {
+	r := &Resource{
 		ID:       getValue[string](fields, "ID"),
 		RootID:   getValue[string](fields, "RootID"),
 		Path:     getValue[string](fields, "Path"),
 		ParentID: getValue[string](fields, "ParentID"),
 		Type:     uint64(getValue[float64](fields, "Type")),
 		Deleted:  getValue[bool](fields, "Deleted"),
+		Hidden:   getValue[bool](fields, "Hidden"),
 		Document: content.Document{
 			Name:     getValue[string](fields, "Name"),
 			Title:    getValue[string](fields, "Title"),
@@ -310,8 +390,55 @@ func (b *Bleve) getResource(id string) (*Resource, error) {
 			MimeType: getValue[string](fields, "MimeType"),
 			Content:  getValue[string](fields, "Content"),
 			Tags:     getSliceValue[string](fields, "Tags"),
+			Language: getValue[string](fields, "Language"),
 		},
-	}, nil
+	}
+
+	// the metadata facets are flattened into fields like Photo.CameraMake
+	if hasFields(fields, "Audio") {
+		r.Audio = &content.Audio{
+			Album:    getValue[string](fields, "Audio.Album"),
+			Artist:   getValue[string](fields, "Audio.Artist"),
+			Duration: uint64(getValue[float64](fields, "Audio.Duration")),
+		}
+	}
+	if hasFields(fields, "Image") {
+		r.Image = &content.Image{
+			Width:  uint64(getValue[float64](fields, "Image.Width")),
+			Height: uint64(getValue[float64](fields, "Image.Height")),
+		}
+	}
+	if hasFields(fields, "Location") {
+		r.Location = &content.GeoCoordinates{
+			Latitude:  getValue[float64](fields, "Location.Latitude"),
+			Longitude: getValue[float64](fields, "Location.Longitude"),
+		}
+	}
+	if hasFields(fields, "Photo") {
+		r.Photo = &content.Photo{
+			CameraMake:    getValue[string](fields, "Photo.CameraMake"),
+			CameraModel:   getValue[string](fields, "Photo.CameraModel"),
+			TakenDateTime: getValue[string](fields, "Photo.TakenDateTime"),
+		}
+	}
+	if hasFields(fields, "Office") {
+		r.Office = &content.Office{
+			Author:    getValue[string](fields, "Office.Author"),
+			PageCount: uint64(getValue[float64](fields, "Office.PageCount")),
+		}
+	}
+
+	return r
+}
+
+// hasFields returns true if there are fields of the given sub document
+func hasFields(fields map[string]interface{}, doc string) bool {
+	for name := range fields {
+		if strings.HasPrefix(name, doc+".") {
+			return true
+		}
+	}
+	return false
 }
 
 func (b *Bleve) updateEntity(id string, mutateFunc func(r *Resource)) (*Resource, error) {
@@ -359,36 +486,34 @@ func (b *Bleve) setDeleted(id string, deleted bool) error {
 	return nil
 }
 
-func formatQuery(q string) string {
-	cq := q
-	fields := []string{"RootID", "Path", "ID", "Name", "Size", "Mtime", "MimeType", "Type"}
-	for _, field := range fields {
-		cq = strings.ReplaceAll(cq, strings.ToLower(field)+":", field+":")
-	}
-
-	fieldRe := regexp.MustCompile(`\w+:[^ ]+`)
-	if fieldRe.MatchString(cq) {
-		nameTagesRe := regexp.MustCompile(`\+?(Name|Tags)`) // detect "Name", "+Name, "Tags" and "+Tags"
-		parts := strings.Split(cq, " ")
-
-		cq = ""
-		for _, part := range parts {
-			fieldParts := strings.SplitN(part, ":", 2)
-			if len(fieldParts) > 1 {
-				key := fieldParts[0]
-				value := fieldParts[1]
-				if nameTagesRe.MatchString(key) {
-					value = strings.ToLower(value) // do a lowercase query on the lowercased fields
-				}
-				cq += key + ":" + value + " "
-			} else {
-				cq += part + " "
+// bleveFacets converts the facets of a bleve search result in the order of the facet names
+func bleveFacets(results search.FacetResults) []*searchMessage.Facet {
+	if len(results) == 0 {
+		return nil
+	}
+
+	facets := make([]*searchMessage.Facet, 0, len(results))
+	for _, name := range []string{FacetMimeType, FacetTags, FacetMtime} {
+		r, ok := results[name]
+		if !ok {
+			continue
+		}
+
+		f := &searchMessage.Facet{Name: name}
+		for _, t := range r.Terms.Terms() {
+			f.Values = append(f.Values, &searchMessage.FacetValue{Value: t.Term, Count: int32(t.Count)})
+		}
+		// bleve sorts the ranges by count, keep the order of the buckets instead
+		counts := make(map[string]int, len(r.DateRanges))
+		for _, dr := range r.DateRanges {
+			counts[dr.Name] = dr.Count
+		}
+		for _, b := range mtimeBuckets(time.Time{}) {
+			if c, ok := counts[b.name]; ok {
+				f.Values = append(f.Values, &searchMessage.FacetValue{Value: b.name, Count: int32(c)})
 			}
 		}
-		return cq // Sophisticated field based search
+		facets = append(facets, f)
 	}
-
-	// this is a basic filename search
-	cq = strings.ReplaceAll(cq, ":", `\:`)
-	return "Name:*" + strings.ReplaceAll(strings.ToLower(cq), " ", `\ `) + "*"
+	return facets
 }
@@ -0,0 +1,225 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search/query"
+	"github.com/owncloud/ocis/v2/services/search/pkg/content"
+	"github.com/owncloud/ocis/v2/services/search/pkg/query/kql"
+)
+
+// buildBleveQuery compiles a parsed query into a bleve query. The query string is only parsed if n is nil.
+func buildBleveQuery(n kql.Node, q string) (query.Query, error) {
+	if n == nil {
+		var err error
+		if n, err = kql.Parse(q); err != nil {
+			return nil, err
+		}
+	}
+	return compileBleveQuery(n, time.Now())
+}
+
+func compileBleveQuery(n kql.Node, now time.Time) (query.Query, error) {
+	switch n := n.(type) {
+	case kql.And:
+		qs, err := compileBleveQueries(n.Nodes, now)
+		if err != nil {
+			return nil, err
+		}
+		return bleve.NewConjunctionQuery(qs...), nil
+	case kql.Or:
+		qs, err := compileBleveQueries(n.Nodes, now)
+		if err != nil {
+			return nil, err
+		}
+		return bleve.NewDisjunctionQuery(qs...), nil
+	case kql.Not:
+		q, err := compileBleveQuery(n.Node, now)
+		if err != nil {
+			return nil, err
+		}
+		return not(q), nil
+	case kql.Restriction:
+		q, err := compileBleveRestriction(n, now)
+		if err != nil {
+			return nil, err
+		}
+		if n.Operator == kql.OpNotEqual {
+			return not(q), nil
+		}
+		return q, nil
+	}
+	return nil, fmt.Errorf("unsupported query node %T", n)
+}
+
+func compileBleveQueries(nodes []kql.Node, now time.Time) ([]query.Query, error) {
+	qs := make([]query.Query, 0, len(nodes))
+	for _, n := range nodes {
+		q, err := compileBleveQuery(n, now)
+		if err != nil {
+			return nil, err
+		}
+		qs = append(qs, q)
+	}
+	return qs, nil
+}
+
+func compileBleveRestriction(r kql.Restriction, now time.Time) (query.Query, error) {
+	field, ok := _indexFields[r.Field]
+	if !ok {
+		field = r.Field
+	}
+
+	if kind, ok := kql.MetadataFieldKind(r.Field); ok {
+		switch kind {
+		case kql.KindNumber:
+			return bleveNumericRange(field, r)
+		case kql.KindDate:
+			return bleveDateRange(field, r, now)
+		}
+		return term(field, strings.ToLower(r.Value), r.Wildcard), nil
+	}
+
+	switch r.Field {
+	case "":
+		// free text searches for file names containing the text
+		v := strings.ToLower(r.Value)
+		if !r.Wildcard {
+			v = "*" + kql.EscapeWildcards(v) + "*"
+		}
+		return wildcard("Name", v), nil
+	case kql.FieldName, kql.FieldTags:
+		return term(field, strings.ToLower(r.Value), r.Wildcard), nil
+	case kql.FieldContent:
+		if r.Wildcard {
+			return wildcard(field, strings.ToLower(r.Value)), nil
+		}
+		return bleveContentQuery(field, r), nil
+	case kql.FieldMediaType:
+		types := kql.MediaTypes(r.Value)
+		qs := make([]query.Query, 0, len(types))
+		for _, t := range types {
+			qs = append(qs, term(field, t, strings.ContainsAny(t, "*?")))
+		}
+		if len(qs) == 1 {
+			return qs[0], nil
+		}
+		return bleve.NewDisjunctionQuery(qs...), nil
+	case kql.FieldMtime:
+		return bleveDateRange(field, r, now)
+	case kql.FieldSize, kql.FieldType:
+		return bleveNumericRange(field, r)
+	case kql.FieldHidden, kql.FieldDeleted:
+		b, err := r.Bool()
+		if err != nil {
+			return nil, err
+		}
+		q := bleve.NewBoolFieldQuery(b)
+		q.SetField(field)
+		return q, nil
+	case kql.FieldID, kql.FieldRootID, kql.FieldParentID, kql.FieldPath:
+		return term(field, r.Value, r.Wildcard), nil
+	case kql.FieldLanguage:
+		return term(field, strings.ToLower(r.Value), r.Wildcard), nil
+	}
+
+	// the type of unknown fields is guessed from the value
+	if r.Operator.Comparison() {
+		if q, err := bleveNumericRange(field, r); err == nil {
+			return q, nil
+		}
+		return bleveDateRange(field, r, now)
+	}
+	if r.Wildcard {
+		return wildcard(field, r.Value), nil
+	}
+	q := bleve.NewMatchQuery(r.Value)
+	q.SetField(field)
+	return q, nil
+}
+
+// bleveContentQuery matches the content of every analyzed language with the value analyzed like the
+// content of the language. The content of other languages is matched with the fulltext analyzer.
+func bleveContentQuery(field string, r kql.Restriction) query.Query {
+	match := func(analyzer string) query.Query {
+		if r.Phrase {
+			q := bleve.NewMatchPhraseQuery(r.Value)
+			q.SetField(field)
+			q.Analyzer = analyzer
+			return q
+		}
+		q := bleve.NewMatchQuery(r.Value)
+		q.SetField(field)
+		q.Analyzer = analyzer
+		q.SetOperator(query.MatchQueryOperatorAnd)
+		return q
+	}
+
+	languages := make([]query.Query, 0, len(content.AnalyzedLanguages))
+	qs := make([]query.Query, 0, len(content.AnalyzedLanguages)+1)
+	for _, lang := range content.AnalyzedLanguages {
+		language := term("Language", lang, false)
+		languages = append(languages, language)
+		qs = append(qs, bleve.NewConjunctionQuery(language, match(lang)))
+	}
+
+	other := bleve.NewBooleanQuery()
+	other.AddMust(match("fulltext"))
+	other.AddMustNot(languages...)
+	return bleve.NewDisjunctionQuery(append(qs, other)...)
+}
+
+func bleveDateRange(field string, r kql.Restriction, now time.Time) (query.Query, error) {
+	rng, err := r.TimeRange(now)
+	if err != nil {
+		return nil, err
+	}
+
+	var start, end time.Time
+	if rng.Min != nil {
+		start = *rng.Min
+	}
+	if rng.Max != nil {
+		end = *rng.Max
+	}
+	q := bleve.NewDateRangeInclusiveQuery(start, end, &rng.MinInclusive, &rng.MaxInclusive)
+	q.SetField(field)
+	return q, nil
+}
+
+func bleveNumericRange(field string, r kql.Restriction) (query.Query, error) {
+	rng, err := r.NumberRange()
+	if err != nil {
+		return nil, err
+	}
+
+	q := bleve.NewNumericRangeInclusiveQuery(rng.Min, rng.Max, &rng.MinInclusive, &rng.MaxInclusive)
+	q.SetField(field)
+	return q, nil
+}
+
+func term(field, value string, isWildcard bool) query.Query {
+	if isWildcard {
+		return wildcard(field, value)
+	}
+	q := bleve.NewTermQuery(value)
+	q.SetField(field)
+	return q
+}
+
+// wildcard matches a wildcard pattern. Bleve wildcard queries can't match literal wildcards, the pattern
+// is converted into a regular expression instead.
+func wildcard(field, pattern string) query.Query {
+	q := bleve.NewRegexpQuery(kql.WildcardRegexp(pattern))
+	q.SetField(field)
+	return q
+}
+
+func not(q query.Query) query.Query {
+	bq := bleve.NewBooleanQuery()
+	bq.AddMustNot(q)
+	return bq
+}
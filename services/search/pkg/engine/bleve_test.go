@@ -3,6 +3,7 @@ package engine_test
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/cs3org/reva/v2/pkg/storagespace"
 
@@ -28,7 +29,7 @@ var _ = Describe("Bleve", func() {
 				return nil, err
 			}
 
-			return eng.Search(ctx, &searchsvc.SearchIndexRequest{
+			return eng.Search(ctx, nil, &searchsvc.SearchIndexRequest{
 				Query: query,
 				Ref: &searchmsg.Reference{
 					ResourceId: &searchmsg.ResourceID{
@@ -122,6 +123,258 @@ var _ = Describe("Bleve", func() {
 				assertDocCount(rootResource.ID, "Size:12344", 0)
 				assertDocCount(rootResource.ID, "Size:<1000", 0)
 				assertDocCount(rootResource.ID, "Size:>100000", 0)
+				assertDocCount(rootResource.ID, "size>=10KB", 1)
+				assertDocCount(rootResource.ID, "size<12KB", 0)
+			})
+
+			It("finds files by modification time", func() {
+				parentResource.Document.Mtime = "2023-05-10T12:00:00Z"
+				err := eng.Upsert(parentResource.ID, parentResource)
+				Expect(err).ToNot(HaveOccurred())
+
+				assertDocCount(rootResource.ID, "mtime:2023-05-10", 1)
+				assertDocCount(rootResource.ID, "mtime>=2023-01-01", 1)
+				assertDocCount(rootResource.ID, "mtime>2023-05-10", 0)
+				assertDocCount(rootResource.ID, "mtime<=2023-05-10", 1)
+				assertDocCount(rootResource.ID, "mtime<2023-05-10T12:00:00Z", 0)
+				assertDocCount(rootResource.ID, `Mtime:>="2023-05-10T12:00:00Z"`, 1)
+			})
+
+			It("finds files by media type", func() {
+				parentResource.Document.MimeType = "application/pdf"
+				err := eng.Upsert(parentResource.ID, parentResource)
+				Expect(err).ToNot(HaveOccurred())
+
+				assertDocCount(rootResource.ID, "mediatype:pdf", 1)
+				assertDocCount(rootResource.ID, "mediatype:application/pdf", 1)
+				assertDocCount(rootResource.ID, "mediatype:image", 0)
+			})
+
+			It("finds files by content", func() {
+				parentResource.Document.Content = "The quick brown fox jumps over the lazy dog"
+				err := eng.Upsert(parentResource.ID, parentResource)
+				Expect(err).ToNot(HaveOccurred())
+
+				assertDocCount(rootResource.ID, "content:fox", 1)
+				assertDocCount(rootResource.ID, `content:"brown fox"`, 1)
+				assertDocCount(rootResource.ID, `content:"fox brown"`, 0)
+				assertDocCount(rootResource.ID, "content:(cat OR dog)", 1)
+				assertDocCount(rootResource.ID, "content:(cat AND dog)", 0)
+			})
+
+			It("finds content by the stems of the words of its language", func() {
+				parentResource.Document.Content = "The contract was signed"
+				parentResource.Document.Language = "en"
+				err := eng.Upsert(parentResource.ID, parentResource)
+				Expect(err).ToNot(HaveOccurred())
+
+				childResource.Document.Content = "Die Verträge wurden unterschrieben"
+				childResource.Document.Language = "de"
+				err = eng.Upsert(childResource.ID, childResource)
+				Expect(err).ToNot(HaveOccurred())
+
+				matches := assertDocCount(rootResource.ID, "content:contracts", 1)
+				Expect(matches[0].Entity.Name).To(Equal("parent d!r"))
+				assertDocCount(rootResource.ID, `content:"contracts were signed"`, 1)
+				matches = assertDocCount(rootResource.ID, "content:vertrag", 1)
+				Expect(matches[0].Entity.Name).To(Equal("child.pdf"))
+				assertDocCount(rootResource.ID, "content:die", 0)
+				assertDocCount(rootResource.ID, "language:de", 1)
+			})
+
+			It("finds files by metadata", func() {
+				childResource.Document.Photo = &content.Photo{CameraMake: "Canon", CameraModel: "EOS 5D", TakenDateTime: "2022-05-12T14:03:22Z"}
+				childResource.Document.Image = &content.Image{Width: 4032, Height: 3024}
+				childResource.Document.Location = &content.GeoCoordinates{Latitude: 52.52, Longitude: 13.405}
+				childResource.Document.Office = &content.Office{Author: "Alice", PageCount: 12}
+				err := eng.Upsert(childResource.ID, childResource)
+				Expect(err).ToNot(HaveOccurred())
+
+				assertDocCount(rootResource.ID, "photo.takenDateTime>2021", 1)
+				assertDocCount(rootResource.ID, "photo.takenDateTime>2022", 0)
+				assertDocCount(rootResource.ID, "photo.cameraMake:canon", 1)
+				assertDocCount(rootResource.ID, "photo.cameraModel:EOS*", 1)
+				assertDocCount(rootResource.ID, "image.width>=4000 image.height<4000", 1)
+				assertDocCount(rootResource.ID, "location.latitude>50", 1)
+				assertDocCount(rootResource.ID, "author:alice pagecount>10", 1)
+				assertDocCount(rootResource.ID, "audio.artist:alice", 0)
+			})
+
+			It("returns the metadata of the matches", func() {
+				childResource.Document.Photo = &content.Photo{CameraMake: "Canon", TakenDateTime: "2022-05-12T14:03:22Z"}
+				childResource.Document.Audio = &content.Audio{Artist: "Queen", Duration: 219500}
+				err := eng.Upsert(childResource.ID, childResource)
+				Expect(err).ToNot(HaveOccurred())
+
+				matches := assertDocCount(rootResource.ID, "Name:child.pdf", 1)
+				Expect(matches[0].Entity.Photo.CameraMake).To(Equal("Canon"))
+				Expect(matches[0].Entity.Photo.TakenDateTime.AsTime()).To(Equal(time.Date(2022, 5, 12, 14, 3, 22, 0, time.UTC)))
+				Expect(matches[0].Entity.Audio.Artist).To(Equal("Queen"))
+				Expect(matches[0].Entity.Audio.Duration).To(Equal(uint64(219500)))
+				Expect(matches[0].Entity.Image).To(BeNil())
+				Expect(matches[0].Entity.Office).To(BeNil())
+			})
+		})
+
+		Context("with pages", func() {
+			BeforeEach(func() {
+				for i := 0; i < 5; i++ {
+					r := childResource
+					r.ID = fmt.Sprintf("1$2!page-%d", i)
+					r.Path = fmt.Sprintf("./page-%d.pdf", i)
+					r.Document.Name = fmt.Sprintf("page-%d.pdf", i)
+					err := eng.Upsert(r.ID, r)
+					Expect(err).ToNot(HaveOccurred())
+				}
+			})
+
+			It("returns stable pages", func() {
+				var names []string
+				token := ""
+				for i := 0; i < 3; i++ {
+					res, err := eng.Search(ctx, nil, &searchsvc.SearchIndexRequest{Query: "page", PageSize: 2, PageToken: token})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(res.TotalMatches).To(Equal(int32(5)))
+					for _, m := range res.Matches {
+						names = append(names, m.Entity.Name)
+					}
+					token = res.NextPageToken
+				}
+				Expect(token).To(BeEmpty())
+				Expect(names).To(Equal([]string{"page-0.pdf", "page-1.pdf", "page-2.pdf", "page-3.pdf", "page-4.pdf"}))
+			})
+
+			It("rejects invalid page tokens", func() {
+				_, err := eng.Search(ctx, nil, &searchsvc.SearchIndexRequest{Query: "page", PageToken: "-1"})
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("with content", func() {
+			BeforeEach(func() {
+				parentResource.Document.Content = "The quick brown fox jumps over the lazy dog"
+				parentResource.Document.MimeType = "text/plain"
+				parentResource.Document.Tags = []string{"animals"}
+				parentResource.Document.Mtime = time.Now().UTC().Format(time.RFC3339)
+				err := eng.Upsert(parentResource.ID, parentResource)
+				Expect(err).ToNot(HaveOccurred())
+
+				childResource.Document.MimeType = "application/pdf"
+				childResource.Document.Tags = []string{"animals", "pdf"}
+				childResource.Document.Mtime = "2020-01-01T00:00:00Z"
+				err = eng.Upsert(childResource.ID, childResource)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("highlights the matching content", func() {
+				matches := assertDocCount(rootResource.ID, "content:fox", 1)
+				Expect(matches[0].Highlights).To(HaveLen(1))
+				Expect(matches[0].Highlights[0]).To(ContainSubstring("<mark>fox</mark>"))
+			})
+
+			It("returns facets if requested", func() {
+				res, err := eng.Search(ctx, nil, &searchsvc.SearchIndexRequest{Query: "tag:animals", Facets: true})
+				Expect(err).ToNot(HaveOccurred())
+
+				facets := map[string]map[string]int32{}
+				for _, f := range res.Facets {
+					facets[f.Name] = map[string]int32{}
+					for _, v := range f.Values {
+						facets[f.Name][v.Value] = v.Count
+					}
+				}
+				Expect(facets).To(HaveKeyWithValue(engine.FacetMimeType, map[string]int32{"text/plain": 1, "application/pdf": 1}))
+				Expect(facets).To(HaveKeyWithValue(engine.FacetTags, map[string]int32{"animals": 2, "pdf": 1}))
+				Expect(facets[engine.FacetMtime]).To(HaveKeyWithValue("today", int32(1)))
+				Expect(facets[engine.FacetMtime]).To(HaveKeyWithValue("older", int32(1)))
+
+				res, err = eng.Search(ctx, nil, &searchsvc.SearchIndexRequest{Query: "tag:animals"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(res.Facets).To(BeEmpty())
+			})
+		})
+
+		Context("with boolean operators", func() {
+			BeforeEach(func() {
+				parentResource.Document.Tags = []string{"foo"}
+				err := eng.Upsert(parentResource.ID, parentResource)
+				Expect(err).ToNot(HaveOccurred())
+
+				childResource.Document.Tags = []string{"bar"}
+				err = eng.Upsert(childResource.ID, childResource)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("combines terms", func() {
+				assertDocCount(rootResource.ID, "tag:foo OR tag:bar", 2)
+				assertDocCount(rootResource.ID, "tag:foo AND tag:bar", 0)
+				assertDocCount(rootResource.ID, "tag:foo AND NOT tag:bar", 1)
+				assertDocCount(rootResource.ID, "NOT tag:foo", 1)
+				assertDocCount(rootResource.ID, "-tag:foo", 1)
+				assertDocCount(rootResource.ID, "(tag:foo OR tag:bar) AND name:child.pdf", 1)
+				assertDocCount(rootResource.ID, `"child.pdf" OR parent`, 2)
+			})
+
+			It("returns syntax errors", func() {
+				for _, query := range []string{`tag:(foo`, `"foo`, "tag:foo AND", "name>foo", "mtime>=yesterweek", "size:big"} {
+					_, err := doSearch(rootResource.ID, query)
+					Expect(err).To(HaveOccurred(), query)
+				}
+			})
+		})
+
+		Context("with the legacy queries of the web client", func() {
+			BeforeEach(func() {
+				parentResource.Document.Name = "Report 2023.pdf"
+				parentResource.Document.Tags = []string{"finance"}
+				parentResource.Document.Size = 2048
+				err := eng.Upsert(parentResource.ID, parentResource)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("still finds files", func() {
+				for query, count := range map[string]int{
+					// plain filename searches
+					"report":      1,
+					"REPORT":      1,
+					"report 2023": 1,
+					"eport 20":    1,
+					"report 2024": 0,
+					// field searches with the capitalized field names of the old query format
+					"+Name:*report*":               1,
+					`+Name:"*Report 2023*"`:        1,
+					`Name:report\ 2023.pdf`:        1,
+					"name:*REPORT*":                1,
+					"+Tags:finance":                1,
+					"+Tags:Finance":                1,
+					"tags:other":                   0,
+					"Size:>1000":                   1,
+					"+Name:*report* +Tags:finance": 1,
+					"+Name:*report* +Tags:other":   0,
+				} {
+					assertDocCount(rootResource.ID, query, count)
+				}
+			})
+		})
+
+		Context("with escaped wildcards", func() {
+			BeforeEach(func() {
+				parentResource.Document.Name = "a*b?.txt"
+				err := eng.Upsert(parentResource.ID, parentResource)
+				Expect(err).ToNot(HaveOccurred())
+
+				childResource.Document.Name = "axby.txt"
+				err = eng.Upsert(childResource.ID, childResource)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("matches escaped wildcards literally", func() {
+				assertDocCount(rootResource.ID, `Name:a\*b\?.txt`, 1)
+				assertDocCount(rootResource.ID, `Name:a\*b*`, 1)
+				assertDocCount(rootResource.ID, `Name:a?b?.txt`, 2)
+				assertDocCount(rootResource.ID, `Name:a\?b*`, 0)
+				assertDocCount(rootResource.ID, `a\*b`, 1)
+				assertDocCount(rootResource.ID, `"a\*b\?"`, 1)
 			})
 		})
 
@@ -330,6 +583,33 @@ var _ = Describe("Bleve", func() {
 		})
 	})
 
+	Describe("List", func() {
+		It("returns all resources of a space including the deleted ones", func() {
+			err := eng.Upsert(parentResource.ID, parentResource)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = eng.Upsert(childResource.ID, childResource)
+			Expect(err).ToNot(HaveOccurred())
+
+			other := engine.Resource{ID: "1$4!5", RootID: "1$4!4", Path: "./other.pdf", Document: content.Document{Name: "other.pdf"}}
+			err = eng.Upsert(other.ID, other)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = eng.Delete(childResource.ID)
+			Expect(err).ToNot(HaveOccurred())
+
+			resources, err := eng.List(rootResource.ID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resources).To(HaveLen(2))
+
+			deleted := map[string]bool{}
+			for _, r := range resources {
+				deleted[r.ID] = r.Deleted
+			}
+			Expect(deleted).To(Equal(map[string]bool{parentResource.ID: false, childResource.ID: true}))
+		})
+	})
+
 	Describe("Move", func() {
 		It("renames the parent and its child resources", func() {
 			err := eng.Upsert(parentResource.ID, parentResource)
@@ -349,6 +629,19 @@ var _ = Describe("Bleve", func() {
 			Expect(matches[0].Entity.Ref.Path).To(Equal("./my/newname/child.pdf"))
 		})
 
+		It("keeps the metadata of moved resources", func() {
+			childResource.Document.Image = &content.Image{Width: 640, Height: 480}
+			err := eng.Upsert(childResource.ID, childResource)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = eng.Move(childResource.ID, childResource.ParentID, "./child.pdf")
+			Expect(err).ToNot(HaveOccurred())
+
+			matches := assertDocCount(rootResource.ID, "image.width:640", 1)
+			Expect(matches[0].Entity.Ref.Path).To(Equal("./child.pdf"))
+			Expect(matches[0].Entity.Image.Height).To(Equal(uint64(480)))
+		})
+
 		It("moves the parent and its child resources", func() {
 			err := eng.Upsert(parentResource.ID, parentResource)
 			Expect(err).ToNot(HaveOccurred())
@@ -2,27 +2,81 @@ package engine
 
 import (
 	"context"
+	"fmt"
 	"regexp"
+	"strconv"
+	"time"
 
 	storageProvider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	searchMessage "github.com/owncloud/ocis/v2/protogen/gen/ocis/messages/search/v0"
 	searchService "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/search/v0"
 	"github.com/owncloud/ocis/v2/services/search/pkg/content"
+	"github.com/owncloud/ocis/v2/services/search/pkg/query/kql"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 var queryEscape = regexp.MustCompile(`([` + regexp.QuoteMeta(`+=&|><!(){}[]^\"~*?:\/`) + `\-\s])`)
 
+// The names of the facets returned for search requests
+const (
+	FacetMimeType = "mimetype"
+	FacetTags     = "tags"
+	FacetSpace    = "space"
+	FacetMtime    = "mtime"
+)
+
+// FacetSize is the maximum number of values returned per facet
+const FacetSize = 20
+
+// FacetShardSize is the number of values the engines return per facet and space. The facets of
+// all spaces are summed up and cut to FacetSize, asking each space for more values keeps values
+// which are frequent overall but not in the top values of every space from being undercounted.
+const FacetShardSize = 5 * FacetSize
+
+// _indexFields maps the fields of the query language to the fields of the indexed resources
+var _indexFields = map[string]string{
+	kql.FieldName:      "Name",
+	kql.FieldContent:   "Content",
+	kql.FieldTags:      "Tags",
+	kql.FieldMediaType: "MimeType",
+	kql.FieldMtime:     "Mtime",
+	kql.FieldSize:      "Size",
+	kql.FieldType:      "Type",
+	kql.FieldID:        "ID",
+	kql.FieldRootID:    "RootID",
+	kql.FieldParentID:  "ParentID",
+	kql.FieldPath:      "Path",
+	kql.FieldHidden:    "Hidden",
+	kql.FieldDeleted:   "Deleted",
+	kql.FieldLanguage:  "Language",
+
+	kql.FieldAudioAlbum:         "Audio.Album",
+	kql.FieldAudioArtist:        "Audio.Artist",
+	kql.FieldAudioDuration:      "Audio.Duration",
+	kql.FieldImageWidth:         "Image.Width",
+	kql.FieldImageHeight:        "Image.Height",
+	kql.FieldLocationLatitude:   "Location.Latitude",
+	kql.FieldLocationLongitude:  "Location.Longitude",
+	kql.FieldPhotoCameraMake:    "Photo.CameraMake",
+	kql.FieldPhotoCameraModel:   "Photo.CameraModel",
+	kql.FieldPhotoTakenDateTime: "Photo.TakenDateTime",
+	kql.FieldOfficeAuthor:       "Office.Author",
+	kql.FieldOfficePageCount:    "Office.PageCount",
+}
+
 //go:generate mockery --name=Engine
 
 // Engine is the interface to the search engine
 type Engine interface {
-	Search(ctx context.Context, req *searchService.SearchIndexRequest) (*searchService.SearchIndexResponse, error)
+	// Search searches the index. n is the parsed query of the request, if it is nil the query of the request is parsed.
+	Search(ctx context.Context, n kql.Node, req *searchService.SearchIndexRequest) (*searchService.SearchIndexResponse, error)
 	Upsert(id string, r Resource) error
 	Move(id string, parentid string, target string) error
 	Delete(id string) error
 	Restore(id string) error
 	Purge(id string) error
 	DocCount() (uint64, error)
+	List(rootID string) ([]*Resource, error)
 }
 
 // Resource is the entity that is stored in the index.
@@ -38,6 +92,12 @@ type Resource struct {
 	Hidden   bool
 }
 
+// BleveType returns the language of the content. It selects the bleve mapping, which indexes the
+// content with the analyzer of the language.
+func (r Resource) BleveType() string {
+	return r.Language
+}
+
 func resourceIDtoSearchID(id storageProvider.ResourceId) *searchMessage.ResourceID {
 	return &searchMessage.ResourceID{
 		StorageId: id.GetStorageId(),
@@ -45,6 +105,28 @@ func resourceIDtoSearchID(id storageProvider.ResourceId) *searchMessage.Resource
 		OpaqueId:  id.GetOpaqueId()}
 }
 
+// setMetadata adds the metadata facets of a document to a search entity
+func setMetadata(e *searchMessage.Entity, doc content.Document) {
+	if a := doc.Audio; a != nil {
+		e.Audio = &searchMessage.Audio{Album: a.Album, Artist: a.Artist, Duration: a.Duration}
+	}
+	if i := doc.Image; i != nil {
+		e.Image = &searchMessage.Image{Width: i.Width, Height: i.Height}
+	}
+	if l := doc.Location; l != nil {
+		e.Location = &searchMessage.GeoCoordinates{Latitude: l.Latitude, Longitude: l.Longitude}
+	}
+	if p := doc.Photo; p != nil {
+		e.Photo = &searchMessage.Photo{CameraMake: p.CameraMake, CameraModel: p.CameraModel}
+		if taken, err := time.Parse(time.RFC3339, p.TakenDateTime); err == nil {
+			e.Photo.TakenDateTime = &timestamppb.Timestamp{Seconds: taken.Unix(), Nanos: int32(taken.Nanosecond())}
+		}
+	}
+	if o := doc.Office; o != nil {
+		e.Office = &searchMessage.Office{Author: o.Author, PageCount: o.PageCount}
+	}
+}
+
 func escapeQuery(s string) string {
 	return queryEscape.ReplaceAllString(s, "\\$1")
 }
@@ -85,3 +167,46 @@ func getSliceValue[T any](m map[string]interface{}, key string) (out []T) {
 
 	return
 }
+
+// ParsePageToken returns the offset of the first match of a page. Page tokens are the offset
+// of the page, an empty token is the first page.
+func ParsePageToken(token string) (int, error) {
+	if token == "" {
+		return 0, nil
+	}
+	offset, err := strconv.Atoi(token)
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid page token '%s'", token)
+	}
+	return offset, nil
+}
+
+// NextPageToken returns the token of the page following the matches in [offset, offset+count),
+// or an empty token if there are no more matches.
+func NextPageToken(offset, count int, total uint64) string {
+	next := offset + count
+	if count == 0 || uint64(next) >= total {
+		return ""
+	}
+	return strconv.Itoa(next)
+}
+
+// mtimeBucket is a bucket of the mtime facet, a zero start or end is unbounded
+type mtimeBucket struct {
+	name       string
+	start, end time.Time
+}
+
+// mtimeBuckets returns the buckets of the mtime facet relative to now. The buckets overlap,
+// except for the last one.
+func mtimeBuckets(now time.Time) []mtimeBucket {
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	return []mtimeBucket{
+		{name: "today", start: today},
+		{name: "last7days", start: today.AddDate(0, 0, -7)},
+		{name: "last30days", start: today.AddDate(0, 0, -30)},
+		{name: "last365days", start: today.AddDate(0, 0, -365)},
+		{name: "older", end: today.AddDate(0, 0, -365)},
+	}
+}
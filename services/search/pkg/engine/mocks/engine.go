@@ -6,6 +6,8 @@ import (
 	context "context"
 
 	engine "github.com/owncloud/ocis/v2/services/search/pkg/engine"
+	kql "github.com/owncloud/ocis/v2/services/search/pkg/query/kql"
+
 	mock "github.com/stretchr/testify/mock"
 
 	v0 "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/search/v0"
@@ -51,6 +53,29 @@ func (_m *Engine) DocCount() (uint64, error) {
 	return r0, r1
 }
 
+// List provides a mock function with given fields: rootID
+func (_m *Engine) List(rootID string) ([]*engine.Resource, error) {
+	ret := _m.Called(rootID)
+
+	var r0 []*engine.Resource
+	if rf, ok := ret.Get(0).(func(string) []*engine.Resource); ok {
+		r0 = rf(rootID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*engine.Resource)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(rootID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Move provides a mock function with given fields: id, parentid, target
 func (_m *Engine) Move(id string, parentid string, target string) error {
 	ret := _m.Called(id, parentid, target)
@@ -93,13 +118,13 @@ func (_m *Engine) Restore(id string) error {
 	return r0
 }
 
-// Search provides a mock function with given fields: ctx, req
-func (_m *Engine) Search(ctx context.Context, req *v0.SearchIndexRequest) (*v0.SearchIndexResponse, error) {
-	ret := _m.Called(ctx, req)
+// Search provides a mock function with given fields: ctx, n, req
+func (_m *Engine) Search(ctx context.Context, n kql.Node, req *v0.SearchIndexRequest) (*v0.SearchIndexResponse, error) {
+	ret := _m.Called(ctx, n, req)
 
 	var r0 *v0.SearchIndexResponse
-	if rf, ok := ret.Get(0).(func(context.Context, *v0.SearchIndexRequest) *v0.SearchIndexResponse); ok {
-		r0 = rf(ctx, req)
+	if rf, ok := ret.Get(0).(func(context.Context, kql.Node, *v0.SearchIndexRequest) *v0.SearchIndexResponse); ok {
+		r0 = rf(ctx, n, req)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*v0.SearchIndexResponse)
@@ -107,8 +132,8 @@ func (_m *Engine) Search(ctx context.Context, req *v0.SearchIndexRequest) (*v0.S
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, *v0.SearchIndexRequest) error); ok {
-		r1 = rf(ctx, req)
+	if rf, ok := ret.Get(1).(func(context.Context, kql.Node, *v0.SearchIndexRequest) error); ok {
+		r1 = rf(ctx, n, req)
 	} else {
 		r1 = ret.Error(1)
 	}
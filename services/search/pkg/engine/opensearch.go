@@ -0,0 +1,613 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	storageProvider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/v2/pkg/storagespace"
+	"github.com/cs3org/reva/v2/pkg/utils"
+	searchMessage "github.com/owncloud/ocis/v2/protogen/gen/ocis/messages/search/v0"
+	searchService "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/search/v0"
+	"github.com/owncloud/ocis/v2/services/search/pkg/config"
+	"github.com/owncloud/ocis/v2/services/search/pkg/content"
+	"github.com/owncloud/ocis/v2/services/search/pkg/query/kql"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// _openSearchMaxResults is the default maximum of from + size of a search request (index.max_result_window)
+const _openSearchMaxResults = 10000
+
+// _openSearchListSize is the page size used to list the resources of a space and to page through search
+// results beyond the max result window
+const _openSearchListSize = 1000
+
+// OpenSearch is an engine which stores the index in an OpenSearch or Elasticsearch cluster,
+// several search services can share the same index.
+type OpenSearch struct {
+	client   *http.Client
+	url      string
+	index    string
+	username string
+	password string
+}
+
+// NewOpenSearchEngine creates a new OpenSearch engine, the index is created if it doesn't exist.
+func NewOpenSearchEngine(cfg config.EngineOpenSearch) (*OpenSearch, error) {
+	o := &OpenSearch{
+		client: &http.Client{
+			Timeout: time.Minute,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{
+					InsecureSkipVerify: cfg.Insecure, //nolint:gosec
+				},
+			},
+		},
+		url:      strings.TrimSuffix(cfg.URL, "/"),
+		index:    cfg.Index,
+		username: cfg.Username,
+		password: cfg.Password,
+	}
+
+	status, err := o.do(http.MethodHead, o.index, nil, nil)
+	switch {
+	case status == http.StatusNotFound:
+		if _, err := o.do(http.MethodPut, o.index, OpenSearchMapping(), nil); err != nil {
+			return nil, fmt.Errorf("could not create the index: %w", err)
+		}
+	case err != nil:
+		return nil, err
+	}
+
+	return o, nil
+}
+
+// _openSearchAnalyzers are the built-in analyzers of the analyzed languages
+var _openSearchAnalyzers = map[string]string{
+	"de": "german",
+	"en": "english",
+	"es": "spanish",
+	"fr": "french",
+	"it": "italian",
+}
+
+// OpenSearchMapping returns the settings and mappings of the index, they are equivalent to the
+// mapping built by BuildBleveMapping.
+func OpenSearchMapping() map[string]interface{} {
+	keyword := osQuery{"type": "keyword"}
+	lowercaseKeyword := osQuery{"type": "keyword", "normalizer": "lowercase"}
+	// the content is indexed with the analyzers of all analyzed languages, queries use the one of the resource
+	languageFields := osQuery{}
+	for lang, analyzer := range _openSearchAnalyzers {
+		languageFields[lang] = osQuery{"type": "text", "analyzer": analyzer}
+	}
+	contentMapping := osQuery{"type": "text", "analyzer": "fulltext", "fields": languageFields}
+	return osQuery{
+		"settings": osQuery{
+			"analysis": osQuery{
+				"normalizer": osQuery{
+					"lowercase": osQuery{"type": "custom", "filter": []string{"lowercase"}},
+				},
+				"analyzer": osQuery{
+					"fulltext": osQuery{
+						"type":      "custom",
+						"tokenizer": "standard",
+						"filter":    []string{"lowercase", "porter_stem"},
+					},
+				},
+			},
+		},
+		"mappings": osQuery{
+			"dynamic_templates": []osQuery{
+				{"strings": osQuery{"match_mapping_type": "string", "mapping": keyword}},
+			},
+			"properties": osQuery{
+				"ID":       keyword,
+				"RootID":   keyword,
+				"ParentID": keyword,
+				"Path":     keyword,
+				"Name":     lowercaseKeyword,
+				"Title":    keyword,
+				"Tags":     lowercaseKeyword,
+				"Content":  contentMapping,
+				"Language": keyword,
+				"MimeType": keyword,
+				"Mtime":    osQuery{"type": "date"},
+				"Size":     osQuery{"type": "long"},
+				"Type":     osQuery{"type": "long"},
+				"Deleted":  osQuery{"type": "boolean"},
+				"Hidden":   osQuery{"type": "boolean"},
+				"Audio": osQuery{"properties": osQuery{
+					"Album":    lowercaseKeyword,
+					"Artist":   lowercaseKeyword,
+					"Duration": osQuery{"type": "long"},
+				}},
+				"Image": osQuery{"properties": osQuery{
+					"Width":  osQuery{"type": "long"},
+					"Height": osQuery{"type": "long"},
+				}},
+				"Location": osQuery{"properties": osQuery{
+					"Latitude":  osQuery{"type": "double"},
+					"Longitude": osQuery{"type": "double"},
+				}},
+				"Photo": osQuery{"properties": osQuery{
+					"CameraMake":    lowercaseKeyword,
+					"CameraModel":   lowercaseKeyword,
+					"TakenDateTime": osQuery{"type": "date"},
+				}},
+				"Office": osQuery{"properties": osQuery{
+					"Author":    lowercaseKeyword,
+					"PageCount": osQuery{"type": "long"},
+				}},
+			},
+		},
+	}
+}
+
+// Search executes a search request operation within the index.
+// Returns a SearchIndexResponse object or an error.
+func (o *OpenSearch) Search(_ context.Context, n kql.Node, sir *searchService.SearchIndexRequest) (*searchService.SearchIndexResponse, error) {
+	uq, err := buildOpenSearchQuery(n, sir.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	filters := []osQuery{
+		// Skip documents that have been marked as deleted
+		osTerm("Deleted", false, false),
+	}
+	if sir.Ref != nil {
+		filters = append(filters, osTerm("RootID", storagespace.FormatResourceID(
+			storageProvider.ResourceId{
+				StorageId: sir.Ref.GetResourceId().GetStorageId(),
+				SpaceId:   sir.Ref.GetResourceId().GetSpaceId(),
+				OpaqueId:  sir.Ref.GetResourceId().GetOpaqueId(),
+			},
+		), false))
+
+		if sir.Ref.Path != "" {
+			filters = append(filters, osQuery{"prefix": osQuery{"Path": utils.MakeRelativePath(path.Join(sir.Ref.Path, "/"))}})
+		}
+	}
+
+	from, err := ParsePageToken(sir.PageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	size := int(sir.PageSize)
+	switch {
+	case size == 0:
+		size = 200
+	case size < -1:
+		return nil, fmt.Errorf("invalid page size '%d'", size)
+	}
+
+	req := osQuery{
+		"query": osQuery{"bool": osQuery{"must": []osQuery{uq}, "filter": filters}},
+		// sort by id on equal scores to keep the pages stable
+		"sort":             []interface{}{"_score", osQuery{"ID": "asc"}},
+		"track_total_hits": true,
+		"highlight":        osQuery{"fields": openSearchHighlightFields()},
+	}
+
+	if sir.Facets {
+		buckets := mtimeBuckets(time.Now())
+		ranges := make([]osQuery, 0, len(buckets))
+		for _, b := range buckets {
+			r := osQuery{"key": b.name}
+			if !b.start.IsZero() {
+				r["from"] = b.start.UTC().Format(time.RFC3339Nano)
+			}
+			if !b.end.IsZero() {
+				r["to"] = b.end.UTC().Format(time.RFC3339Nano)
+			}
+			ranges = append(ranges, r)
+		}
+		req["aggs"] = osQuery{
+			FacetMimeType: osQuery{"terms": osQuery{"field": "MimeType", "size": FacetShardSize}},
+			FacetTags:     osQuery{"terms": osQuery{"field": "Tags", "size": FacetShardSize}},
+			FacetMtime:    osQuery{"date_range": osQuery{"field": "Mtime", "ranges": ranges}},
+		}
+	}
+
+	res, err := o.search(req, from, size)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]*searchMessage.Match, 0, len(res.Hits.Hits))
+	for _, hit := range res.Hits.Hits {
+		r := hit.Source
+		rootID, err := storagespace.ParseID(r.RootID)
+		if err != nil {
+			return nil, err
+		}
+
+		rID, err := storagespace.ParseID(r.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		pID, _ := storagespace.ParseID(r.ParentID)
+		match := &searchMessage.Match{
+			Score: hit.Score,
+			Entity: &searchMessage.Entity{
+				Ref: &searchMessage.Reference{
+					ResourceId: resourceIDtoSearchID(rootID),
+					Path:       r.Path,
+				},
+				Id:       resourceIDtoSearchID(rID),
+				Name:     r.Name,
+				ParentId: resourceIDtoSearchID(pID),
+				Size:     r.Size,
+				Type:     r.Type,
+				MimeType: r.MimeType,
+				Deleted:  r.Deleted,
+				Tags:     r.Tags,
+			},
+			Highlights: openSearchHighlights(hit.Highlight, r.Language),
+		}
+		setMetadata(match.Entity, r.Document)
+
+		if mtime, err := time.Parse(time.RFC3339, r.Mtime); err == nil {
+			match.Entity.LastModifiedTime = &timestamppb.Timestamp{Seconds: mtime.Unix(), Nanos: int32(mtime.Nanosecond())}
+		}
+
+		matches = append(matches, match)
+	}
+
+	return &searchService.SearchIndexResponse{
+		Matches:       matches,
+		TotalMatches:  int32(res.Hits.Total.Value),
+		NextPageToken: NextPageToken(from, len(matches), res.Hits.Total.Value),
+		Facets:        openSearchFacets(res.Aggregations),
+	}, nil
+}
+
+// search runs the search request and returns the hits [from, from+size), a size of -1 returns all hits
+// from the offset on. Pages within the max result window of the index are requested with from and size,
+// pages beyond it are collected with search_after. The total and the aggregations are those of the first
+// request.
+func (o *OpenSearch) search(req osQuery, from, size int) (*osSearchResponse, error) {
+	if size != -1 && from+size <= _openSearchMaxResults {
+		req["from"] = from
+		req["size"] = size
+		res := &osSearchResponse{}
+		if _, err := o.do(http.MethodPost, o.index+"/_search", req, res); err != nil {
+			return nil, err
+		}
+		return res, nil
+	}
+
+	var (
+		res   *osSearchResponse
+		hits  []osHit
+		after []interface{}
+	)
+	skip := from
+	req["size"] = _openSearchListSize
+	for {
+		page := &osSearchResponse{}
+		if _, err := o.do(http.MethodPost, o.index+"/_search", req, page); err != nil {
+			return nil, err
+		}
+		if res == nil {
+			res = page
+			// the following requests only page through the hits
+			delete(req, "aggs")
+			req["track_total_hits"] = false
+		}
+
+		for _, hit := range page.Hits.Hits {
+			after = hit.Sort
+			if skip > 0 {
+				skip--
+				continue
+			}
+			hits = append(hits, hit)
+		}
+		if len(page.Hits.Hits) < _openSearchListSize || (size != -1 && len(hits) >= size) {
+			break
+		}
+		req["search_after"] = after
+	}
+
+	if size != -1 && len(hits) > size {
+		hits = hits[:size]
+	}
+	res.Hits.Hits = hits
+	return res, nil
+}
+
+// Upsert indexes or stores Resource data fields.
+func (o *OpenSearch) Upsert(id string, r Resource) error {
+	_, err := o.do(http.MethodPut, o.index+"/_doc/"+url.PathEscape(id)+"?refresh=wait_for", openSearchDocument(r), nil)
+	return err
+}
+
+// Move updates the resource location and all of its necessary fields.
+func (o *OpenSearch) Move(id string, parentid string, target string) error {
+	r, err := o.getResource(id)
+	if err != nil {
+		return err
+	}
+	currentPath := r.Path
+	nextPath := utils.MakeRelativePath(target)
+
+	r.Path = nextPath
+	r.Name = path.Base(nextPath)
+	r.ParentID = parentid
+	if err := o.Upsert(id, *r); err != nil {
+		return err
+	}
+
+	if r.Type == uint64(storageProvider.ResourceType_RESOURCE_TYPE_CONTAINER) {
+		return o.updateChildren(r.RootID, currentPath, osQuery{
+			"source": "ctx._source.Path = params.path + ctx._source.Path.substring(params.prefix)",
+			"params": osQuery{"path": nextPath, "prefix": len(currentPath)},
+		})
+	}
+
+	return nil
+}
+
+// Delete marks the resource as deleted.
+// The resource object will stay in the index,
+// instead of removing the resource it just marks it as deleted!
+// can be undone
+func (o *OpenSearch) Delete(id string) error {
+	return o.setDeleted(id, true)
+}
+
+// Restore is the counterpart to Delete.
+// It restores the resource which makes it available again.
+func (o *OpenSearch) Restore(id string) error {
+	return o.setDeleted(id, false)
+}
+
+// Purge removes a resource from the index, irreversible operation.
+func (o *OpenSearch) Purge(id string) error {
+	_, err := o.do(http.MethodDelete, o.index+"/_doc/"+url.PathEscape(id)+"?refresh=wait_for", nil, nil)
+	return err
+}
+
+// DocCount returns the number of resources in the index.
+func (o *OpenSearch) DocCount() (uint64, error) {
+	res := &struct {
+		Count uint64 `json:"count"`
+	}{}
+	if _, err := o.do(http.MethodGet, o.index+"/_count", nil, res); err != nil {
+		return 0, err
+	}
+	return res.Count, nil
+}
+
+// List returns all resources of a space, including the deleted ones
+func (o *OpenSearch) List(rootID string) ([]*Resource, error) {
+	var resources []*Resource
+	var after []interface{}
+	for {
+		req := osQuery{
+			"query": osTerm("RootID", rootID, false),
+			"size":  _openSearchListSize,
+			"sort":  []interface{}{osQuery{"ID": "asc"}},
+		}
+		if after != nil {
+			req["search_after"] = after
+		}
+
+		res := &osSearchResponse{}
+		if _, err := o.do(http.MethodPost, o.index+"/_search", req, res); err != nil {
+			return nil, err
+		}
+		for _, hit := range res.Hits.Hits {
+			r := hit.Source
+			resources = append(resources, &r)
+			after = hit.Sort
+		}
+		if len(res.Hits.Hits) < _openSearchListSize {
+			return resources, nil
+		}
+	}
+}
+
+func (o *OpenSearch) getResource(id string) (*Resource, error) {
+	res := &struct {
+		Source Resource `json:"_source"`
+	}{}
+	status, err := o.do(http.MethodGet, o.index+"/_doc/"+url.PathEscape(id), nil, res)
+	if status == http.StatusNotFound {
+		return nil, errors.New("entity not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &res.Source, nil
+}
+
+func (o *OpenSearch) setDeleted(id string, deleted bool) error {
+	r, err := o.getResource(id)
+	if err != nil {
+		return err
+	}
+
+	r.Deleted = deleted
+	if err := o.Upsert(id, *r); err != nil {
+		return err
+	}
+
+	if r.Type == uint64(storageProvider.ResourceType_RESOURCE_TYPE_CONTAINER) {
+		return o.updateChildren(r.RootID, r.Path, osQuery{
+			"source": "ctx._source.Deleted = params.deleted",
+			"params": osQuery{"deleted": deleted},
+		})
+	}
+
+	return nil
+}
+
+// updateChildren runs a script on all resources below a path
+func (o *OpenSearch) updateChildren(rootID, parentPath string, script osQuery) error {
+	script["lang"] = "painless"
+	req := osQuery{
+		"query": osQuery{"bool": osQuery{"filter": []osQuery{
+			osTerm("RootID", rootID, false),
+			{"prefix": osQuery{"Path": parentPath + "/"}},
+		}}},
+		"script": script,
+	}
+	_, err := o.do(http.MethodPost, o.index+"/_update_by_query?refresh=true&conflicts=proceed", req, nil)
+	return err
+}
+
+// do sends a request to the cluster and decodes the response into out. The status code of the
+// response is returned along with an error for unsuccessful requests.
+func (o *OpenSearch) do(method, p string, body interface{}, out interface{}) (int, error) {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, o.url+"/"+p, r)
+	if err != nil {
+		return 0, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if o.username != "" {
+		req.SetBasicAuth(o.username, o.password)
+	}
+
+	res, err := o.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusMultipleChoices {
+		e := &struct {
+			Error struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		}{}
+		if err := json.NewDecoder(res.Body).Decode(e); err == nil && e.Error.Reason != "" {
+			return res.StatusCode, fmt.Errorf("opensearch request failed with status %d: %s: %s", res.StatusCode, e.Error.Type, e.Error.Reason)
+		}
+		return res.StatusCode, fmt.Errorf("opensearch request failed with status %d", res.StatusCode)
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+			return res.StatusCode, err
+		}
+	}
+	return res.StatusCode, nil
+}
+
+// openSearchHighlightFields returns the content fields which are highlighted
+func openSearchHighlightFields() osQuery {
+	fields := osQuery{"Content": osQuery{}}
+	for _, lang := range content.AnalyzedLanguages {
+		fields["Content."+lang] = osQuery{}
+	}
+	return fields
+}
+
+// openSearchHighlights returns the highlights of the content field matching the language of the resource
+func openSearchHighlights(highlights map[string][]string, language string) []string {
+	if content.IsAnalyzedLanguage(language) {
+		return highlights["Content."+language]
+	}
+	return highlights["Content"]
+}
+
+// openSearchDocument returns the document stored for a resource. Empty dates are left out,
+// they can't be stored in date fields.
+func openSearchDocument(r Resource) map[string]interface{} {
+	b, _ := json.Marshal(r)
+	doc := map[string]interface{}{}
+	_ = json.Unmarshal(b, &doc)
+	if r.Mtime == "" {
+		delete(doc, "Mtime")
+	}
+	return doc
+}
+
+type osSearchResponse struct {
+	Hits struct {
+		Total struct {
+			Value uint64 `json:"value"`
+		} `json:"total"`
+		Hits []osHit `json:"hits"`
+	} `json:"hits"`
+	Aggregations map[string]osAggregation `json:"aggregations"`
+}
+
+type osHit struct {
+	ID        string              `json:"_id"`
+	Score     float32             `json:"_score"`
+	Source    Resource            `json:"_source"`
+	Highlight map[string][]string `json:"highlight"`
+	Sort      []interface{}       `json:"sort"`
+}
+
+type osAggregation struct {
+	Buckets []struct {
+		Key      interface{} `json:"key"`
+		DocCount int         `json:"doc_count"`
+	} `json:"buckets"`
+}
+
+func openSearchFacets(aggs map[string]osAggregation) []*searchMessage.Facet {
+	if len(aggs) == 0 {
+		return nil
+	}
+
+	facets := make([]*searchMessage.Facet, 0, len(aggs))
+	for _, name := range []string{FacetMimeType, FacetTags, FacetMtime} {
+		agg, ok := aggs[name]
+		if !ok {
+			continue
+		}
+
+		f := &searchMessage.Facet{Name: name}
+		if name != FacetMtime {
+			for _, b := range agg.Buckets {
+				f.Values = append(f.Values, &searchMessage.FacetValue{Value: fmt.Sprint(b.Key), Count: int32(b.DocCount)})
+			}
+			facets = append(facets, f)
+			continue
+		}
+
+		// the ranges are sorted by their bounds, keep the order of the buckets instead and
+		// leave out empty ranges like bleve
+		counts := make(map[string]int, len(agg.Buckets))
+		for _, b := range agg.Buckets {
+			counts[fmt.Sprint(b.Key)] = b.DocCount
+		}
+		for _, b := range mtimeBuckets(time.Time{}) {
+			if c := counts[b.name]; c > 0 {
+				f.Values = append(f.Values, &searchMessage.FacetValue{Value: b.name, Count: int32(c)})
+			}
+		}
+		facets = append(facets, f)
+	}
+	return facets
+}
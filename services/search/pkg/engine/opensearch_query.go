@@ -0,0 +1,222 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/owncloud/ocis/v2/services/search/pkg/content"
+	"github.com/owncloud/ocis/v2/services/search/pkg/query/kql"
+)
+
+// osQuery is a query of the OpenSearch query DSL
+type osQuery = map[string]interface{}
+
+// buildOpenSearchQuery compiles a parsed query into an OpenSearch query. The query string is only parsed if n is nil.
+func buildOpenSearchQuery(n kql.Node, q string) (osQuery, error) {
+	if n == nil {
+		var err error
+		if n, err = kql.Parse(q); err != nil {
+			return nil, err
+		}
+	}
+	return compileOpenSearchQuery(n, time.Now())
+}
+
+func compileOpenSearchQuery(n kql.Node, now time.Time) (osQuery, error) {
+	switch n := n.(type) {
+	case kql.And:
+		qs, err := compileOpenSearchQueries(n.Nodes, now)
+		if err != nil {
+			return nil, err
+		}
+		return osQuery{"bool": osQuery{"must": qs}}, nil
+	case kql.Or:
+		qs, err := compileOpenSearchQueries(n.Nodes, now)
+		if err != nil {
+			return nil, err
+		}
+		return osQuery{"bool": osQuery{"should": qs, "minimum_should_match": 1}}, nil
+	case kql.Not:
+		q, err := compileOpenSearchQuery(n.Node, now)
+		if err != nil {
+			return nil, err
+		}
+		return osNot(q), nil
+	case kql.Restriction:
+		q, err := compileOpenSearchRestriction(n, now)
+		if err != nil {
+			return nil, err
+		}
+		if n.Operator == kql.OpNotEqual {
+			return osNot(q), nil
+		}
+		return q, nil
+	}
+	return nil, fmt.Errorf("unsupported query node %T", n)
+}
+
+func compileOpenSearchQueries(nodes []kql.Node, now time.Time) ([]osQuery, error) {
+	qs := make([]osQuery, 0, len(nodes))
+	for _, n := range nodes {
+		q, err := compileOpenSearchQuery(n, now)
+		if err != nil {
+			return nil, err
+		}
+		qs = append(qs, q)
+	}
+	return qs, nil
+}
+
+func compileOpenSearchRestriction(r kql.Restriction, now time.Time) (osQuery, error) {
+	field, ok := _indexFields[r.Field]
+	if !ok {
+		field = r.Field
+	}
+
+	if kind, ok := kql.MetadataFieldKind(r.Field); ok {
+		switch kind {
+		case kql.KindNumber:
+			return openSearchNumericRange(field, r)
+		case kql.KindDate:
+			return openSearchDateRange(field, r, now)
+		}
+		return osTerm(field, strings.ToLower(r.Value), r.Wildcard), nil
+	}
+
+	switch r.Field {
+	case "":
+		// free text searches for file names containing the text
+		v := strings.ToLower(r.Value)
+		if !r.Wildcard {
+			v = "*" + kql.EscapeWildcards(v) + "*"
+		}
+		return osWildcard("Name", v), nil
+	case kql.FieldName, kql.FieldTags:
+		return osTerm(field, strings.ToLower(r.Value), r.Wildcard), nil
+	case kql.FieldContent:
+		if r.Wildcard {
+			return osWildcard(field, strings.ToLower(r.Value)), nil
+		}
+		return openSearchContentQuery(field, r), nil
+	case kql.FieldMediaType:
+		types := kql.MediaTypes(r.Value)
+		qs := make([]osQuery, 0, len(types))
+		for _, t := range types {
+			qs = append(qs, osTerm(field, t, strings.ContainsAny(t, "*?")))
+		}
+		if len(qs) == 1 {
+			return qs[0], nil
+		}
+		return osQuery{"bool": osQuery{"should": qs, "minimum_should_match": 1}}, nil
+	case kql.FieldMtime:
+		return openSearchDateRange(field, r, now)
+	case kql.FieldSize, kql.FieldType:
+		return openSearchNumericRange(field, r)
+	case kql.FieldHidden, kql.FieldDeleted:
+		b, err := r.Bool()
+		if err != nil {
+			return nil, err
+		}
+		return osTerm(field, b, false), nil
+	case kql.FieldID, kql.FieldRootID, kql.FieldParentID, kql.FieldPath:
+		return osTerm(field, r.Value, r.Wildcard), nil
+	case kql.FieldLanguage:
+		return osTerm(field, strings.ToLower(r.Value), r.Wildcard), nil
+	}
+
+	// the type of unknown fields is guessed from the value
+	if r.Operator.Comparison() {
+		if q, err := openSearchNumericRange(field, r); err == nil {
+			return q, nil
+		}
+		return openSearchDateRange(field, r, now)
+	}
+	if r.Wildcard {
+		return osWildcard(field, r.Value), nil
+	}
+	return osQuery{"match": osQuery{field: r.Value}}, nil
+}
+
+// openSearchContentQuery matches the content of every analyzed language with the sub field analyzed
+// like the language. The content of other languages is matched with the fulltext analyzer.
+func openSearchContentQuery(field string, r kql.Restriction) osQuery {
+	match := func(field string) osQuery {
+		if r.Phrase {
+			return osQuery{"match_phrase": osQuery{field: r.Value}}
+		}
+		return osQuery{"match": osQuery{field: osQuery{"query": r.Value, "operator": "and"}}}
+	}
+
+	qs := make([]osQuery, 0, len(content.AnalyzedLanguages)+1)
+	for _, lang := range content.AnalyzedLanguages {
+		qs = append(qs, osQuery{"bool": osQuery{
+			"filter": []osQuery{{"term": osQuery{"Language": lang}}},
+			"must":   []osQuery{match(field + "." + lang)},
+		}})
+	}
+	qs = append(qs, osQuery{"bool": osQuery{
+		"must":     []osQuery{match(field)},
+		"must_not": []osQuery{{"terms": osQuery{"Language": content.AnalyzedLanguages}}},
+	}})
+	return osQuery{"bool": osQuery{"should": qs, "minimum_should_match": 1}}
+}
+
+func openSearchDateRange(field string, r kql.Restriction, now time.Time) (osQuery, error) {
+	rng, err := r.TimeRange(now)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := osRangeBounds(rng.Min, rng.Max, rng.MinInclusive, rng.MaxInclusive, func(t time.Time) interface{} {
+		return t.UTC().Format(time.RFC3339Nano)
+	})
+	return osQuery{"range": osQuery{field: bounds}}, nil
+}
+
+func openSearchNumericRange(field string, r kql.Restriction) (osQuery, error) {
+	rng, err := r.NumberRange()
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := osRangeBounds(rng.Min, rng.Max, rng.MinInclusive, rng.MaxInclusive, func(f float64) interface{} {
+		return f
+	})
+	return osQuery{"range": osQuery{field: bounds}}, nil
+}
+
+// osRangeBounds returns the bounds of a range query, nil bounds are unbounded
+func osRangeBounds[T any](lower, upper *T, lowerInclusive, upperInclusive bool, format func(T) interface{}) osQuery {
+	bounds := osQuery{}
+	if lower != nil {
+		op := "gt"
+		if lowerInclusive {
+			op = "gte"
+		}
+		bounds[op] = format(*lower)
+	}
+	if upper != nil {
+		op := "lt"
+		if upperInclusive {
+			op = "lte"
+		}
+		bounds[op] = format(*upper)
+	}
+	return bounds
+}
+
+func osTerm(field string, value interface{}, isWildcard bool) osQuery {
+	if s, ok := value.(string); ok && isWildcard {
+		return osWildcard(field, s)
+	}
+	return osQuery{"term": osQuery{field: value}}
+}
+
+func osWildcard(field, value string) osQuery {
+	return osQuery{"wildcard": osQuery{field: osQuery{"value": value}}}
+}
+
+func osNot(q osQuery) osQuery {
+	return osQuery{"bool": osQuery{"must_not": []osQuery{q}}}
+}
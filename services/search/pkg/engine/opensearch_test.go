@@ -0,0 +1,403 @@
+package engine_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	sprovider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	searchmsg "github.com/owncloud/ocis/v2/protogen/gen/ocis/messages/search/v0"
+	searchsvc "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/search/v0"
+	"github.com/owncloud/ocis/v2/services/search/pkg/config"
+	"github.com/owncloud/ocis/v2/services/search/pkg/content"
+	"github.com/owncloud/ocis/v2/services/search/pkg/engine"
+)
+
+// osRequest is a request received by the OpenSearch stand-in
+type osRequest struct {
+	method string
+	uri    string
+	body   map[string]interface{}
+}
+
+var _ = Describe("OpenSearch", func() {
+	var (
+		server    *httptest.Server
+		mu        sync.Mutex
+		requests  []osRequest
+		responses map[string]string
+		eng       *engine.OpenSearch
+
+		lastRequest = func(method, uri string) osRequest {
+			mu.Lock()
+			defer mu.Unlock()
+			for i := len(requests) - 1; i >= 0; i-- {
+				if requests[i].method == method && requests[i].uri == uri {
+					return requests[i]
+				}
+			}
+			Fail("no request " + method + " " + uri)
+			return osRequest{}
+		}
+
+		newEngine = func() (*engine.OpenSearch, error) {
+			return engine.NewOpenSearchEngine(config.EngineOpenSearch{URL: server.URL + "/", Index: "ocis-resources"})
+		}
+
+		container = `{"_source": {"ID": "1$2!3", "RootID": "1$2!2", "ParentID": "1$2!2", "Path": "./parent", "Name": "parent", "Type": 2}}`
+	)
+
+	BeforeEach(func() {
+		requests = nil
+		responses = map[string]string{
+			"HEAD /ocis-resources": "",
+		}
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := io.ReadAll(r.Body)
+			req := osRequest{method: r.Method, uri: r.URL.RequestURI()}
+			if len(b) > 0 {
+				Expect(json.Unmarshal(b, &req.body)).To(Succeed())
+			}
+
+			mu.Lock()
+			requests = append(requests, req)
+			res, ok := responses[req.method+" "+r.URL.Path]
+			mu.Unlock()
+
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"error": {"type": "not_found", "reason": "not found"}}`))
+				return
+			}
+			_, _ = w.Write([]byte(res))
+		}))
+
+		var err error
+		eng, err = newEngine()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("New", func() {
+		It("keeps an existing index", func() {
+			for _, r := range requests {
+				Expect(r.method).ToNot(Equal(http.MethodPut))
+			}
+		})
+
+		It("creates the index if it doesn't exist", func() {
+			delete(responses, "HEAD /ocis-resources")
+			responses["PUT /ocis-resources"] = `{"acknowledged": true}`
+
+			_, err := newEngine()
+			Expect(err).ToNot(HaveOccurred())
+
+			req := lastRequest(http.MethodPut, "/ocis-resources")
+			properties := req.body["mappings"].(map[string]interface{})["properties"].(map[string]interface{})
+			contentMapping := properties["Content"].(map[string]interface{})
+			Expect(contentMapping["analyzer"]).To(Equal("fulltext"))
+			Expect(contentMapping["fields"]).To(HaveKeyWithValue("de", map[string]interface{}{"type": "text", "analyzer": "german"}))
+			Expect(properties["Language"]).To(Equal(map[string]interface{}{"type": "keyword"}))
+			Expect(properties["Name"]).To(Equal(map[string]interface{}{"type": "keyword", "normalizer": "lowercase"}))
+		})
+
+		It("returns the errors of the cluster", func() {
+			delete(responses, "HEAD /ocis-resources")
+
+			_, err := newEngine()
+			Expect(err).To(MatchError(ContainSubstring("not_found: not found")))
+		})
+	})
+
+	Describe("Upsert", func() {
+		It("stores the resource", func() {
+			responses["PUT /ocis-resources/_doc/1$2!3"] = `{"result": "created"}`
+
+			err := eng.Upsert("1$2!3", engine.Resource{
+				ID:       "1$2!3",
+				RootID:   "1$2!2",
+				Path:     "./foo.pdf",
+				Document: content.Document{Name: "foo.pdf", Tags: []string{"a"}},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			req := lastRequest(http.MethodPut, "/ocis-resources/_doc/1$2%213?refresh=wait_for")
+			Expect(req.body["Name"]).To(Equal("foo.pdf"))
+			Expect(req.body["Tags"]).To(Equal([]interface{}{"a"}))
+			Expect(req.body).ToNot(HaveKey("Mtime"))
+		})
+	})
+
+	Describe("Search", func() {
+		BeforeEach(func() {
+			responses["POST /ocis-resources/_search"] = `{
+				"hits": {
+					"total": {"value": 20},
+					"hits": [{
+						"_id": "1$2!3",
+						"_score": 1.5,
+						"_source": {"ID": "1$2!3", "RootID": "1$2!2", "ParentID": "1$2!2", "Path": "./foo.pdf", "Name": "foo.pdf", "Mtime": "2023-01-02T10:00:00Z", "Tags": ["a"], "Office": {"Author": "Alice", "PageCount": 3}},
+						"highlight": {"Content": ["some <mark>content</mark>"]}
+					}]
+				},
+				"aggregations": {
+					"mimetype": {"buckets": [{"key": "application/pdf", "doc_count": 20}]},
+					"tags": {"buckets": [{"key": "a", "doc_count": 3}]},
+					"mtime": {"buckets": [
+						{"key": "older", "doc_count": 4},
+						{"key": "last365days", "doc_count": 16},
+						{"key": "last30days", "doc_count": 0}
+					]}
+				}
+			}`
+		})
+
+		It("sends the compiled query", func() {
+			_, err := eng.Search(context.Background(), nil, &searchsvc.SearchIndexRequest{
+				Query:     "name:Foo.pdf size>=1KB",
+				PageToken: "10",
+				PageSize:  5,
+				Ref: &searchmsg.Reference{
+					ResourceId: &searchmsg.ResourceID{StorageId: "1", SpaceId: "2", OpaqueId: "2"},
+					Path:       "./dir",
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			req := lastRequest(http.MethodPost, "/ocis-resources/_search")
+			Expect(req.body["from"]).To(Equal(float64(10)))
+			Expect(req.body["size"]).To(Equal(float64(5)))
+			Expect(req.body).ToNot(HaveKey("aggs"))
+
+			q, _ := json.Marshal(req.body["query"])
+			Expect(q).To(MatchJSON(`{"bool": {
+				"must": [{"bool": {"must": [
+					{"term": {"Name": "foo.pdf"}},
+					{"range": {"Size": {"gte": 1024}}}
+				]}}],
+				"filter": [
+					{"term": {"Deleted": false}},
+					{"term": {"RootID": "1$2!2"}},
+					{"prefix": {"Path": "./dir"}}
+				]
+			}}`))
+		})
+
+		It("compiles metadata restrictions", func() {
+			_, err := eng.Search(context.Background(), nil, &searchsvc.SearchIndexRequest{
+				Query: "photo.takenDateTime>=2022 author:Alice",
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			req := lastRequest(http.MethodPost, "/ocis-resources/_search")
+			q, _ := json.Marshal(req.body["query"])
+			Expect(q).To(MatchJSON(`{"bool": {
+				"must": [{"bool": {"must": [
+					{"range": {"Photo.TakenDateTime": {"gte": "2022-01-01T00:00:00Z"}}},
+					{"term": {"Office.Author": "alice"}}
+				]}}],
+				"filter": [
+					{"term": {"Deleted": false}}
+				]
+			}}`))
+		})
+
+		It("keeps escaped wildcards escaped", func() {
+			_, err := eng.Search(context.Background(), nil, &searchsvc.SearchIndexRequest{Query: `name:a\*b* a\?b`})
+			Expect(err).ToNot(HaveOccurred())
+
+			req := lastRequest(http.MethodPost, "/ocis-resources/_search")
+			q, _ := json.Marshal(req.body["query"].(map[string]interface{})["bool"].(map[string]interface{})["must"])
+			Expect(q).To(MatchJSON(`[{"bool": {"must": [
+				{"wildcard": {"Name": {"value": "a\\*b*"}}},
+				{"wildcard": {"Name": {"value": "*a\\?b*"}}}
+			]}}]`))
+		})
+
+		It("analyzes content queries like the content of each language", func() {
+			_, err := eng.Search(context.Background(), nil, &searchsvc.SearchIndexRequest{Query: "content:contracts"})
+			Expect(err).ToNot(HaveOccurred())
+
+			req := lastRequest(http.MethodPost, "/ocis-resources/_search")
+			q, _ := json.Marshal(req.body["query"].(map[string]interface{})["bool"].(map[string]interface{})["must"])
+			Expect(q).To(MatchJSON(`[{"bool": {"should": [
+				{"bool": {"filter": [{"term": {"Language": "de"}}], "must": [{"match": {"Content.de": {"query": "contracts", "operator": "and"}}}]}},
+				{"bool": {"filter": [{"term": {"Language": "en"}}], "must": [{"match": {"Content.en": {"query": "contracts", "operator": "and"}}}]}},
+				{"bool": {"filter": [{"term": {"Language": "es"}}], "must": [{"match": {"Content.es": {"query": "contracts", "operator": "and"}}}]}},
+				{"bool": {"filter": [{"term": {"Language": "fr"}}], "must": [{"match": {"Content.fr": {"query": "contracts", "operator": "and"}}}]}},
+				{"bool": {"filter": [{"term": {"Language": "it"}}], "must": [{"match": {"Content.it": {"query": "contracts", "operator": "and"}}}]}},
+				{"bool": {
+					"must": [{"match": {"Content": {"query": "contracts", "operator": "and"}}}],
+					"must_not": [{"terms": {"Language": ["de", "en", "es", "fr", "it"]}}]
+				}}
+			], "minimum_should_match": 1}}]`))
+		})
+
+		It("returns the matches and facets", func() {
+			res, err := eng.Search(context.Background(), nil, &searchsvc.SearchIndexRequest{
+				Query:     "foo",
+				PageToken: "10",
+				Facets:    true,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			req := lastRequest(http.MethodPost, "/ocis-resources/_search")
+			Expect(req.body).To(HaveKey("aggs"))
+
+			Expect(res.TotalMatches).To(Equal(int32(20)))
+			Expect(res.NextPageToken).To(Equal("11"))
+			Expect(res.Matches).To(HaveLen(1))
+			Expect(res.Matches[0].Score).To(Equal(float32(1.5)))
+			Expect(res.Matches[0].Entity.Name).To(Equal("foo.pdf"))
+			Expect(res.Matches[0].Entity.Id.OpaqueId).To(Equal("3"))
+			Expect(res.Matches[0].Entity.Ref.Path).To(Equal("./foo.pdf"))
+			Expect(res.Matches[0].Entity.Tags).To(Equal([]string{"a"}))
+			Expect(res.Matches[0].Entity.LastModifiedTime.Seconds).To(Equal(int64(1672653600)))
+			Expect(res.Matches[0].Highlights).To(Equal([]string{"some <mark>content</mark>"}))
+			Expect(res.Matches[0].Entity.Office).To(Equal(&searchmsg.Office{Author: "Alice", PageCount: 3}))
+			Expect(res.Matches[0].Entity.Photo).To(BeNil())
+
+			Expect(res.Facets).To(HaveLen(3))
+			Expect(res.Facets[0].Name).To(Equal(engine.FacetMimeType))
+			Expect(res.Facets[0].Values[0]).To(Equal(&searchmsg.FacetValue{Value: "application/pdf", Count: 20}))
+			Expect(res.Facets[2].Name).To(Equal(engine.FacetMtime))
+			Expect(res.Facets[2].Values).To(Equal([]*searchmsg.FacetValue{
+				{Value: "last365days", Count: 16},
+				{Value: "older", Count: 4},
+			}))
+		})
+
+		It("pages through the results beyond the max result window with search_after", func() {
+			hits := make([]string, 1000)
+			for i := range hits {
+				hits[i] = fmt.Sprintf(`{"_id": "1$2!%[1]d", "_score": 1, "_source": {"ID": "1$2!%[1]d", "RootID": "1$2!2", "Name": "f%[1]d"}, "sort": [1, "1$2!%[1]d"]}`, i)
+			}
+			responses["POST /ocis-resources/_search"] = `{"hits": {"total": {"value": 20000}, "hits": [` + strings.Join(hits, ",") + `]}, "aggregations": {}}`
+
+			res, err := eng.Search(context.Background(), nil, &searchsvc.SearchIndexRequest{
+				Query:     "foo",
+				PageToken: "9500",
+				PageSize:  600,
+				Facets:    true,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.Matches).To(HaveLen(600))
+			Expect(res.Matches[0].Entity.Name).To(Equal("f500"))
+			Expect(res.Matches[599].Entity.Name).To(Equal("f99"))
+			Expect(res.TotalMatches).To(Equal(int32(20000)))
+			Expect(res.NextPageToken).To(Equal("10100"))
+
+			var searches []osRequest
+			for _, r := range requests {
+				if r.uri == "/ocis-resources/_search" {
+					searches = append(searches, r)
+				}
+			}
+			Expect(searches).To(HaveLen(11))
+			Expect(searches[0].body).ToNot(HaveKey("from"))
+			Expect(searches[0].body).ToNot(HaveKey("search_after"))
+			Expect(searches[0].body).To(HaveKey("aggs"))
+			Expect(searches[0].body["size"]).To(Equal(float64(1000)))
+			Expect(searches[1].body).ToNot(HaveKey("aggs"))
+			Expect(searches[1].body["search_after"]).To(Equal([]interface{}{float64(1), "1$2!999"}))
+		})
+
+		It("returns syntax errors without querying the cluster", func() {
+			_, err := eng.Search(context.Background(), nil, &searchsvc.SearchIndexRequest{Query: "name:(foo"})
+			Expect(err).To(HaveOccurred())
+			for _, r := range requests {
+				Expect(r.uri).ToNot(Equal("/ocis-resources/_search"))
+			}
+		})
+	})
+
+	Describe("Delete", func() {
+		It("marks a container and its children as deleted", func() {
+			responses["GET /ocis-resources/_doc/1$2!3"] = container
+			responses["PUT /ocis-resources/_doc/1$2!3"] = `{"result": "updated"}`
+			responses["POST /ocis-resources/_update_by_query"] = `{"updated": 1}`
+
+			Expect(eng.Delete("1$2!3")).To(Succeed())
+
+			Expect(lastRequest(http.MethodPut, "/ocis-resources/_doc/1$2%213?refresh=wait_for").body["Deleted"]).To(BeTrue())
+			req := lastRequest(http.MethodPost, "/ocis-resources/_update_by_query?refresh=true&conflicts=proceed")
+			b, _ := json.Marshal(req.body)
+			Expect(b).To(MatchJSON(`{
+				"query": {"bool": {"filter": [
+					{"term": {"RootID": "1$2!2"}},
+					{"prefix": {"Path": "./parent/"}}
+				]}},
+				"script": {"lang": "painless", "source": "ctx._source.Deleted = params.deleted", "params": {"deleted": true}}
+			}`))
+		})
+
+		It("fails for unknown resources", func() {
+			Expect(eng.Delete("1$2!4")).To(MatchError("entity not found"))
+		})
+	})
+
+	Describe("Move", func() {
+		It("moves a container and its children", func() {
+			responses["GET /ocis-resources/_doc/1$2!3"] = container
+			responses["PUT /ocis-resources/_doc/1$2!3"] = `{"result": "updated"}`
+			responses["POST /ocis-resources/_update_by_query"] = `{"updated": 1}`
+
+			Expect(eng.Move("1$2!3", "1$2!4", "./other/moved")).To(Succeed())
+
+			body := lastRequest(http.MethodPut, "/ocis-resources/_doc/1$2%213?refresh=wait_for").body
+			Expect(body["Path"]).To(Equal("./other/moved"))
+			Expect(body["Name"]).To(Equal("moved"))
+			Expect(body["ParentID"]).To(Equal("1$2!4"))
+			Expect(body["Type"]).To(Equal(float64(sprovider.ResourceType_RESOURCE_TYPE_CONTAINER)))
+
+			script := lastRequest(http.MethodPost, "/ocis-resources/_update_by_query?refresh=true&conflicts=proceed").body["script"]
+			Expect(script).To(HaveKeyWithValue("params", map[string]interface{}{"path": "./other/moved", "prefix": float64(len("./parent"))}))
+		})
+	})
+
+	Describe("Purge", func() {
+		It("removes the resource", func() {
+			responses["DELETE /ocis-resources/_doc/1$2!3"] = `{"result": "deleted"}`
+
+			Expect(eng.Purge("1$2!3")).To(Succeed())
+			lastRequest(http.MethodDelete, "/ocis-resources/_doc/1$2%213?refresh=wait_for")
+		})
+	})
+
+	Describe("DocCount", func() {
+		It("returns the number of resources", func() {
+			responses["GET /ocis-resources/_count"] = `{"count": 42}`
+
+			count, err := eng.DocCount()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(count).To(Equal(uint64(42)))
+		})
+	})
+
+	Describe("List", func() {
+		It("returns the resources of a space", func() {
+			responses["POST /ocis-resources/_search"] = `{"hits": {"total": {"value": 2}, "hits": [
+				{"_id": "1$2!3", "_source": {"ID": "1$2!3", "RootID": "1$2!2"}, "sort": ["1$2!3"]},
+				{"_id": "1$2!4", "_source": {"ID": "1$2!4", "RootID": "1$2!2", "Deleted": true}, "sort": ["1$2!4"]}
+			]}}`
+
+			resources, err := eng.List("1$2!2")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resources).To(HaveLen(2))
+			Expect(resources[1].Deleted).To(BeTrue())
+
+			req := lastRequest(http.MethodPost, "/ocis-resources/_search")
+			Expect(req.body["query"]).To(Equal(map[string]interface{}{"term": map[string]interface{}{"RootID": "1$2!2"}}))
+		})
+	})
+})
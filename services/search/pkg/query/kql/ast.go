@@ -0,0 +1,111 @@
+// Package kql implements a parser for the keyword query language (KQL) used by the search service.
+//
+// The parser turns a query string into an abstract syntax tree, search engines compile the tree
+// into their native queries.
+package kql
+
+import (
+	"strings"
+)
+
+// Node is a node of the abstract syntax tree
+type Node interface {
+	String() string
+}
+
+// And matches if all of its nodes match
+type And struct {
+	Nodes []Node
+}
+
+// String returns the query representation of the node
+func (n And) String() string {
+	return join(n.Nodes, " AND ")
+}
+
+// Or matches if any of its nodes matches
+type Or struct {
+	Nodes []Node
+}
+
+// String returns the query representation of the node
+func (n Or) String() string {
+	return join(n.Nodes, " OR ")
+}
+
+// Not matches if its node does not match
+type Not struct {
+	Node Node
+}
+
+// String returns the query representation of the node
+func (n Not) String() string {
+	return "NOT " + group(n.Node)
+}
+
+// Restriction matches a value against a field. Free text restrictions have an empty field.
+type Restriction struct {
+	Field    string
+	Operator Operator
+	// Value holds the unescaped value. If Wildcard is set, it is a wildcard pattern instead in which
+	// literal wildcards and backslashes are escaped with a backslash.
+	Value string
+	// Phrase is set if the value was quoted
+	Phrase bool
+	// Wildcard is set if the value contains unescaped wildcards ('*' or '?')
+	Wildcard bool
+}
+
+// String returns the query representation of the node
+func (n Restriction) String() string {
+	v := n.Value
+	if !n.Wildcard {
+		v = EscapeWildcards(v)
+	}
+	if n.Phrase {
+		v = `"` + strings.ReplaceAll(v, `"`, `\"`) + `"`
+	}
+	if n.Field == "" {
+		return v
+	}
+	return n.Field + string(n.Operator) + v
+}
+
+// Operator compares the value of a field with the value of a restriction
+type Operator string
+
+const (
+	// OpEqual matches equal values, ':' and '=' in the query
+	OpEqual Operator = ":"
+	// OpNotEqual matches values which are not equal
+	OpNotEqual Operator = "<>"
+	// OpLess matches values less than the given one
+	OpLess Operator = "<"
+	// OpLessEqual matches values less than or equal to the given one
+	OpLessEqual Operator = "<="
+	// OpGreater matches values greater than the given one
+	OpGreater Operator = ">"
+	// OpGreaterEqual matches values greater than or equal to the given one
+	OpGreaterEqual Operator = ">="
+)
+
+// Comparison returns true if the operator compares values instead of matching them
+func (o Operator) Comparison() bool {
+	return o != OpEqual && o != OpNotEqual
+}
+
+func join(nodes []Node, sep string) string {
+	parts := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		parts = append(parts, group(n))
+	}
+	return strings.Join(parts, sep)
+}
+
+func group(n Node) string {
+	switch n.(type) {
+	case And, Or:
+		return "(" + n.String() + ")"
+	}
+	return n.String()
+}
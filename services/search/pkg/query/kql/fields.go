@@ -0,0 +1,328 @@
+package kql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The fields known to the query language. Unknown fields are passed to the engines as they are.
+const (
+	FieldName      = "name"
+	FieldContent   = "content"
+	FieldTags      = "tags"
+	FieldMediaType = "mediatype"
+	FieldMtime     = "mtime"
+	FieldSize      = "size"
+	FieldType      = "type"
+	FieldID        = "id"
+	FieldRootID    = "rootid"
+	FieldParentID  = "parentid"
+	FieldPath      = "path"
+	FieldHidden    = "hidden"
+	FieldDeleted   = "deleted"
+	FieldLanguage  = "language"
+
+	FieldAudioAlbum         = "audio.album"
+	FieldAudioArtist        = "audio.artist"
+	FieldAudioDuration      = "audio.duration"
+	FieldImageWidth         = "image.width"
+	FieldImageHeight        = "image.height"
+	FieldLocationLatitude   = "location.latitude"
+	FieldLocationLongitude  = "location.longitude"
+	FieldPhotoCameraMake    = "photo.cameramake"
+	FieldPhotoCameraModel   = "photo.cameramodel"
+	FieldPhotoTakenDateTime = "photo.takendatetime"
+	FieldOfficeAuthor       = "office.author"
+	FieldOfficePageCount    = "office.pagecount"
+)
+
+// FieldKind is the kind of the values of a metadata field
+type FieldKind int
+
+// The kinds of metadata fields
+const (
+	// KindText fields are matched case insensitive
+	KindText FieldKind = iota
+	// KindNumber fields support comparisons with numbers
+	KindNumber
+	// KindDate fields support comparisons with dates
+	KindDate
+)
+
+// _metadataFields are the fields of the image, photo, location, audio and office metadata of files
+var _metadataFields = map[string]FieldKind{
+	FieldAudioAlbum:         KindText,
+	FieldAudioArtist:        KindText,
+	FieldAudioDuration:      KindNumber,
+	FieldImageWidth:         KindNumber,
+	FieldImageHeight:        KindNumber,
+	FieldLocationLatitude:   KindNumber,
+	FieldLocationLongitude:  KindNumber,
+	FieldPhotoCameraMake:    KindText,
+	FieldPhotoCameraModel:   KindText,
+	FieldPhotoTakenDateTime: KindDate,
+	FieldOfficeAuthor:       KindText,
+	FieldOfficePageCount:    KindNumber,
+}
+
+var _fieldAliases = map[string]string{
+	"name":                 FieldName,
+	"content":              FieldContent,
+	"tag":                  FieldTags,
+	"tags":                 FieldTags,
+	"mediatype":            FieldMediaType,
+	"mimetype":             FieldMediaType,
+	"mtime":                FieldMtime,
+	"lastmodifieddatetime": FieldMtime,
+	"size":                 FieldSize,
+	"type":                 FieldType,
+	"id":                   FieldID,
+	"rootid":               FieldRootID,
+	"parentid":             FieldParentID,
+	"path":                 FieldPath,
+	"hidden":               FieldHidden,
+	"deleted":              FieldDeleted,
+	"language":             FieldLanguage,
+	"audio.album":          FieldAudioAlbum,
+	"audio.artist":         FieldAudioArtist,
+	"audio.duration":       FieldAudioDuration,
+	"image.width":          FieldImageWidth,
+	"image.height":         FieldImageHeight,
+	"location.latitude":    FieldLocationLatitude,
+	"location.longitude":   FieldLocationLongitude,
+	"photo.cameramake":     FieldPhotoCameraMake,
+	"photo.cameramodel":    FieldPhotoCameraModel,
+	"photo.takendatetime":  FieldPhotoTakenDateTime,
+	"office.author":        FieldOfficeAuthor,
+	"author":               FieldOfficeAuthor,
+	"office.pagecount":     FieldOfficePageCount,
+	"pagecount":            FieldOfficePageCount,
+}
+
+// _mediaTypes maps the media type groups which can be used with the mediatype field to mime types
+var _mediaTypes = map[string][]string{
+	"folder": {"httpd/unix-directory"},
+	"document": {
+		"application/msword",
+		"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		"application/vnd.oasis.opendocument.text",
+		"application/rtf",
+		"text/plain",
+		"text/markdown",
+	},
+	"spreadsheet": {
+		"application/vnd.ms-excel",
+		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		"application/vnd.oasis.opendocument.spreadsheet",
+		"text/csv",
+	},
+	"presentation": {
+		"application/vnd.ms-powerpoint",
+		"application/vnd.openxmlformats-officedocument.presentationml.presentation",
+		"application/vnd.oasis.opendocument.presentation",
+	},
+	"pdf":   {"application/pdf"},
+	"image": {"image/*"},
+	"video": {"video/*"},
+	"audio": {"audio/*"},
+	"archive": {
+		"application/zip",
+		"application/gzip",
+		"application/x-tar",
+		"application/x-7z-compressed",
+		"application/x-rar-compressed",
+	},
+}
+
+// _sizeUnits are the size suffixes accepted by the size field
+var _sizeUnits = []struct {
+	suffix string
+	factor float64
+}{
+	{"KB", 1 << 10},
+	{"MB", 1 << 20},
+	{"GB", 1 << 30},
+	{"TB", 1 << 40},
+	{"B", 1},
+}
+
+// CanonicalField returns the canonical name of a known field, unknown fields are returned as they are
+func CanonicalField(field string) string {
+	if f, ok := _fieldAliases[strings.ToLower(field)]; ok {
+		return f
+	}
+	return field
+}
+
+// MetadataFieldKind returns the kind of a metadata field, ok is false for other fields
+func MetadataFieldKind(field string) (kind FieldKind, ok bool) {
+	kind, ok = _metadataFields[field]
+	return kind, ok
+}
+
+// MediaTypes returns the mime type patterns matching a value of the mediatype field.
+// The patterns may contain wildcards.
+func MediaTypes(value string) []string {
+	if types, ok := _mediaTypes[strings.ToLower(value)]; ok {
+		return types
+	}
+	if strings.Contains(value, "/") || strings.ContainsAny(value, "*?") {
+		return []string{value}
+	}
+	return []string{"*" + value + "*"}
+}
+
+// Range is a range of values, a nil bound is unbounded
+type Range[T any] struct {
+	Min, Max                   *T
+	MinInclusive, MaxInclusive bool
+}
+
+// newRange returns the range of an operator applied to a span of values. The span either is a
+// single point, or the half open interval [lower, upper), like a day for date values.
+func newRange[T any](op Operator, lower, upper T, point bool) Range[T] {
+	switch op {
+	case OpGreater:
+		if point {
+			return Range[T]{Min: &lower}
+		}
+		return Range[T]{Min: &upper, MinInclusive: true}
+	case OpGreaterEqual:
+		return Range[T]{Min: &lower, MinInclusive: true}
+	case OpLess:
+		return Range[T]{Max: &lower}
+	case OpLessEqual:
+		return Range[T]{Max: &upper, MaxInclusive: point}
+	default:
+		// the caller negates the range for OpNotEqual
+		return Range[T]{Min: &lower, MinInclusive: true, Max: &upper, MaxInclusive: point}
+	}
+}
+
+// TimeRange returns the time range matched by a date restriction. Dates without a time match the
+// whole day, years like 2023 and months like 2023-01 match the whole year or month, "today" and
+// "yesterday" are relative to now.
+func (n Restriction) TimeRange(now time.Time) (Range[time.Time], error) {
+	switch strings.ToLower(n.Value) {
+	case "today":
+		day := truncateDay(now)
+		return newRange(n.Operator, day, day.AddDate(0, 0, 1), false), nil
+	case "yesterday":
+		day := truncateDay(now).AddDate(0, 0, -1)
+		return newRange(n.Operator, day, day.AddDate(0, 0, 1), false), nil
+	}
+
+	if day, err := time.ParseInLocation("2006-01-02", n.Value, time.UTC); err == nil {
+		return newRange(n.Operator, day, day.AddDate(0, 0, 1), false), nil
+	}
+
+	if month, err := time.ParseInLocation("2006-01", n.Value, time.UTC); err == nil {
+		return newRange(n.Operator, month, month.AddDate(0, 1, 0), false), nil
+	}
+
+	if year, err := time.ParseInLocation("2006", n.Value, time.UTC); err == nil {
+		return newRange(n.Operator, year, year.AddDate(1, 0, 0), false), nil
+	}
+
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02T15:04"} {
+		if t, err := time.ParseInLocation(layout, n.Value, time.UTC); err == nil {
+			return newRange(n.Operator, t, t, true), nil
+		}
+	}
+
+	return Range[time.Time]{}, fmt.Errorf("invalid date '%s', expected a date like 2023-01-31 or 2023-01-31T12:00:00Z", n.Value)
+}
+
+// NumberRange returns the range matched by a numeric restriction. Sizes may use the units KB, MB, GB and TB.
+func (n Restriction) NumberRange() (Range[float64], error) {
+	v := strings.ToUpper(n.Value)
+	factor := 1.0
+	if n.Field == FieldSize {
+		for _, u := range _sizeUnits {
+			if strings.HasSuffix(v, u.suffix) {
+				v, factor = strings.TrimSpace(strings.TrimSuffix(v, u.suffix)), u.factor
+				break
+			}
+		}
+	}
+
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		if n.Field == FieldSize {
+			return Range[float64]{}, fmt.Errorf("invalid size '%s', expected a number like 100, 10KB or 1.5MB", n.Value)
+		}
+		return Range[float64]{}, fmt.Errorf("invalid number '%s'", n.Value)
+	}
+	f *= factor
+	return newRange(n.Operator, f, f, true), nil
+}
+
+// Bool returns the value of a boolean restriction
+func (n Restriction) Bool() (bool, error) {
+	switch strings.ToLower(n.Value) {
+	case "t", "true":
+		return true, nil
+	case "f", "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid boolean '%s', expected true or false", n.Value)
+}
+
+// validate checks that the value and the operator of a restriction fit to its field
+func (n Restriction) validate() error {
+	if kind, ok := MetadataFieldKind(n.Field); ok {
+		return n.validateMetadata(kind)
+	}
+
+	var err error
+	switch n.Field {
+	case FieldMtime:
+		_, err = n.TimeRange(time.Now())
+	case FieldSize, FieldType:
+		_, err = n.NumberRange()
+	case FieldHidden, FieldDeleted:
+		if n.Operator.Comparison() {
+			return fmt.Errorf("operator '%s' is not supported for field '%s'", n.Operator, n.Field)
+		}
+		_, err = n.Bool()
+	case "", FieldName, FieldContent, FieldTags, FieldMediaType, FieldID, FieldRootID, FieldParentID, FieldPath, FieldLanguage:
+		if n.Operator.Comparison() {
+			if n.Field == "" {
+				return fmt.Errorf("operator '%s' requires a field", n.Operator)
+			}
+			return fmt.Errorf("operator '%s' is not supported for field '%s'", n.Operator, n.Field)
+		}
+	default:
+		if n.Operator.Comparison() {
+			_, nerr := n.NumberRange()
+			_, terr := n.TimeRange(time.Now())
+			if nerr != nil && terr != nil {
+				return fmt.Errorf("operator '%s' requires a number or a date, got '%s'", n.Operator, n.Value)
+			}
+		}
+	}
+	return err
+}
+
+// validateMetadata checks that the value and the operator of a restriction fit to the kind of its metadata field
+func (n Restriction) validateMetadata(kind FieldKind) error {
+	var err error
+	switch kind {
+	case KindNumber:
+		_, err = n.NumberRange()
+	case KindDate:
+		_, err = n.TimeRange(time.Now())
+	default:
+		if n.Operator.Comparison() {
+			return fmt.Errorf("operator '%s' is not supported for field '%s'", n.Operator, n.Field)
+		}
+	}
+	return err
+}
+
+func truncateDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
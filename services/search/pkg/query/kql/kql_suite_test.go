@@ -0,0 +1,13 @@
+package kql_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestKQL(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "KQL Suite")
+}
@@ -0,0 +1,487 @@
+package kql
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// SyntaxError is returned for queries which can't be parsed
+type SyntaxError struct {
+	// Pos is the position of the error in the query, starting at 1
+	Pos int
+	Msg string
+}
+
+// Error implements the error interface
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at position %d: %s", e.Pos, e.Msg)
+}
+
+type tokenType int
+
+const (
+	tEOF tokenType = iota
+	tWord
+	tPhrase
+	tField
+	tAnd
+	tOr
+	tNot
+	tPlus
+	tMinus
+	tLParen
+	tRParen
+)
+
+type token struct {
+	typ tokenType
+	pos int
+	// value is the unescaped value of words and phrases or the name of fields
+	value    string
+	wildcard bool
+	op       Operator
+}
+
+func (t token) String() string {
+	switch t.typ {
+	case tEOF:
+		return "end of query"
+	case tPhrase:
+		return `"` + t.value + `"`
+	case tField:
+		return "'" + t.value + string(t.op) + "'"
+	case tAnd:
+		return "'AND'"
+	case tOr:
+		return "'OR'"
+	case tNot:
+		return "'NOT'"
+	case tPlus:
+		return "'+'"
+	case tMinus:
+		return "'-'"
+	case tLParen:
+		return "'('"
+	case tRParen:
+		return "')'"
+	}
+	return "'" + t.value + "'"
+}
+
+// Parse parses a query into its abstract syntax tree.
+//
+// Terms are combined with AND, OR and NOT, which bind in the order NOT, AND, OR. Terms which
+// are just separated by whitespace are combined with AND, except for restrictions on the same
+// field, which are combined with OR. '-' negates a term, '+' is accepted as a required marker.
+// Parentheses group terms, 'field:(a b)' restricts all terms of the group to the field.
+func Parse(q string) (Node, error) {
+	p := &parser{s: &scanner{input: []rune(q)}}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if p.tok.typ == tEOF {
+		return nil, &SyntaxError{Pos: 1, Msg: "empty query"}
+	}
+
+	n, err := p.parseOr("")
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.typ != tEOF {
+		return nil, p.errorf("unexpected %s", p.tok)
+	}
+	return n, nil
+}
+
+type parser struct {
+	s   *scanner
+	tok token
+}
+
+func (p *parser) next() (err error) {
+	p.tok, err = p.s.scan(false)
+	return err
+}
+
+func (p *parser) nextValue() (err error) {
+	p.tok, err = p.s.scan(true)
+	return err
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Pos: p.tok.pos + 1, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) parseOr(field string) (Node, error) {
+	n, err := p.parseAnd(field)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []Node{n}
+	for p.tok.typ == tOr {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		n, err := p.parseAnd(field)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return Or{Nodes: nodes}, nil
+}
+
+func (p *parser) parseAnd(field string) (Node, error) {
+	var nodes []Node
+	// open collects adjacent restrictions on the same field, which are combined with OR
+	var open []Node
+	flush := func() {
+		switch len(open) {
+		case 0:
+		case 1:
+			nodes = append(nodes, open[0])
+		default:
+			nodes = append(nodes, Or{Nodes: open})
+		}
+		open = nil
+	}
+
+	explicit := false
+	for {
+		n, required, err := p.parseUnary(field)
+		if err != nil {
+			return nil, err
+		}
+
+		r, ok := n.(Restriction)
+		mergeable := ok && !required && !explicit && r.Field != ""
+		if !mergeable || len(open) == 0 || open[0].(Restriction).Field != r.Field {
+			flush()
+		}
+		if ok && !required && r.Field != "" {
+			open = append(open, n)
+		} else {
+			nodes = append(nodes, n)
+		}
+
+		switch p.tok.typ {
+		case tAnd:
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			explicit = true
+		case tWord, tPhrase, tField, tNot, tPlus, tMinus, tLParen:
+			explicit = false
+		default:
+			flush()
+			if len(nodes) == 1 {
+				return nodes[0], nil
+			}
+			return And{Nodes: nodes}, nil
+		}
+	}
+}
+
+// parseUnary parses a single term, required is set if the term was marked with '+'
+func (p *parser) parseUnary(field string) (n Node, required bool, err error) {
+	switch p.tok.typ {
+	case tNot, tMinus:
+		if err := p.next(); err != nil {
+			return nil, false, err
+		}
+		n, _, err := p.parseUnary(field)
+		if err != nil {
+			return nil, false, err
+		}
+		return Not{Node: n}, false, nil
+	case tPlus:
+		if err := p.next(); err != nil {
+			return nil, false, err
+		}
+		n, _, err := p.parseUnary(field)
+		return n, true, err
+	case tLParen:
+		n, err := p.parseGroup(field)
+		return n, false, err
+	case tField:
+		return p.parseRestriction()
+	case tWord, tPhrase:
+		r := Restriction{
+			Field:    field,
+			Operator: OpEqual,
+			Value:    p.tok.value,
+			Phrase:   p.tok.typ == tPhrase,
+			Wildcard: p.tok.wildcard,
+		}
+		if err := r.validate(); err != nil {
+			return nil, false, p.errorf("%s", err)
+		}
+		return r, false, p.next()
+	case tEOF:
+		return nil, false, p.errorf("unexpected end of query, expected a term")
+	}
+	return nil, false, p.errorf("unexpected %s, expected a term", p.tok)
+}
+
+func (p *parser) parseGroup(field string) (Node, error) {
+	start := p.tok
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if p.tok.typ == tRParen {
+		return nil, p.errorf("empty group")
+	}
+
+	n, err := p.parseOr(field)
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.typ != tRParen {
+		if p.tok.typ == tEOF {
+			return nil, &SyntaxError{Pos: start.pos + 1, Msg: "missing closing parenthesis"}
+		}
+		return nil, p.errorf("unexpected %s, expected ')'", p.tok)
+	}
+	return n, p.next()
+}
+
+func (p *parser) parseRestriction() (Node, bool, error) {
+	field := p.tok
+	if err := p.nextValue(); err != nil {
+		return nil, false, err
+	}
+
+	switch p.tok.typ {
+	case tLParen:
+		if field.op != OpEqual {
+			return nil, false, p.errorf("operator '%s' can't be used with a group", field.op)
+		}
+		n, err := p.parseGroup(field.value)
+		return n, false, err
+	case tWord, tPhrase:
+		r := Restriction{
+			Field:    field.value,
+			Operator: field.op,
+			Value:    p.tok.value,
+			Phrase:   p.tok.typ == tPhrase,
+			Wildcard: p.tok.wildcard,
+		}
+		if err := r.validate(); err != nil {
+			return nil, false, p.errorf("%s", err)
+		}
+		return r, false, p.next()
+	}
+	return nil, false, &SyntaxError{Pos: field.pos + 1, Msg: fmt.Sprintf("missing value for field '%s'", field.value)}
+}
+
+type scanner struct {
+	input []rune
+	pos   int
+}
+
+func (s *scanner) peek(offset int) rune {
+	if s.pos+offset >= len(s.input) {
+		return 0
+	}
+	return s.input[s.pos+offset]
+}
+
+// scan returns the next token. Values directly follow a field, they may contain ':' and the
+// comparison characters.
+func (s *scanner) scan(value bool) (token, error) {
+	if !value {
+		for s.pos < len(s.input) && unicode.IsSpace(s.input[s.pos]) {
+			s.pos++
+		}
+	}
+
+	start := s.pos
+	if s.pos >= len(s.input) {
+		return token{typ: tEOF, pos: start}, nil
+	}
+
+	switch r := s.input[s.pos]; {
+	case value && unicode.IsSpace(r):
+		return token{typ: tEOF, pos: start}, nil
+	case r == '(':
+		s.pos++
+		return token{typ: tLParen, pos: start}, nil
+	case r == ')':
+		s.pos++
+		return token{typ: tRParen, pos: start}, nil
+	case r == '"':
+		return s.scanPhrase()
+	case !value && (r == '+' || r == '-') && s.startsTerm(1):
+		s.pos++
+		if r == '+' {
+			return token{typ: tPlus, pos: start}, nil
+		}
+		return token{typ: tMinus, pos: start}, nil
+	}
+
+	if !value {
+		if t, ok := s.scanField(); ok {
+			return t, nil
+		}
+	}
+
+	t, err := s.scanWord(value)
+	if err != nil || value {
+		return t, err
+	}
+	switch t.value {
+	case "AND", "OR", "NOT":
+		if s.pos-start != len(t.value) {
+			// the keyword was escaped
+			break
+		}
+		t.typ = map[string]tokenType{"AND": tAnd, "OR": tOr, "NOT": tNot}[t.value]
+	}
+	return t, nil
+}
+
+// startsTerm returns true if a term starts at the given offset
+func (s *scanner) startsTerm(offset int) bool {
+	r := s.peek(offset)
+	return r != 0 && r != ')' && !unicode.IsSpace(r)
+}
+
+// scanField scans a field name and its operator, the position is not changed if there is no field
+func (s *scanner) scanField() (token, bool) {
+	end := s.pos
+	for end < len(s.input) && (unicode.IsLetter(s.input[end]) || unicode.IsDigit(s.input[end]) || s.input[end] == '_' || s.input[end] == '.') {
+		end++
+	}
+	if end == s.pos {
+		return token{}, false
+	}
+
+	name := string(s.input[s.pos:end])
+	op, n := scanOperator(s.input[end:])
+	if n == 0 {
+		return token{}, false
+	}
+
+	t := token{typ: tField, pos: s.pos, value: CanonicalField(name), op: op}
+	s.pos = end + n
+	return t, true
+}
+
+// scanOperator returns the operator at the start of the input and its length. The legacy
+// form 'field:>=value' is accepted as well.
+func scanOperator(in []rune) (Operator, int) {
+	at := func(i int) rune {
+		if i < len(in) {
+			return in[i]
+		}
+		return 0
+	}
+
+	offset := 0
+	if at(0) == ':' {
+		offset = 1
+	}
+
+	switch {
+	case at(offset) == '<' && at(offset+1) == '>':
+		return OpNotEqual, offset + 2
+	case at(offset) == '<' && at(offset+1) == '=':
+		return OpLessEqual, offset + 2
+	case at(offset) == '>' && at(offset+1) == '=':
+		return OpGreaterEqual, offset + 2
+	case at(offset) == '<':
+		return OpLess, offset + 1
+	case at(offset) == '>':
+		return OpGreater, offset + 1
+	case at(offset) == '=':
+		return OpEqual, offset + 1
+	case offset == 1:
+		return OpEqual, 1
+	}
+	return "", 0
+}
+
+func (s *scanner) scanWord(value bool) (token, error) {
+	t := token{typ: tWord, pos: s.pos}
+	var v valueBuilder
+	for s.pos < len(s.input) {
+		r := s.input[s.pos]
+		if unicode.IsSpace(r) || r == '(' || r == ')' {
+			break
+		}
+		if r == '\\' {
+			if s.pos+1 >= len(s.input) {
+				return token{}, &SyntaxError{Pos: s.pos + 1, Msg: "unfinished escape sequence"}
+			}
+			v.writeEscaped(s.input[s.pos+1])
+			s.pos += 2
+			continue
+		}
+		v.write(r)
+		s.pos++
+	}
+	t.value, t.wildcard = v.String(), v.wildcard
+	return t, nil
+}
+
+func (s *scanner) scanPhrase() (token, error) {
+	t := token{typ: tPhrase, pos: s.pos}
+	var v valueBuilder
+	s.pos++
+	for s.pos < len(s.input) {
+		r := s.input[s.pos]
+		switch {
+		case r == '"':
+			s.pos++
+			t.value, t.wildcard = v.String(), v.wildcard
+			return t, nil
+		case r == '\\' && s.pos+1 < len(s.input):
+			v.writeEscaped(s.input[s.pos+1])
+			s.pos += 2
+			continue
+		}
+		v.write(r)
+		s.pos++
+	}
+	return token{}, &SyntaxError{Pos: t.pos + 1, Msg: "missing closing quote"}
+}
+
+// valueBuilder collects the value of a word or phrase. Escaped characters are taken literally. Once the value
+// contains an unescaped wildcard, it is returned as wildcard pattern in which literal wildcards and
+// backslashes are escaped.
+type valueBuilder struct {
+	literal  strings.Builder
+	pattern  strings.Builder
+	wildcard bool
+}
+
+func (v *valueBuilder) write(r rune) {
+	if r == '*' || r == '?' {
+		v.wildcard = true
+		v.pattern.WriteRune(r)
+		v.literal.WriteRune(r)
+		return
+	}
+	v.writeEscaped(r)
+}
+
+func (v *valueBuilder) writeEscaped(r rune) {
+	v.literal.WriteRune(r)
+	if isWildcardMeta(r) {
+		v.pattern.WriteRune('\\')
+	}
+	v.pattern.WriteRune(r)
+}
+
+func (v *valueBuilder) String() string {
+	if v.wildcard {
+		return v.pattern.String()
+	}
+	return v.literal.String()
+}
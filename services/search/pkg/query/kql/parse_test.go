@@ -0,0 +1,178 @@
+package kql_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/owncloud/ocis/v2/services/search/pkg/query/kql"
+)
+
+var _ = Describe("Parse", func() {
+	DescribeTable("builds the syntax tree",
+		func(query string, expected kql.Node) {
+			n, err := kql.Parse(query)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(expected))
+		},
+		Entry("free text", "foo",
+			kql.Restriction{Operator: kql.OpEqual, Value: "foo"},
+		),
+		Entry("implicit and", "foo bar",
+			kql.And{Nodes: []kql.Node{
+				kql.Restriction{Operator: kql.OpEqual, Value: "foo"},
+				kql.Restriction{Operator: kql.OpEqual, Value: "bar"},
+			}},
+		),
+		Entry("implicit or of the same field", "tag:foo tags:bar name:baz",
+			kql.And{Nodes: []kql.Node{
+				kql.Or{Nodes: []kql.Node{
+					kql.Restriction{Field: kql.FieldTags, Operator: kql.OpEqual, Value: "foo"},
+					kql.Restriction{Field: kql.FieldTags, Operator: kql.OpEqual, Value: "bar"},
+				}},
+				kql.Restriction{Field: kql.FieldName, Operator: kql.OpEqual, Value: "baz"},
+			}},
+		),
+		Entry("required terms", "+tag:foo +tag:bar",
+			kql.And{Nodes: []kql.Node{
+				kql.Restriction{Field: kql.FieldTags, Operator: kql.OpEqual, Value: "foo"},
+				kql.Restriction{Field: kql.FieldTags, Operator: kql.OpEqual, Value: "bar"},
+			}},
+		),
+		Entry("precedence", "a OR b AND NOT c",
+			kql.Or{Nodes: []kql.Node{
+				kql.Restriction{Operator: kql.OpEqual, Value: "a"},
+				kql.And{Nodes: []kql.Node{
+					kql.Restriction{Operator: kql.OpEqual, Value: "b"},
+					kql.Not{Node: kql.Restriction{Operator: kql.OpEqual, Value: "c"}},
+				}},
+			}},
+		),
+		Entry("groups", "(a OR b) -c",
+			kql.And{Nodes: []kql.Node{
+				kql.Or{Nodes: []kql.Node{
+					kql.Restriction{Operator: kql.OpEqual, Value: "a"},
+					kql.Restriction{Operator: kql.OpEqual, Value: "b"},
+				}},
+				kql.Not{Node: kql.Restriction{Operator: kql.OpEqual, Value: "c"}},
+			}},
+		),
+		Entry("field groups", `content:(fox OR "lazy dog")`,
+			kql.Or{Nodes: []kql.Node{
+				kql.Restriction{Field: kql.FieldContent, Operator: kql.OpEqual, Value: "fox"},
+				kql.Restriction{Field: kql.FieldContent, Operator: kql.OpEqual, Value: "lazy dog", Phrase: true},
+			}},
+		),
+		Entry("phrases and escapes", `"a \"b\"" c\ d*`,
+			kql.And{Nodes: []kql.Node{
+				kql.Restriction{Operator: kql.OpEqual, Value: `a "b"`, Phrase: true},
+				kql.Restriction{Operator: kql.OpEqual, Value: "c d*", Wildcard: true},
+			}},
+		),
+		Entry("escaped wildcards", `a\*b a\*b* "c\?d?" e\\f*`,
+			kql.And{Nodes: []kql.Node{
+				kql.Restriction{Operator: kql.OpEqual, Value: "a*b"},
+				kql.Restriction{Operator: kql.OpEqual, Value: `a\*b*`, Wildcard: true},
+				kql.Restriction{Operator: kql.OpEqual, Value: `c\?d?`, Phrase: true, Wildcard: true},
+				kql.Restriction{Operator: kql.OpEqual, Value: `e\\f*`, Wildcard: true},
+			}},
+		),
+		Entry("lowercase keywords", "foo and bar",
+			kql.And{Nodes: []kql.Node{
+				kql.Restriction{Operator: kql.OpEqual, Value: "foo"},
+				kql.Restriction{Operator: kql.OpEqual, Value: "and"},
+				kql.Restriction{Operator: kql.OpEqual, Value: "bar"},
+			}},
+		),
+		Entry("comparisons", "mtime>=2023-01-01 size:<10MB",
+			kql.And{Nodes: []kql.Node{
+				kql.Restriction{Field: kql.FieldMtime, Operator: kql.OpGreaterEqual, Value: "2023-01-01"},
+				kql.Restriction{Field: kql.FieldSize, Operator: kql.OpLess, Value: "10MB"},
+			}},
+		),
+		Entry("metadata fields", "photo.takenDateTime>2022 Author:alice",
+			kql.And{Nodes: []kql.Node{
+				kql.Restriction{Field: kql.FieldPhotoTakenDateTime, Operator: kql.OpGreater, Value: "2022"},
+				kql.Restriction{Field: kql.FieldOfficeAuthor, Operator: kql.OpEqual, Value: "alice"},
+			}},
+		),
+		Entry("values with colons", `+ID:1$2!3 +Mtime:>="2023-05-10T12:00:00Z"`,
+			kql.And{Nodes: []kql.Node{
+				kql.Restriction{Field: kql.FieldID, Operator: kql.OpEqual, Value: "1$2!3"},
+				kql.Restriction{Field: kql.FieldMtime, Operator: kql.OpGreaterEqual, Value: "2023-05-10T12:00:00Z", Phrase: true},
+			}},
+		),
+	)
+
+	DescribeTable("returns syntax errors",
+		func(query string, pos int, msg string) {
+			_, err := kql.Parse(query)
+			Expect(err).To(Equal(&kql.SyntaxError{Pos: pos, Msg: msg}))
+		},
+		Entry("empty query", "  ", 1, "empty query"),
+		Entry("unclosed group", "a (b OR c", 3, "missing closing parenthesis"),
+		Entry("unexpected parenthesis", "a)", 2, "unexpected ')'"),
+		Entry("unclosed phrase", `a "b`, 3, "missing closing quote"),
+		Entry("dangling operator", "a OR", 5, "unexpected end of query, expected a term"),
+		Entry("missing value", "name: foo", 1, "missing value for field 'name'"),
+		Entry("invalid date", "mtime>2023-13-01", 7, "invalid date '2023-13-01', expected a date like 2023-01-31 or 2023-01-31T12:00:00Z"),
+		Entry("invalid size", "size>1XB", 6, "invalid size '1XB', expected a number like 100, 10KB or 1.5MB"),
+		Entry("unsupported operator", "name>foo", 6, "operator '>' is not supported for field 'name'"),
+		Entry("invalid metadata number", "image.width>wide", 13, "invalid number 'wide'"),
+		Entry("unsupported metadata operator", "audio.artist>a", 14, "operator '>' is not supported for field 'audio.artist'"),
+	)
+})
+
+var _ = Describe("Restriction", func() {
+	now := time.Date(2023, 5, 10, 15, 30, 0, 0, time.UTC)
+	day := time.Date(2023, 5, 10, 0, 0, 0, 0, time.UTC)
+	next := day.AddDate(0, 0, 1)
+	year := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	nextYear := year.AddDate(1, 0, 0)
+
+	DescribeTable("TimeRange",
+		func(op kql.Operator, value string, expected kql.Range[time.Time]) {
+			r, err := kql.Restriction{Field: kql.FieldMtime, Operator: op, Value: value}.TimeRange(now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(r).To(Equal(expected))
+		},
+		Entry("a whole day", kql.OpEqual, "2023-05-10", kql.Range[time.Time]{Min: &day, MinInclusive: true, Max: &next}),
+		Entry("today", kql.OpEqual, "today", kql.Range[time.Time]{Min: &day, MinInclusive: true, Max: &next}),
+		Entry("after a day", kql.OpGreater, "2023-05-10", kql.Range[time.Time]{Min: &next, MinInclusive: true}),
+		Entry("until a day", kql.OpLessEqual, "2023-05-10", kql.Range[time.Time]{Max: &next}),
+		Entry("before a day", kql.OpLess, "2023-05-10", kql.Range[time.Time]{Max: &day}),
+		Entry("a whole year", kql.OpEqual, "2023", kql.Range[time.Time]{Min: &year, MinInclusive: true, Max: &nextYear}),
+		Entry("after a year", kql.OpGreater, "2022", kql.Range[time.Time]{Min: &year, MinInclusive: true}),
+		Entry("a point in time", kql.OpGreater, "2023-05-10T00:00:00Z", kql.Range[time.Time]{Min: &day}),
+	)
+
+	It("converts size units", func() {
+		r, err := kql.Restriction{Field: kql.FieldSize, Operator: kql.OpGreaterEqual, Value: "1.5kb"}.NumberRange()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*r.Min).To(Equal(1536.0))
+		Expect(r.Max).To(BeNil())
+	})
+})
+
+var _ = Describe("Wildcards", func() {
+	DescribeTable("restrictions keep their escapes in the query representation",
+		func(query string) {
+			n, err := kql.Parse(query)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n.String()).To(Equal(query))
+		},
+		Entry("literal", `a\*b`),
+		Entry("pattern", `name:a\*b?`),
+		Entry("phrase", `"a\?b"`),
+	)
+
+	DescribeTable("converts patterns into regular expressions",
+		func(pattern string, expected string) {
+			Expect(kql.WildcardRegexp(pattern)).To(Equal(expected))
+		},
+		Entry("wildcards", "a*b?", `a.*b.`),
+		Entry("escaped wildcards", `a\*b\?*`, `a\*b\?.*`),
+		Entry("escaped backslash", `a\\*`, `a\\.*`),
+		Entry("regexp characters", "a.b(c)*", `a\.b\(c\).*`),
+	)
+})
@@ -0,0 +1,48 @@
+package kql
+
+import (
+	"regexp"
+	"strings"
+)
+
+// EscapeWildcards escapes the wildcards and backslashes of a literal value to use it in a wildcard pattern
+func EscapeWildcards(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if isWildcardMeta(r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// WildcardRegexp converts a wildcard pattern into a regular expression for engines which don't support
+// escaping in wildcard queries. '*' matches any sequence of characters, '?' any single character.
+func WildcardRegexp(pattern string) string {
+	var b strings.Builder
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '*':
+			b.WriteString(".*")
+		case r == '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	if escaped {
+		b.WriteString(regexp.QuoteMeta(`\`))
+	}
+	return b.String()
+}
+
+func isWildcardMeta(r rune) bool {
+	return r == '*' || r == '?' || r == '\\'
+}
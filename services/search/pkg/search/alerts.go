@@ -0,0 +1,182 @@
+package search
+
+import (
+	"context"
+	"time"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/v2/pkg/events"
+	"github.com/cs3org/reva/v2/pkg/storagespace"
+	"github.com/owncloud/ocis/v2/ocis-pkg/event"
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	searchmsg "github.com/owncloud/ocis/v2/protogen/gen/ocis/messages/search/v0"
+	searchsvc "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/search/v0"
+	"github.com/owncloud/ocis/v2/services/search/pkg/config"
+	"github.com/owncloud/ocis/v2/services/search/pkg/query/kql"
+)
+
+// Alerter checks uploaded files against the saved searches and notifies
+// the owners of the searches about new matches.
+type Alerter struct {
+	service   *Service
+	store     *SavedSearchStore
+	publisher events.Publisher
+	logger    log.Logger
+}
+
+// NewAlerter returns a new Alerter
+func NewAlerter(s *Service, st *SavedSearchStore, publisher events.Publisher, logger log.Logger) *Alerter {
+	return &Alerter{
+		service:   s,
+		store:     st,
+		publisher: publisher,
+		logger:    logger,
+	}
+}
+
+// Match indexes the uploaded resource and evaluates it against all saved searches with alerts.
+func (a *Alerter) Match(ref *provider.Reference, uID *user.UserId) {
+	alerts, err := a.store.Alerts()
+	if err != nil {
+		a.logger.Error().Err(err).Msg("failed to read the saved searches")
+		return
+	}
+	if len(alerts) == 0 {
+		return
+	}
+
+	ctx, stat, path := a.service.resInfo(uID, ref)
+	if ctx == nil || stat == nil || path == "" {
+		return
+	}
+	info := stat.GetInfo()
+
+	// the file has to be in the index before the queries can be evaluated, the debounced
+	// reindex of the space skips it later on as it did not change in the meantime
+	if err := a.service.indexResource(ctx, info, path); err != nil {
+		a.logger.Error().Err(err).Msg("error adding updating the resource in the index")
+		return
+	}
+
+	id := storagespace.FormatResourceID(*info.GetId())
+	for owner, searches := range alerts {
+		var matched []*searchmsg.SavedSearch
+		for _, ss := range searches {
+			ok, err := a.matches(ctx, ss.GetQuery(), id)
+			if err != nil {
+				a.logger.Error().Err(err).Str("owner", owner).Str("savedSearch", ss.GetId()).Msg("failed to evaluate the saved search")
+				continue
+			}
+			if ok {
+				matched = append(matched, ss)
+			}
+		}
+
+		// only tell users about files they are allowed to see
+		if len(matched) == 0 || !a.canAccess(owner, info.GetId()) {
+			continue
+		}
+
+		for _, ss := range matched {
+			isNew, err := a.store.MarkMatched(owner, ss.GetId(), id)
+			if err != nil {
+				a.logger.Error().Err(err).Str("owner", owner).Str("savedSearch", ss.GetId()).Msg("failed to record the match")
+				continue
+			}
+			if !isNew {
+				continue
+			}
+
+			if err := events.Publish(a.publisher, event.SavedSearchMatched{
+				Owner:           &user.UserId{OpaqueId: owner},
+				SavedSearchID:   ss.GetId(),
+				SavedSearchName: ss.GetName(),
+				ResourceID:      info.GetId(),
+				Filename:        info.GetName(),
+				Email:           ss.GetEmail(),
+				Timestamp:       time.Now(),
+			}); err != nil {
+				a.logger.Error().Err(err).Str("owner", owner).Str("savedSearch", ss.GetId()).Msg("failed to publish the saved search match")
+			}
+		}
+	}
+}
+
+// matches checks if the query of a saved search matches the indexed resource
+func (a *Alerter) matches(ctx context.Context, query, id string) (bool, error) {
+	q, err := kql.Parse(query)
+	if err != nil {
+		return false, err
+	}
+
+	n := kql.And{Nodes: []kql.Node{
+		kql.Restriction{Field: kql.FieldID, Operator: kql.OpEqual, Value: id},
+		q,
+	}}
+	res, err := a.service.engine.Search(ctx, n, &searchsvc.SearchIndexRequest{
+		Query:    n.String(),
+		PageSize: 1,
+	})
+	if err != nil {
+		return false, err
+	}
+	return res.GetTotalMatches() > 0, nil
+}
+
+func (a *Alerter) canAccess(owner string, id *provider.ResourceId) bool {
+	ownerCtx, err := getAuthContext(&user.User{Id: &user.UserId{OpaqueId: owner}}, a.service.gatewaySelector, a.service.secret, a.logger)
+	if err != nil {
+		return false
+	}
+
+	gatewayClient, err := a.service.gatewaySelector.Next()
+	if err != nil {
+		return false
+	}
+
+	res, err := gatewayClient.Stat(ownerCtx, &provider.StatRequest{Ref: &provider.Reference{ResourceId: id}})
+	return err == nil && res.GetStatus().GetCode() == rpc.Code_CODE_OK
+}
+
+// HandleSavedSearchEvents listens to the upload events and checks the
+// uploaded files against the saved searches.
+func HandleSavedSearchEvents(a *Alerter, bus events.Consumer, logger log.Logger, cfg *config.Config) error {
+	evts := []events.Unmarshaller{events.FileUploaded{}}
+	if cfg.Events.AsyncUploads {
+		evts = []events.Unmarshaller{events.UploadReady{}}
+	}
+
+	ch, err := events.Consume(bus, "search-alerts", evts...)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for e := range ch {
+			switch ev := e.Event.(type) {
+			case events.FileUploaded:
+				a.Match(ev.Ref, firstUser(ev.SpaceOwner, ev.Executant))
+			case events.UploadReady:
+				if ev.Failed {
+					continue
+				}
+				a.Match(ev.FileRef, firstUser(ev.SpaceOwner, ev.ExecutingUser.GetId()))
+			default:
+				logger.Debug().Interface("event", e).Msg("ignoring event")
+			}
+		}
+	}()
+
+	return nil
+}
+
+func firstUser(users ...*user.UserId) *user.UserId {
+	for _, u := range users {
+		if u != nil {
+			return u
+		}
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package search_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userv1beta1 "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	sprovider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	revactx "github.com/cs3org/reva/v2/pkg/ctx"
+	"github.com/cs3org/reva/v2/pkg/rgrpc/status"
+	"github.com/cs3org/reva/v2/pkg/rgrpc/todo/pool"
+	cs3mocks "github.com/cs3org/reva/v2/tests/cs3mocks/mocks"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/owncloud/ocis/v2/ocis-pkg/event"
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	searchmsg "github.com/owncloud/ocis/v2/protogen/gen/ocis/messages/search/v0"
+	searchsvc "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/search/v0"
+	"github.com/owncloud/ocis/v2/services/search/pkg/config"
+	"github.com/owncloud/ocis/v2/services/search/pkg/content"
+	contentMocks "github.com/owncloud/ocis/v2/services/search/pkg/content/mocks"
+	engineMocks "github.com/owncloud/ocis/v2/services/search/pkg/engine/mocks"
+	"github.com/owncloud/ocis/v2/services/search/pkg/query/kql"
+	"github.com/owncloud/ocis/v2/services/search/pkg/search"
+	"github.com/stretchr/testify/mock"
+	mEvents "go-micro.dev/v4/events"
+	"go-micro.dev/v4/store"
+	"google.golang.org/grpc"
+	grpcmetadata "google.golang.org/grpc/metadata"
+)
+
+// recordingPublisher keeps the published events
+type recordingPublisher struct {
+	mu     sync.Mutex
+	events []interface{}
+}
+
+func (p *recordingPublisher) Publish(_ string, ev interface{}, _ ...mEvents.PublishOption) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, ev)
+	return nil
+}
+
+var _ = Describe("Alerter", func() {
+	var (
+		alerter       *search.Alerter
+		savedSearches *search.SavedSearchStore
+		publisher     *recordingPublisher
+		gatewayClient *cs3mocks.GatewayAPIClient
+		indexClient   *engineMocks.Engine
+		queries       []kql.Node
+
+		ref = &sprovider.Reference{
+			ResourceId: &sprovider.ResourceId{StorageId: "storageid", SpaceId: "spaceid", OpaqueId: "opaqueid"},
+		}
+		ri = &sprovider.ResourceInfo{
+			Id:   &sprovider.ResourceId{StorageId: "storageid", SpaceId: "spaceid", OpaqueId: "opaqueid"},
+			Name: "invoice.pdf",
+			Path: "invoice.pdf",
+		}
+	)
+
+	// tokenOf returns the user the outgoing context was authenticated for
+	tokenOf := func(ctx context.Context) string {
+		md, _ := grpcmetadata.FromOutgoingContext(ctx)
+		if t := md.Get(revactx.TokenHeader); len(t) > 0 {
+			return t[0]
+		}
+		return ""
+	}
+
+	BeforeEach(func() {
+		pool.RemoveSelector("GatewaySelector" + "com.owncloud.api.gateway")
+		gatewayClient = &cs3mocks.GatewayAPIClient{}
+		gatewaySelector := pool.GetSelector[gateway.GatewayAPIClient](
+			"GatewaySelector",
+			"com.owncloud.api.gateway",
+			func(cc *grpc.ClientConn) gateway.GatewayAPIClient {
+				return gatewayClient
+			},
+		)
+
+		gatewayClient.On("Authenticate", mock.Anything, mock.Anything).Return(func(_ context.Context, req *gateway.AuthenticateRequest, _ ...grpc.CallOption) *gateway.AuthenticateResponse {
+			return &gateway.AuthenticateResponse{Status: status.NewOK(context.Background()), Token: strings.TrimPrefix(req.ClientId, "userid:")}
+		}, nil)
+		// otheruser can't see the uploaded file
+		gatewayClient.On("Stat", mock.MatchedBy(func(ctx context.Context) bool { return tokenOf(ctx) == "otheruser" }), mock.Anything).Return(&sprovider.StatResponse{
+			Status: status.NewNotFound(context.Background(), "not found"),
+		}, nil)
+		gatewayClient.On("Stat", mock.Anything, mock.Anything).Return(&sprovider.StatResponse{
+			Status: status.NewOK(context.Background()),
+			Info:   ri,
+		}, nil)
+		gatewayClient.On("GetPath", mock.Anything, mock.Anything).Return(&sprovider.GetPathResponse{
+			Status: status.NewOK(context.Background()),
+			Path:   ri.Path,
+		}, nil)
+
+		extractor := &contentMocks.Extractor{}
+		extractor.On("Extract", mock.Anything, mock.Anything).Return(content.Document{Name: ri.Name}, nil)
+
+		queries = nil
+		indexClient = &engineMocks.Engine{}
+		indexClient.On("Upsert", mock.Anything, mock.Anything).Return(nil)
+		indexClient.On("Search", mock.Anything, mock.Anything, mock.Anything).Return(func(_ context.Context, n kql.Node, _ *searchsvc.SearchIndexRequest) *searchsvc.SearchIndexResponse {
+			queries = append(queries, n)
+			if strings.Contains(n.String(), "invoice") {
+				return &searchsvc.SearchIndexResponse{TotalMatches: 1}
+			}
+			return &searchsvc.SearchIndexResponse{}
+		}, nil)
+
+		s := search.NewService(gatewaySelector, indexClient, extractor, log.NopLogger(), &config.Config{})
+		savedSearches = search.NewSavedSearchStore(store.NewMemoryStore(), "search", "saved-searches", "saved-search-matches")
+		publisher = &recordingPublisher{}
+		alerter = search.NewAlerter(s, savedSearches, publisher, log.NopLogger())
+	})
+
+	It("notifies the owners of matching saved searches about new matches once", func() {
+		invoices, err := savedSearches.Save("user", &searchmsg.SavedSearch{Name: "invoices", Query: "name:invoice*", Alerts: true, Email: true})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = savedSearches.Save("user", &searchmsg.SavedSearch{Name: "reports", Query: "name:report*", Alerts: true})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = savedSearches.Save("user", &searchmsg.SavedSearch{Name: "all invoices", Query: "name:invoice*"})
+		Expect(err).ToNot(HaveOccurred())
+
+		uploader := &userv1beta1.UserId{OpaqueId: "uploader"}
+		alerter.Match(ref, uploader)
+		alerter.Match(ref, uploader)
+
+		Expect(publisher.events).To(HaveLen(1))
+		ev := publisher.events[0].(event.SavedSearchMatched)
+		Expect(ev.Owner.GetOpaqueId()).To(Equal("user"))
+		Expect(ev.SavedSearchID).To(Equal(invoices.Id))
+		Expect(ev.SavedSearchName).To(Equal("invoices"))
+		Expect(ev.ResourceID).To(Equal(ri.Id))
+		Expect(ev.Filename).To(Equal("invoice.pdf"))
+		Expect(ev.Email).To(BeTrue())
+
+		// the resource is indexed before it is matched
+		indexClient.AssertCalled(GinkgoT(), "Upsert", "storageid$spaceid!opaqueid", mock.Anything)
+	})
+
+	It("restricts the query of the saved search to the uploaded resource", func() {
+		_, err := savedSearches.Save("user", &searchmsg.SavedSearch{Name: "invoices", Query: "name:invoice* OR tags:finance", Alerts: true})
+		Expect(err).ToNot(HaveOccurred())
+
+		alerter.Match(ref, &userv1beta1.UserId{OpaqueId: "uploader"})
+
+		Expect(queries).To(HaveLen(1))
+		Expect(queries[0]).To(Equal(kql.And{Nodes: []kql.Node{
+			kql.Restriction{Field: kql.FieldID, Operator: kql.OpEqual, Value: "storageid$spaceid!opaqueid"},
+			kql.Or{Nodes: []kql.Node{
+				kql.Restriction{Field: kql.FieldName, Operator: kql.OpEqual, Value: "invoice*", Wildcard: true},
+				kql.Restriction{Field: kql.FieldTags, Operator: kql.OpEqual, Value: "finance"},
+			}},
+		}}))
+	})
+
+	It("doesn't tell users about files they can't access", func() {
+		ss, err := savedSearches.Save("otheruser", &searchmsg.SavedSearch{Name: "invoices", Query: "name:invoice*", Alerts: true})
+		Expect(err).ToNot(HaveOccurred())
+
+		alerter.Match(ref, &userv1beta1.UserId{OpaqueId: "uploader"})
+
+		Expect(publisher.events).To(BeEmpty())
+		// the match isn't recorded for users without access
+		isNew, err := savedSearches.MarkMatched("otheruser", ss.Id, "storageid$spaceid!opaqueid")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(isNew).To(BeTrue())
+	})
+
+	It("does nothing without saved searches with alerts", func() {
+		_, err := savedSearches.Save("user", &searchmsg.SavedSearch{Name: "invoices", Query: "name:invoice*"})
+		Expect(err).ToNot(HaveOccurred())
+
+		alerter.Match(ref, &userv1beta1.UserId{OpaqueId: "uploader"})
+
+		Expect(publisher.events).To(BeEmpty())
+		indexClient.AssertNotCalled(GinkgoT(), "Upsert", mock.Anything, mock.Anything)
+		gatewayClient.AssertNotCalled(GinkgoT(), "Authenticate", mock.Anything, mock.Anything)
+	})
+})
@@ -0,0 +1,154 @@
+package search
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/v2/pkg/storage/utils/walker"
+	"github.com/cs3org/reva/v2/pkg/storagespace"
+	"github.com/cs3org/reva/v2/pkg/utils"
+	searchsvc "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/search/v0"
+	"github.com/owncloud/ocis/v2/services/search/pkg/engine"
+)
+
+// The kinds of inconsistencies reported by CheckIndex
+const (
+	// InconsistencyMissing is reported for resources which are not in the index
+	InconsistencyMissing = "missing"
+	// InconsistencyStale is reported for resources which changed since they were indexed
+	InconsistencyStale = "stale"
+	// InconsistencyOrphaned is reported for indexed resources which don't exist anymore
+	InconsistencyOrphaned = "orphaned"
+	// InconsistencyDeleted is reported for existing resources which are marked as deleted in the index
+	InconsistencyDeleted = "deleted"
+)
+
+// _checkProgressInterval is the number of resources after which the progress of a check is logged
+const _checkProgressInterval = 1000
+
+// CheckIndex compares the index of a space with the resources in the storage. If repair is set,
+// missing, stale and wrongly deleted resources are reindexed and orphaned ones are purged.
+//
+// Indexed resources which are marked as deleted and don't exist in the storage anymore are
+// expected to be in the trash bin, they are not reported.
+func (s *Service) CheckIndex(spaceID *provider.StorageSpaceId, uID *user.UserId, repair bool) (*searchsvc.CheckIndexResponse, error) {
+	ownerCtx, err := getAuthContext(&user.User{Id: uID}, s.gatewaySelector, s.secret, s.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	rootID, err := spaceRootID(spaceID)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("invalid space id")
+		return nil, err
+	}
+
+	indexed, err := s.engine.List(storagespace.FormatResourceID(rootID))
+	if err != nil {
+		return nil, err
+	}
+	resources := make(map[string]*engine.Resource, len(indexed))
+	for _, r := range indexed {
+		resources[r.ID] = r
+	}
+
+	res := &searchsvc.CheckIndexResponse{}
+	report := func(kind, id, path string, fix func() error) {
+		inconsistency := &searchsvc.IndexInconsistency{Kind: kind, Id: id, Path: path}
+		if repair {
+			if err := fix(); err != nil {
+				s.logger.Error().Err(err).Str("id", id).Str("kind", kind).Msg("failed to repair the index")
+			} else {
+				inconsistency.Repaired = true
+			}
+		}
+		res.Inconsistencies = append(res.Inconsistencies, inconsistency)
+	}
+
+	w := walker.NewWalker(s.gatewaySelector)
+	err = w.Walk(ownerCtx, &rootID, func(wd string, info *provider.ResourceInfo, err error) error {
+		if err != nil {
+			s.logger.Error().Err(err).Msg("error walking the tree")
+			return err
+		}
+
+		if info == nil {
+			return nil
+		}
+
+		res.Checked++
+		if res.Checked%_checkProgressInterval == 0 {
+			s.logger.Info().Str("space", spaceID.OpaqueId).Int32("checked", res.Checked).Msg("checking the index")
+		}
+
+		id := storagespace.FormatResourceID(*info.Id)
+		path := utils.MakeRelativePath(filepath.Join(wd, info.Path))
+		reindex := func() error {
+			return s.indexResource(ownerCtx, info, path)
+		}
+
+		r, ok := resources[id]
+		delete(resources, id)
+		switch {
+		case !ok:
+			report(InconsistencyMissing, id, path, reindex)
+		case r.Deleted:
+			report(InconsistencyDeleted, id, path, reindex)
+		case r.Mtime != formatMtime(info) || (wd != "" && r.Path != path):
+			// the path of the space root is resolved differently when it is indexed
+			report(InconsistencyStale, id, path, reindex)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	orphans := make([]*engine.Resource, 0, len(resources))
+	for _, r := range resources {
+		if !r.Deleted {
+			orphans = append(orphans, r)
+		}
+	}
+	sort.Slice(orphans, func(i, j int) bool {
+		return orphans[i].Path < orphans[j].Path
+	})
+	for _, r := range orphans {
+		id := r.ID
+		report(InconsistencyOrphaned, id, r.Path, func() error {
+			return s.engine.Purge(id)
+		})
+	}
+
+	s.logger.Info().Str("space", spaceID.OpaqueId).Int32("checked", res.Checked).Int("inconsistencies", len(res.Inconsistencies)).Bool("repair", repair).Msg("checked the index")
+	if repair {
+		logDocCount(s.engine, s.logger)
+	}
+
+	return res, nil
+}
+
+// spaceRootID returns the id of the root of a space
+func spaceRootID(spaceID *provider.StorageSpaceId) (provider.ResourceId, error) {
+	rootID, err := storagespace.ParseID(spaceID.GetOpaqueId())
+	if err != nil {
+		return provider.ResourceId{}, err
+	}
+	if rootID.StorageId == "" || rootID.SpaceId == "" {
+		return provider.ResourceId{}, fmt.Errorf("invalid space id")
+	}
+	rootID.OpaqueId = rootID.SpaceId
+	return rootID, nil
+}
+
+// formatMtime formats the modification time of a resource like it is stored in the index
+func formatMtime(info *provider.ResourceInfo) string {
+	if info.GetMtime() == nil {
+		return ""
+	}
+	return utils.TSToTime(info.GetMtime()).UTC().Format(time.RFC3339Nano)
+}
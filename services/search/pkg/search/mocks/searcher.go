@@ -18,6 +18,29 @@ type Searcher struct {
 	mock.Mock
 }
 
+// CheckIndex provides a mock function with given fields: rID, uID, repair
+func (_m *Searcher) CheckIndex(rID *providerv1beta1.StorageSpaceId, uID *userv1beta1.UserId, repair bool) (*v0.CheckIndexResponse, error) {
+	ret := _m.Called(rID, uID, repair)
+
+	var r0 *v0.CheckIndexResponse
+	if rf, ok := ret.Get(0).(func(*providerv1beta1.StorageSpaceId, *userv1beta1.UserId, bool) *v0.CheckIndexResponse); ok {
+		r0 = rf(rID, uID, repair)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*v0.CheckIndexResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*providerv1beta1.StorageSpaceId, *userv1beta1.UserId, bool) error); ok {
+		r1 = rf(rID, uID, repair)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // IndexSpace provides a mock function with given fields: rID, uID
 func (_m *Searcher) IndexSpace(rID *providerv1beta1.StorageSpaceId, uID *userv1beta1.UserId) error {
 	ret := _m.Called(rID, uID)
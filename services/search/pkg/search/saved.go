@@ -0,0 +1,222 @@
+package search
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/cs3org/reva/v2/pkg/errtypes"
+	"github.com/google/uuid"
+	searchmsg "github.com/owncloud/ocis/v2/protogen/gen/ocis/messages/search/v0"
+	"github.com/owncloud/ocis/v2/services/search/pkg/query/kql"
+	"go-micro.dev/v4/store"
+)
+
+const (
+	_savedSearchesPrefix = "searches/"
+
+	// _maxMatches is the number of matches remembered per saved search. Older matches are forgotten,
+	// their resources are reported again if they match again.
+	_maxMatches = 1000
+)
+
+// SavedSearchStore persists the saved searches of the users and the matches they were notified about
+type SavedSearchStore struct {
+	store    store.Store
+	database string
+	table    string
+	// matchesTable keeps the recently matched resources per saved search apart from the saved
+	// searches, so that they can't evict the saved searches from size limited stores
+	matchesTable string
+	// serializes the read-modify-write cycles on the list of a user and on the matches of a search
+	mu sync.Mutex
+}
+
+// NewSavedSearchStore returns a SavedSearchStore keeping the saved searches and their matches in
+// two tables of the given store. The tables are passed with every call, stores like ocmem only keep
+// them apart that way.
+func NewSavedSearchStore(st store.Store, database, table, matchesTable string) *SavedSearchStore {
+	return &SavedSearchStore{store: st, database: database, table: table, matchesTable: matchesTable}
+}
+
+// List returns the saved searches of a user
+func (s *SavedSearchStore) List(userID string) ([]*searchmsg.SavedSearch, error) {
+	recs, err := s.store.Read(_savedSearchesPrefix+userID, store.ReadFrom(s.database, s.table))
+	switch {
+	case err == store.ErrNotFound:
+		return []*searchmsg.SavedSearch{}, nil
+	case err != nil:
+		return nil, err
+	case len(recs) == 0:
+		return []*searchmsg.SavedSearch{}, nil
+	}
+
+	var searches []*searchmsg.SavedSearch
+	if err := json.Unmarshal(recs[0].Value, &searches); err != nil {
+		return nil, err
+	}
+	return searches, nil
+}
+
+// Get returns a single saved search of a user
+func (s *SavedSearchStore) Get(userID, id string) (*searchmsg.SavedSearch, error) {
+	searches, err := s.List(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ss := range searches {
+		if ss.GetId() == id {
+			return ss, nil
+		}
+	}
+	return nil, errtypes.NotFound("saved search " + id)
+}
+
+// Save creates a saved search or, if the id is set, replaces the existing one
+func (s *SavedSearchStore) Save(userID string, ss *searchmsg.SavedSearch) (*searchmsg.SavedSearch, error) {
+	if strings.TrimSpace(ss.GetName()) == "" {
+		return nil, errtypes.BadRequest("the saved search needs a name")
+	}
+	if _, err := kql.Parse(ss.GetQuery()); err != nil {
+		return nil, errtypes.BadRequest(err.Error())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	searches, err := s.List(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if ss.GetId() == "" {
+		ss.Id = uuid.New().String()
+		searches = append(searches, ss)
+	} else {
+		found := false
+		for i := range searches {
+			if searches[i].GetId() == ss.GetId() {
+				searches[i] = ss
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errtypes.NotFound("saved search " + ss.GetId())
+		}
+	}
+
+	if err := s.write(userID, searches); err != nil {
+		return nil, err
+	}
+	return ss, nil
+}
+
+// Delete removes a saved search of a user together with its recorded matches
+func (s *SavedSearchStore) Delete(userID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	searches, err := s.List(userID)
+	if err != nil {
+		return err
+	}
+
+	remaining := make([]*searchmsg.SavedSearch, 0, len(searches))
+	for _, ss := range searches {
+		if ss.GetId() != id {
+			remaining = append(remaining, ss)
+		}
+	}
+	if len(remaining) == len(searches) {
+		return errtypes.NotFound("saved search " + id)
+	}
+
+	if err := s.write(userID, remaining); err != nil {
+		return err
+	}
+
+	if err := s.store.Delete(matchesKey(userID, id), store.DeleteFrom(s.database, s.matchesTable)); err != nil && err != store.ErrNotFound {
+		return err
+	}
+	return nil
+}
+
+// Alerts returns all saved searches which notify about new matches, grouped by the id of their owner
+func (s *SavedSearchStore) Alerts() (map[string][]*searchmsg.SavedSearch, error) {
+	keys, err := s.store.List(store.ListFrom(s.database, s.table), store.ListPrefix(_savedSearchesPrefix))
+	if err != nil {
+		return nil, err
+	}
+
+	alerts := map[string][]*searchmsg.SavedSearch{}
+	for _, k := range keys {
+		userID := strings.TrimPrefix(k, _savedSearchesPrefix)
+		searches, err := s.List(userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, ss := range searches {
+			if ss.GetAlerts() {
+				alerts[userID] = append(alerts[userID], ss)
+			}
+		}
+	}
+	return alerts, nil
+}
+
+// MarkMatched records that the owner of a saved search was notified about a resource matching it.
+// It returns false if that happened before already. Only the last _maxMatches matches of a saved
+// search are remembered.
+func (s *SavedSearchStore) MarkMatched(userID, id, resourceID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := matchesKey(userID, id)
+	var matched []string
+	recs, err := s.store.Read(key, store.ReadFrom(s.database, s.matchesTable))
+	switch {
+	case err != nil && err != store.ErrNotFound:
+		return false, err
+	case err == nil && len(recs) > 0:
+		if err := json.Unmarshal(recs[0].Value, &matched); err != nil {
+			return false, err
+		}
+	}
+
+	for _, m := range matched {
+		if m == resourceID {
+			return false, nil
+		}
+	}
+
+	matched = append(matched, resourceID)
+	if len(matched) > _maxMatches {
+		matched = matched[len(matched)-_maxMatches:]
+	}
+	b, err := json.Marshal(matched)
+	if err != nil {
+		return false, err
+	}
+	return true, s.store.Write(&store.Record{Key: key, Value: b}, store.WriteTo(s.database, s.matchesTable))
+}
+
+func (s *SavedSearchStore) write(userID string, searches []*searchmsg.SavedSearch) error {
+	if len(searches) == 0 {
+		if err := s.store.Delete(_savedSearchesPrefix+userID, store.DeleteFrom(s.database, s.table)); err != nil && err != store.ErrNotFound {
+			return err
+		}
+		return nil
+	}
+
+	b, err := json.Marshal(searches)
+	if err != nil {
+		return err
+	}
+	return s.store.Write(&store.Record{Key: _savedSearchesPrefix + userID, Value: b}, store.WriteTo(s.database, s.table))
+}
+
+func matchesKey(userID, id string) string {
+	return userID + "/" + id
+}
@@ -0,0 +1,133 @@
+package search_test
+
+import (
+	"fmt"
+
+	"github.com/cs3org/reva/v2/pkg/errtypes"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	searchmsg "github.com/owncloud/ocis/v2/protogen/gen/ocis/messages/search/v0"
+	"github.com/owncloud/ocis/v2/services/search/pkg/search"
+	"go-micro.dev/v4/store"
+)
+
+var _ = Describe("SavedSearchStore", func() {
+	var st *search.SavedSearchStore
+
+	BeforeEach(func() {
+		st = search.NewSavedSearchStore(store.NewMemoryStore(), "search", "saved-searches", "saved-search-matches")
+	})
+
+	It("saves and lists the searches per user", func() {
+		ss, err := st.Save("user", &searchmsg.SavedSearch{Name: "invoices", Query: "name:invoice*", Alerts: true})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ss.Id).ToNot(BeEmpty())
+
+		_, err = st.Save("otheruser", &searchmsg.SavedSearch{Name: "reports", Query: "report"})
+		Expect(err).ToNot(HaveOccurred())
+
+		searches, err := st.List("user")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(searches).To(HaveLen(1))
+		Expect(searches[0].Name).To(Equal("invoices"))
+
+		searches, err = st.List("unknown")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(searches).To(BeEmpty())
+	})
+
+	It("updates existing searches", func() {
+		ss, err := st.Save("user", &searchmsg.SavedSearch{Name: "invoices", Query: "invoice"})
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = st.Save("user", &searchmsg.SavedSearch{Id: ss.Id, Name: "invoices", Query: "invoice mtime>2023-01-01"})
+		Expect(err).ToNot(HaveOccurred())
+
+		got, err := st.Get("user", ss.Id)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(got.Query).To(Equal("invoice mtime>2023-01-01"))
+
+		_, err = st.Save("user", &searchmsg.SavedSearch{Id: "unknown", Name: "foo", Query: "foo"})
+		Expect(err).To(BeAssignableToTypeOf(errtypes.NotFound("")))
+	})
+
+	It("rejects invalid searches", func() {
+		_, err := st.Save("user", &searchmsg.SavedSearch{Name: "broken", Query: "a (b OR c"})
+		Expect(err).To(BeAssignableToTypeOf(errtypes.BadRequest("")))
+
+		_, err = st.Save("user", &searchmsg.SavedSearch{Query: "foo"})
+		Expect(err).To(BeAssignableToTypeOf(errtypes.BadRequest("")))
+	})
+
+	It("deletes searches", func() {
+		ss, err := st.Save("user", &searchmsg.SavedSearch{Name: "invoices", Query: "invoice"})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(st.Delete("user", ss.Id)).To(Succeed())
+		_, err = st.Get("user", ss.Id)
+		Expect(err).To(BeAssignableToTypeOf(errtypes.NotFound("")))
+		Expect(st.Delete("user", ss.Id)).To(BeAssignableToTypeOf(errtypes.NotFound("")))
+	})
+
+	It("returns the searches with alerts", func() {
+		a, err := st.Save("user", &searchmsg.SavedSearch{Name: "invoices", Query: "invoice", Alerts: true})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = st.Save("user", &searchmsg.SavedSearch{Name: "reports", Query: "report"})
+		Expect(err).ToNot(HaveOccurred())
+		b, err := st.Save("otheruser", &searchmsg.SavedSearch{Name: "photos", Query: "mediatype:image", Alerts: true, Email: true})
+		Expect(err).ToNot(HaveOccurred())
+
+		alerts, err := st.Alerts()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(alerts).To(HaveLen(2))
+		Expect(alerts["user"]).To(ConsistOf(a))
+		Expect(alerts["otheruser"]).To(ConsistOf(b))
+	})
+
+	It("notifies about every match only once", func() {
+		ss, err := st.Save("user", &searchmsg.SavedSearch{Name: "invoices", Query: "invoice", Alerts: true})
+		Expect(err).ToNot(HaveOccurred())
+
+		isNew, err := st.MarkMatched("user", ss.Id, "storageid$spaceid!opaqueid")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(isNew).To(BeTrue())
+
+		isNew, err = st.MarkMatched("user", ss.Id, "storageid$spaceid!opaqueid")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(isNew).To(BeFalse())
+
+		// matches are forgotten together with the search
+		Expect(st.Delete("user", ss.Id)).To(Succeed())
+		isNew, err = st.MarkMatched("user", ss.Id, "storageid$spaceid!opaqueid")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(isNew).To(BeTrue())
+	})
+
+	It("keeps the matches apart from the searches and remembers only the latest ones", func() {
+		mem := store.NewMemoryStore()
+		st = search.NewSavedSearchStore(mem, "search", "saved-searches", "saved-search-matches")
+		ss, err := st.Save("user", &searchmsg.SavedSearch{Name: "invoices", Query: "invoice", Alerts: true})
+		Expect(err).ToNot(HaveOccurred())
+
+		for i := 0; i <= 1000; i++ {
+			isNew, err := st.MarkMatched("user", ss.Id, fmt.Sprintf("storageid$spaceid!%d", i))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(isNew).To(BeTrue())
+		}
+
+		keys, err := mem.List(store.ListFrom("search", "saved-searches"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(keys).To(ConsistOf("searches/user"))
+		keys, err = mem.List(store.ListFrom("search", "saved-search-matches"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(keys).To(ConsistOf("user/" + ss.Id))
+
+		// the oldest match was forgotten
+		isNew, err := st.MarkMatched("user", ss.Id, "storageid$spaceid!0")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(isNew).To(BeTrue())
+		isNew, err = st.MarkMatched("user", ss.Id, "storageid$spaceid!1000")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(isNew).To(BeFalse())
+	})
+})
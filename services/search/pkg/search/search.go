@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
 	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
@@ -16,6 +17,7 @@ import (
 	"github.com/cs3org/reva/v2/pkg/utils"
 	"github.com/owncloud/ocis/v2/ocis-pkg/log"
 	searchmsg "github.com/owncloud/ocis/v2/protogen/gen/ocis/messages/search/v0"
+	searchsvc "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/search/v0"
 	"github.com/owncloud/ocis/v2/services/search/pkg/engine"
 	"google.golang.org/grpc/metadata"
 )
@@ -56,7 +58,84 @@ func (ma matchArray) Swap(i, j int) {
 	ma[i], ma[j] = ma[j], ma[i]
 }
 func (ma matchArray) Less(i, j int) bool {
-	return ma[i].Score > ma[j].Score
+	if ma[i].Score != ma[j].Score {
+		return ma[i].Score > ma[j].Score
+	}
+	// order matches with equal scores by id to keep the pages stable
+	a, b := ma[i].GetEntity(), ma[j].GetEntity()
+	if ka, kb := matchKey(a.GetId()), matchKey(b.GetId()); ka != kb {
+		return ka < kb
+	}
+	return a.GetRef().GetPath() < b.GetRef().GetPath()
+}
+
+func matchKey(id *searchmsg.ResourceID) string {
+	return id.GetStorageId() + "$" + id.GetSpaceId() + "!" + id.GetOpaqueId()
+}
+
+// spaceResult is the search result of a single space
+type spaceResult struct {
+	space string
+	res   *searchsvc.SearchIndexResponse
+}
+
+// facetMerger sums up the facets of several spaces. The spaces only return their FacetShardSize most
+// frequent values, so the sums of the mime type and tag values are approximate.
+type facetMerger struct {
+	names  []string
+	values map[string]map[string]int32
+	order  map[string][]string
+}
+
+func (m *facetMerger) add(facets []*searchmsg.Facet) {
+	for _, f := range facets {
+		for _, v := range f.GetValues() {
+			m.addValue(f.GetName(), v.GetValue(), v.GetCount())
+		}
+	}
+}
+
+func (m *facetMerger) addValue(name, value string, count int32) {
+	if m.values == nil {
+		m.values = map[string]map[string]int32{}
+		m.order = map[string][]string{}
+	}
+	if _, ok := m.values[name]; !ok {
+		m.names = append(m.names, name)
+		m.values[name] = map[string]int32{}
+	}
+	if _, ok := m.values[name][value]; !ok {
+		m.order[name] = append(m.order[name], value)
+	}
+	m.values[name][value] += count
+}
+
+// facets returns the merged facets. The values are sorted by count, except for the
+// buckets of the mtime facet which keep their order.
+func (m *facetMerger) facets() []*searchmsg.Facet {
+	facets := make([]*searchmsg.Facet, 0, len(m.names))
+	for _, name := range m.names {
+		values := m.order[name]
+		if name != engine.FacetMtime {
+			sort.SliceStable(values, func(i, j int) bool {
+				ci, cj := m.values[name][values[i]], m.values[name][values[j]]
+				if ci != cj {
+					return ci > cj
+				}
+				return values[i] < values[j]
+			})
+			if len(values) > engine.FacetSize {
+				values = values[:engine.FacetSize]
+			}
+		}
+
+		f := &searchmsg.Facet{Name: name, Values: make([]*searchmsg.FacetValue, 0, len(values))}
+		for _, v := range values {
+			f.Values = append(f.Values, &searchmsg.FacetValue{Value: v, Count: m.values[name][v]})
+		}
+		facets = append(facets, f)
+	}
+	return facets
 }
 
 func logDocCount(engine engine.Engine, logger log.Logger) {
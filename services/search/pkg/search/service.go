@@ -3,6 +3,7 @@ package search
 import (
 	"context"
 	"fmt"
+	"math"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -25,6 +26,7 @@ import (
 	"github.com/owncloud/ocis/v2/services/search/pkg/config"
 	"github.com/owncloud/ocis/v2/services/search/pkg/content"
 	"github.com/owncloud/ocis/v2/services/search/pkg/engine"
+	"github.com/owncloud/ocis/v2/services/search/pkg/query/kql"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -39,6 +41,7 @@ const (
 type Searcher interface {
 	Search(ctx context.Context, req *searchsvc.SearchRequest) (*searchsvc.SearchResponse, error)
 	IndexSpace(rID *provider.StorageSpaceId, uID *user.UserId) error
+	CheckIndex(rID *provider.StorageSpaceId, uID *user.UserId, repair bool) (*searchsvc.CheckIndexResponse, error)
 	TrashItem(rID *provider.ResourceId)
 	UpsertItem(ref *provider.Reference, uID *user.UserId)
 	RestoreItem(ref *provider.Reference, uID *user.UserId)
@@ -75,6 +78,29 @@ func (s *Service) Search(ctx context.Context, req *searchsvc.SearchRequest) (*se
 	if req.Query == "" {
 		return nil, errtypes.BadRequest("empty query provided")
 	}
+	q, err := kql.Parse(req.Query)
+	if err != nil {
+		return nil, errtypes.BadRequest(err.Error())
+	}
+	offset, err := engine.ParsePageToken(req.PageToken)
+	if err != nil {
+		return nil, errtypes.BadRequest(err.Error())
+	}
+	limit := req.PageSize
+	if limit == 0 {
+		limit = 200
+	}
+	if limit < -1 {
+		return nil, errtypes.BadRequest(fmt.Sprintf("invalid page size '%d'", limit))
+	}
+	// every space has to return the matches of all pages up to the requested one to merge them
+	size := int32(-1)
+	if limit != -1 {
+		if offset > math.MaxInt32-int(limit) {
+			return nil, errtypes.BadRequest(fmt.Sprintf("invalid page token '%s'", req.PageToken))
+		}
+		size = int32(offset) + limit
+	}
 	s.logger.Debug().Str("query", req.Query).Msg("performing a search")
 
 	gatewayClient, err := s.gatewaySelector.Next()
@@ -129,7 +155,7 @@ func (s *Service) Search(ctx context.Context, req *searchsvc.SearchRequest) (*se
 
 	errg, ctx := errgroup.WithContext(ctx)
 	work := make(chan *provider.StorageSpace, len(spaces))
-	results := make(chan *searchsvc.SearchIndexResponse, len(spaces))
+	results := make(chan spaceResult, len(spaces))
 
 	// Distribute work
 	errg.Go(func() error {
@@ -152,13 +178,18 @@ func (s *Service) Search(ctx context.Context, req *searchsvc.SearchRequest) (*se
 	for i := 0; i < numWorkers; i++ {
 		errg.Go(func() error {
 			for space := range work {
-				res, err := s.searchIndex(ctx, req, space, mountpointMap[space.Id.OpaqueId])
+				res, err := s.searchIndex(ctx, req, q, size, space, mountpointMap[space.Id.OpaqueId])
 				if err != nil && err != errSkipSpace {
 					return err
 				}
 
+				id := space.GetId().GetOpaqueId()
+				if mountpointID, ok := mountpointMap[id]; ok {
+					id = mountpointID
+				}
+
 				select {
-				case results <- res:
+				case results <- spaceResult{space: id, res: res}:
 				case <-ctx.Done():
 					return ctx.Err()
 				}
@@ -173,44 +204,53 @@ func (s *Service) Search(ctx context.Context, req *searchsvc.SearchRequest) (*se
 		close(results)
 	}()
 
-	responses := make([]*searchsvc.SearchIndexResponse, len(spaces))
-	i := 0
+	responses := make([]spaceResult, 0, len(spaces))
 	for r := range results {
-		responses[i] = r
-		i++
+		responses = append(responses, r)
 	}
 
 	if err := errg.Wait(); err != nil {
 		return nil, err
 	}
 
-	for _, res := range responses {
-		if res == nil {
+	var facets facetMerger
+	for _, r := range responses {
+		if r.res == nil {
 			continue
 		}
-		total += res.TotalMatches
-		for _, match := range res.Matches {
-			matches = append(matches, match)
+		total += r.res.TotalMatches
+		matches = append(matches, r.res.Matches...)
+		if req.Facets {
+			facets.add(r.res.Facets)
+			if r.res.TotalMatches > 0 {
+				facets.addValue(engine.FacetSpace, r.space, r.res.TotalMatches)
+			}
 		}
 	}
 
-	// compile one sorted list of matches from all spaces and apply the limit if needed
+	// compile one sorted list of matches from all spaces and cut out the requested page
 	sort.Sort(matches)
-	limit := req.PageSize
-	if limit == 0 {
-		limit = 200
-	}
-	if int32(len(matches)) > limit && limit != -1 {
-		matches = matches[0:limit]
+	switch {
+	case offset >= len(matches):
+		matches = matchArray{}
+	case limit == -1 || offset+int(limit) > len(matches):
+		matches = matches[offset:]
+	default:
+		matches = matches[offset : offset+int(limit)]
 	}
 
-	return &searchsvc.SearchResponse{
-		Matches:      matches,
-		TotalMatches: total,
-	}, nil
+	res := &searchsvc.SearchResponse{
+		Matches:       matches,
+		TotalMatches:  total,
+		NextPageToken: engine.NextPageToken(offset, len(matches), uint64(total)),
+	}
+	if req.Facets {
+		res.Facets = facets.facets()
+	}
+	return res, nil
 }
 
-func (s *Service) searchIndex(ctx context.Context, req *searchsvc.SearchRequest, space *provider.StorageSpace, mountpointID string) (*searchsvc.SearchIndexResponse, error) {
+func (s *Service) searchIndex(ctx context.Context, req *searchsvc.SearchRequest, q kql.Node, size int32, space *provider.StorageSpace, mountpointID string) (*searchsvc.SearchIndexResponse, error) {
 	if req.Ref != nil &&
 		(req.Ref.ResourceId.StorageId != space.Root.StorageId ||
 			req.Ref.ResourceId.SpaceId != space.Root.SpaceId ||
@@ -281,10 +321,11 @@ func (s *Service) searchIndex(ctx context.Context, req *searchsvc.SearchRequest,
 			ResourceId: searchRootID,
 			Path:       mountpointPrefix,
 		},
-		PageSize: req.PageSize,
+		PageSize: size,
+		Facets:   req.Facets,
 	}
 	start := time.Now()
-	res, err := s.engine.Search(ctx, searchRequest)
+	res, err := s.engine.Search(ctx, q, searchRequest)
 	duration := time.Since(start)
 	if err != nil {
 		s.logger.Error().Err(err).Str("duration", fmt.Sprint(duration)).Str("space", space.Id.OpaqueId).Msg("failed to search the index")
@@ -320,16 +361,11 @@ func (s *Service) IndexSpace(spaceID *provider.StorageSpaceId, uID *user.UserId)
 		return err
 	}
 
-	rootID, err := storagespace.ParseID(spaceID.OpaqueId)
+	rootID, err := spaceRootID(spaceID)
 	if err != nil {
 		s.logger.Error().Err(err).Msg("invalid space id")
 		return err
 	}
-	if rootID.StorageId == "" || rootID.SpaceId == "" {
-		s.logger.Error().Err(err).Msg("invalid space id")
-		return fmt.Errorf("invalid space id")
-	}
-	rootID.OpaqueId = rootID.SpaceId
 
 	w := walker.NewWalker(s.gatewaySelector)
 	err = w.Walk(ownerCtx, &rootID, func(wd string, info *provider.ResourceInfo, err error) error {
@@ -348,7 +384,7 @@ func (s *Service) IndexSpace(spaceID *provider.StorageSpaceId, uID *user.UserId)
 		}
 		s.logger.Debug().Str("path", ref.Path).Msg("Walking tree")
 
-		searchRes, err := s.engine.Search(ownerCtx, &searchsvc.SearchIndexRequest{
+		searchRes, err := s.engine.Search(ownerCtx, nil, &searchsvc.SearchIndexRequest{
 			Query: "+ID:" + storagespace.FormatResourceID(*info.Id) + ` +Mtime:>="` + utils.TSToTime(info.Mtime).Format(time.RFC3339Nano) + `"`,
 		})
 
@@ -390,34 +426,38 @@ func (s *Service) UpsertItem(ref *provider.Reference, uID *user.UserId) {
 		return
 	}
 
-	doc, err := s.extractor.Extract(ctx, stat.Info)
+	if err := s.indexResource(ctx, stat.Info, path); err != nil {
+		s.logger.Error().Err(err).Msg("error adding updating the resource in the index")
+	} else {
+		logDocCount(s.engine, s.logger)
+	}
+}
+
+// indexResource extracts the document of a resource and adds or updates it in the index
+func (s *Service) indexResource(ctx context.Context, info *provider.ResourceInfo, path string) error {
+	doc, err := s.extractor.Extract(ctx, info)
 	if err != nil {
-		s.logger.Error().Err(err).Msg("failed to extract resource content")
-		return
+		return fmt.Errorf("failed to extract resource content: %w", err)
 	}
 
 	r := engine.Resource{
-		ID: storagespace.FormatResourceID(*stat.Info.Id),
+		ID: storagespace.FormatResourceID(*info.Id),
 		RootID: storagespace.FormatResourceID(provider.ResourceId{
-			StorageId: stat.Info.Id.StorageId,
-			OpaqueId:  stat.Info.Id.SpaceId,
-			SpaceId:   stat.Info.Id.SpaceId,
+			StorageId: info.Id.StorageId,
+			OpaqueId:  info.Id.SpaceId,
+			SpaceId:   info.Id.SpaceId,
 		}),
 		Path:     utils.MakeRelativePath(path),
-		Type:     uint64(stat.Info.Type),
+		Type:     uint64(info.Type),
 		Document: doc,
 	}
 	r.Hidden = strings.HasPrefix(r.Path, ".")
 
-	if parentID := stat.GetInfo().GetParentId(); parentID != nil {
+	if parentID := info.GetParentId(); parentID != nil {
 		r.ParentID = storagespace.FormatResourceID(*parentID)
 	}
 
-	if err = s.engine.Upsert(r.ID, r); err != nil {
-		s.logger.Error().Err(err).Msg("error adding updating the resource in the index")
-	} else {
-		logDocCount(s.engine, s.logger)
-	}
+	return s.engine.Upsert(r.ID, r)
 }
 
 // RestoreItem makes the item available again.
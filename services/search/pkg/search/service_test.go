@@ -8,8 +8,10 @@ import (
 	sprovider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	typesv1beta1 "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	revactx "github.com/cs3org/reva/v2/pkg/ctx"
+	"github.com/cs3org/reva/v2/pkg/errtypes"
 	"github.com/cs3org/reva/v2/pkg/rgrpc/status"
 	"github.com/cs3org/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/v2/pkg/storagespace"
 	cs3mocks "github.com/cs3org/reva/v2/tests/cs3mocks/mocks"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -19,7 +21,9 @@ import (
 	"github.com/owncloud/ocis/v2/services/search/pkg/config"
 	"github.com/owncloud/ocis/v2/services/search/pkg/content"
 	contentMocks "github.com/owncloud/ocis/v2/services/search/pkg/content/mocks"
+	"github.com/owncloud/ocis/v2/services/search/pkg/engine"
 	engineMocks "github.com/owncloud/ocis/v2/services/search/pkg/engine/mocks"
+	"github.com/owncloud/ocis/v2/services/search/pkg/query/kql"
 	"github.com/owncloud/ocis/v2/services/search/pkg/search"
 	"github.com/stretchr/testify/mock"
 	"google.golang.org/grpc"
@@ -122,13 +126,69 @@ var _ = Describe("Searchprovider", func() {
 			}, nil)
 			extractor.On("Extract", mock.Anything, mock.Anything, mock.Anything).Return(content.Document{}, nil)
 			indexClient.On("Upsert", mock.Anything, mock.Anything).Return(nil)
-			indexClient.On("Search", mock.Anything, mock.Anything).Return(&searchsvc.SearchIndexResponse{}, nil)
+			indexClient.On("Search", mock.Anything, mock.Anything, mock.Anything).Return(&searchsvc.SearchIndexResponse{}, nil)
 
 			err := s.IndexSpace(&sprovider.StorageSpaceId{OpaqueId: "storageid$spaceid!spaceid"}, user.Id)
 			Expect(err).ShouldNot(HaveOccurred())
 		})
 	})
 
+	Describe("CheckIndex", func() {
+		var (
+			spaceID = &sprovider.StorageSpaceId{OpaqueId: "storageid$spaceid!spaceid"}
+			riID    string
+			orphan  = &engine.Resource{ID: "storageid$spaceid!orphan", RootID: "storageid$spaceid!spaceid", Path: "./gone.pdf"}
+			trashed = &engine.Resource{ID: "storageid$spaceid!trashed", RootID: "storageid$spaceid!spaceid", Path: "./trashed.pdf", Deleted: true}
+		)
+
+		BeforeEach(func() {
+			riID = storagespace.FormatResourceID(*ri.Id)
+			gatewayClient.On("GetUserByClaim", mock.Anything, mock.Anything).Return(&userv1beta1.GetUserByClaimResponse{
+				Status: status.NewOK(context.Background()),
+				User:   user,
+			}, nil)
+		})
+
+		It("reports missing and orphaned resources", func() {
+			indexClient.On("List", "storageid$spaceid!spaceid").Return([]*engine.Resource{orphan, trashed}, nil)
+
+			res, err := s.CheckIndex(spaceID, user.Id, false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.Checked).To(Equal(int32(1)))
+			Expect(res.Inconsistencies).To(HaveLen(2))
+			Expect(res.Inconsistencies[0].Kind).To(Equal(search.InconsistencyMissing))
+			Expect(res.Inconsistencies[0].Id).To(Equal(riID))
+			Expect(res.Inconsistencies[0].Repaired).To(BeFalse())
+			Expect(res.Inconsistencies[1].Kind).To(Equal(search.InconsistencyOrphaned))
+			Expect(res.Inconsistencies[1].Id).To(Equal(orphan.ID))
+			indexClient.AssertNotCalled(GinkgoT(), "Upsert", mock.Anything, mock.Anything)
+			indexClient.AssertNotCalled(GinkgoT(), "Purge", mock.Anything)
+		})
+
+		It("repairs stale and orphaned resources", func() {
+			stale := &engine.Resource{ID: riID, RootID: "storageid$spaceid!spaceid", Path: "./foo.pdf", Document: content.Document{Mtime: "1970-01-01T00:00:00Z"}}
+			indexClient.On("List", "storageid$spaceid!spaceid").Return([]*engine.Resource{stale, orphan}, nil)
+			extractor.On("Extract", mock.Anything, mock.Anything, mock.Anything).Return(content.Document{}, nil)
+			indexClient.On("Upsert", riID, mock.Anything).Return(nil)
+			indexClient.On("Purge", orphan.ID).Return(nil)
+
+			res, err := s.CheckIndex(spaceID, user.Id, true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.Inconsistencies).To(HaveLen(2))
+			Expect(res.Inconsistencies[0].Kind).To(Equal(search.InconsistencyStale))
+			Expect(res.Inconsistencies[0].Repaired).To(BeTrue())
+			Expect(res.Inconsistencies[1].Kind).To(Equal(search.InconsistencyOrphaned))
+			Expect(res.Inconsistencies[1].Repaired).To(BeTrue())
+			indexClient.AssertCalled(GinkgoT(), "Upsert", riID, mock.Anything)
+			indexClient.AssertCalled(GinkgoT(), "Purge", orphan.ID)
+		})
+
+		It("fails for invalid space ids", func() {
+			_, err := s.CheckIndex(&sprovider.StorageSpaceId{OpaqueId: "invalid"}, user.Id, false)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
 	Describe("Search", func() {
 		It("fails when an empty query is given", func() {
 			res, err := s.Search(ctx, &searchsvc.SearchRequest{
@@ -138,13 +198,22 @@ var _ = Describe("Searchprovider", func() {
 			Expect(res).To(BeNil())
 		})
 
+		It("fails with a bad request when the query is invalid", func() {
+			res, err := s.Search(ctx, &searchsvc.SearchRequest{
+				Query: "name:(foo OR bar",
+			})
+			Expect(err).To(BeAssignableToTypeOf(errtypes.BadRequest("")))
+			Expect(err.Error()).To(ContainSubstring("syntax error at position 6: missing closing parenthesis"))
+			Expect(res).To(BeNil())
+		})
+
 		Context("with a personal space", func() {
 			BeforeEach(func() {
 				gatewayClient.On("ListStorageSpaces", mock.Anything, mock.Anything).Return(&sprovider.ListStorageSpacesResponse{
 					Status:        status.NewOK(ctx),
 					StorageSpaces: []*sprovider.StorageSpace{personalSpace},
 				}, nil)
-				indexClient.On("Search", mock.Anything, mock.Anything).Return(&searchsvc.SearchIndexResponse{
+				indexClient.On("Search", mock.Anything, mock.Anything, mock.Anything).Return(&searchsvc.SearchIndexResponse{
 					TotalMatches: 1,
 					Matches: []*searchmsg.Match{
 						{
@@ -174,11 +243,24 @@ var _ = Describe("Searchprovider", func() {
 					Query: "Size:<10",
 				})
 				Expect(err).ToNot(HaveOccurred())
-				indexClient.AssertCalled(GinkgoT(), "Search", mock.Anything, mock.MatchedBy(func(req *searchsvc.SearchIndexRequest) bool {
+				indexClient.AssertCalled(GinkgoT(), "Search", mock.Anything, mock.Anything, mock.MatchedBy(func(req *searchsvc.SearchIndexRequest) bool {
 					return req.Query == "Size:<10"
 				}))
 			})
 
+			It("passes the parsed query to the engine", func() {
+				_, err := s.Search(ctx, &searchsvc.SearchRequest{
+					Query: "name:foo*",
+				})
+				Expect(err).ToNot(HaveOccurred())
+				indexClient.AssertCalled(GinkgoT(), "Search", mock.Anything, kql.Restriction{
+					Field:    kql.FieldName,
+					Operator: kql.OpEqual,
+					Value:    "foo*",
+					Wildcard: true,
+				}, mock.Anything)
+			})
+
 			It("searches the personal user space", func() {
 				res, err := s.Search(ctx, &searchsvc.SearchRequest{
 					Query: "foo",
@@ -234,7 +316,7 @@ var _ = Describe("Searchprovider", func() {
 					Status:        status.NewOK(ctx),
 					StorageSpaces: []*sprovider.StorageSpace{grantSpace, mountpointSpace},
 				}, nil)
-				indexClient.On("Search", mock.Anything, mock.Anything).Return(&searchsvc.SearchIndexResponse{
+				indexClient.On("Search", mock.Anything, mock.Anything, mock.Anything).Return(&searchsvc.SearchIndexResponse{
 					TotalMatches: 1,
 					Matches: []*searchmsg.Match{
 						{
@@ -276,11 +358,14 @@ var _ = Describe("Searchprovider", func() {
 						Status:        status.NewOK(ctx),
 						StorageSpaces: []*sprovider.StorageSpace{personalSpace, grantSpace, mountpointSpace},
 					}, nil)
-					indexClient.On("Search", mock.Anything, mock.MatchedBy(func(req *searchsvc.SearchIndexRequest) bool {
+					indexClient.On("Search", mock.Anything, mock.Anything, mock.MatchedBy(func(req *searchsvc.SearchIndexRequest) bool {
 						return req.Ref.ResourceId.OpaqueId == grantSpace.Root.SpaceId &&
 							req.Ref.ResourceId.SpaceId == grantSpace.Root.SpaceId
 					})).Return(&searchsvc.SearchIndexResponse{
 						TotalMatches: 2,
+						Facets: []*searchmsg.Facet{
+							{Name: "mimetype", Values: []*searchmsg.FacetValue{{Value: "application/pdf", Count: 2}}},
+						},
 						Matches: []*searchmsg.Match{
 							{
 								Score: 2,
@@ -320,11 +405,15 @@ var _ = Describe("Searchprovider", func() {
 							},
 						},
 					}, nil)
-					indexClient.On("Search", mock.Anything, mock.MatchedBy(func(req *searchsvc.SearchIndexRequest) bool {
+					indexClient.On("Search", mock.Anything, mock.Anything, mock.MatchedBy(func(req *searchsvc.SearchIndexRequest) bool {
 						return req.Ref.ResourceId.OpaqueId == personalSpace.Root.OpaqueId &&
 							req.Ref.ResourceId.SpaceId == personalSpace.Root.SpaceId
 					})).Return(&searchsvc.SearchIndexResponse{
 						TotalMatches: 1,
+						Facets: []*searchmsg.Facet{
+							{Name: "mimetype", Values: []*searchmsg.FacetValue{{Value: "application/pdf", Count: 1}}},
+							{Name: "tags", Values: []*searchmsg.FacetValue{{Value: "foo", Count: 1}}},
+						},
 						Matches: []*searchmsg.Match{
 							{
 								Score: 1,
@@ -387,6 +476,81 @@ var _ = Describe("Searchprovider", func() {
 					ids := []string{res.Matches[0].Entity.Id.OpaqueId, res.Matches[1].Entity.Id.OpaqueId}
 					Expect(ids).To(Equal([]string{"grant-shared-id", "foo-id"}))
 				})
+
+				It("pages through the combined results from all spaces", func() {
+					res, err := s.Search(ctx, &searchsvc.SearchRequest{
+						Query:    "foo",
+						PageSize: 2,
+					})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(res.NextPageToken).To(Equal("2"))
+
+					res, err = s.Search(ctx, &searchsvc.SearchRequest{
+						Query:     "foo",
+						PageSize:  2,
+						PageToken: res.NextPageToken,
+					})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(len(res.Matches)).To(Equal(1))
+					Expect(res.Matches[0].Entity.Id.OpaqueId).To(Equal("grant-irrelevant-id"))
+					Expect(res.NextPageToken).To(BeEmpty())
+					Expect(res.TotalMatches).To(Equal(int32(3)))
+
+					// every space has to return the matches up to the end of the requested page
+					indexClient.AssertCalled(GinkgoT(), "Search", mock.Anything, mock.Anything, mock.MatchedBy(func(req *searchsvc.SearchIndexRequest) bool {
+						return req.PageSize == 4 && req.PageToken == ""
+					}))
+				})
+
+				It("rejects invalid page tokens", func() {
+					_, err := s.Search(ctx, &searchsvc.SearchRequest{
+						Query:     "foo",
+						PageToken: "next",
+					})
+					Expect(err).To(BeAssignableToTypeOf(errtypes.BadRequest("")))
+				})
+
+				It("rejects page tokens which overflow the result size", func() {
+					_, err := s.Search(ctx, &searchsvc.SearchRequest{
+						Query:     "foo",
+						PageSize:  2,
+						PageToken: "2147483646",
+					})
+					Expect(err).To(BeAssignableToTypeOf(errtypes.BadRequest("")))
+					indexClient.AssertNotCalled(GinkgoT(), "Search", mock.Anything, mock.Anything, mock.Anything)
+				})
+
+				It("rejects negative page sizes", func() {
+					_, err := s.Search(ctx, &searchsvc.SearchRequest{
+						Query:    "foo",
+						PageSize: -2,
+					})
+					Expect(err).To(BeAssignableToTypeOf(errtypes.BadRequest("")))
+				})
+
+				It("merges the facets of all spaces", func() {
+					res, err := s.Search(ctx, &searchsvc.SearchRequest{
+						Query:  "foo",
+						Facets: true,
+					})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(res.Facets).To(ConsistOf(
+						&searchmsg.Facet{Name: "mimetype", Values: []*searchmsg.FacetValue{{Value: "application/pdf", Count: 3}}},
+						&searchmsg.Facet{Name: "tags", Values: []*searchmsg.FacetValue{{Value: "foo", Count: 1}}},
+						&searchmsg.Facet{Name: "space", Values: []*searchmsg.FacetValue{
+							{Value: mountpointSpace.Id.OpaqueId, Count: 2},
+							{Value: personalSpace.Id.OpaqueId, Count: 1},
+						}},
+					))
+				})
+
+				It("does not return facets unless requested", func() {
+					res, err := s.Search(ctx, &searchsvc.SearchRequest{
+						Query: "foo",
+					})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(res.Facets).To(BeEmpty())
+				})
 			})
 		})
 	})
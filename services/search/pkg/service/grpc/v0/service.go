@@ -15,6 +15,7 @@ import (
 	"github.com/cs3org/reva/v2/pkg/errtypes"
 	"github.com/cs3org/reva/v2/pkg/events/stream"
 	"github.com/cs3org/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/v2/pkg/store"
 	"github.com/cs3org/reva/v2/pkg/token"
 	"github.com/cs3org/reva/v2/pkg/token/manager/jwt"
 	"github.com/go-micro/plugins/v4/events/natsjs"
@@ -22,13 +23,13 @@ import (
 	ociscrypto "github.com/owncloud/ocis/v2/ocis-pkg/crypto"
 	"github.com/owncloud/ocis/v2/ocis-pkg/log"
 	"github.com/owncloud/ocis/v2/ocis-pkg/registry"
-	v0 "github.com/owncloud/ocis/v2/protogen/gen/ocis/messages/search/v0"
 	searchsvc "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/search/v0"
 	"github.com/owncloud/ocis/v2/services/search/pkg/content"
 	"github.com/owncloud/ocis/v2/services/search/pkg/engine"
 	"github.com/owncloud/ocis/v2/services/search/pkg/search"
 	merrors "go-micro.dev/v4/errors"
 	"go-micro.dev/v4/metadata"
+	microstore "go-micro.dev/v4/store"
 	grpcmetadata "google.golang.org/grpc/metadata"
 )
 
@@ -53,6 +54,13 @@ func NewHandler(opts ...Option) (searchsvc.SearchProviderHandler, func(), error)
 		}
 
 		eng = engine.NewBleveEngine(idx)
+	case "opensearch":
+		openSearch, err := engine.NewOpenSearchEngine(cfg.Engine.OpenSearch)
+		if err != nil {
+			return nil, teardown, err
+		}
+
+		eng = openSearch
 	default:
 		return nil, teardown, fmt.Errorf("unknown search engine: %s", cfg.Engine.Type)
 	}
@@ -116,6 +124,18 @@ func NewHandler(opts ...Option) (searchsvc.SearchProviderHandler, func(), error)
 		return nil, teardown, err
 	}
 
+	savedSearches := search.NewSavedSearchStore(store.Create(
+		store.Store(cfg.Store.Store),
+		store.TTL(cfg.Store.TTL),
+		store.Size(cfg.Store.Size),
+		microstore.Nodes(cfg.Store.Nodes...),
+		microstore.Database(cfg.Store.Database),
+		microstore.Table(cfg.Store.Table),
+	), cfg.Store.Database, cfg.Store.Table, cfg.Store.MatchesTable)
+	if err := search.HandleSavedSearchEvents(search.NewAlerter(ss, savedSearches, bus, logger), bus, logger, cfg); err != nil {
+		return nil, teardown, err
+	}
+
 	cache := ttlcache.NewCache()
 	if err := cache.SetTTL(time.Second); err != nil {
 		return nil, teardown, err
@@ -130,56 +150,44 @@ func NewHandler(opts ...Option) (searchsvc.SearchProviderHandler, func(), error)
 	}
 
 	return &Service{
-		id:           cfg.GRPC.Namespace + "." + cfg.Service.Name,
-		log:          logger,
-		searcher:     ss,
-		cache:        cache,
-		tokenManager: tokenManager,
+		id:            cfg.GRPC.Namespace + "." + cfg.Service.Name,
+		log:           logger,
+		searcher:      ss,
+		savedSearches: savedSearches,
+		cache:         cache,
+		tokenManager:  tokenManager,
 	}, teardown, nil
 }
 
 // Service implements the searchServiceHandler interface
 type Service struct {
-	id           string
-	log          log.Logger
-	searcher     search.Searcher
-	cache        *ttlcache.Cache
-	tokenManager token.Manager
+	id            string
+	log           log.Logger
+	searcher      search.Searcher
+	savedSearches *search.SavedSearchStore
+	cache         *ttlcache.Cache
+	tokenManager  token.Manager
 }
 
 // Search handles the search
 func (s Service) Search(ctx context.Context, in *searchsvc.SearchRequest, out *searchsvc.SearchResponse) error {
-	// Get token from the context (go-micro) and make it known to the reva client too (grpc)
-	t, ok := metadata.Get(ctx, revactx.TokenHeader)
-	if !ok {
-		s.log.Error().Msg("Could not get token from context")
-		return errors.New("could not get token from context")
-	}
-	ctx = grpcmetadata.AppendToOutgoingContext(ctx, revactx.TokenHeader, t)
-
-	// unpack user
-	u, _, err := s.tokenManager.DismantleToken(ctx, t)
+	ctx, u, err := s.authenticate(ctx)
 	if err != nil {
 		return err
 	}
-	ctx = revactx.ContextSetUser(ctx, u)
 
-	key := cacheKey(in.Query, in.PageSize, in.Ref, u)
+	key := cacheKey(in, u)
 	res, ok := s.FromCache(key)
 	if !ok {
-		var err error
 		res, err = s.searcher.Search(ctx, &searchsvc.SearchRequest{
-			Query:    in.Query,
-			PageSize: in.PageSize,
-			Ref:      in.Ref,
+			Query:     in.Query,
+			PageSize:  in.PageSize,
+			PageToken: in.PageToken,
+			Ref:       in.Ref,
+			Facets:    in.Facets,
 		})
 		if err != nil {
-			switch err.(type) {
-			case errtypes.BadRequest:
-				return merrors.BadRequest(s.id, err.Error())
-			default:
-				return merrors.InternalServerError(s.id, err.Error())
-			}
+			return s.toMicroError(err)
 		}
 
 		s.Cache(key, res)
@@ -188,6 +196,7 @@ func (s Service) Search(ctx context.Context, in *searchsvc.SearchRequest, out *s
 	out.Matches = res.Matches
 	out.TotalMatches = res.TotalMatches
 	out.NextPageToken = res.NextPageToken
+	out.Facets = res.Facets
 	return nil
 }
 
@@ -196,6 +205,110 @@ func (s Service) IndexSpace(ctx context.Context, in *searchsvc.IndexSpaceRequest
 	return s.searcher.IndexSpace(&provider.StorageSpaceId{OpaqueId: in.SpaceId}, &user.UserId{OpaqueId: in.UserId})
 }
 
+// CheckIndex compares the index of a space with the storage and optionally repairs it.
+func (s Service) CheckIndex(ctx context.Context, in *searchsvc.CheckIndexRequest, out *searchsvc.CheckIndexResponse) error {
+	res, err := s.searcher.CheckIndex(&provider.StorageSpaceId{OpaqueId: in.SpaceId}, &user.UserId{OpaqueId: in.UserId}, in.Repair)
+	if err != nil {
+		return err
+	}
+
+	out.Checked = res.Checked
+	out.Inconsistencies = res.Inconsistencies
+	return nil
+}
+
+// SaveSearch creates or updates a saved search of the current user.
+func (s Service) SaveSearch(ctx context.Context, in *searchsvc.SaveSearchRequest, out *searchsvc.SaveSearchResponse) error {
+	_, u, err := s.authenticate(ctx)
+	if err != nil {
+		return err
+	}
+
+	ss, err := s.savedSearches.Save(u.GetId().GetOpaqueId(), in.GetSavedSearch())
+	if err != nil {
+		return s.toMicroError(err)
+	}
+
+	out.SavedSearch = ss
+	return nil
+}
+
+// ListSavedSearches lists the saved searches of the current user.
+func (s Service) ListSavedSearches(ctx context.Context, _ *searchsvc.ListSavedSearchesRequest, out *searchsvc.ListSavedSearchesResponse) error {
+	_, u, err := s.authenticate(ctx)
+	if err != nil {
+		return err
+	}
+
+	searches, err := s.savedSearches.List(u.GetId().GetOpaqueId())
+	if err != nil {
+		return s.toMicroError(err)
+	}
+
+	out.SavedSearches = searches
+	return nil
+}
+
+// DeleteSavedSearch deletes a saved search of the current user.
+func (s Service) DeleteSavedSearch(ctx context.Context, in *searchsvc.DeleteSavedSearchRequest, _ *searchsvc.DeleteSavedSearchResponse) error {
+	_, u, err := s.authenticate(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.toMicroError(s.savedSearches.Delete(u.GetId().GetOpaqueId(), in.GetId()))
+}
+
+// RunSavedSearch runs the query of a saved search of the current user.
+func (s Service) RunSavedSearch(ctx context.Context, in *searchsvc.RunSavedSearchRequest, out *searchsvc.SearchResponse) error {
+	_, u, err := s.authenticate(ctx)
+	if err != nil {
+		return err
+	}
+
+	ss, err := s.savedSearches.Get(u.GetId().GetOpaqueId(), in.GetId())
+	if err != nil {
+		return s.toMicroError(err)
+	}
+
+	return s.Search(ctx, &searchsvc.SearchRequest{
+		Query:     ss.GetQuery(),
+		PageSize:  in.GetPageSize(),
+		PageToken: in.GetPageToken(),
+		Facets:    in.GetFacets(),
+	}, out)
+}
+
+// authenticate unpacks the user from the token in the context (go-micro) and
+// makes the token known to the reva client too (grpc)
+func (s Service) authenticate(ctx context.Context) (context.Context, *user.User, error) {
+	t, ok := metadata.Get(ctx, revactx.TokenHeader)
+	if !ok {
+		s.log.Error().Msg("Could not get token from context")
+		return nil, nil, errors.New("could not get token from context")
+	}
+	ctx = grpcmetadata.AppendToOutgoingContext(ctx, revactx.TokenHeader, t)
+
+	u, _, err := s.tokenManager.DismantleToken(ctx, t)
+	if err != nil {
+		return nil, nil, err
+	}
+	return revactx.ContextSetUser(ctx, u), u, nil
+}
+
+func (s Service) toMicroError(err error) error {
+	switch err.(type) {
+	case nil:
+		return nil
+	case errtypes.BadRequest:
+		return merrors.BadRequest(s.id, err.Error())
+	case errtypes.NotFound:
+		return merrors.NotFound(s.id, err.Error())
+	default:
+		return merrors.InternalServerError(s.id, err.Error())
+	}
+}
+
 // FromCache pulls a search result from cache
 func (s Service) FromCache(key string) (*searchsvc.SearchResponse, bool) {
 	v, err := s.cache.Get(key)
@@ -213,6 +326,7 @@ func (s Service) Cache(key string, res *searchsvc.SearchResponse) {
 	_ = s.cache.Set(key, res)
 }
 
-func cacheKey(query string, pagesize int32, ref *v0.Reference, user *user.User) string {
-	return fmt.Sprintf("%s|%d|%s$%s!%s/%s|%s", query, pagesize, ref.GetResourceId().GetStorageId(), ref.GetResourceId().GetSpaceId(), ref.GetResourceId().GetOpaqueId(), ref.GetPath(), user.GetId().GetOpaqueId())
+func cacheKey(req *searchsvc.SearchRequest, user *user.User) string {
+	ref := req.GetRef()
+	return fmt.Sprintf("%s|%d|%s|%t|%s$%s!%s/%s|%s", req.GetQuery(), req.GetPageSize(), req.GetPageToken(), req.GetFacets(), ref.GetResourceId().GetStorageId(), ref.GetResourceId().GetSpaceId(), ref.GetResourceId().GetOpaqueId(), ref.GetPath(), user.GetId().GetOpaqueId())
 }
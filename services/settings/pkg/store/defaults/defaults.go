@@ -107,6 +107,11 @@ const (
 	WritePublicLinkPermissionID string = "11516bbd-7157-49e1-b6ac-d00c820f980b"
 	// WritePublicLinkPermissionName is the hardcoded setting name for the PublicLink.Write permission
 	WritePublicLinkPermissionName string = "PublicLink.Write"
+
+	// QuarantineManagementPermissionID is the hardcoded setting UUID for the quarantine management permission
+	QuarantineManagementPermissionID string = "c1858a10-1698-4b46-b9f7-a80df9e82dad"
+	// QuarantineManagementPermissionName is the hardcoded setting name for the quarantine management permission
+	QuarantineManagementPermissionName string = "Quarantine.ReadWrite"
 )
 
 // GenerateBundlesDefaultRoles bootstraps the default roles.
@@ -330,6 +335,21 @@ func generateBundleAdminRole() *settingsmsg.Bundle {
 					},
 				},
 			},
+			{
+				Id:          QuarantineManagementPermissionID,
+				Name:        QuarantineManagementPermissionName,
+				DisplayName: "Quarantine Management",
+				Description: "This permission allows to inspect, download, release and purge quarantined files.",
+				Resource: &settingsmsg.Resource{
+					Type: settingsmsg.Resource_TYPE_SYSTEM,
+				},
+				Value: &settingsmsg.Setting_PermissionValue{
+					PermissionValue: &settingsmsg.Permission{
+						Operation:  settingsmsg.Permission_OPERATION_READWRITE,
+						Constraint: settingsmsg.Permission_CONSTRAINT_ALL,
+					},
+				},
+			},
 			{
 				Id:          WritePublicLinkPermissionID,
 				Name:        WritePublicLinkPermissionName,
@@ -0,0 +1,116 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/config/configlog"
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	ogrpc "github.com/owncloud/ocis/v2/ocis-pkg/service/grpc"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/config"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/config/parser"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/logging"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/metrics"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/thumbnail/storage"
+	"github.com/urfave/cli/v2"
+)
+
+// Cleanup is the entrypoint for the cleanup command.
+func Cleanup(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "cleanup",
+		Usage: "remove thumbnails which exceed the maximum age or size of the thumbnail storage",
+		Flags: []cli.Flag{
+			&cli.DurationFlag{
+				Name:  "max-age",
+				Usage: "remove thumbnails not accessed for this duration, defaults to the configured maximum age",
+			},
+			&cli.Uint64Flag{
+				Name:  "max-size",
+				Usage: "remove the least recently accessed thumbnails until the storage is smaller than this size in bytes, defaults to the configured maximum size",
+			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "only report which thumbnails would be removed",
+			},
+		},
+		Before: func(c *cli.Context) error {
+			return configlog.ReturnFatal(parser.ParseConfig(cfg))
+		},
+		Action: func(c *cli.Context) error {
+			logger := logging.Configure(cfg.Service.Name, cfg.Log)
+			if err := ogrpc.Configure(ogrpc.GetClientOptions(cfg.GRPCClientTLS)...); err != nil {
+				return err
+			}
+
+			pruner, maxSize, maxAge, _, err := newPruner(cfg.Thumbnail, logger)
+			if err != nil {
+				return err
+			}
+			if c.IsSet("max-age") {
+				maxAge = c.Duration("max-age")
+			}
+			if c.IsSet("max-size") {
+				maxSize = c.Uint64("max-size")
+			}
+
+			res, err := pruner.Prune(maxSize, maxAge, c.Bool("dry-run"))
+			if err != nil {
+				return err
+			}
+
+			verb := "Removed"
+			if c.Bool("dry-run") {
+				verb = "Would remove"
+			}
+			fmt.Printf("Thumbnails: %d (%d bytes)\n", res.Files, res.Bytes)
+			fmt.Printf("%s: %d (%d bytes)\n", verb, res.RemovedFiles, res.RemovedBytes)
+			fmt.Printf("Remaining: %d (%d bytes)\n", res.Files-res.RemovedFiles, res.Bytes-res.RemovedBytes)
+			return nil
+		},
+	}
+}
+
+// newPruner returns the configured thumbnail storage and its limits and cleanup interval
+func newPruner(cfg config.Thumbnail, logger log.Logger) (storage.Pruner, uint64, time.Duration, time.Duration, error) {
+	switch cfg.Storage {
+	case "", "filesystem":
+		fs := cfg.FileSystemStorage
+		return storage.NewFileSystemStorage(fs, logger), fs.MaxSize, fs.MaxAge, fs.CleanupInterval, nil
+	case "cs3":
+		cs3 := cfg.CS3Storage
+		s, err := storage.NewCS3Storage(cs3, cfg.RevaGateway, logger)
+		if err != nil {
+			return nil, 0, 0, 0, err
+		}
+		return s, cs3.MaxSize, cs3.MaxAge, cs3.CleanupInterval, nil
+	default:
+		return nil, 0, 0, 0, fmt.Errorf("unknown thumbnail storage: %s", cfg.Storage)
+	}
+}
+
+// cleanupLoop periodically prunes the thumbnail storage and updates the storage usage metrics.
+func cleanupLoop(ctx context.Context, pruner storage.Pruner, maxSize uint64, maxAge, interval time.Duration, m *metrics.Metrics, logger log.Logger) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		res, err := pruner.Prune(maxSize, maxAge, false)
+		if err != nil {
+			logger.Error().Err(err).Msg("could not clean up the thumbnails")
+		} else {
+			if res.RemovedFiles > 0 {
+				logger.Info().Int("files", res.RemovedFiles).Int64("bytes", res.RemovedBytes).Msg("removed thumbnails")
+			}
+			m.CacheFiles.Set(float64(res.Files - res.RemovedFiles))
+			m.CacheSize.Set(float64(res.Bytes - res.RemovedBytes))
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
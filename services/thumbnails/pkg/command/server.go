@@ -110,6 +110,18 @@ func Server(cfg *config.Config) *cli.Command {
 				cancel()
 			})
 
+			pruner, maxSize, maxAge, interval, err := newPruner(cfg.Thumbnail, logger)
+			if err != nil {
+				return err
+			}
+			if interval > 0 {
+				gr.Add(func() error {
+					return cleanupLoop(ctx, pruner, maxSize, maxAge, interval, metrics, logger)
+				}, func(_ error) {
+					cancel()
+				})
+			}
+
 			return gr.Run()
 		},
 	}
@@ -3,6 +3,7 @@ package config
 
 import (
 	"context"
+	"time"
 
 	"github.com/owncloud/ocis/v2/ocis-pkg/shared"
 )
@@ -23,23 +24,74 @@ type Config struct {
 	GRPCClientTLS *shared.GRPCClientTLS `yaml:"grpc_client_tls"`
 
 	Thumbnail Thumbnail `yaml:"thumbnail"`
+	Events    Events    `yaml:"events"`
+
+	MachineAuthAPIKey string `yaml:"machine_auth_api_key" env:"OCIS_MACHINE_AUTH_API_KEY;THUMBNAILS_MACHINE_AUTH_API_KEY" desc:"Machine auth API key used to validate internal requests necessary to access resources from other services. Only needed when pre-generating thumbnails."`
 
 	Context context.Context `yaml:"-"`
 }
 
 // FileSystemStorage defines the available filesystem storage configuration.
 type FileSystemStorage struct {
-	RootDirectory string `yaml:"root_directory" env:"THUMBNAILS_FILESYSTEMSTORAGE_ROOT" desc:"The directory where the filesystem storage will store the thumbnails. If not defined, the root directory derives from $OCIS_BASE_DATA_PATH:/thumbnails."`
+	RootDirectory   string        `yaml:"root_directory" env:"THUMBNAILS_FILESYSTEMSTORAGE_ROOT" desc:"The directory where the filesystem storage will store the thumbnails. If not defined, the root directory derives from $OCIS_BASE_DATA_PATH:/thumbnails."`
+	MaxSize         uint64        `yaml:"max_size" env:"THUMBNAILS_FILESYSTEMSTORAGE_MAX_SIZE" desc:"The maximum size of all stored thumbnails in bytes. The least recently used thumbnails are removed when the size is exceeded. The default value is 0 which means no limit."`
+	MaxAge          time.Duration `yaml:"max_age" env:"THUMBNAILS_FILESYSTEMSTORAGE_MAX_AGE" desc:"Thumbnails which were not accessed for this duration are removed. The duration can be set as number followed by a unit identifier like s, m or h. The default value is 0 which means thumbnails do not expire."`
+	CleanupInterval time.Duration `yaml:"cleanup_interval" env:"THUMBNAILS_FILESYSTEMSTORAGE_CLEANUP_INTERVAL" desc:"The interval in which the thumbnails exceeding the maximum size and age are removed and the disk usage metrics are updated. The duration can be set as number followed by a unit identifier like s, m or h. Set to 0 to disable the cleanup. Defaults to '1h'."`
+}
+
+// CS3Storage defines the configuration of the storage which keeps the thumbnails in the metadata storage.
+type CS3Storage struct {
+	ProviderAddr     string        `yaml:"provider_addr" env:"THUMBNAILS_CS3STORAGE_PROVIDER_ADDR" desc:"GRPC address of the STORAGE-SYSTEM service."`
+	SystemUserID     string        `yaml:"system_user_id" env:"OCIS_SYSTEM_USER_ID;THUMBNAILS_CS3STORAGE_SYSTEM_USER_ID" desc:"ID of the oCIS STORAGE-SYSTEM system user. Admins need to set the ID for the STORAGE-SYSTEM system user in this config option which is then used to reference the user. Any reasonable long string is possible, preferably this would be an UUIDv4 format."`
+	SystemUserIDP    string        `yaml:"system_user_idp" env:"OCIS_SYSTEM_USER_IDP;THUMBNAILS_CS3STORAGE_SYSTEM_USER_IDP" desc:"IDP of the oCIS STORAGE-SYSTEM system user."`
+	SystemUserAPIKey string        `yaml:"system_user_api_key" env:"OCIS_SYSTEM_USER_API_KEY;THUMBNAILS_CS3STORAGE_SYSTEM_USER_API_KEY" desc:"API key for the STORAGE-SYSTEM system user."`
+	MaxSize          uint64        `yaml:"max_size" env:"THUMBNAILS_CS3STORAGE_MAX_SIZE" desc:"The maximum size of all stored thumbnails in bytes. The oldest thumbnails are removed when the size is exceeded. The default value is 0 which means no limit."`
+	MaxAge           time.Duration `yaml:"max_age" env:"THUMBNAILS_CS3STORAGE_MAX_AGE" desc:"Thumbnails which were generated longer ago than this duration are removed. The duration can be set as number followed by a unit identifier like s, m or h. The default value is 0 which means thumbnails do not expire."`
+	CleanupInterval  time.Duration `yaml:"cleanup_interval" env:"THUMBNAILS_CS3STORAGE_CLEANUP_INTERVAL" desc:"The interval in which the thumbnails exceeding the maximum size and age are removed and the storage usage metrics are updated. The duration can be set as number followed by a unit identifier like s, m or h. Set to 0 to disable the cleanup. Defaults to '1h'."`
 }
 
 // Thumbnail defines the available thumbnail related configuration.
 type Thumbnail struct {
-	Resolutions         []string          `yaml:"resolutions" env:"THUMBNAILS_RESOLUTIONS" desc:"The supported target resolutions in the format WidthxHeight e.g. 32x32. You can define any resolution as required and separate multiple resolutions by blank or comma."`
-	FileSystemStorage   FileSystemStorage `yaml:"filesystem_storage"`
-	WebdavAllowInsecure bool              `yaml:"webdav_allow_insecure" env:"OCIS_INSECURE;THUMBNAILS_WEBDAVSOURCE_INSECURE" desc:"Ignore untrusted SSL certificates when connecting to the webdav source."`
-	CS3AllowInsecure    bool              `yaml:"cs3_allow_insecure" env:"OCIS_INSECURE;THUMBNAILS_CS3SOURCE_INSECURE" desc:"Ignore untrusted SSL certificates when connecting to the CS3 source."`
-	RevaGateway         string            `yaml:"reva_gateway" env:"OCIS_REVA_GATEWAY;REVA_GATEWAY" desc:"CS3 gateway used to look up user metadata" deprecationVersion:"3.0" removalVersion:"4.0.0" deprecationInfo:"REVA_GATEWAY changing name for consistency" deprecationReplacement:"OCIS_REVA_GATEWAY"`
-	FontMapFile         string            `yaml:"font_map_file" env:"THUMBNAILS_TXT_FONTMAP_FILE" desc:"The path to a font file for txt thumbnails."`
-	TransferSecret      string            `yaml:"transfer_secret" env:"THUMBNAILS_TRANSFER_TOKEN" desc:"The secret to sign JWT to download the actual thumbnail file."`
-	DataEndpoint        string            `yaml:"data_endpoint" env:"THUMBNAILS_DATA_ENDPOINT" desc:"The HTTP endpoint where the actual thumbnail file can be downloaded."`
+	Resolutions           []string          `yaml:"resolutions" env:"THUMBNAILS_RESOLUTIONS" desc:"The supported target resolutions in the format WidthxHeight e.g. 32x32. You can define any resolution as required and separate multiple resolutions by blank or comma."`
+	Storage               string            `yaml:"storage" env:"THUMBNAILS_STORAGE" desc:"The storage of the generated thumbnails. Supported values are 'filesystem' and 'cs3'. Use 'cs3' to share the thumbnails between multiple instances of the service. See the text description for details."`
+	FileSystemStorage     FileSystemStorage `yaml:"filesystem_storage"`
+	CS3Storage            CS3Storage        `yaml:"cs3_storage"`
+	WebdavAllowInsecure   bool              `yaml:"webdav_allow_insecure" env:"OCIS_INSECURE;THUMBNAILS_WEBDAVSOURCE_INSECURE" desc:"Ignore untrusted SSL certificates when connecting to the webdav source."`
+	CS3AllowInsecure      bool              `yaml:"cs3_allow_insecure" env:"OCIS_INSECURE;THUMBNAILS_CS3SOURCE_INSECURE" desc:"Ignore untrusted SSL certificates when connecting to the CS3 source."`
+	RevaGateway           string            `yaml:"reva_gateway" env:"OCIS_REVA_GATEWAY;REVA_GATEWAY" desc:"CS3 gateway used to look up user metadata" deprecationVersion:"3.0" removalVersion:"4.0.0" deprecationInfo:"REVA_GATEWAY changing name for consistency" deprecationReplacement:"OCIS_REVA_GATEWAY"`
+	FontMapFile           string            `yaml:"font_map_file" env:"THUMBNAILS_TXT_FONTMAP_FILE" desc:"The path to a font file for txt thumbnails."`
+	TransferSecret        string            `yaml:"transfer_secret" env:"THUMBNAILS_TRANSFER_TOKEN" desc:"The secret to sign JWT to download the actual thumbnail file."`
+	DataEndpoint          string            `yaml:"data_endpoint" env:"THUMBNAILS_DATA_ENDPOINT" desc:"The HTTP endpoint where the actual thumbnail file can be downloaded."`
+	MaxInputSize          uint64            `yaml:"max_input_size" env:"THUMBNAILS_MAX_INPUT_SIZE" desc:"The maximum size in bytes of a file thumbnails are generated for. Defaults to 52428800 (50MB). Set to 0 for no limit."`
+	MaxInputPixels        uint64            `yaml:"max_input_pixels" env:"THUMBNAILS_MAX_INPUT_PIXELS" desc:"The maximum number of pixels (width x height) of an image thumbnails are generated for. The dimensions are checked before the image is decoded, the frames of an animated GIF count together. Defaults to 100000000 (100 megapixels). Set to 0 for no limit."`
+	MaxGIFFrames          int               `yaml:"max_gif_frames" env:"THUMBNAILS_MAX_GIF_FRAMES" desc:"The maximum number of frames of an animated GIF thumbnails are generated for. Defaults to 300. Set to 0 for no limit."`
+	MaxConcurrentRequests int               `yaml:"max_concurrent_requests" env:"THUMBNAILS_MAX_CONCURRENT_REQUESTS" desc:"The maximum number of thumbnails which are generated at the same time. Further requests wait until a generation finished. Thumbnails which already exist are served without limit. The default value is 0 which means no limit."`
+	Converters            []Converter       `yaml:"converters"`
+	Pregenerate           Pregenerate       `yaml:"pregenerate"`
+}
+
+// Pregenerate defines the configuration for generating thumbnails right after a file was uploaded.
+type Pregenerate struct {
+	Enabled     bool     `yaml:"enabled" env:"THUMBNAILS_PREGENERATE_ENABLED" desc:"Generate the thumbnails of uploaded files in advance instead of on the first request. Requires a connection to the event system."`
+	Resolutions []string `yaml:"resolutions" env:"THUMBNAILS_PREGENERATE_RESOLUTIONS" desc:"The resolutions which are generated in advance in the format WidthxHeight e.g. 32x32. Separate multiple resolutions by blank or comma. Only requests for exactly these resolutions benefit from the pre-generation. If not set, all resolutions of THUMBNAILS_RESOLUTIONS are generated."`
+	NumWorkers  int      `yaml:"num_workers" env:"THUMBNAILS_PREGENERATE_NUM_WORKERS" desc:"The number of files for which the thumbnails are generated concurrently. Defaults to 2."`
+	MaxFileSize uint64   `yaml:"max_file_size" env:"THUMBNAILS_PREGENERATE_MAX_FILE_SIZE" desc:"Files bigger than this size in bytes are skipped, their thumbnails are still generated on the first request. Defaults to 52428800 (50MB). Set to 0 for no limit."`
+}
+
+// Events combines the configuration options for the event bus.
+type Events struct {
+	Endpoint             string `yaml:"endpoint" env:"OCIS_EVENTS_ENDPOINT;THUMBNAILS_EVENTS_ENDPOINT" desc:"The address of the event system. The event system is the message queuing service. It is used as message broker for the microservice architecture."`
+	Cluster              string `yaml:"cluster" env:"OCIS_EVENTS_CLUSTER;THUMBNAILS_EVENTS_CLUSTER" desc:"The clusterID of the event system. The event system is the message queuing service. It is used as message broker for the microservice architecture. Mandatory when using NATS as event system."`
+	TLSInsecure          bool   `yaml:"tls_insecure" env:"OCIS_INSECURE;THUMBNAILS_EVENTS_TLS_INSECURE" desc:"Whether to verify the server TLS certificates."`
+	TLSRootCACertificate string `yaml:"tls_root_ca_certificate" env:"OCIS_EVENTS_TLS_ROOT_CA_CERTIFICATE;THUMBNAILS_EVENTS_TLS_ROOT_CA_CERTIFICATE" desc:"The root CA certificate used to validate the server's TLS certificate. If provided THUMBNAILS_EVENTS_TLS_INSECURE will be seen as false."`
+	EnableTLS            bool   `yaml:"enable_tls" env:"OCIS_EVENTS_ENABLE_TLS;THUMBNAILS_EVENTS_ENABLE_TLS" desc:"Enable TLS for the connection to the events broker. The events broker is the ocis service which receives and delivers events between the services."`
+}
+
+// Converter defines an external command which renders files of the given mime types as PNG image.
+type Converter struct {
+	MimeTypes []string      `yaml:"mime_types"`
+	Command   string        `yaml:"command"`
+	Args      []string      `yaml:"args"`
+	Timeout   time.Duration `yaml:"timeout"`
+	MaxMemory uint64        `yaml:"max_memory"`
 }
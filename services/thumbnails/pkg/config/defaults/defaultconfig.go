@@ -3,6 +3,7 @@ package defaults
 import (
 	"path"
 	"strings"
+	"time"
 
 	"github.com/owncloud/ocis/v2/ocis-pkg/config/defaults"
 	"github.com/owncloud/ocis/v2/ocis-pkg/shared"
@@ -41,13 +42,31 @@ func DefaultConfig() *config.Config {
 		},
 		Thumbnail: config.Thumbnail{
 			Resolutions: []string{"16x16", "32x32", "64x64", "128x128", "1080x1920", "1920x1080", "2160x3840", "3840x2160", "4320x7680", "7680x4320"},
+			Storage:     "filesystem",
 			FileSystemStorage: config.FileSystemStorage{
-				RootDirectory: path.Join(defaults.BaseDataPath(), "thumbnails"),
+				RootDirectory:   path.Join(defaults.BaseDataPath(), "thumbnails"),
+				CleanupInterval: time.Hour,
+			},
+			CS3Storage: config.CS3Storage{
+				ProviderAddr:    "com.owncloud.api.storage-system",
+				SystemUserIDP:   "internal",
+				CleanupInterval: time.Hour,
 			},
 			WebdavAllowInsecure: false,
 			RevaGateway:         shared.DefaultRevaConfig().Address,
 			CS3AllowInsecure:    false,
 			DataEndpoint:        "http://127.0.0.1:9186/thumbnails/data",
+			MaxInputSize:        52428800,
+			MaxInputPixels:      100000000,
+			MaxGIFFrames:        300,
+			Pregenerate: config.Pregenerate{
+				NumWorkers:  2,
+				MaxFileSize: 52428800,
+			},
+		},
+		Events: config.Events{
+			Endpoint: "127.0.0.1:9233",
+			Cluster:  "ocis-cluster",
 		},
 	}
 }
@@ -87,6 +106,18 @@ func EnsureDefaults(cfg *config.Config) {
 	if cfg.Commons != nil {
 		cfg.HTTP.TLS = cfg.Commons.HTTPServiceTLS
 	}
+
+	if cfg.MachineAuthAPIKey == "" && cfg.Commons != nil && cfg.Commons.MachineAuthAPIKey != "" {
+		cfg.MachineAuthAPIKey = cfg.Commons.MachineAuthAPIKey
+	}
+
+	if cfg.Thumbnail.CS3Storage.SystemUserAPIKey == "" && cfg.Commons != nil && cfg.Commons.SystemUserAPIKey != "" {
+		cfg.Thumbnail.CS3Storage.SystemUserAPIKey = cfg.Commons.SystemUserAPIKey
+	}
+
+	if cfg.Thumbnail.CS3Storage.SystemUserID == "" && cfg.Commons != nil && cfg.Commons.SystemUserID != "" {
+		cfg.Thumbnail.CS3Storage.SystemUserID = cfg.Commons.SystemUserID
+	}
 }
 
 // Sanitize sanitized the configuration
@@ -95,4 +126,7 @@ func Sanitize(cfg *config.Config) {
 	if len(cfg.Thumbnail.Resolutions) == 1 && strings.Contains(cfg.Thumbnail.Resolutions[0], ",") {
 		cfg.Thumbnail.Resolutions = strings.Split(cfg.Thumbnail.Resolutions[0], ",")
 	}
+	if len(cfg.Thumbnail.Pregenerate.Resolutions) == 1 && strings.Contains(cfg.Thumbnail.Pregenerate.Resolutions[0], ",") {
+		cfg.Thumbnail.Pregenerate.Resolutions = strings.Split(cfg.Thumbnail.Pregenerate.Resolutions[0], ",")
+	}
 }
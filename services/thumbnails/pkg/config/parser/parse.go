@@ -4,6 +4,7 @@ import (
 	"errors"
 
 	ociscfg "github.com/owncloud/ocis/v2/ocis-pkg/config"
+	"github.com/owncloud/ocis/v2/ocis-pkg/shared"
 	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/config"
 	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/config/defaults"
 
@@ -34,5 +35,16 @@ func ParseConfig(cfg *config.Config) error {
 }
 
 func Validate(cfg *config.Config) error {
+	if cfg.Thumbnail.Pregenerate.Enabled && cfg.MachineAuthAPIKey == "" {
+		return shared.MissingMachineAuthApiKeyError(cfg.Service.Name)
+	}
+
+	if cfg.Thumbnail.Storage == "cs3" && cfg.Thumbnail.CS3Storage.SystemUserAPIKey == "" {
+		return shared.MissingSystemUserApiKeyError(cfg.Service.Name)
+	}
+
+	if cfg.Thumbnail.Storage == "cs3" && cfg.Thumbnail.CS3Storage.SystemUserID == "" {
+		return shared.MissingSystemUserID(cfg.Service.Name)
+	}
 	return nil
 }
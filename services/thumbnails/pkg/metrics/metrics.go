@@ -16,6 +16,10 @@ type Metrics struct {
 	Latency   *prometheus.SummaryVec
 	Duration  *prometheus.HistogramVec
 	BuildInfo *prometheus.GaugeVec
+
+	CacheRequests *prometheus.CounterVec
+	CacheSize     prometheus.Gauge
+	CacheFiles    prometheus.Gauge
 }
 
 // New initializes the available metrics.
@@ -45,6 +49,24 @@ func New() *Metrics {
 			Name:      "build_info",
 			Help:      "Build information",
 		}, []string{"version"}),
+		CacheRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: Subsystem,
+			Name:      "cache_requests_total",
+			Help:      "Thumbnail cache lookups by result, hit or miss",
+		}, []string{"result"}),
+		CacheSize: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: Subsystem,
+			Name:      "cache_size_bytes",
+			Help:      "Disk usage of the stored thumbnails in bytes",
+		}),
+		CacheFiles: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: Subsystem,
+			Name:      "cache_files",
+			Help:      "Number of stored thumbnails",
+		}),
 	}
 
 	_ = prometheus.Register(
@@ -63,5 +85,17 @@ func New() *Metrics {
 		m.BuildInfo,
 	)
 
+	_ = prometheus.Register(
+		m.CacheRequests,
+	)
+
+	_ = prometheus.Register(
+		m.CacheSize,
+	)
+
+	_ = prometheus.Register(
+		m.CacheFiles,
+	)
+
 	return m
 }
@@ -0,0 +1,169 @@
+package preprocessor
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// InputPlaceholder is replaced with the path of the file to convert in the arguments of an ExternalConverter
+	InputPlaceholder = "{input}"
+	// OutputPlaceholder is replaced with the path the PNG image has to be written to in the arguments
+	// of an ExternalConverter. If the arguments do not contain it, the image is read from stdout.
+	OutputPlaceholder = "{output}"
+
+	_defaultConverterTimeout = 30 * time.Second
+	// _maxConverterOutput limits the size of the image read from stdout without MaxPixels
+	_maxConverterOutput = 256 * 1024 * 1024
+)
+
+var (
+	// ErrConverterTimeout is returned when an external converter did not finish in time
+	ErrConverterTimeout = errors.New("external converter timed out")
+
+	errConverterOutputTooLarge = errors.New("the output of the external converter is too large")
+)
+
+// ExternalConverter converts files by running an external command, e.g. a PDF rasterizer, an office
+// to PDF converter or a video frame grabber, which renders the file as PNG image.
+//
+// The command runs in its own process group in an empty temporary directory with a minimal
+// environment. It is killed when it exceeds the timeout, MaxMemory limits its virtual memory.
+type ExternalConverter struct {
+	Command   string
+	Args      []string
+	Timeout   time.Duration
+	MaxMemory uint64 // in bytes, 0 means no limit
+	// MaxPixels limits the dimensions of the rendered image, 0 means no limit
+	MaxPixels uint64
+}
+
+// Convert runs the command and decodes the PNG image it renders
+func (c ExternalConverter) Convert(r io.Reader) (interface{}, error) {
+	workDir, err := os.MkdirTemp("", "thumbnails-converter-")
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create the working directory of the converter")
+	}
+	defer os.RemoveAll(workDir) // nolint:errcheck
+
+	input := filepath.Join(workDir, "input")
+	output := filepath.Join(workDir, "output.png")
+	if err := writeFile(input, r); err != nil {
+		return nil, errors.Wrap(err, "could not write the input of the converter")
+	}
+
+	args := make([]string, 0, len(c.Args))
+	toStdout := true
+	for _, a := range c.Args {
+		if strings.Contains(a, OutputPlaceholder) {
+			toStdout = false
+		}
+		a = strings.ReplaceAll(a, InputPlaceholder, input)
+		a = strings.ReplaceAll(a, OutputPlaceholder, output)
+		args = append(args, a)
+	}
+
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = _defaultConverterTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := c.command(ctx, args)
+	cmd.Dir = workDir
+	cmd.Env = []string{"PATH=" + os.Getenv("PATH"), "HOME=" + workDir, "TMPDIR=" + workDir}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	// kill the whole process group, converters like office suites spawn child processes
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+
+	var stdout, stderr bytes.Buffer
+	// the command gets a broken pipe when it writes more than the limit
+	limitedStdout := &limitedWriter{w: &stdout, n: c.maxOutput()}
+	cmd.Stdout = limitedStdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, ErrConverterTimeout
+	}
+	if limitedStdout.exceeded {
+		return nil, errors.Wrapf(errConverterOutputTooLarge, "more than %d bytes", c.maxOutput())
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "external converter failed: %s", strings.TrimSpace(stderr.String()))
+	}
+
+	var img io.Reader = &stdout
+	if !toStdout {
+		f, err := os.Open(output)
+		if err != nil {
+			return nil, errors.Wrap(err, "external converter did not write an image")
+		}
+		defer f.Close() // nolint:errcheck
+		img = f
+	}
+	return ImageDecoder{MaxPixels: c.MaxPixels}.Convert(img)
+}
+
+// maxOutput returns the maximum size of the image read from stdout. A PNG image needs at most
+// 8 bytes per pixel and some space for the metadata.
+func (c ExternalConverter) maxOutput() int64 {
+	if c.MaxPixels == 0 {
+		return _maxConverterOutput
+	}
+	return int64(c.MaxPixels)*8 + 1024*1024
+}
+
+// command returns the command to run. The memory limit is applied by a shell, the
+// limits of a child process can't be set before it is started otherwise.
+func (c ExternalConverter) command(ctx context.Context, args []string) *exec.Cmd {
+	if c.MaxMemory == 0 {
+		return exec.CommandContext(ctx, c.Command, args...)
+	}
+
+	limit := strconv.FormatUint(c.MaxMemory/1024, 10)
+	shArgs := append([]string{"-c", `ulimit -v "$1" || exit 1; shift; exec "$@"`, "sh", limit, c.Command}, args...)
+	return exec.CommandContext(ctx, "/bin/sh", shArgs...)
+}
+
+// limitedWriter fails when more than n bytes are written to it
+type limitedWriter struct {
+	w        io.Writer
+	n        int64
+	exceeded bool
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > l.n {
+		l.exceeded = true
+		return 0, errConverterOutputTooLarge
+	}
+	n, err := l.w.Write(p)
+	l.n -= int64(n)
+	return n, err
+}
+
+func writeFile(name string, r io.Reader) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close() // nolint:errcheck
+		return err
+	}
+	return f.Close()
+}
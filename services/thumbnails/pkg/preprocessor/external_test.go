@@ -0,0 +1,78 @@
+package preprocessor
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testPNG(t *testing.T) []byte {
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, image.NewRGBA(image.Rect(0, 0, 4, 3))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExternalConverter(t *testing.T) {
+	tests := map[string]ExternalConverter{
+		"output file": {Command: "cp", Args: []string{InputPlaceholder, OutputPlaceholder}},
+		"stdout":      {Command: "cat", Args: []string{InputPlaceholder}},
+		"memory limit": {
+			Command:   "cat",
+			Args:      []string{InputPlaceholder},
+			MaxMemory: 512 * 1024 * 1024,
+		},
+	}
+
+	for name, c := range tests {
+		t.Run(name, func(t *testing.T) {
+			img, err := c.Convert(bytes.NewReader(testPNG(t)))
+			assert.NoError(t, err)
+			if assert.IsType(t, &image.NRGBA{}, img) {
+				assert.Equal(t, image.Rect(0, 0, 4, 3), img.(image.Image).Bounds())
+			}
+		})
+	}
+}
+
+func TestExternalConverterErrors(t *testing.T) {
+	_, err := ExternalConverter{Command: "false"}.Convert(bytes.NewReader(nil))
+	assert.Error(t, err)
+
+	_, err = ExternalConverter{Command: "sleep", Args: []string{"5"}, Timeout: 100 * time.Millisecond}.Convert(bytes.NewReader(nil))
+	assert.ErrorIs(t, err, ErrConverterTimeout)
+
+	_, err = ExternalConverter{Command: "true", Args: []string{OutputPlaceholder}}.Convert(bytes.NewReader(nil))
+	assert.Error(t, err)
+}
+
+func TestExternalConverterLimits(t *testing.T) {
+	// the png has 4x3 pixels
+	_, err := ExternalConverter{Command: "cat", Args: []string{InputPlaceholder}, MaxPixels: 11}.Convert(bytes.NewReader(testPNG(t)))
+	assert.ErrorIs(t, err, ErrImageTooLarge)
+
+	_, err = ExternalConverter{Command: "cp", Args: []string{InputPlaceholder, OutputPlaceholder}, MaxPixels: 11}.Convert(bytes.NewReader(testPNG(t)))
+	assert.ErrorIs(t, err, ErrImageTooLarge)
+
+	// the output on stdout is limited by the maximum number of pixels
+	_, err = ExternalConverter{Command: "head", Args: []string{"-c", "2000000", "/dev/zero"}, MaxPixels: 12}.Convert(bytes.NewReader(nil))
+	assert.ErrorIs(t, err, errConverterOutputTooLarge)
+}
+
+func TestForTypeConverters(t *testing.T) {
+	c := ExternalConverter{Command: "pdftoppm"}
+	opts := map[string]interface{}{
+		"converters": map[string]ExternalConverter{"application/pdf": c},
+	}
+
+	assert.Equal(t, c, ForType("application/pdf", opts))
+	assert.Equal(t, ImageDecoder{}, ForType("image/png", opts))
+
+	opts["maxPixels"] = uint64(100)
+	assert.Equal(t, ExternalConverter{Command: "pdftoppm", MaxPixels: 100}, ForType("application/pdf", opts))
+}
@@ -0,0 +1,90 @@
+package preprocessor
+
+import (
+	"encoding/binary"
+	"image"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrImageTooLarge is returned when the dimensions of an image exceed the maximum number of pixels
+	ErrImageTooLarge = errors.New("image exceeds the maximum number of pixels")
+	// ErrTooManyFrames is returned when an animated image has more frames than allowed
+	ErrTooManyFrames = errors.New("image exceeds the maximum number of frames")
+
+	errMalformedGIF = errors.New("malformed gif")
+)
+
+// checkPixels makes sure the dimensions of the image don't exceed the maximum number of pixels.
+// A maximum of 0 means no limit.
+func checkPixels(cfg image.Config, maxPixels uint64) error {
+	if maxPixels == 0 {
+		return nil
+	}
+	if uint64(cfg.Width)*uint64(cfg.Height) > maxPixels {
+		return errors.Wrapf(ErrImageTooLarge, "%dx%d", cfg.Width, cfg.Height)
+	}
+	return nil
+}
+
+// checkGIFFrames makes sure a GIF has at most maxFrames frames and the frames together have at most
+// maxTotalPixels pixels by walking its blocks without decoding them. It stops as soon as a limit is
+// exceeded, a maximum of 0 means no limit.
+// See https://www.w3.org/Graphics/GIF/spec-gif89a.txt for the structure.
+func checkGIFFrames(data []byte, maxFrames int, maxTotalPixels uint64) error {
+	// header and logical screen descriptor
+	if len(data) < 13 {
+		return errMalformedGIF
+	}
+	pos := 13
+	if flags := data[10]; flags&0x80 != 0 {
+		pos += 3 << (flags&0x07 + 1) // global color table
+	}
+
+	frames := 0
+	var pixels uint64
+	for pos < len(data) {
+		switch data[pos] {
+		case 0x21: // extension introducer and label
+			pos += 2
+		case 0x2C: // image descriptor
+			frames++
+			if maxFrames > 0 && frames > maxFrames {
+				return errors.Wrapf(ErrTooManyFrames, "more than %d frames", maxFrames)
+			}
+			if pos+10 > len(data) {
+				return errMalformedGIF
+			}
+			// every frame is decoded into an image of its own dimensions
+			pixels += uint64(binary.LittleEndian.Uint16(data[pos+5:])) * uint64(binary.LittleEndian.Uint16(data[pos+7:]))
+			if maxTotalPixels > 0 && pixels > maxTotalPixels {
+				return errors.Wrapf(ErrImageTooLarge, "the first %d frames have %d pixels", frames, pixels)
+			}
+			flags := data[pos+9]
+			pos += 10
+			if flags&0x80 != 0 {
+				pos += 3 << (flags&0x07 + 1) // local color table
+			}
+			pos++ // LZW minimum code size
+		case 0x3B: // trailer
+			return nil
+		default:
+			return errMalformedGIF
+		}
+
+		// data sub-blocks, terminated by a block of size 0
+		for {
+			if pos >= len(data) {
+				return errMalformedGIF
+			}
+			n := int(data[pos])
+			pos += n + 1
+			if n == 0 {
+				break
+			}
+		}
+	}
+	// the decoder decides about a missing trailer
+	return nil
+}
@@ -0,0 +1,72 @@
+package preprocessor
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color/palette"
+	"image/gif"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testGIF(t *testing.T, frames int) []byte {
+	m := &gif.GIF{}
+	for i := 0; i < frames; i++ {
+		m.Image = append(m.Image, image.NewPaletted(image.Rect(0, 0, 8, 6), palette.Plan9))
+		m.Delay = append(m.Delay, 10)
+	}
+	buf := new(bytes.Buffer)
+	if err := gif.EncodeAll(buf, m); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestCheckGIFFrames(t *testing.T) {
+	for _, frames := range []int{2, 3, 25} {
+		data := testGIF(t, frames)
+		assert.NoError(t, checkGIFFrames(data, frames, uint64(frames*48)))
+		assert.ErrorIs(t, checkGIFFrames(data, frames-1, 0), ErrTooManyFrames)
+		assert.ErrorIs(t, checkGIFFrames(data, 0, uint64(frames*48-1)), ErrImageTooLarge)
+	}
+
+	data := testGIF(t, 2)
+	assert.Error(t, checkGIFFrames(data[:len(data)/2], 0, 0), "truncated gif")
+
+	// the walk stops at the first frame exceeding the limit, the rest of the data isn't read
+	data = testGIF(t, 3)
+	assert.ErrorIs(t, checkGIFFrames(data[:len(data)-10], 1, 0), ErrTooManyFrames)
+}
+
+func TestImageDecoderMaxPixels(t *testing.T) {
+	// the png has 4x3 pixels
+	img, err := ImageDecoder{MaxPixels: 12}.Convert(bytes.NewReader(testPNG(t)))
+	assert.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 4, 3), img.(image.Image).Bounds())
+
+	_, err = ImageDecoder{MaxPixels: 11}.Convert(bytes.NewReader(testPNG(t)))
+	assert.True(t, errors.Is(err, ErrImageTooLarge))
+}
+
+func TestGifDecoderLimits(t *testing.T) {
+	data := testGIF(t, 5)
+
+	img, err := GifDecoder{MaxPixels: 48, MaxFrames: 5}.Convert(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Len(t, img.(*gif.GIF).Image, 5)
+
+	_, err = GifDecoder{MaxFrames: 4}.Convert(bytes.NewReader(data))
+	assert.True(t, errors.Is(err, ErrTooManyFrames))
+
+	_, err = GifDecoder{MaxPixels: 47}.Convert(bytes.NewReader(data))
+	assert.True(t, errors.Is(err, ErrImageTooLarge))
+
+	// the frames fit the maximum number of pixels on their own, but not together
+	_, err = GifDecoder{MaxPixels: 48, MaxTotalPixels: 239}.Convert(bytes.NewReader(data))
+	assert.True(t, errors.Is(err, ErrImageTooLarge))
+
+	_, err = ForType("image/gif", map[string]interface{}{"maxPixels": uint64(100)}).Convert(bytes.NewReader(data))
+	assert.True(t, errors.Is(err, ErrImageTooLarge))
+}
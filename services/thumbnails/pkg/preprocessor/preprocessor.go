@@ -2,6 +2,7 @@ package preprocessor
 
 import (
 	"bufio"
+	"bytes"
 	"image"
 	"image/draw"
 	"image/gif"
@@ -21,9 +22,26 @@ type FileConverter interface {
 	Convert(r io.Reader) (interface{}, error)
 }
 
-type ImageDecoder struct{}
+// ImageDecoder decodes images of the formats registered in the image package
+type ImageDecoder struct {
+	// MaxPixels limits the dimensions of the image, 0 means no limit
+	MaxPixels uint64
+}
 
 func (i ImageDecoder) Convert(r io.Reader) (interface{}, error) {
+	if i.MaxPixels > 0 {
+		// check the dimensions before allocating the memory for the image
+		header := new(bytes.Buffer)
+		cfg, _, err := image.DecodeConfig(io.TeeReader(r, header))
+		if err != nil {
+			return nil, errors.Wrap(err, `could not decode the image`)
+		}
+		if err := checkPixels(cfg, i.MaxPixels); err != nil {
+			return nil, err
+		}
+		r = io.MultiReader(header, r)
+	}
+
 	img, err := imaging.Decode(r, imaging.AutoOrientation(true))
 	if err != nil {
 		return nil, errors.Wrap(err, `could not decode the image`)
@@ -31,10 +49,42 @@ func (i ImageDecoder) Convert(r io.Reader) (interface{}, error) {
 	return img, nil
 }
 
-type GifDecoder struct{}
+// GifDecoder decodes all frames of a GIF
+type GifDecoder struct {
+	// MaxPixels limits the dimensions of the image, 0 means no limit
+	MaxPixels uint64
+	// MaxFrames limits the number of frames, 0 means no limit
+	MaxFrames int
+	// MaxTotalPixels limits the pixels of all frames together, which are decoded at once, 0 means no limit
+	MaxTotalPixels uint64
+}
 
 func (i GifDecoder) Convert(r io.Reader) (interface{}, error) {
-	img, err := gif.DecodeAll(r)
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, `could not read the image`)
+	}
+
+	if i.MaxPixels > 0 {
+		cfg, err := gif.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, errors.Wrap(err, `could not decode the image`)
+		}
+		if err := checkPixels(cfg, i.MaxPixels); err != nil {
+			return nil, err
+		}
+	}
+	if i.MaxFrames > 0 || i.MaxTotalPixels > 0 {
+		err := checkGIFFrames(data, i.MaxFrames, i.MaxTotalPixels)
+		switch {
+		case errors.Is(err, ErrTooManyFrames), errors.Is(err, ErrImageTooLarge):
+			return nil, err
+		case err != nil:
+			return nil, errors.Wrap(err, `could not decode the image`)
+		}
+	}
+
+	img, err := gif.DecodeAll(bytes.NewReader(data))
 	if err != nil {
 		return nil, errors.Wrap(err, `could not decode the image`)
 	}
@@ -165,6 +215,16 @@ func ForType(mimeType string, opts map[string]interface{}) FileConverter {
 	// We can ignore the error here because we parse it in IsMimeTypeSupported before and if it fails
 	// return the service call. So we should only get here when the mimeType parses fine.
 	mimeType, _, _ = mime.ParseMediaType(mimeType)
+	maxPixels, _ := opts["maxPixels"].(uint64)
+	maxGIFFrames, _ := opts["maxGIFFrames"].(int)
+
+	if converters, ok := opts["converters"].(map[string]ExternalConverter); ok {
+		if c, ok := converters[mimeType]; ok {
+			c.MaxPixels = maxPixels
+			return c
+		}
+	}
+
 	switch mimeType {
 	case "text/plain":
 		fontFileMap := ""
@@ -196,8 +256,15 @@ func ForType(mimeType string, opts map[string]interface{}) FileConverter {
 			fontLoader: fontLoader,
 		}
 	case "image/gif":
-		return GifDecoder{}
+		return GifDecoder{
+			MaxPixels: maxPixels,
+			MaxFrames: maxGIFFrames,
+			// all frames are decoded at once, together they must not need more memory than a single image
+			MaxTotalPixels: maxPixels,
+		}
 	default:
-		return ImageDecoder{}
+		return ImageDecoder{
+			MaxPixels: maxPixels,
+		}
 	}
 }
@@ -1,6 +1,8 @@
 package grpc
 
 import (
+	"github.com/cs3org/reva/v2/pkg/events"
+	"github.com/cs3org/reva/v2/pkg/events/stream"
 	"github.com/cs3org/reva/v2/pkg/rgrpc/todo/pool"
 	"github.com/owncloud/ocis/v2/ocis-pkg/registry"
 	"github.com/owncloud/ocis/v2/ocis-pkg/service/grpc"
@@ -52,20 +54,32 @@ func NewService(opts ...Option) grpc.Service {
 		options.Logger.Error().Err(err).Msg("could not get gateway selector")
 		return grpc.Service{}
 	}
+	thumbnailStorage, err := storage.New(tconf, options.Logger)
+	if err != nil {
+		options.Logger.Error().Err(err).Msg("could not create the thumbnail storage")
+		return grpc.Service{}
+	}
+
+	var consumer events.Consumer
+	if tconf.Pregenerate.Enabled {
+		consumer, err = stream.NatsFromConfig(stream.NatsConfig(options.Config.Events))
+		if err != nil {
+			options.Logger.Error().Err(err).Msg("could not connect to the event system")
+			return grpc.Service{}
+		}
+	}
+
 	var thumbnail decorators.DecoratedService
 	{
 		thumbnail = svc.NewService(
 			svc.Config(options.Config),
 			svc.Logger(options.Logger),
 			svc.ThumbnailSource(imgsource.NewWebDavSource(tconf)),
-			svc.ThumbnailStorage(
-				storage.NewFileSystemStorage(
-					tconf.FileSystemStorage,
-					options.Logger,
-				),
-			),
+			svc.ThumbnailStorage(thumbnailStorage),
 			svc.CS3Source(imgsource.NewCS3Source(tconf, gatewaySelector)),
 			svc.GatewaySelector(gatewaySelector),
+			svc.Metrics(options.Metrics),
+			svc.EventsConsumer(consumer),
 		)
 		thumbnail = decorators.NewInstrument(thumbnail, options.Metrics)
 		thumbnail = decorators.NewLogging(thumbnail, options.Logger)
@@ -32,6 +32,11 @@ func Server(opts ...Option) (http.Service, error) {
 		return http.Service{}, fmt.Errorf("could not initialize http service: %w", err)
 	}
 
+	thumbnailStorage, err := storage.New(options.Config.Thumbnail, options.Logger)
+	if err != nil {
+		return http.Service{}, err
+	}
+
 	handle := svc.NewService(
 		svc.Logger(options.Logger),
 		svc.Config(options.Config),
@@ -45,12 +50,7 @@ func Server(opts ...Option) (http.Service, error) {
 			),
 			ocismiddleware.Logger(options.Logger),
 		),
-		svc.ThumbnailStorage(
-			storage.NewFileSystemStorage(
-				options.Config.Thumbnail.FileSystemStorage,
-				options.Logger,
-			),
-		),
+		svc.ThumbnailStorage(thumbnailStorage),
 	)
 
 	{
@@ -4,9 +4,11 @@ import (
 	"net/http"
 
 	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	"github.com/cs3org/reva/v2/pkg/events"
 	"github.com/cs3org/reva/v2/pkg/rgrpc/todo/pool"
 	"github.com/owncloud/ocis/v2/ocis-pkg/log"
 	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/config"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/metrics"
 	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/thumbnail/imgsource"
 	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/thumbnail/storage"
 )
@@ -23,6 +25,8 @@ type Options struct {
 	ImageSource      imgsource.Source
 	CS3Source        imgsource.Source
 	GatewaySelector  pool.Selectable[gateway.GatewayAPIClient]
+	Metrics          *metrics.Metrics
+	EventsConsumer   events.Consumer
 }
 
 // newOptions initializes the available default options.
@@ -84,3 +88,17 @@ func GatewaySelector(val pool.Selectable[gateway.GatewayAPIClient]) Option {
 		o.GatewaySelector = val
 	}
 }
+
+// Metrics provides a function to set the metrics option.
+func Metrics(val *metrics.Metrics) Option {
+	return func(o *Options) {
+		o.Metrics = val
+	}
+}
+
+// EventsConsumer provides a function to set the consumer of the events used to pre-generate thumbnails.
+func EventsConsumer(val events.Consumer) Option {
+	return func(o *Options) {
+		o.EventsConsumer = val
+	}
+}
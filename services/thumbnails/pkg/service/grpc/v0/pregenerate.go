@@ -0,0 +1,172 @@
+package svc
+
+import (
+	"context"
+	"sync"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	revactx "github.com/cs3org/reva/v2/pkg/ctx"
+	"github.com/cs3org/reva/v2/pkg/events"
+	"github.com/cs3org/reva/v2/pkg/storagespace"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/thumbnail"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/thumbnail/imgsource"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+)
+
+// _defaultPregenerateType is the thumbnail type used for files which are no jpg, png or gif images.
+// It is the type the webdav service requests for them.
+const _defaultPregenerateType = "jpg"
+
+// Pregenerator generates the thumbnails of uploaded files before they are requested
+type Pregenerator struct {
+	thumbnail         Thumbnail
+	resolutions       thumbnail.Resolutions
+	numWorkers        int
+	maxFileSize       uint64
+	machineAuthAPIKey string
+}
+
+// NewPregenerator returns a Pregenerator generating the given resolutions with the manager and sources of the service
+func NewPregenerator(t Thumbnail, resolutions thumbnail.Resolutions, numWorkers int, maxFileSize uint64, machineAuthAPIKey string) Pregenerator {
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+	return Pregenerator{
+		thumbnail:         t,
+		resolutions:       resolutions,
+		numWorkers:        numWorkers,
+		maxFileSize:       maxFileSize,
+		machineAuthAPIKey: machineAuthAPIKey,
+	}
+}
+
+// Run generates the thumbnails for the files of the UploadReady and FileTouched events in a pool of workers.
+// It returns when the channel is closed and all workers are done.
+func (p Pregenerator) Run(ch <-chan events.Event) {
+	wg := sync.WaitGroup{}
+	for i := 0; i < p.numWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range ch {
+				switch ev := e.Event.(type) {
+				case events.UploadReady:
+					if ev.Failed {
+						continue
+					}
+					uID := ev.ExecutingUser.GetId()
+					if uID == nil {
+						uID = ev.SpaceOwner
+					}
+					p.Pregenerate(ev.FileRef, uID)
+				case events.FileTouched:
+					uID := ev.Executant
+					if uID == nil {
+						uID = ev.SpaceOwner
+					}
+					p.Pregenerate(ev.Ref, uID)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// Pregenerate generates the missing thumbnails of a file on behalf of the given user.
+// Unsupported files and files exceeding the maximum size are skipped.
+func (p Pregenerator) Pregenerate(ref *provider.Reference, uID *user.UserId) {
+	logger := p.thumbnail.logger.With().Interface("ref", ref).Logger()
+
+	auth, err := p.authenticate(uID)
+	if err != nil {
+		logger.Error().Err(err).Interface("user", uID).Msg("could not impersonate the user")
+		return
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), revactx.TokenHeader, auth)
+	sRes, err := p.thumbnail.statRef(ctx, ref)
+	if err != nil {
+		// unsupported, still processing or gone
+		logger.Debug().Err(err).Msg("skipping thumbnail generation")
+		return
+	}
+	info := sRes.GetInfo()
+	if p.maxFileSize > 0 && info.GetSize() > p.maxFileSize {
+		logger.Debug().Uint64("size", info.GetSize()).Msg("file is too big, skipping thumbnail generation")
+		return
+	}
+
+	tType := thumbnail.GetExtForMime(info.GetMimeType())
+	if tType == "" {
+		tType = _defaultPregenerateType
+	}
+
+	requests := make([]thumbnail.Request, 0, len(p.resolutions))
+	for _, r := range p.resolutions {
+		tr, err := thumbnail.PrepareRequest(r.Dx(), r.Dy(), tType, info.GetChecksum().GetSum())
+		if err != nil {
+			logger.Error().Err(err).Msg("could not prepare the thumbnail request")
+			return
+		}
+		if _, exists := p.thumbnail.manager.CheckThumbnail(tr); !exists {
+			requests = append(requests, tr)
+		}
+	}
+	if len(requests) == 0 {
+		return
+	}
+
+	// pre-generations share the limit of concurrent generations with the requests
+	release, err := p.thumbnail.acquire(context.Background())
+	if err != nil {
+		return
+	}
+	defer release()
+
+	ctx = imgsource.ContextSetAuthorization(ctx, auth)
+	r, err := p.thumbnail.cs3Source.Get(ctx, storagespace.FormatResourceID(*info.GetId()))
+	if err != nil {
+		logger.Error().Err(err).Msg("could not get image from source")
+		return
+	}
+	defer r.Close() // nolint:errcheck
+	img, err := p.thumbnail.convert(r, info.GetMimeType())
+	if err != nil {
+		logger.Debug().Err(err).Msg("could not get image")
+		return
+	}
+
+	for _, tr := range requests {
+		if _, err := p.thumbnail.manager.Generate(tr, img); err != nil {
+			logger.Error().Err(err).Str("resolution", tr.Resolution.String()).Msg("could not generate thumbnail")
+		}
+	}
+}
+
+// authenticate returns a token of the user using the machine auth
+func (p Pregenerator) authenticate(uID *user.UserId) (string, error) {
+	if uID == nil {
+		return "", errors.New("user is missing")
+	}
+
+	gwc, err := p.thumbnail.selector.Next()
+	if err != nil {
+		return "", err
+	}
+	res, err := gwc.Authenticate(context.Background(), &gateway.AuthenticateRequest{
+		Type:         "machine",
+		ClientId:     "userid:" + uID.GetOpaqueId(),
+		ClientSecret: p.machineAuthAPIKey,
+	})
+	if err != nil {
+		return "", err
+	}
+	if res.GetStatus().GetCode() != rpc.Code_CODE_OK {
+		return "", errors.Errorf("could not authenticate: %s", res.GetStatus().GetMessage())
+	}
+	return res.GetToken(), nil
+}
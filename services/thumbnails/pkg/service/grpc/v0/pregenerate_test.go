@@ -0,0 +1,107 @@
+package svc
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"io"
+	"testing"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/v2/pkg/rgrpc/todo/pool"
+	cs3mocks "github.com/cs3org/reva/v2/tests/cs3mocks/mocks"
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/thumbnail"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/thumbnail/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+)
+
+type countingSource struct {
+	img   []byte
+	calls int
+}
+
+func (s *countingSource) Get(_ context.Context, _ string) (io.ReadCloser, error) {
+	s.calls++
+	return io.NopCloser(bytes.NewReader(s.img)), nil
+}
+
+func newTestPregenerator(t *testing.T, size uint64, maxFileSize uint64) (Pregenerator, *countingSource) {
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, image.NewRGBA(image.Rect(0, 0, 64, 64))); err != nil {
+		t.Fatal(err)
+	}
+	src := &countingSource{img: buf.Bytes()}
+
+	gatewayClient := &cs3mocks.GatewayAPIClient{}
+	gatewayClient.On("Authenticate", mock.Anything, mock.Anything).Return(&gateway.AuthenticateResponse{
+		Status: &rpc.Status{Code: rpc.Code_CODE_OK},
+		Token:  "token",
+	}, nil)
+	gatewayClient.On("Stat", mock.Anything, mock.Anything).Return(&provider.StatResponse{
+		Status: &rpc.Status{Code: rpc.Code_CODE_OK},
+		Info: &provider.ResourceInfo{
+			Id:       &provider.ResourceId{StorageId: "storage", SpaceId: "space", OpaqueId: "file"},
+			Type:     provider.ResourceType_RESOURCE_TYPE_FILE,
+			MimeType: "image/png",
+			Size:     size,
+			Checksum: &provider.ResourceChecksum{Sum: "5c4b7b0e9f2b5e6a"},
+		},
+	}, nil)
+
+	pool.RemoveSelector("GatewaySelector" + "com.owncloud.api.gateway")
+	selector := pool.GetSelector[gateway.GatewayAPIClient](
+		"GatewaySelector",
+		"com.owncloud.api.gateway",
+		func(cc *grpc.ClientConn) gateway.GatewayAPIClient {
+			return gatewayClient
+		},
+	)
+
+	resolutions, err := thumbnail.ParseResolutions([]string{"16x16", "32x32"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := Thumbnail{
+		manager:            thumbnail.NewSimpleManager(resolutions, storage.NewInMemoryStorage(), log.NopLogger()),
+		cs3Source:          src,
+		logger:             log.NopLogger(),
+		selector:           selector,
+		supportedMimeTypes: thumbnail.SupportedMimeTypes,
+	}
+	return NewPregenerator(svc, resolutions, 1, maxFileSize, "secret"), src
+}
+
+func TestPregenerate(t *testing.T) {
+	p, src := newTestPregenerator(t, 1024, 2048)
+	ref := &provider.Reference{ResourceId: &provider.ResourceId{StorageId: "storage", SpaceId: "space", OpaqueId: "file"}}
+
+	p.Pregenerate(ref, &user.UserId{OpaqueId: "user"})
+	assert.Equal(t, 1, src.calls, "the file should be downloaded once")
+
+	for _, res := range []string{"16x16", "32x32"} {
+		r, _ := thumbnail.ParseResolution(res)
+		tr, err := thumbnail.PrepareRequest(r.Dx(), r.Dy(), "png", "5c4b7b0e9f2b5e6a")
+		assert.NoError(t, err)
+		_, exists := p.thumbnail.manager.CheckThumbnail(tr)
+		assert.True(t, exists, "thumbnail %s should exist", res)
+	}
+
+	// existing thumbnails are not generated again
+	p.Pregenerate(ref, &user.UserId{OpaqueId: "user"})
+	assert.Equal(t, 1, src.calls)
+}
+
+func TestPregenerateSkipsBigFiles(t *testing.T) {
+	p, src := newTestPregenerator(t, 4096, 2048)
+	ref := &provider.Reference{ResourceId: &provider.ResourceId{StorageId: "storage", SpaceId: "space", OpaqueId: "file"}}
+
+	p.Pregenerate(ref, &user.UserId{OpaqueId: "user"})
+	assert.Equal(t, 0, src.calls)
+}
@@ -2,6 +2,8 @@ package svc
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"path"
@@ -12,12 +14,14 @@ import (
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	revactx "github.com/cs3org/reva/v2/pkg/ctx"
+	"github.com/cs3org/reva/v2/pkg/events"
 	"github.com/cs3org/reva/v2/pkg/rgrpc/todo/pool"
 	"github.com/cs3org/reva/v2/pkg/storagespace"
 	"github.com/cs3org/reva/v2/pkg/utils"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/owncloud/ocis/v2/ocis-pkg/log"
 	thumbnailssvc "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/thumbnails/v0"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/metrics"
 	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/preprocessor"
 	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/service/grpc/v0/decorators"
 	tjwt "github.com/owncloud/ocis/v2/services/thumbnails/pkg/service/jwt"
@@ -36,6 +40,23 @@ func NewService(opts ...Option) decorators.DecoratedService {
 	if err != nil {
 		logger.Fatal().Err(err).Msg("resolutions not configured correctly")
 	}
+	// the mime types of the external converters are supported in addition to the built-in ones
+	supportedMimeTypes := make(map[string]struct{}, len(thumbnail.SupportedMimeTypes))
+	for m := range thumbnail.SupportedMimeTypes {
+		supportedMimeTypes[m] = struct{}{}
+	}
+	converters := map[string]preprocessor.ExternalConverter{}
+	for _, c := range options.Config.Thumbnail.Converters {
+		for _, m := range c.MimeTypes {
+			converters[m] = preprocessor.ExternalConverter{
+				Command:   c.Command,
+				Args:      c.Args,
+				Timeout:   c.Timeout,
+				MaxMemory: c.MaxMemory,
+			}
+			supportedMimeTypes[m] = struct{}{}
+		}
+	}
 	svc := Thumbnail{
 		serviceID: options.Config.GRPC.Namespace + "." + options.Config.Service.Name,
 		manager: thumbnail.NewSimpleManager(
@@ -49,9 +70,34 @@ func NewService(opts ...Option) decorators.DecoratedService {
 		selector:     options.GatewaySelector,
 		preprocessorOpts: PreprocessorOpts{
 			TxtFontFileMap: options.Config.Thumbnail.FontMapFile,
+			Converters:     converters,
+			MaxPixels:      options.Config.Thumbnail.MaxInputPixels,
+			MaxGIFFrames:   options.Config.Thumbnail.MaxGIFFrames,
 		},
-		dataEndpoint:   options.Config.Thumbnail.DataEndpoint,
-		transferSecret: options.Config.Thumbnail.TransferSecret,
+		supportedMimeTypes: supportedMimeTypes,
+		dataEndpoint:       options.Config.Thumbnail.DataEndpoint,
+		transferSecret:     options.Config.Thumbnail.TransferSecret,
+		metrics:            options.Metrics,
+		maxInputSize:       options.Config.Thumbnail.MaxInputSize,
+	}
+	if n := options.Config.Thumbnail.MaxConcurrentRequests; n > 0 {
+		svc.generationSlots = make(chan struct{}, n)
+	}
+
+	if options.EventsConsumer != nil {
+		pregen := options.Config.Thumbnail.Pregenerate
+		pregenResolutions := resolutions
+		if len(pregen.Resolutions) > 0 {
+			pregenResolutions, err = thumbnail.ParseResolutions(pregen.Resolutions)
+			if err != nil {
+				logger.Fatal().Err(err).Msg("pre-generation resolutions not configured correctly")
+			}
+		}
+		ch, err := events.Consume(options.EventsConsumer, "thumbnails", events.UploadReady{}, events.FileTouched{})
+		if err != nil {
+			logger.Fatal().Err(err).Msg("could not consume the events for the pre-generation of thumbnails")
+		}
+		go NewPregenerator(svc, pregenResolutions, pregen.NumWorkers, pregen.MaxFileSize, options.Config.MachineAuthAPIKey).Run(ch)
 	}
 
 	return svc
@@ -68,11 +114,20 @@ type Thumbnail struct {
 	logger           log.Logger
 	selector         pool.Selectable[gateway.GatewayAPIClient]
 	preprocessorOpts PreprocessorOpts
+	// supportedMimeTypes are the built-in mime types and those of the external converters
+	supportedMimeTypes map[string]struct{}
+	metrics            *metrics.Metrics
+	maxInputSize       uint64
+	// limits the concurrent generations, nil means no limit
+	generationSlots chan struct{}
 }
 
 // PreprocessorOpts holds the options for the preprocessor
 type PreprocessorOpts struct {
 	TxtFontFileMap string
+	Converters     map[string]preprocessor.ExternalConverter
+	MaxPixels      uint64
+	MaxGIFFrames   int
 }
 
 // GetThumbnail retrieves a thumbnail for an image
@@ -129,23 +184,25 @@ func (g Thumbnail) handleCS3Source(ctx context.Context, req *thumbnailssvc.GetTh
 		return "", merrors.BadRequest(g.serviceID, err.Error())
 	}
 
-	if key, exists := g.manager.CheckThumbnail(tr); exists {
+	if key, exists := g.checkThumbnail(tr); exists {
 		return key, nil
 	}
 
+	release, err := g.acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
 	ctx = imgsource.ContextSetAuthorization(ctx, src.Authorization)
 	r, err := g.cs3Source.Get(ctx, src.Path)
 	if err != nil {
 		return "", merrors.InternalServerError(g.serviceID, "could not get image from source: %s", err.Error())
 	}
 	defer r.Close() // nolint:errcheck
-	ppOpts := map[string]interface{}{
-		"fontFileMap": g.preprocessorOpts.TxtFontFileMap,
-	}
-	pp := preprocessor.ForType(sRes.GetInfo().GetMimeType(), ppOpts)
-	img, err := pp.Convert(r)
-	if img == nil || err != nil {
-		return "", merrors.InternalServerError(g.serviceID, "could not get image")
+	img, err := g.convert(r, sRes.GetInfo().GetMimeType())
+	if err != nil {
+		return "", err
 	}
 
 	key, err := g.manager.Generate(tr, img)
@@ -212,10 +269,16 @@ func (g Thumbnail) handleWebdavSource(ctx context.Context, req *thumbnailssvc.Ge
 		return "", merrors.BadRequest(g.serviceID, err.Error())
 	}
 
-	if key, exists := g.manager.CheckThumbnail(tr); exists {
+	if key, exists := g.checkThumbnail(tr); exists {
 		return key, nil
 	}
 
+	release, err := g.acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
 	if src.WebdavAuthorization != "" {
 		ctx = imgsource.ContextSetAuthorization(ctx, src.WebdavAuthorization)
 	}
@@ -225,13 +288,9 @@ func (g Thumbnail) handleWebdavSource(ctx context.Context, req *thumbnailssvc.Ge
 		return "", merrors.InternalServerError(g.serviceID, "could not get image from source: %s", err.Error())
 	}
 	defer r.Close() // nolint:errcheck
-	ppOpts := map[string]interface{}{
-		"fontFileMap": g.preprocessorOpts.TxtFontFileMap,
-	}
-	pp := preprocessor.ForType(sRes.GetInfo().GetMimeType(), ppOpts)
-	img, err := pp.Convert(r)
-	if img == nil || err != nil {
-		return "", merrors.InternalServerError(g.serviceID, "could not get image")
+	img, err := g.convert(r, sRes.GetInfo().GetMimeType())
+	if err != nil {
+		return "", err
 	}
 
 	key, err := g.manager.Generate(tr, img)
@@ -241,6 +300,74 @@ func (g Thumbnail) handleWebdavSource(ctx context.Context, req *thumbnailssvc.Ge
 	return key, nil
 }
 
+// acquire waits for a free generation slot if the number of concurrent generations is limited.
+// The returned function releases the slot.
+func (g Thumbnail) acquire(ctx context.Context) (func(), error) {
+	if g.generationSlots == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case g.generationSlots <- struct{}{}:
+		return func() { <-g.generationSlots }, nil
+	case <-ctx.Done():
+		return nil, &merrors.Error{
+			Id:     g.serviceID,
+			Code:   http.StatusTooManyRequests,
+			Detail: "too many thumbnails are generated at the moment",
+			Status: http.StatusText(http.StatusTooManyRequests),
+		}
+	}
+}
+
+// convert decodes the source file into an image with the preprocessor for its mime type
+func (g Thumbnail) convert(r io.Reader, mimeType string) (interface{}, error) {
+	ppOpts := map[string]interface{}{
+		"fontFileMap":  g.preprocessorOpts.TxtFontFileMap,
+		"converters":   g.preprocessorOpts.Converters,
+		"maxPixels":    g.preprocessorOpts.MaxPixels,
+		"maxGIFFrames": g.preprocessorOpts.MaxGIFFrames,
+	}
+	pp := preprocessor.ForType(mimeType, ppOpts)
+	img, err := pp.Convert(r)
+	switch {
+	case errors.Is(err, preprocessor.ErrImageTooLarge), errors.Is(err, preprocessor.ErrTooManyFrames):
+		return nil, g.limitExceeded(err.Error())
+	case errors.Is(err, preprocessor.ErrConverterTimeout):
+		return nil, &merrors.Error{
+			Id:     g.serviceID,
+			Code:   http.StatusGatewayTimeout,
+			Detail: fmt.Sprintf("the converter for %s files did not finish in time", mimeType),
+			Status: http.StatusText(http.StatusGatewayTimeout),
+		}
+	case img == nil || err != nil:
+		return nil, merrors.InternalServerError(g.serviceID, "could not get image")
+	}
+	return img, nil
+}
+
+func (g Thumbnail) limitExceeded(detail string) error {
+	return &merrors.Error{
+		Id:     g.serviceID,
+		Code:   http.StatusRequestEntityTooLarge,
+		Detail: detail,
+		Status: http.StatusText(http.StatusRequestEntityTooLarge),
+	}
+}
+
+// checkThumbnail looks the thumbnail up in the storage and counts the cache hits and misses
+func (g Thumbnail) checkThumbnail(tr thumbnail.Request) (string, bool) {
+	key, exists := g.manager.CheckThumbnail(tr)
+	if g.metrics != nil {
+		result := "miss"
+		if exists {
+			result = "hit"
+		}
+		g.metrics.CacheRequests.WithLabelValues(result).Inc()
+	}
+	return key, exists
+}
+
 func (g Thumbnail) stat(path, auth string) (*provider.StatResponse, error) {
 	ctx := metadata.AppendToOutgoingContext(context.Background(), revactx.TokenHeader, auth)
 
@@ -252,12 +379,21 @@ func (g Thumbnail) stat(path, auth string) (*provider.StatResponse, error) {
 			Path: path,
 		}
 	}
+	return g.statRef(ctx, &ref)
+}
+
+// statRef stats the referenced file and checks if a thumbnail can be generated for it
+func (g Thumbnail) statRef(ctx context.Context, ref *provider.Reference) (*provider.StatResponse, error) {
+	path := ref.GetPath()
+	if ref.GetResourceId() != nil {
+		path = storagespace.FormatResourceID(*ref.GetResourceId()) + "/" + path
+	}
 
 	client, err := g.selector.Next()
 	if err != nil {
 		return nil, merrors.InternalServerError(g.serviceID, "could not select next gateway client: %s", err.Error())
 	}
-	req := &provider.StatRequest{Ref: &ref}
+	req := &provider.StatRequest{Ref: ref}
 	rsp, err := client.Stat(ctx, req)
 	if err != nil {
 		g.logger.Error().Err(err).Str("path", path).Msg("could not stat file")
@@ -288,8 +424,11 @@ func (g Thumbnail) stat(path, auth string) (*provider.StatResponse, error) {
 		g.logger.Error().Msg("resource info is missing checksum")
 		return nil, merrors.NotFound(g.serviceID, "resource info is missing a checksum")
 	}
-	if !thumbnail.IsMimeTypeSupported(rsp.Info.MimeType) {
+	if !thumbnail.IsMimeTypeSupported(rsp.Info.MimeType, g.supportedMimeTypes) {
 		return nil, merrors.NotFound(g.serviceID, "Unsupported file type")
 	}
+	if g.maxInputSize > 0 && rsp.Info.GetSize() > g.maxInputSize {
+		return nil, g.limitExceeded(fmt.Sprintf("file size %d exceeds the maximum of %d bytes", rsp.Info.GetSize(), g.maxInputSize))
+	}
 	return rsp, nil
 }
@@ -0,0 +1,42 @@
+package svc
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/config"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/preprocessor"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/thumbnail"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	merrors "go-micro.dev/v4/errors"
+)
+
+func TestConverterMimeTypesStayOnTheService(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Thumbnail.Resolutions = []string{"16x16"}
+	cfg.Thumbnail.Converters = []config.Converter{{MimeTypes: []string{"application/pdf"}, Command: "pdftoppm"}}
+
+	svc := NewService(Config(cfg), Logger(log.NopLogger())).(Thumbnail)
+	assert.True(t, thumbnail.IsMimeTypeSupported("application/pdf", svc.supportedMimeTypes))
+	assert.True(t, thumbnail.IsMimeTypeSupported("image/png", svc.supportedMimeTypes))
+	assert.False(t, thumbnail.IsMimeTypeSupported("application/pdf", thumbnail.SupportedMimeTypes))
+}
+
+func TestConvertConverterTimeout(t *testing.T) {
+	g := Thumbnail{
+		serviceID: "com.owncloud.api.thumbnails",
+		preprocessorOpts: PreprocessorOpts{Converters: map[string]preprocessor.ExternalConverter{
+			"application/pdf": {Command: "sleep", Args: []string{"5"}, Timeout: 100 * time.Millisecond},
+		}},
+	}
+
+	_, err := g.convert(bytes.NewReader(nil), "application/pdf")
+	require.Error(t, err)
+	e := merrors.FromError(err)
+	assert.Equal(t, int32(http.StatusGatewayTimeout), e.Code)
+	assert.Contains(t, e.Detail, "did not finish in time")
+}
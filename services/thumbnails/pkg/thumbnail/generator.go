@@ -38,16 +38,20 @@ type GifGenerator struct{}
 func (g GifGenerator) GenerateThumbnail(size image.Rectangle, img interface{}) (interface{}, error) {
 	// Code inspired by https://github.com/willnorris/gifresize/blob/db93a7e1dcb1c279f7eeb99cc6d90b9e2e23e871/gifresize.go
 
-	m, ok := img.(*gif.GIF)
+	src, ok := img.(*gif.GIF)
 	if !ok {
 		return nil, ErrInvalidType2
 	}
+	// The source is not modified, the same image can be used to generate several thumbnails.
+	m := *src
+	m.Image = make([]*image.Paletted, len(src.Image))
+
 	// Create a new RGBA image to hold the incremental frames.
 	srcX, srcY := m.Config.Width, m.Config.Height
 	b := image.Rect(0, 0, srcX, srcY)
 	tmp := image.NewRGBA(b)
 
-	for i, frame := range m.Image {
+	for i, frame := range src.Image {
 		bounds := frame.Bounds()
 		prev := tmp
 		draw.Draw(tmp, bounds, frame, bounds.Min, draw.Over)
@@ -64,7 +68,7 @@ func (g GifGenerator) GenerateThumbnail(size image.Rectangle, img interface{}) (
 	m.Config.Width = size.Dx()
 	m.Config.Height = size.Dy()
 
-	return m, nil
+	return &m, nil
 }
 
 func (g GifGenerator) imageToPaletted(img image.Image, p color.Palette) *image.Paletted {
@@ -0,0 +1,32 @@
+package thumbnail
+
+import (
+	"image"
+	"image/color/palette"
+	"image/gif"
+	"testing"
+)
+
+func TestGifGeneratorKeepsSource(t *testing.T) {
+	src := &gif.GIF{
+		Image:    []*image.Paletted{image.NewPaletted(image.Rect(0, 0, 64, 64), palette.Plan9), image.NewPaletted(image.Rect(0, 0, 64, 64), palette.Plan9)},
+		Delay:    []int{10, 10},
+		Disposal: []byte{gif.DisposalNone, gif.DisposalNone},
+		Config:   image.Config{Width: 64, Height: 64},
+	}
+
+	for _, size := range []image.Rectangle{image.Rect(0, 0, 32, 32), image.Rect(0, 0, 16, 16)} {
+		thumb, err := GifGenerator{}.GenerateThumbnail(size, src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := thumb.(*gif.GIF)
+		if m.Config.Width != size.Dx() || m.Image[1].Bounds() != size {
+			t.Errorf("expected a thumbnail of %v, got %v", size, m.Image[1].Bounds())
+		}
+	}
+
+	if src.Config.Width != 64 || src.Image[0].Bounds() != image.Rect(0, 0, 64, 64) {
+		t.Error("the source image was modified")
+	}
+}
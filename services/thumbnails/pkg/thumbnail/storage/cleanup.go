@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// PruneResult reports the usage of the thumbnail cache before and after pruning it.
+type PruneResult struct {
+	Files        int
+	Bytes        int64
+	RemovedFiles int
+	RemovedBytes int64
+}
+
+type cachedFile struct {
+	path       string
+	size       int64
+	lastAccess time.Time
+}
+
+// Usage returns the number of thumbnails and their total size in bytes.
+func (s FileSystem) Usage() (int, int64, error) {
+	files, err := s.files()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var size int64
+	for _, f := range files {
+		size += f.size
+	}
+	return len(files), size, nil
+}
+
+// Pruner is implemented by the storages which can remove thumbnails exceeding a maximum age or size.
+type Pruner interface {
+	Prune(maxSize uint64, maxAge time.Duration, dryRun bool) (PruneResult, error)
+}
+
+// Prune removes the thumbnails which were not accessed within maxAge and then the least recently
+// accessed thumbnails until the total size does not exceed maxSize. Zero values disable the limits.
+// With dryRun the thumbnails to remove are only counted.
+func (s FileSystem) Prune(maxSize uint64, maxAge time.Duration, dryRun bool) (PruneResult, error) {
+	files, err := s.files()
+	if err != nil {
+		return PruneResult{}, err
+	}
+
+	res, err := prune(files, maxSize, maxAge, dryRun, func(f cachedFile) error {
+		if err := os.Remove(f.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return errors.Wrapf(err, "could not remove thumbnail %s", f.path)
+		}
+		return nil
+	})
+	if !dryRun && res.RemovedFiles > 0 {
+		s.removeEmptyDirs()
+	}
+	return res, err
+}
+
+// prune removes the expired files and then the least recently accessed files until the total
+// size does not exceed maxSize.
+func prune(files []cachedFile, maxSize uint64, maxAge time.Duration, dryRun bool, remove func(cachedFile) error) (PruneResult, error) {
+	res := PruneResult{Files: len(files)}
+	for _, f := range files {
+		res.Bytes += f.size
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].lastAccess.Before(files[j].lastAccess)
+	})
+
+	remaining := res.Bytes
+	for _, f := range files {
+		expired := maxAge > 0 && time.Since(f.lastAccess) > maxAge
+		tooLarge := maxSize > 0 && uint64(remaining) > maxSize
+		if !expired && !tooLarge {
+			// the files are sorted by last access, none of the others expired either
+			break
+		}
+
+		if !dryRun {
+			if err := remove(f); err != nil {
+				return res, err
+			}
+		}
+		remaining -= f.size
+		res.RemovedFiles++
+		res.RemovedBytes += f.size
+	}
+	return res, nil
+}
+
+// files lists all thumbnails. The modification time of a thumbnail is its last access,
+// it is updated whenever the thumbnail is loaded.
+func (s FileSystem) files() ([]cachedFile, error) {
+	var files []cachedFile
+	err := filepath.WalkDir(filepath.Join(s.root, filesDir), func(path string, d fs.DirEntry, err error) error {
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			return nil
+		case err != nil:
+			return err
+		case d.IsDir():
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		files = append(files, cachedFile{path: path, size: info.Size(), lastAccess: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list the thumbnails")
+	}
+	return files, nil
+}
+
+// removeEmptyDirs removes the directories which do not contain thumbnails anymore,
+// the deepest directories first.
+func (s FileSystem) removeEmptyDirs() {
+	root := filepath.Join(s.root, filesDir)
+	var dirs []string
+	_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() && path != root {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		// fails for directories which are not empty
+		_ = os.Remove(dirs[i])
+	}
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrune(t *testing.T) {
+	now := time.Now()
+	setup := func(t *testing.T) FileSystem {
+		fs := NewFileSystemStorage(config.FileSystemStorage{RootDirectory: t.TempDir()}, log.NewLogger())
+		for key, age := range map[string]time.Duration{
+			"aa/bb/old/32x32.png":    48 * time.Hour,
+			"aa/cc/recent/32x32.png": time.Hour,
+			"aa/dd/new/32x32.png":    0,
+		} {
+			require.NoError(t, fs.Put(key, make([]byte, 100)))
+			ts := now.Add(-age)
+			require.NoError(t, os.Chtimes(filepath.Join(fs.root, filesDir, key), ts, ts))
+		}
+		return fs
+	}
+
+	t.Run("max age", func(t *testing.T) {
+		fs := setup(t)
+		res, err := fs.Prune(0, 24*time.Hour, false)
+		require.NoError(t, err)
+		assert.Equal(t, PruneResult{Files: 3, Bytes: 300, RemovedFiles: 1, RemovedBytes: 100}, res)
+		assert.False(t, fs.Stat("aa/bb/old/32x32.png"))
+		assert.True(t, fs.Stat("aa/cc/recent/32x32.png"))
+
+		_, err = os.Stat(filepath.Join(fs.root, filesDir, "aa/bb"))
+		assert.True(t, os.IsNotExist(err), "empty directories are removed")
+	})
+
+	t.Run("max size removes the least recently used", func(t *testing.T) {
+		fs := setup(t)
+		res, err := fs.Prune(150, 0, false)
+		require.NoError(t, err)
+		assert.Equal(t, 2, res.RemovedFiles)
+		assert.False(t, fs.Stat("aa/bb/old/32x32.png"))
+		assert.False(t, fs.Stat("aa/cc/recent/32x32.png"))
+		assert.True(t, fs.Stat("aa/dd/new/32x32.png"))
+	})
+
+	t.Run("get updates the last access", func(t *testing.T) {
+		fs := setup(t)
+		_, err := fs.Get("aa/bb/old/32x32.png")
+		require.NoError(t, err)
+
+		_, err = fs.Prune(0, 24*time.Hour, false)
+		require.NoError(t, err)
+		assert.True(t, fs.Stat("aa/bb/old/32x32.png"))
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		fs := setup(t)
+		res, err := fs.Prune(1, 0, true)
+		require.NoError(t, err)
+		assert.Equal(t, 3, res.RemovedFiles)
+
+		files, size, err := fs.Usage()
+		require.NoError(t, err)
+		assert.Equal(t, 3, files)
+		assert.Equal(t, int64(300), size)
+	})
+}
@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"context"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/v2/pkg/errtypes"
+	"github.com/cs3org/reva/v2/pkg/storage/utils/metadata"
+	"github.com/cs3org/reva/v2/pkg/utils"
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/config"
+	"github.com/pkg/errors"
+)
+
+// thumbnailsSpaceID is the id of the metadata space holding the thumbnails
+const thumbnailsSpaceID = "c9dd0c55-e561-4cca-b203-a9648f67e038"
+
+// NewCS3Storage creates a new instance of CS3 storing the thumbnails in the metadata storage
+// of the given gateway.
+func NewCS3Storage(cfg config.CS3Storage, gatewayAddr string, logger log.Logger) (*CS3, error) {
+	mds, err := metadata.NewCS3Storage(gatewayAddr, cfg.ProviderAddr, cfg.SystemUserID, cfg.SystemUserIDP, cfg.SystemUserAPIKey)
+	if err != nil {
+		return nil, err
+	}
+	return NewMetadataStorage(mds, logger), nil
+}
+
+// NewMetadataStorage creates a new instance of CS3 using the given metadata storage
+func NewMetadataStorage(mds metadata.Storage, logger log.Logger) *CS3 {
+	return &CS3{
+		mds:    mds,
+		logger: logger,
+	}
+}
+
+// CS3 represents a storage for the thumbnails in the metadata storage. All instances of the
+// service using the same metadata storage share the thumbnails.
+type CS3 struct {
+	mds    metadata.Storage
+	logger log.Logger
+
+	// the metadata storage might not be ready when the service starts, so the space is initialized lazily
+	initMu      sync.Mutex
+	initialized bool
+	// directories known to exist, creating them is a round trip to the storage
+	dirs sync.Map
+}
+
+func (s *CS3) Stat(key string) bool {
+	if err := s.init(); err != nil {
+		return false
+	}
+	_, err := s.mds.Stat(context.Background(), key)
+	return err == nil
+}
+
+func (s *CS3) Get(key string) ([]byte, error) {
+	if err := s.init(); err != nil {
+		return nil, err
+	}
+	content, err := s.mds.SimpleDownload(context.Background(), key)
+	if err != nil {
+		s.logger.Debug().Str("err", err.Error()).Str("key", key).Msg("could not load thumbnail from store")
+		return nil, err
+	}
+	return content, nil
+}
+
+func (s *CS3) Put(key string, img []byte) error {
+	if err := s.init(); err != nil {
+		return err
+	}
+	if err := s.makeDirs(path.Dir(key)); err != nil {
+		return err
+	}
+	if err := s.mds.SimpleUpload(context.Background(), key, img); err != nil {
+		return errors.Wrapf(err, "could not upload thumbnail \"%s\"", key)
+	}
+	return nil
+}
+
+// BuildKey generate the unique key for a thumbnail.
+// The key has the same structure as the keys of the FileSystem storage and is the path
+// of the thumbnail in the metadata space.
+func (s *CS3) BuildKey(r Request) string {
+	checksum := r.Checksum
+	filetype := r.Types[0]
+	filename := strconv.Itoa(r.Resolution.Dx()) + "x" + strconv.Itoa(r.Resolution.Dy()) + "." + filetype
+
+	return path.Join(checksum[:2], checksum[2:4], checksum[4:], filename)
+}
+
+func (s *CS3) init() error {
+	s.initMu.Lock()
+	defer s.initMu.Unlock()
+
+	if s.initialized {
+		return nil
+	}
+	if err := s.mds.Init(context.Background(), thumbnailsSpaceID); err != nil {
+		s.logger.Error().Err(err).Msg("could not initialize the thumbnails space in the metadata storage")
+		return err
+	}
+	s.initialized = true
+	return nil
+}
+
+// makeDirs creates the directory and all its parents, the metadata storage only creates one level at a time
+func (s *CS3) makeDirs(dir string) error {
+	if dir == "." || dir == "/" {
+		return nil
+	}
+	if _, ok := s.dirs.Load(dir); ok {
+		return nil
+	}
+	if err := s.makeDirs(path.Dir(dir)); err != nil {
+		return err
+	}
+	if err := s.mds.MakeDirIfNotExist(context.Background(), dir); err != nil {
+		return errors.Wrapf(err, "error while creating directory %s", dir)
+	}
+	s.dirs.Store(dir, struct{}{})
+	return nil
+}
+
+// Prune removes the thumbnails which were generated before maxAge and then the oldest thumbnails
+// until the total size does not exceed maxSize. Zero values disable the limits. With dryRun the
+// thumbnails to remove are only counted.
+//
+// Unlike the FileSystem storage, the age of a thumbnail is counted from its generation, updating
+// the modification time on every download would mean uploading the thumbnail again. The
+// directories are kept, another instance might be about to store a thumbnail in them.
+func (s *CS3) Prune(maxSize uint64, maxAge time.Duration, dryRun bool) (PruneResult, error) {
+	if err := s.init(); err != nil {
+		return PruneResult{}, err
+	}
+
+	var files []cachedFile
+	if err := s.listFiles(".", &files); err != nil {
+		return PruneResult{}, errors.Wrap(err, "could not list the thumbnails")
+	}
+
+	return prune(files, maxSize, maxAge, dryRun, func(f cachedFile) error {
+		err := s.mds.Delete(context.Background(), f.path)
+		var notFound errtypes.IsNotFound
+		// another instance might have removed the thumbnail in the meantime
+		if err != nil && !errors.As(err, &notFound) {
+			return errors.Wrapf(err, "could not remove thumbnail %s", f.path)
+		}
+		return nil
+	})
+}
+
+// listFiles adds the thumbnails in the directory and its subdirectories to files
+func (s *CS3) listFiles(dir string, files *[]cachedFile) error {
+	infos, err := s.mds.ListDir(context.Background(), dir)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		p := path.Join(dir, info.GetName())
+		if info.GetType() == provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+			if err := s.listFiles(p, files); err != nil {
+				return err
+			}
+			continue
+		}
+		*files = append(*files, cachedFile{path: p, size: int64(info.GetSize()), lastAccess: utils.TSToTime(info.GetMtime())})
+	}
+	return nil
+}
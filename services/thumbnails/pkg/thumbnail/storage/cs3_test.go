@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"context"
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/v2/pkg/storage/utils/metadata"
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCS3Storage(t *testing.T) {
+	dir := t.TempDir()
+	newStorage := func() *CS3 {
+		mds, err := metadata.NewDiskStorage(dir)
+		require.NoError(t, err)
+		return NewMetadataStorage(mds, log.NewLogger())
+	}
+
+	s := newStorage()
+	key := s.BuildKey(Request{
+		Checksum:   "979f4c8db98f7b82e768ef478d3c8612",
+		Types:      []string{"png"},
+		Resolution: image.Rect(0, 0, 32, 32),
+	})
+	assert.Equal(t, "97/9f/4c8db98f7b82e768ef478d3c8612/32x32.png", key)
+
+	assert.False(t, s.Stat(key))
+	require.NoError(t, s.Put(key, []byte("thumbnail")))
+	assert.True(t, s.Stat(key))
+
+	// another instance using the same metadata storage finds the thumbnail
+	other := newStorage()
+	assert.True(t, other.Stat(key))
+	content, err := other.Get(key)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("thumbnail"), content)
+
+	_, err = other.Get("97/9f/missing/32x32.png")
+	assert.Error(t, err)
+}
+
+// sizedDiskStorage adds the sizes to the listings of the disk metadata storage like the CS3 metadata storage does
+type sizedDiskStorage struct {
+	metadata.Storage
+	dir string
+}
+
+func (s sizedDiskStorage) ListDir(ctx context.Context, p string) ([]*provider.ResourceInfo, error) {
+	infos, err := s.Storage.ListDir(ctx, p)
+	for _, info := range infos {
+		if fi, err := os.Stat(filepath.Join(s.dir, p, info.GetName())); err == nil && !fi.IsDir() {
+			info.Size = uint64(fi.Size())
+		}
+	}
+	return infos, err
+}
+
+func TestCS3StoragePrune(t *testing.T) {
+	now := time.Now()
+	setup := func(t *testing.T) *CS3 {
+		dir := t.TempDir()
+		mds, err := metadata.NewDiskStorage(dir)
+		require.NoError(t, err)
+		s := NewMetadataStorage(sizedDiskStorage{Storage: mds, dir: dir}, log.NewLogger())
+		for key, age := range map[string]time.Duration{
+			"aa/bb/old/32x32.png":    48 * time.Hour,
+			"aa/cc/recent/32x32.png": time.Hour,
+			"aa/dd/new/32x32.png":    0,
+		} {
+			require.NoError(t, s.Put(key, make([]byte, 100)))
+			ts := now.Add(-age)
+			require.NoError(t, os.Chtimes(filepath.Join(dir, key), ts, ts))
+		}
+		return s
+	}
+
+	t.Run("max age", func(t *testing.T) {
+		s := setup(t)
+		res, err := s.Prune(0, 24*time.Hour, false)
+		require.NoError(t, err)
+		assert.Equal(t, PruneResult{Files: 3, Bytes: 300, RemovedFiles: 1, RemovedBytes: 100}, res)
+		assert.False(t, s.Stat("aa/bb/old/32x32.png"))
+		assert.True(t, s.Stat("aa/cc/recent/32x32.png"))
+
+		// the directories are kept, the thumbnail can be stored again
+		require.NoError(t, s.Put("aa/bb/old/32x32.png", []byte("thumbnail")))
+	})
+
+	t.Run("max size removes the oldest", func(t *testing.T) {
+		s := setup(t)
+		res, err := s.Prune(150, 0, false)
+		require.NoError(t, err)
+		assert.Equal(t, 2, res.RemovedFiles)
+		assert.False(t, s.Stat("aa/bb/old/32x32.png"))
+		assert.False(t, s.Stat("aa/cc/recent/32x32.png"))
+		assert.True(t, s.Stat("aa/dd/new/32x32.png"))
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		s := setup(t)
+		res, err := s.Prune(0, 24*time.Hour, true)
+		require.NoError(t, err)
+		assert.Equal(t, 1, res.RemovedFiles)
+		assert.True(t, s.Stat("aa/bb/old/32x32.png"))
+	})
+}
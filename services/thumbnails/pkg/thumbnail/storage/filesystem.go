@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"time"
 
 	"github.com/owncloud/ocis/v2/ocis-pkg/log"
 	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/config"
@@ -46,6 +47,12 @@ func (s FileSystem) Get(key string) ([]byte, error) {
 		}
 		return nil, err
 	}
+
+	// the modification time tracks the last access, the least recently used thumbnails are pruned first
+	now := time.Now()
+	if err := os.Chtimes(img, now, now); err != nil {
+		s.logger.Debug().Str("err", err.Error()).Str("key", key).Msg("could not update the last access of the thumbnail")
+	}
 	return content, nil
 }
 
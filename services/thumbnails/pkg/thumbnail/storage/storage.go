@@ -1,7 +1,11 @@
 package storage
 
 import (
+	"fmt"
 	"image"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/config"
 )
 
 // Request combines different attributes needed for storage operations.
@@ -24,3 +28,19 @@ type Storage interface {
 	Put(string, []byte) error
 	BuildKey(Request) string
 }
+
+// New returns the storage configured for the thumbnails.
+func New(cfg config.Thumbnail, logger log.Logger) (Storage, error) {
+	switch cfg.Storage {
+	case "", "filesystem":
+		return NewFileSystemStorage(cfg.FileSystemStorage, logger), nil
+	case "cs3":
+		s, err := NewCS3Storage(cfg.CS3Storage, cfg.RevaGateway, logger)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("unknown thumbnail storage: %s", cfg.Storage)
+	}
+}
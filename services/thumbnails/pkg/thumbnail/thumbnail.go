@@ -104,14 +104,15 @@ func mapToStorageRequest(r Request) storage.Request {
 	}
 }
 
-// IsMimeTypeSupported validate if the mime type is supported
-func IsMimeTypeSupported(m string) bool {
+// IsMimeTypeSupported validate if the mime type is in the given set of supported mime types,
+// e.g. SupportedMimeTypes
+func IsMimeTypeSupported(m string, supported map[string]struct{}) bool {
 	mimeType, _, err := mime.ParseMediaType(m)
 	if err != nil {
 		return false
 	}
-	_, supported := SupportedMimeTypes[mimeType]
-	return supported
+	_, ok := supported[mimeType]
+	return ok
 }
 
 // PrepareRequest prepare the request based on image parameters
@@ -10,6 +10,7 @@ import (
 	"github.com/cs3org/reva/v2/pkg/store"
 	"github.com/oklog/run"
 	"github.com/owncloud/ocis/v2/ocis-pkg/config/configlog"
+	ocisevent "github.com/owncloud/ocis/v2/ocis-pkg/event"
 	"github.com/owncloud/ocis/v2/ocis-pkg/handlers"
 	"github.com/owncloud/ocis/v2/ocis-pkg/registry"
 	"github.com/owncloud/ocis/v2/ocis-pkg/service/debug"
@@ -29,6 +30,9 @@ import (
 var _registeredEvents = []events.Unmarshaller{
 	// file related
 	events.PostprocessingStepFinished{},
+	ocisevent.FileInfected{},
+	ocisevent.PostprocessingFailed{},
+	ocisevent.SavedSearchMatched{},
 
 	// space related
 	events.SpaceDisabled{},
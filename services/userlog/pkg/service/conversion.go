@@ -20,6 +20,7 @@ import (
 	"github.com/cs3org/reva/v2/pkg/storagespace"
 	"github.com/cs3org/reva/v2/pkg/utils"
 	"github.com/leonelquinteros/gotext"
+	ocisevent "github.com/owncloud/ocis/v2/ocis-pkg/event"
 	ehmsg "github.com/owncloud/ocis/v2/protogen/gen/ocis/messages/eventhistory/v0"
 )
 
@@ -106,10 +107,23 @@ func (c *Converter) ConvertEvent(event *ehmsg.Event) (OC10Notification, error) {
 			res := ev.Result.(events.VirusscanResult)
 			return c.virusMessage(event.Id, VirusFound, ev.ExecutingUser, res.ResourceID, ev.Filename, res.Description, res.Scandate)
 		case events.PPStepPolicies:
-			return c.policiesMessage(event.Id, PoliciesEnforced, ev.ExecutingUser, ev.Filename, time.Now())
+			reason, err := policiesReason(ev.Result)
+			if err != nil {
+				return OC10Notification{}, err
+			}
+			if reason != "" {
+				return c.policiesMessage(event.Id, PoliciesEnforcedWithReason, ev.ExecutingUser, ev.Filename, reason, time.Now())
+			}
+			return c.policiesMessage(event.Id, PoliciesEnforced, ev.ExecutingUser, ev.Filename, "", time.Now())
 		default:
 			return OC10Notification{}, fmt.Errorf("unknown postprocessing step: %s", ev.FinishedStep)
 		}
+	case ocisevent.FileInfected:
+		return c.virusMessage(event.Id, VirusFoundInExistingFile, ev.Owner, ev.ResourceID, ev.Filename, ev.Description, ev.Scandate)
+	case ocisevent.PostprocessingFailed:
+		return c.processingFailedMessage(event.Id, ProcessingFailed, ev.ExecutingUser, ev.ResourceID, ev.Filename, string(ev.FailedStep), ev.Retries, ev.Timestamp)
+	case ocisevent.SavedSearchMatched:
+		return c.savedSearchMessage(event.Id, SavedSearchMatched, ev.ResourceID, ev.Filename, ev.SavedSearchID, ev.SavedSearchName, ev.Timestamp)
 	// space related
 	case events.SpaceDisabled:
 		return c.spaceMessage(event.Id, SpaceDisabled, ev.Executant, ev.ID.GetOpaqueId(), ev.Timestamp)
@@ -275,9 +289,10 @@ func (c *Converter) virusMessage(eventid string, nt NotificationTemplate, execut
 	}, nil
 }
 
-func (c *Converter) policiesMessage(eventid string, nt NotificationTemplate, executant *user.User, filename string, ts time.Time) (OC10Notification, error) {
+func (c *Converter) processingFailedMessage(eventid string, nt NotificationTemplate, executant *user.User, rid *storageprovider.ResourceId, filename string, step string, retries int, ts time.Time) (OC10Notification, error) {
 	subj, subjraw, msg, msgraw, err := composeMessage(nt, c.locale, c.translationPath, map[string]interface{}{
 		"resourcename": filename,
+		"step":         step,
 	})
 	if err != nil {
 		return OC10Notification{}, err
@@ -287,6 +302,48 @@ func (c *Converter) policiesMessage(eventid string, nt NotificationTemplate, exe
 		"resource": map[string]string{
 			"name": filename,
 		},
+		"postprocessing": map[string]interface{}{
+			"step":    step,
+			"retries": retries,
+		},
+	}
+
+	var resourceID string
+	if rid != nil {
+		resourceID = storagespace.FormatResourceID(*rid)
+	}
+
+	return OC10Notification{
+		EventID:        eventid,
+		Service:        c.serviceName,
+		UserName:       executant.GetUsername(),
+		Timestamp:      ts.Format(time.RFC3339Nano),
+		ResourceID:     resourceID,
+		ResourceType:   _resourceTypeResource,
+		Subject:        subj,
+		SubjectRaw:     subjraw,
+		Message:        msg,
+		MessageRaw:     msgraw,
+		MessageDetails: dets,
+	}, nil
+}
+
+func (c *Converter) policiesMessage(eventid string, nt NotificationTemplate, executant *user.User, filename string, reason string, ts time.Time) (OC10Notification, error) {
+	subj, subjraw, msg, msgraw, err := composeMessage(nt, c.locale, c.translationPath, map[string]interface{}{
+		"resourcename": filename,
+		"reason":       reason,
+	})
+	if err != nil {
+		return OC10Notification{}, err
+	}
+
+	dets := map[string]interface{}{
+		"resource": map[string]string{
+			"name": filename,
+		},
+	}
+	if reason != "" {
+		dets["reason"] = reason
 	}
 
 	return OC10Notification{
@@ -303,6 +360,57 @@ func (c *Converter) policiesMessage(eventid string, nt NotificationTemplate, exe
 	}, nil
 }
 
+func (c *Converter) savedSearchMessage(eventid string, nt NotificationTemplate, rid *storageprovider.ResourceId, filename string, searchid string, searchname string, ts time.Time) (OC10Notification, error) {
+	subj, subjraw, msg, msgraw, err := composeMessage(nt, c.locale, c.translationPath, map[string]interface{}{
+		"resourcename": filename,
+		"searchname":   searchname,
+	})
+	if err != nil {
+		return OC10Notification{}, err
+	}
+
+	dets := map[string]interface{}{
+		"resource": map[string]string{
+			"id":   storagespace.FormatResourceID(*rid),
+			"name": filename,
+		},
+		"search": map[string]string{
+			"id":   searchid,
+			"name": searchname,
+		},
+	}
+
+	return OC10Notification{
+		EventID:        eventid,
+		Service:        c.serviceName,
+		Timestamp:      ts.Format(time.RFC3339Nano),
+		ResourceID:     storagespace.FormatResourceID(*rid),
+		ResourceType:   _resourceTypeResource,
+		Subject:        subj,
+		SubjectRaw:     subjraw,
+		Message:        msg,
+		MessageRaw:     msgraw,
+		MessageDetails: dets,
+	}, nil
+}
+
+// policiesReason returns the reasons the policies step reported for deleting the file.
+func policiesReason(result interface{}) (string, error) {
+	res, err := ocisevent.DecodePoliciesResult(result)
+	if err != nil {
+		return "", err
+	}
+
+	msgs := make([]string, 0, len(res.Reasons))
+	for _, r := range res.Reasons {
+		if r.Message == "" {
+			continue
+		}
+		msgs = append(msgs, r.Message)
+	}
+	return strings.Join(msgs, ", "), nil
+}
+
 func (c *Converter) authenticate(usr *user.User) (context.Context, error) {
 	if ctx, ok := c.contexts[usr.GetId().GetOpaqueId()]; ok {
 		return ctx, nil
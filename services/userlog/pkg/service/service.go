@@ -17,6 +17,7 @@ import (
 	"github.com/cs3org/reva/v2/pkg/rgrpc/todo/pool"
 	"github.com/cs3org/reva/v2/pkg/utils"
 	"github.com/go-chi/chi/v5"
+	ocisevent "github.com/owncloud/ocis/v2/ocis-pkg/event"
 	"github.com/owncloud/ocis/v2/ocis-pkg/log"
 	ehmsg "github.com/owncloud/ocis/v2/protogen/gen/ocis/messages/eventhistory/v0"
 	ehsvc "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/eventhistory/v0"
@@ -112,6 +113,12 @@ func (ul *UserlogService) MemorizeEvents(ch <-chan events.Event) {
 				continue
 
 			}
+		case ocisevent.FileInfected:
+			users = append(users, e.Owner.GetId().GetOpaqueId())
+		case ocisevent.PostprocessingFailed:
+			users = append(users, e.ExecutingUser.GetId().GetOpaqueId())
+		case ocisevent.SavedSearchMatched:
+			users = append(users, e.Owner.GetOpaqueId())
 		// space related // TODO: how to find spaceadmins?
 		case events.SpaceDisabled:
 			executant = e.Executant
@@ -10,11 +10,31 @@ var (
 		Message: Template("Virus found in {resource}. Upload not possible. Virus: {virus}"),
 	}
 
+	VirusFoundInExistingFile = NotificationTemplate{
+		Subject: Template("Virus found"),
+		Message: Template("Virus found in existing file {resource}. Virus: {virus}"),
+	}
+
 	PoliciesEnforced = NotificationTemplate{
 		Subject: Template("Policies enforced"),
 		Message: Template("File {resource} was deleted because it violates the policies"),
 	}
 
+	PoliciesEnforcedWithReason = NotificationTemplate{
+		Subject: Template("Policies enforced"),
+		Message: Template("File {resource} was deleted because it violates the policies: {reason}"),
+	}
+
+	ProcessingFailed = NotificationTemplate{
+		Subject: Template("Processing failed"),
+		Message: Template("Processing of file {resource} failed in step {step}. Please contact your administrator."),
+	}
+
+	SavedSearchMatched = NotificationTemplate{
+		Subject: Template("New search result"),
+		Message: Template("{resource} matches your saved search {search}"),
+	}
+
 	SpaceShared = NotificationTemplate{
 		Subject: Template("Space shared"),
 		Message: Template("{user} added you to Space {space}"),
@@ -62,6 +82,9 @@ var _placeholders = map[string]string{
 	"{space}":    "{{ .spacename }}",
 	"{resource}": "{{ .resourcename }}",
 	"{virus}":    "{{ .virusdescription }}",
+	"{reason}":   "{{ .reason }}",
+	"{search}":   "{{ .searchname }}",
+	"{step}":     "{{ .step }}",
 }
 
 // NotificationTemplate is the data structure for the notifications
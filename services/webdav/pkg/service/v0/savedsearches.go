@@ -0,0 +1,134 @@
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	revactx "github.com/cs3org/reva/v2/pkg/ctx"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	searchmsg "github.com/owncloud/ocis/v2/protogen/gen/ocis/messages/search/v0"
+	searchsvc "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/search/v0"
+	merrors "go-micro.dev/v4/errors"
+	"go-micro.dev/v4/metadata"
+)
+
+// savedSearchesResponse is the list of the saved searches of a user
+type savedSearchesResponse struct {
+	Value []*searchmsg.SavedSearch `json:"value"`
+}
+
+// ListSavedSearches is the endpoint for listing the saved searches of the current user
+func (g Webdav) ListSavedSearches(w http.ResponseWriter, r *http.Request) {
+	rsp, err := g.searchClient.ListSavedSearches(searchContext(r), &searchsvc.ListSavedSearchesRequest{})
+	if err != nil {
+		g.renderSearchError(w, r, err, "could not list the saved searches")
+		return
+	}
+
+	searches := rsp.GetSavedSearches()
+	if searches == nil {
+		searches = []*searchmsg.SavedSearch{}
+	}
+	render.JSON(w, r, savedSearchesResponse{Value: searches})
+}
+
+// SaveSearch is the endpoint for creating (POST) or updating (PUT) a saved search of the current user
+func (g Webdav) SaveSearch(w http.ResponseWriter, r *http.Request) {
+	ss := &searchmsg.SavedSearch{}
+	if err := json.NewDecoder(r.Body).Decode(ss); err != nil {
+		renderError(w, r, errBadRequest("invalid saved search: "+err.Error()))
+		return
+	}
+	// the id of the url identifies the updated search, new searches get an id from the search service
+	ss.Id = chi.URLParam(r, "id")
+
+	rsp, err := g.searchClient.SaveSearch(searchContext(r), &searchsvc.SaveSearchRequest{SavedSearch: ss})
+	if err != nil {
+		g.renderSearchError(w, r, err, "could not save the search")
+		return
+	}
+
+	if ss.Id == "" {
+		render.Status(r, http.StatusCreated)
+	}
+	render.JSON(w, r, rsp.GetSavedSearch())
+}
+
+// DeleteSavedSearch is the endpoint for deleting a saved search of the current user
+func (g Webdav) DeleteSavedSearch(w http.ResponseWriter, r *http.Request) {
+	_, err := g.searchClient.DeleteSavedSearch(searchContext(r), &searchsvc.DeleteSavedSearchRequest{Id: chi.URLParam(r, "id")})
+	if err != nil {
+		g.renderSearchError(w, r, err, "could not delete the saved search")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RunSavedSearch is the endpoint for running a saved search of the current user. The results are
+// paged with the `limit` and `offset` query parameters and returned like the results of a
+// search-files report, `facets=true` adds the facets.
+func (g Webdav) RunSavedSearch(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	req := &searchsvc.RunSavedSearchRequest{Id: chi.URLParam(r, "id")}
+
+	var offset int
+	if v := q.Get("offset"); v != "" {
+		o, err := strconv.Atoi(v)
+		if err != nil || o < 0 {
+			renderError(w, r, errBadRequest("invalid offset '"+v+"'"))
+			return
+		}
+		offset = o
+		// page tokens of the search service are offsets
+		req.PageToken = v
+	}
+	if v := q.Get("limit"); v != "" {
+		l, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			renderError(w, r, errBadRequest("invalid limit '"+v+"'"))
+			return
+		}
+		req.PageSize = int32(l)
+	}
+	if v := q.Get("facets"); v != "" {
+		f, err := strconv.ParseBool(v)
+		if err != nil {
+			renderError(w, r, errBadRequest("invalid facets '"+v+"'"))
+			return
+		}
+		req.Facets = f
+	}
+
+	rsp, err := g.searchClient.RunSavedSearch(searchContext(r), req)
+	if err != nil {
+		g.renderSearchError(w, r, err, "could not run the saved search")
+		return
+	}
+
+	g.sendSearchResponse(rsp, offset, w, r)
+}
+
+// renderSearchError renders the error returned by the search service
+func (g Webdav) renderSearchError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	e := merrors.Parse(err.Error())
+	switch e.Code {
+	case http.StatusBadRequest:
+		renderError(w, r, errBadRequest(e.Detail))
+	case http.StatusNotFound:
+		renderError(w, r, errNotFound(e.Detail))
+	default:
+		logger := g.log.SubloggerWithRequestID(r.Context())
+		logger.Error().Err(err).Msg(msg)
+		renderError(w, r, errInternalError(msg))
+	}
+}
+
+// searchContext passes the token of the request on to the search service
+func searchContext(r *http.Request) context.Context {
+	t := r.Header.Get(TokenHeader)
+	ctx := revactx.ContextSetToken(r.Context(), t)
+	return metadata.Set(ctx, revactx.TokenHeader, t)
+}
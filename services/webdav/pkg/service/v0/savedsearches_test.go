@@ -0,0 +1,127 @@
+package svc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	revactx "github.com/cs3org/reva/v2/pkg/ctx"
+	"github.com/go-chi/chi/v5"
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	searchmsg "github.com/owncloud/ocis/v2/protogen/gen/ocis/messages/search/v0"
+	searchsvc "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/search/v0"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go-micro.dev/v4/client"
+	merrors "go-micro.dev/v4/errors"
+	"go-micro.dev/v4/metadata"
+)
+
+// savedSearchClient records the saved search requests sent to the search service
+type savedSearchClient struct {
+	searchsvc.SearchProviderService
+	token string
+	save  *searchsvc.SaveSearchRequest
+	run   *searchsvc.RunSavedSearchRequest
+	err   error
+}
+
+func (c *savedSearchClient) SaveSearch(ctx context.Context, in *searchsvc.SaveSearchRequest, _ ...client.CallOption) (*searchsvc.SaveSearchResponse, error) {
+	c.token, _ = metadata.Get(ctx, revactx.TokenHeader)
+	c.save = in
+	if c.err != nil {
+		return nil, c.err
+	}
+	ss := &searchmsg.SavedSearch{Id: in.GetSavedSearch().GetId(), Name: in.GetSavedSearch().GetName(), Query: in.GetSavedSearch().GetQuery()}
+	if ss.Id == "" {
+		ss.Id = "new"
+	}
+	return &searchsvc.SaveSearchResponse{SavedSearch: ss}, nil
+}
+
+func (c *savedSearchClient) ListSavedSearches(_ context.Context, _ *searchsvc.ListSavedSearchesRequest, _ ...client.CallOption) (*searchsvc.ListSavedSearchesResponse, error) {
+	return &searchsvc.ListSavedSearchesResponse{}, c.err
+}
+
+func (c *savedSearchClient) RunSavedSearch(_ context.Context, in *searchsvc.RunSavedSearchRequest, _ ...client.CallOption) (*searchsvc.SearchResponse, error) {
+	c.run = in
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &searchsvc.SearchResponse{Matches: []*searchmsg.Match{testMatch()}, TotalMatches: 20}, nil
+}
+
+func savedSearchesMux(c *savedSearchClient) http.Handler {
+	g := Webdav{log: log.NopLogger(), searchClient: c}
+	m := chi.NewMux()
+	m.Route("/dav/saved-searches", func(r chi.Router) {
+		r.Get("/", g.ListSavedSearches)
+		r.Post("/", g.SaveSearch)
+		r.Put("/{id}", g.SaveSearch)
+		r.Get("/{id}/results", g.RunSavedSearch)
+	})
+	return m
+}
+
+func TestSaveSearch(t *testing.T) {
+	c := &savedSearchClient{}
+	m := savedSearchesMux(c)
+
+	r := httptest.NewRequest(http.MethodPost, "/dav/saved-searches", strings.NewReader(`{"name": "invoices", "query": "name:invoice*", "alerts": true}`))
+	r.Header.Set(TokenHeader, "token")
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{"id": "new", "name": "invoices", "query": "name:invoice*"}`, w.Body.String())
+	assert.Equal(t, "token", c.token)
+	assert.True(t, c.save.GetSavedSearch().GetAlerts())
+
+	// the id of the url wins over the id of the body
+	r = httptest.NewRequest(http.MethodPut, "/dav/saved-searches/abc", strings.NewReader(`{"id": "other", "name": "invoices", "query": "invoice"}`))
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "abc", c.save.GetSavedSearch().GetId())
+
+	r = httptest.NewRequest(http.MethodPost, "/dav/saved-searches", strings.NewReader(`{"name": `))
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	c.err = merrors.BadRequest("com.owncloud.api.search", "the saved search needs a name")
+	r = httptest.NewRequest(http.MethodPost, "/dav/saved-searches", strings.NewReader(`{"query": "invoice"}`))
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "the saved search needs a name")
+}
+
+func TestListSavedSearches(t *testing.T) {
+	w := httptest.NewRecorder()
+	savedSearchesMux(&savedSearchClient{}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/dav/saved-searches", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"value": []}`, w.Body.String())
+}
+
+func TestRunSavedSearch(t *testing.T) {
+	c := &savedSearchClient{}
+	m := savedSearchesMux(c)
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/dav/saved-searches/abc/results?limit=5&offset=10&facets=true", nil))
+	require.Equal(t, http.StatusMultiStatus, w.Code)
+	assert.Equal(t, &searchsvc.RunSavedSearchRequest{Id: "abc", PageSize: 5, PageToken: "10", Facets: true}, c.run)
+	assert.Equal(t, "rows 10-10/20", w.Header().Get("Content-Range"))
+	assert.Contains(t, w.Body.String(), "<oc:name>holiday.jpg</oc:name>")
+
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/dav/saved-searches/abc/results?offset=-1", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	c.err = merrors.NotFound("com.owncloud.api.search", "saved search abc")
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/dav/saved-searches/abc/results", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -12,7 +12,6 @@ import (
 	"time"
 
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
-	revactx "github.com/cs3org/reva/v2/pkg/ctx"
 	"github.com/cs3org/reva/v2/pkg/storagespace"
 	"github.com/cs3org/reva/v2/pkg/tags"
 	"github.com/cs3org/reva/v2/pkg/utils"
@@ -22,7 +21,6 @@ import (
 	"github.com/owncloud/ocis/v2/services/webdav/pkg/prop"
 	"github.com/owncloud/ocis/v2/services/webdav/pkg/propfind"
 	merrors "go-micro.dev/v4/errors"
-	"go-micro.dev/v4/metadata"
 )
 
 const (
@@ -46,13 +44,16 @@ func (g Webdav) Search(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	t := r.Header.Get(TokenHeader)
-	ctx := revactx.ContextSetToken(r.Context(), t)
-	ctx = metadata.Set(ctx, revactx.TokenHeader, t)
+	ctx := searchContext(r)
 
 	req := &searchsvc.SearchRequest{
 		Query:    rep.SearchFiles.Search.Pattern,
 		PageSize: int32(rep.SearchFiles.Search.Limit),
+		Facets:   rep.SearchFiles.Search.Facets,
+	}
+	// page tokens of the search service are offsets
+	if rep.SearchFiles.Search.Offset > 0 {
+		req.PageToken = strconv.Itoa(rep.SearchFiles.Search.Offset)
 	}
 
 	// Limit search to the according space when searching /dav/spaces/
@@ -85,12 +86,12 @@ func (g Webdav) Search(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	g.sendSearchResponse(rsp, w, r)
+	g.sendSearchResponse(rsp, rep.SearchFiles.Search.Offset, w, r)
 }
 
-func (g Webdav) sendSearchResponse(rsp *searchsvc.SearchResponse, w http.ResponseWriter, r *http.Request) {
+func (g Webdav) sendSearchResponse(rsp *searchsvc.SearchResponse, offset int, w http.ResponseWriter, r *http.Request) {
 	logger := g.log.SubloggerWithRequestID(r.Context())
-	responsesXML, err := multistatusResponse(r.Context(), rsp.Matches)
+	responsesXML, err := multistatusResponse(r.Context(), rsp.Matches, rsp.Facets)
 	if err != nil {
 		logger.Error().Err(err).Msg("error formatting propfind")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -99,7 +100,7 @@ func (g Webdav) sendSearchResponse(rsp *searchsvc.SearchResponse, w http.Respons
 	w.Header().Set(net.HeaderDav, "1, 3, extended-mkcol")
 	w.Header().Set(net.HeaderContentType, "application/xml; charset=utf-8")
 	if len(rsp.Matches) > 0 {
-		w.Header().Set(net.HeaderContentRange, fmt.Sprintf("rows 0-%d/%d", len(rsp.Matches)-1, rsp.TotalMatches))
+		w.Header().Set(net.HeaderContentRange, fmt.Sprintf("rows %d-%d/%d", offset, offset+len(rsp.Matches)-1, rsp.TotalMatches))
 	}
 	w.WriteHeader(http.StatusMultiStatus)
 	if _, err := w.Write(responsesXML); err != nil {
@@ -107,8 +108,8 @@ func (g Webdav) sendSearchResponse(rsp *searchsvc.SearchResponse, w http.Respons
 	}
 }
 
-// multistatusResponse converts a list of matches into a multistatus response string
-func multistatusResponse(ctx context.Context, matches []*searchmsg.Match) ([]byte, error) {
+// multistatusResponse converts a list of matches and the facets into a multistatus response string
+func multistatusResponse(ctx context.Context, matches []*searchmsg.Match, facets []*searchmsg.Facet) ([]byte, error) {
 	responses := make([]*propfind.ResponseXML, 0, len(matches))
 	for i := range matches {
 		res, err := matchToPropResponse(ctx, matches[i])
@@ -118,8 +119,18 @@ func multistatusResponse(ctx context.Context, matches []*searchmsg.Match) ([]byt
 		responses = append(responses, res)
 	}
 
-	msr := propfind.NewMultiStatusResponseXML()
+	msr := searchMultiStatusXML{MultiStatusResponseXML: propfind.NewMultiStatusResponseXML()}
 	msr.Responses = responses
+	if len(facets) > 0 {
+		msr.Facets = &facetsXML{}
+	}
+	for _, f := range facets {
+		fx := facetXML{Name: f.GetName()}
+		for _, v := range f.GetValues() {
+			fx.Values = append(fx.Values, facetValueXML{Count: v.GetCount(), Value: v.GetValue()})
+		}
+		msr.Facets.Facets = append(msr.Facets.Facets, fx)
+	}
 	msg, err := xml.Marshal(msr)
 	if err != nil {
 		return nil, err
@@ -192,6 +203,7 @@ func matchToPropResponse(ctx context.Context, match *searchmsg.Match) (*propfind
 
 	t := tags.New(match.Entity.Tags...)
 	propstatOK.Prop = append(propstatOK.Prop, prop.Escaped("oc:tags", t.AsList()))
+	propstatOK.Prop = append(propstatOK.Prop, metadataProps(match.Entity)...)
 
 	// those seem empty - bug?
 	propstatOK.Prop = append(propstatOK.Prop, prop.Escaped("d:getetag", match.Entity.Etag))
@@ -209,6 +221,10 @@ func matchToPropResponse(ctx context.Context, match *searchmsg.Match) (*propfind
 	score := strconv.FormatFloat(float64(match.Score), 'f', -1, 64)
 	propstatOK.Prop = append(propstatOK.Prop, prop.Escaped("oc:score", score))
 
+	if len(match.Highlights) > 0 {
+		propstatOK.Prop = append(propstatOK.Prop, prop.Escaped("oc:highlights", strings.Join(match.Highlights, " … ")))
+	}
+
 	if len(propstatOK.Prop) > 0 {
 		response.Propstat = append(response.Propstat, propstatOK)
 	}
@@ -216,6 +232,53 @@ func matchToPropResponse(ctx context.Context, match *searchmsg.Match) (*propfind
 	return &response, nil
 }
 
+// metadataProps returns the audio, image, location, photo and office metadata of an entity.
+// The metadata is rendered as nested properties, empty values are left out.
+func metadataProps(e *searchmsg.Entity) []prop.PropertyXML {
+	var props []prop.PropertyXML
+	add := func(key string, values ...string) {
+		var b strings.Builder
+		for i := 0; i < len(values); i += 2 {
+			if values[i+1] != "" {
+				fmt.Fprintf(&b, "<%s>%s</%s>", values[i], prop.Escape(values[i+1]), values[i])
+			}
+		}
+		if b.Len() > 0 {
+			props = append(props, prop.Raw(key, b.String()))
+		}
+	}
+	formatUint := func(v uint64) string {
+		if v == 0 {
+			return ""
+		}
+		return strconv.FormatUint(v, 10)
+	}
+
+	if a := e.GetAudio(); a != nil {
+		add("oc:audio", "oc:album", a.Album, "oc:artist", a.Artist, "oc:duration", formatUint(a.Duration))
+	}
+	if i := e.GetImage(); i != nil {
+		add("oc:image", "oc:width", formatUint(i.Width), "oc:height", formatUint(i.Height))
+	}
+	if l := e.GetLocation(); l != nil {
+		add("oc:location",
+			"oc:latitude", strconv.FormatFloat(l.Latitude, 'f', -1, 64),
+			"oc:longitude", strconv.FormatFloat(l.Longitude, 'f', -1, 64),
+		)
+	}
+	if p := e.GetPhoto(); p != nil {
+		var taken string
+		if p.TakenDateTime != nil {
+			taken = p.TakenDateTime.AsTime().Format(time.RFC3339)
+		}
+		add("oc:photo", "oc:camera-make", p.CameraMake, "oc:camera-model", p.CameraModel, "oc:taken-date-time", taken)
+	}
+	if o := e.GetOffice(); o != nil {
+		add("oc:office", "oc:author", o.Author, "oc:page-count", formatUint(o.PageCount))
+	}
+	return props
+}
+
 type report struct {
 	SearchFiles *reportSearchFiles
 	// FilterFiles TODO add this for tag based search
@@ -231,6 +294,27 @@ type reportSearchFilesSearch struct {
 	Pattern string `xml:"pattern"`
 	Limit   int    `xml:"limit"`
 	Offset  int    `xml:"offset"`
+	Facets  bool   `xml:"facets"`
+}
+
+// searchMultiStatusXML is a multistatus response with the facets of the search
+type searchMultiStatusXML struct {
+	*propfind.MultiStatusResponseXML
+	Facets *facetsXML `xml:"oc:facets,omitempty"`
+}
+
+type facetsXML struct {
+	Facets []facetXML `xml:"oc:facet"`
+}
+
+type facetXML struct {
+	Name   string          `xml:"name,attr"`
+	Values []facetValueXML `xml:"oc:value"`
+}
+
+type facetValueXML struct {
+	Count int32  `xml:"count,attr"`
+	Value string `xml:",chardata"`
 }
 
 type reportFilterFiles struct {
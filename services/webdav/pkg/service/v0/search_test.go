@@ -0,0 +1,78 @@
+package svc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	searchmsg "github.com/owncloud/ocis/v2/protogen/gen/ocis/messages/search/v0"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func testMatch() *searchmsg.Match {
+	return &searchmsg.Match{
+		Score: 1.5,
+		Entity: &searchmsg.Entity{
+			Ref: &searchmsg.Reference{
+				ResourceId: &searchmsg.ResourceID{StorageId: "storage", SpaceId: "space"},
+				Path:       "./photos/holiday.jpg",
+			},
+			Id:               &searchmsg.ResourceID{StorageId: "storage", SpaceId: "space", OpaqueId: "file"},
+			Name:             "holiday.jpg",
+			MimeType:         "image/jpeg",
+			LastModifiedTime: timestamppb.New(time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)),
+		},
+	}
+}
+
+func TestMultistatusResponseFacets(t *testing.T) {
+	facets := []*searchmsg.Facet{
+		{Name: "mimetype", Values: []*searchmsg.FacetValue{{Value: "image/jpeg", Count: 3}, {Value: "text/plain", Count: 1}}},
+		{Name: "mtime", Values: []*searchmsg.FacetValue{{Value: "today", Count: 4}}},
+	}
+
+	b, err := multistatusResponse(context.Background(), []*searchmsg.Match{testMatch()}, facets)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `<oc:facets><oc:facet name="mimetype"><oc:value count="3">image/jpeg</oc:value><oc:value count="1">text/plain</oc:value></oc:facet><oc:facet name="mtime"><oc:value count="4">today</oc:value></oc:facet></oc:facets>`)
+
+	b, err = multistatusResponse(context.Background(), []*searchmsg.Match{testMatch()}, nil)
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), "oc:facets")
+}
+
+func TestMultistatusResponseHighlights(t *testing.T) {
+	m := testMatch()
+	b, err := multistatusResponse(context.Background(), []*searchmsg.Match{m}, nil)
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), "oc:highlights")
+
+	m.Highlights = []string{"a <mark>sunny</mark> day", "the <mark>sunny</mark> beach"}
+	b, err = multistatusResponse(context.Background(), []*searchmsg.Match{m}, nil)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "<oc:highlights>a &lt;mark&gt;sunny&lt;/mark&gt; day … the &lt;mark&gt;sunny&lt;/mark&gt; beach</oc:highlights>")
+}
+
+func TestMultistatusResponseMetadata(t *testing.T) {
+	m := testMatch()
+	m.Entity.Image = &searchmsg.Image{Width: 1920, Height: 1080}
+	m.Entity.Location = &searchmsg.GeoCoordinates{Latitude: 52.52, Longitude: 13.405}
+	m.Entity.Photo = &searchmsg.Photo{
+		CameraMake:    "Canon & Co",
+		TakenDateTime: timestamppb.New(time.Date(2022, 8, 1, 10, 30, 0, 0, time.UTC)),
+	}
+	m.Entity.Audio = &searchmsg.Audio{}
+	m.Entity.Office = &searchmsg.Office{Author: "Alice", PageCount: 3}
+
+	b, err := multistatusResponse(context.Background(), []*searchmsg.Match{m}, nil)
+	require.NoError(t, err)
+	xml := string(b)
+	assert.Contains(t, xml, "<oc:image><oc:width>1920</oc:width><oc:height>1080</oc:height></oc:image>")
+	assert.Contains(t, xml, "<oc:location><oc:latitude>52.52</oc:latitude><oc:longitude>13.405</oc:longitude></oc:location>")
+	// empty values are left out and values are escaped
+	assert.Contains(t, xml, "<oc:photo><oc:camera-make>Canon &amp; Co</oc:camera-make><oc:taken-date-time>2022-08-01T10:30:00Z</oc:taken-date-time></oc:photo>")
+	assert.Contains(t, xml, "<oc:office><oc:author>Alice</oc:author><oc:page-count>3</oc:page-count></oc:office>")
+	// metadata without any values is left out completely
+	assert.NotContains(t, xml, "oc:audio")
+}
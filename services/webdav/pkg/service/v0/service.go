@@ -114,6 +114,14 @@ func NewService(opts ...Option) (Service, error) {
 			r.Get("/webdav/*", svc.Thumbnail)
 		})
 
+		r.Route("/dav/saved-searches", func(r chi.Router) {
+			r.Get("/", svc.ListSavedSearches)
+			r.Post("/", svc.SaveSearch)
+			r.Put("/{id}", svc.SaveSearch)
+			r.Delete("/{id}", svc.DeleteSavedSearch)
+			r.Get("/{id}/results", svc.RunSavedSearch)
+		})
+
 		// r.MethodFunc("REPORT", "/remote.php/dav/files/{id}/*", svc.Search)
 
 		// This is a workaround for the go-chi concurrent map read write issue.
@@ -255,6 +263,10 @@ func (g Webdav) SpacesThumbnail(w http.ResponseWriter, r *http.Request) {
 			// StatusTooEarly if file is processing
 			renderError(w, r, errTooEarly(err.Error()))
 			return
+		case http.StatusRequestEntityTooLarge, http.StatusTooManyRequests, http.StatusGatewayTimeout:
+			// the file exceeds the limits of the thumbnails service, it is busy or the converter timed out
+			renderError(w, r, newErrResponse(int(e.Code), e.Detail))
+			return
 		case http.StatusBadRequest:
 			renderError(w, r, errBadRequest(err.Error()))
 		default:
@@ -343,6 +355,10 @@ func (g Webdav) Thumbnail(w http.ResponseWriter, r *http.Request) {
 			// StatusNotFound is expected for unsupported files
 			renderError(w, r, errNotFound(notFoundMsg(tr.Filename)))
 			return
+		case http.StatusRequestEntityTooLarge, http.StatusTooManyRequests, http.StatusGatewayTimeout:
+			// the file exceeds the limits of the thumbnails service, it is busy or the converter timed out
+			renderError(w, r, newErrResponse(int(e.Code), e.Detail))
+			return
 		case http.StatusBadRequest:
 			renderError(w, r, errBadRequest(err.Error()))
 		default:
@@ -384,6 +400,10 @@ func (g Webdav) PublicThumbnail(w http.ResponseWriter, r *http.Request) {
 			// StatusNotFound is expected for unsupported files
 			renderError(w, r, errNotFound(notFoundMsg(tr.Filename)))
 			return
+		case http.StatusRequestEntityTooLarge, http.StatusTooManyRequests, http.StatusGatewayTimeout:
+			// the file exceeds the limits of the thumbnails service, it is busy or the converter timed out
+			renderError(w, r, newErrResponse(int(e.Code), e.Detail))
+			return
 		case http.StatusBadRequest:
 			renderError(w, r, errBadRequest(err.Error()))
 		default:
@@ -425,6 +445,10 @@ func (g Webdav) PublicThumbnailHead(w http.ResponseWriter, r *http.Request) {
 			// StatusNotFound is expected for unsupported files
 			renderError(w, r, errNotFound(notFoundMsg(tr.Filename)))
 			return
+		case http.StatusRequestEntityTooLarge, http.StatusTooManyRequests, http.StatusGatewayTimeout:
+			// the file exceeds the limits of the thumbnails service, it is busy or the converter timed out
+			renderError(w, r, newErrResponse(int(e.Code), e.Detail))
+			return
 		case http.StatusBadRequest:
 			renderError(w, r, errBadRequest(err.Error()))
 		default:
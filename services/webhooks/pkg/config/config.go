@@ -0,0 +1,78 @@
+package config
+
+import (
+	"context"
+	"time"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/shared"
+)
+
+// Config combines all available configuration parts.
+type Config struct {
+	Commons *shared.Commons `yaml:"-"` // don't use this directly as configuration for a service
+
+	File string
+	Log  *Log
+
+	Debug Debug `mask:"struct" yaml:"debug"`
+
+	Service Service `yaml:"-"`
+
+	HTTP   HTTP   `yaml:"http"`
+	Events Events `yaml:"events"`
+
+	PublicURL             string          `yaml:"public_url" env:"OCIS_URL;WEBHOOKS_PUBLIC_URL" desc:"URL where oCIS is reachable for the webhook endpoints. It is used to build the callback URLs sent to the endpoints."`
+	CallbackSecret        string          `yaml:"callback_secret" env:"WEBHOOKS_CALLBACK_SECRET" desc:"The secret to sign and validate the tokens of the callback URLs. It must not be shared with other services. 'ocis init' generates it."`
+	CallbackTTL           time.Duration   `yaml:"callback_ttl" env:"WEBHOOKS_CALLBACK_TTL" desc:"How long a callback URL sent to a webhook endpoint stays valid. The duration can be set as number followed by a unit identifier like s, m or h."`
+	MaxConcurrentRequests int             `yaml:"max_concurrent_requests" env:"WEBHOOKS_MAX_CONCURRENT_REQUESTS" desc:"The maximum number of webhook requests running at the same time."`
+	Steps                 map[string]Step `yaml:"steps"`
+
+	Context context.Context `yaml:"-" json:"-"`
+}
+
+// Step configures the webhook endpoint of a postprocessing step
+type Step struct {
+	URL          string        `yaml:"url"`           // the endpoint the step metadata is posted to
+	Secret       string        `yaml:"secret"`        // signs the request body, sent as 'X-Ocis-Signature' header
+	Timeout      time.Duration `yaml:"timeout"`       // timeout of the request, 0 means no timeout
+	Insecure     bool          `yaml:"insecure"`      // skip the certificate verification of the endpoint
+	ErrorOutcome string        `yaml:"error_outcome"` // outcome published when the endpoint can't be reached, empty to publish nothing
+}
+
+// Service defines the available service configuration.
+type Service struct {
+	Name string `yaml:"-"`
+}
+
+// Log defines the available log configuration.
+type Log struct {
+	Level  string `mapstructure:"level" env:"OCIS_LOG_LEVEL;WEBHOOKS_LOG_LEVEL" desc:"The log level. Valid values are: \"panic\", \"fatal\", \"error\", \"warn\", \"info\", \"debug\", \"trace\"."`
+	Pretty bool   `mapstructure:"pretty" env:"OCIS_LOG_PRETTY;WEBHOOKS_LOG_PRETTY" desc:"Activates pretty log output."`
+	Color  bool   `mapstructure:"color" env:"OCIS_LOG_COLOR;WEBHOOKS_LOG_COLOR" desc:"Activates colorized log output."`
+	File   string `mapstructure:"file" env:"OCIS_LOG_FILE;WEBHOOKS_LOG_FILE" desc:"The path to the log file. Activates logging to this file if set."`
+}
+
+// Debug defines the available debug configuration.
+type Debug struct {
+	Addr   string `yaml:"addr" env:"WEBHOOKS_DEBUG_ADDR" desc:"Bind address of the debug server, where metrics, health, config and debug endpoints will be exposed."`
+	Token  string `yaml:"token" env:"WEBHOOKS_DEBUG_TOKEN" desc:"Token to secure the metrics endpoint."`
+	Pprof  bool   `yaml:"pprof" env:"WEBHOOKS_DEBUG_PPROF" desc:"Enables pprof, which can be used for profiling."`
+	Zpages bool   `yaml:"zpages" env:"WEBHOOKS_DEBUG_ZPAGES" desc:"Enables zpages, which can be used for collecting and viewing in-memory traces."`
+}
+
+// Events combines the configuration options for the event bus.
+type Events struct {
+	Endpoint             string `yaml:"endpoint" env:"OCIS_EVENTS_ENDPOINT;WEBHOOKS_EVENTS_ENDPOINT" desc:"The address of the event system. The event system is the message queuing service. It is used as message broker for the microservice architecture."`
+	Cluster              string `yaml:"cluster" env:"OCIS_EVENTS_CLUSTER;WEBHOOKS_EVENTS_CLUSTER" desc:"The clusterID of the event system. The event system is the message queuing service. It is used as message broker for the microservice architecture. Mandatory when using NATS as event system."`
+	TLSInsecure          bool   `yaml:"tls_insecure" env:"OCIS_INSECURE;WEBHOOKS_EVENTS_TLS_INSECURE" desc:"Whether to verify the server TLS certificates."`
+	TLSRootCACertificate string `yaml:"tls_root_ca_certificate" env:"OCIS_EVENTS_TLS_ROOT_CA_CERTIFICATE;WEBHOOKS_EVENTS_TLS_ROOT_CA_CERTIFICATE" desc:"The root CA certificate used to validate the server's TLS certificate. If provided WEBHOOKS_EVENTS_TLS_INSECURE will be seen as false."`
+	EnableTLS            bool   `yaml:"enable_tls" env:"OCIS_EVENTS_ENABLE_TLS;WEBHOOKS_EVENTS_ENABLE_TLS" desc:"Enable TLS for the connection to the events broker. The events broker is the ocis service which receives and delivers events between the services."`
+}
+
+// HTTP defines the available http configuration.
+type HTTP struct {
+	Addr      string                `yaml:"addr" env:"WEBHOOKS_HTTP_ADDR" desc:"The bind address of the HTTP service receiving the callbacks of the webhook endpoints."`
+	Namespace string                `yaml:"-"`
+	Root      string                `yaml:"root" env:"WEBHOOKS_HTTP_ROOT" desc:"Subdirectory that serves as the root for this HTTP service."`
+	TLS       shared.HTTPServiceTLS `yaml:"tls"`
+}
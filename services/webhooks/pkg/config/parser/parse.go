@@ -0,0 +1,61 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cs3org/reva/v2/pkg/events"
+	ociscfg "github.com/owncloud/ocis/v2/ocis-pkg/config"
+	"github.com/owncloud/ocis/v2/ocis-pkg/shared"
+	"github.com/owncloud/ocis/v2/services/webhooks/pkg/config"
+	"github.com/owncloud/ocis/v2/services/webhooks/pkg/config/defaults"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/config/envdecode"
+)
+
+// ParseConfig loads configuration from known paths.
+func ParseConfig(cfg *config.Config) error {
+	_, err := ociscfg.BindSourcesToStructs(cfg.Service.Name, cfg)
+	if err != nil {
+		return err
+	}
+
+	defaults.EnsureDefaults(cfg)
+
+	// load all env variables relevant to the config in the current context.
+	if err := envdecode.Decode(cfg); err != nil {
+		// no environment variable set for this config is an expected "error"
+		if !errors.Is(err, envdecode.ErrNoTargetFieldsAreSet) {
+			return err
+		}
+	}
+
+	defaults.Sanitize(cfg)
+
+	return Validate(cfg)
+}
+
+// Validate validates our little config
+func Validate(cfg *config.Config) error {
+	if cfg.CallbackSecret == "" {
+		return shared.MissingWebhooksCallbackSecretError(cfg.Service.Name)
+	}
+
+	if cfg.MaxConcurrentRequests < 1 {
+		return fmt.Errorf("max concurrent requests of service %s must be at least 1", cfg.Service.Name)
+	}
+
+	for name, step := range cfg.Steps {
+		if step.URL == "" {
+			return fmt.Errorf("no url configured for webhook step '%s'", name)
+		}
+
+		switch events.PostprocessingOutcome(step.ErrorOutcome) {
+		case "", events.PPOutcomeContinue, events.PPOutcomeAbort, events.PPOutcomeDelete:
+		default:
+			return fmt.Errorf("unknown error outcome '%s' for webhook step '%s'", step.ErrorOutcome, name)
+		}
+	}
+
+	return nil
+}
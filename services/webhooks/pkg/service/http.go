@@ -0,0 +1,66 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+)
+
+// CallbackHandler receives the outcomes of webhook endpoints answering asynchronously
+type CallbackHandler struct {
+	m  *chi.Mux
+	l  log.Logger
+	wh *Webhooks
+}
+
+// NewCallbackHandler returns the http handler for the callback API
+func NewCallbackHandler(mux *chi.Mux, root string, l log.Logger, wh *Webhooks) *CallbackHandler {
+	h := &CallbackHandler{
+		m:  mux,
+		l:  l,
+		wh: wh,
+	}
+
+	h.m.Route(root, func(r chi.Router) {
+		r.Post("/callback", h.HandleCallback)
+	})
+
+	return h
+}
+
+// ServeHTTP fulfills Handler interface
+func (h *CallbackHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.m.ServeHTTP(w, r)
+}
+
+// HandleCallback is the POST handler publishing the outcome of a step
+func (h *CallbackHandler) HandleCallback(w http.ResponseWriter, r *http.Request) {
+	var res Response
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&res); err != nil {
+		h.l.Debug().Err(err).Int("returned statuscode", http.StatusBadRequest).Msg("invalid callback body")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err := validOutcome(res.Outcome); err != nil {
+		h.l.Debug().Err(err).Int("returned statuscode", http.StatusBadRequest).Msg("invalid callback outcome")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err := h.wh.Callback(r.URL.Query().Get("token"), res.Outcome)
+	switch {
+	case errors.Is(err, ErrInvalidToken):
+		h.l.Debug().Err(err).Int("returned statuscode", http.StatusUnauthorized).Msg("invalid callback token")
+		w.WriteHeader(http.StatusUnauthorized)
+	case err != nil:
+		h.l.Error().Err(err).Int("returned statuscode", http.StatusInternalServerError).Msg("handling callback failed")
+		w.WriteHeader(http.StatusInternalServerError)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,272 @@
+package service
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/v2/pkg/events"
+	"github.com/cs3org/reva/v2/pkg/rhttp"
+	"github.com/cs3org/reva/v2/pkg/utils"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/services/webhooks/pkg/config"
+)
+
+// SignatureHeader carries the HMAC-SHA256 signature of the request body when a secret is configured for a step
+const SignatureHeader = "X-Ocis-Signature"
+
+const (
+	// CallbackIssuer is the issuer of the callback tokens
+	CallbackIssuer = "ocis-webhooks"
+	// CallbackAudience is the audience of the callback tokens
+	CallbackAudience = "ocis-webhooks-callback"
+)
+
+// ErrInvalidToken is returned when a callback token can't be validated
+var ErrInvalidToken = errors.New("invalid callback token")
+
+// Request is the body posted to the webhook endpoint of a step
+type Request struct {
+	Step        string               `json:"step"`
+	UploadID    string               `json:"uploadId"`
+	Filename    string               `json:"filename"`
+	Filesize    uint64               `json:"filesize"`
+	ResourceID  *provider.ResourceId `json:"resourceId,omitempty"`
+	User        *user.UserId         `json:"user,omitempty"`
+	DownloadURL string               `json:"downloadUrl"`
+	CallbackURL string               `json:"callbackUrl"`
+}
+
+// Response is the body returned by the webhook endpoint or posted to the callback url
+type Response struct {
+	Outcome events.PostprocessingOutcome `json:"outcome"`
+}
+
+// CallbackClaims are the claims of the token in the callback url
+type CallbackClaims struct {
+	jwt.RegisteredClaims
+	UploadID string       `json:"uploadId"`
+	Step     string       `json:"step"`
+	Filename string       `json:"filename"`
+	User     *user.UserId `json:"user"`
+}
+
+// Webhooks runs postprocessing steps by calling the configured webhook endpoints
+type Webhooks struct {
+	c       *config.Config
+	l       log.Logger
+	stream  events.Stream
+	clients map[string]*http.Client
+	sem     chan struct{}
+}
+
+// NewWebhooks returns a new webhooks service
+func NewWebhooks(c *config.Config, l log.Logger, stream events.Stream) (*Webhooks, error) {
+	wh := &Webhooks{
+		c:       c,
+		l:       l,
+		stream:  stream,
+		clients: make(map[string]*http.Client, len(c.Steps)),
+		sem:     make(chan struct{}, c.MaxConcurrentRequests),
+	}
+
+	for name, step := range c.Steps {
+		if _, err := url.Parse(step.URL); err != nil {
+			return nil, fmt.Errorf("invalid url for webhook step '%s': %w", name, err)
+		}
+		wh.clients[name] = rhttp.GetHTTPClient(rhttp.Insecure(step.Insecure), rhttp.Timeout(step.Timeout))
+	}
+
+	return wh, nil
+}
+
+// Run consumes the postprocessing events and calls the webhooks of the configured steps
+func (wh *Webhooks) Run() error {
+	ch, err := events.Consume(wh.stream, "webhooks", events.StartPostprocessingStep{})
+	if err != nil {
+		return err
+	}
+
+	for e := range ch {
+		ev, ok := e.Event.(events.StartPostprocessingStep)
+		if !ok {
+			continue
+		}
+
+		if _, ok := wh.c.Steps[string(ev.StepToStart)]; !ok {
+			continue
+		}
+
+		wh.sem <- struct{}{}
+		go func() {
+			defer func() { <-wh.sem }()
+			wh.handle(ev)
+		}()
+	}
+
+	return nil
+}
+
+// Callback publishes the outcome posted to a callback url
+func (wh *Webhooks) Callback(token string, outcome events.PostprocessingOutcome) error {
+	claims := &CallbackClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return []byte(wh.c.CallbackSecret), nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidToken, err)
+	}
+	if !claims.VerifyIssuer(CallbackIssuer, true) || !claims.VerifyAudience(CallbackAudience, true) {
+		return fmt.Errorf("%w: unexpected issuer or audience", ErrInvalidToken)
+	}
+
+	if err := validOutcome(outcome); err != nil {
+		return err
+	}
+
+	return wh.finish(claims.UploadID, claims.Step, claims.Filename, claims.User, outcome)
+}
+
+// handle calls the webhook of a step and publishes its outcome if it is returned synchronously
+func (wh *Webhooks) handle(ev events.StartPostprocessingStep) {
+	step := string(ev.StepToStart)
+	logger := wh.l.With().Str("uploadid", ev.UploadID).Str("step", step).Logger()
+
+	outcome, err := wh.call(ev)
+	switch {
+	case err != nil:
+		logger.Error().Err(err).Msg("calling webhook failed")
+		outcome = events.PostprocessingOutcome(wh.c.Steps[step].ErrorOutcome)
+		if outcome == "" {
+			// the postprocessing service handles the step like any other step that doesn't finish
+			return
+		}
+	case outcome == "":
+		logger.Debug().Msg("webhook accepted the request, waiting for callback")
+		return
+	}
+
+	if err := wh.finish(ev.UploadID, step, ev.Filename, ev.ExecutingUser.GetId(), outcome); err != nil {
+		logger.Error().Err(err).Msg("cannot publish postprocessing step finished event")
+	}
+}
+
+// call posts the step to its webhook. It returns an empty outcome when the endpoint will answer via callback.
+func (wh *Webhooks) call(ev events.StartPostprocessingStep) (events.PostprocessingOutcome, error) {
+	step := string(ev.StepToStart)
+	cfg := wh.c.Steps[step]
+
+	callbackURL, err := wh.callbackURL(ev)
+	if err != nil {
+		return "", err
+	}
+
+	body, err := json.Marshal(Request{
+		Step:        step,
+		UploadID:    ev.UploadID,
+		Filename:    ev.Filename,
+		Filesize:    ev.Filesize,
+		ResourceID:  ev.ResourceID,
+		User:        ev.ExecutingUser.GetId(),
+		DownloadURL: ev.URL,
+		CallbackURL: callbackURL,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if cfg.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(cfg.Secret, body))
+	}
+
+	res, err := wh.clients[step].Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusAccepted:
+		return "", nil
+	case http.StatusOK:
+	default:
+		return "", fmt.Errorf("unexpected status code from webhook %v", res.StatusCode)
+	}
+
+	var r Response
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&r); err != nil {
+		return "", fmt.Errorf("invalid response from webhook: %w", err)
+	}
+
+	return r.Outcome, validOutcome(r.Outcome)
+}
+
+// callbackURL returns the url the webhook endpoint can post the outcome to
+func (wh *Webhooks) callbackURL(ev events.StartPostprocessingStep) (string, error) {
+	now := time.Now()
+	claims := CallbackClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    CallbackIssuer,
+			Audience:  jwt.ClaimStrings{CallbackAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(wh.c.CallbackTTL)),
+		},
+		UploadID: ev.UploadID,
+		Step:     string(ev.StepToStart),
+		Filename: ev.Filename,
+		User:     ev.ExecutingUser.GetId(),
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(wh.c.CallbackSecret))
+	if err != nil {
+		return "", err
+	}
+
+	return wh.c.PublicURL + wh.c.HTTP.Root + "/callback?token=" + url.QueryEscape(token), nil
+}
+
+func (wh *Webhooks) finish(uploadID, step, filename string, executant *user.UserId, outcome events.PostprocessingOutcome) error {
+	return events.Publish(wh.stream, events.PostprocessingStepFinished{
+		UploadID:      uploadID,
+		ExecutingUser: &user.User{Id: executant},
+		Filename:      filename,
+		FinishedStep:  events.Postprocessingstep(step),
+		Outcome:       outcome,
+		Timestamp:     utils.TSNow(),
+	})
+}
+
+// Sign returns the value of the signature header for a request body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func validOutcome(o events.PostprocessingOutcome) error {
+	switch o {
+	case events.PPOutcomeContinue, events.PPOutcomeAbort, events.PPOutcomeDelete:
+		return nil
+	default:
+		return fmt.Errorf("unknown outcome '%s'", o)
+	}
+}
@@ -0,0 +1,192 @@
+package service_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	userv1beta1 "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/v2/pkg/events"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/services/webhooks/pkg/config"
+	"github.com/owncloud/ocis/v2/services/webhooks/pkg/service"
+	microevents "go-micro.dev/v4/events"
+)
+
+var _ = Describe("Webhooks", func() {
+	var (
+		cfg       *config.Config
+		bus       *testBus
+		requests  chan service.Request
+		status    int
+		endpoint  *httptest.Server
+		callbacks http.Handler
+	)
+
+	BeforeEach(func() {
+		requests = make(chan service.Request, 1)
+		status = http.StatusOK
+		endpoint = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			Expect(r.Header.Get(service.SignatureHeader)).To(Equal(service.Sign("secret", body)))
+
+			var req service.Request
+			Expect(json.Unmarshal(body, &req)).To(Succeed())
+			requests <- req
+
+			w.WriteHeader(status)
+			if status == http.StatusOK {
+				w.Write([]byte(`{"outcome":"abort"}`))
+			}
+		}))
+
+		cfg = &config.Config{
+			HTTP:                  config.HTTP{Root: "/api/v0/webhooks"},
+			CallbackSecret:        "callbacksecret",
+			PublicURL:             "https://localhost:9200",
+			CallbackTTL:           time.Minute,
+			MaxConcurrentRequests: 1,
+			Steps: map[string]config.Step{
+				"dlp": {URL: endpoint.URL, Secret: "secret"},
+			},
+		}
+
+		bus = &testBus{
+			in:  make(chan microevents.Event),
+			out: make(chan events.PostprocessingStepFinished, 1),
+		}
+		wh, err := service.NewWebhooks(cfg, log.NopLogger(), bus)
+		Expect(err).ToNot(HaveOccurred())
+		go wh.Run()
+
+		mux := chi.NewMux()
+		service.NewCallbackHandler(mux, cfg.HTTP.Root, log.NopLogger(), wh)
+		callbacks = mux
+	})
+
+	AfterEach(func() {
+		endpoint.Close()
+	})
+
+	It("publishes the outcome returned by the webhook", func() {
+		bus.start(events.StartPostprocessingStep{
+			UploadID:      "upload",
+			URL:           "https://localhost:9200/data/token",
+			Filename:      "file.txt",
+			ExecutingUser: &userv1beta1.User{Id: &userv1beta1.UserId{OpaqueId: "user"}},
+			StepToStart:   "dlp",
+		})
+
+		var req service.Request
+		Eventually(requests).Should(Receive(&req))
+		Expect(req.UploadID).To(Equal("upload"))
+		Expect(req.DownloadURL).To(Equal("https://localhost:9200/data/token"))
+		Expect(req.CallbackURL).To(HavePrefix("https://localhost:9200/api/v0/webhooks/callback?token="))
+
+		var ev events.PostprocessingStepFinished
+		Eventually(bus.out).Should(Receive(&ev))
+		Expect(ev.UploadID).To(Equal("upload"))
+		Expect(ev.FinishedStep).To(Equal(events.Postprocessingstep("dlp")))
+		Expect(ev.Outcome).To(Equal(events.PPOutcomeAbort))
+	})
+
+	It("publishes the outcome posted to the callback url", func() {
+		status = http.StatusAccepted
+		bus.start(events.StartPostprocessingStep{
+			UploadID:    "upload",
+			Filename:    "file.txt",
+			StepToStart: "dlp",
+		})
+
+		var req service.Request
+		Eventually(requests).Should(Receive(&req))
+		Consistently(bus.out).ShouldNot(Receive())
+
+		u, err := url.Parse(req.CallbackURL)
+		Expect(err).ToNot(HaveOccurred())
+
+		rec := httptest.NewRecorder()
+		callbacks.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, u.RequestURI(), strings.NewReader(`{"outcome":"delete"}`)))
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+
+		var ev events.PostprocessingStepFinished
+		Eventually(bus.out).Should(Receive(&ev))
+		Expect(ev.UploadID).To(Equal("upload"))
+		Expect(ev.Outcome).To(Equal(events.PPOutcomeDelete))
+	})
+
+	It("rejects callbacks with an invalid token", func() {
+		rec := httptest.NewRecorder()
+		callbacks.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v0/webhooks/callback?token=invalid", strings.NewReader(`{"outcome":"continue"}`)))
+		Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+		Expect(bus.out).ToNot(Receive())
+	})
+
+	DescribeTable("rejects callback tokens which were not issued for callbacks",
+		func(secret string, issuer string, audience string) {
+			token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, service.CallbackClaims{
+				RegisteredClaims: jwt.RegisteredClaims{
+					Issuer:    issuer,
+					Audience:  jwt.ClaimStrings{audience},
+					ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+				},
+				UploadID: "upload",
+				Step:     "dlp",
+			}).SignedString([]byte(secret))
+			Expect(err).ToNot(HaveOccurred())
+
+			rec := httptest.NewRecorder()
+			callbacks.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v0/webhooks/callback?token="+url.QueryEscape(token), strings.NewReader(`{"outcome":"continue"}`)))
+			Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+			Expect(bus.out).ToNot(Receive())
+		},
+		Entry("signed with another secret", "jwtsecret", service.CallbackIssuer, service.CallbackAudience),
+		Entry("other issuer", "callbacksecret", "ocis", service.CallbackAudience),
+		Entry("other audience", "callbacksecret", service.CallbackIssuer, "web"),
+		Entry("no issuer and audience", "callbacksecret", "", ""),
+	)
+
+	It("ignores steps without webhook", func() {
+		bus.start(events.StartPostprocessingStep{
+			UploadID:    "upload",
+			StepToStart: "other",
+		})
+
+		Consistently(requests).ShouldNot(Receive())
+	})
+})
+
+type testBus struct {
+	in  chan microevents.Event
+	out chan events.PostprocessingStepFinished
+}
+
+func (tb *testBus) Consume(_ string, _ ...microevents.ConsumeOption) (<-chan microevents.Event, error) {
+	return tb.in, nil
+}
+
+func (tb *testBus) Publish(_ string, e interface{}, _ ...microevents.PublishOption) error {
+	if ev, ok := e.(events.PostprocessingStepFinished); ok {
+		tb.out <- ev
+	}
+	return nil
+}
+
+func (tb *testBus) start(ev events.StartPostprocessingStep) {
+	b, _ := json.Marshal(ev)
+	tb.in <- microevents.Event{
+		Payload: b,
+		Metadata: map[string]string{
+			events.MetadatakeyEventType: reflect.TypeOf(ev).String(),
+		},
+	}
+}